@@ -26,6 +26,10 @@ func codesOk(code int) (int, string) {
 		return http.StatusOK, "Logged Out!"
 	case 1003:
 		return http.StatusOK, "Logged In!"
+	case 1004: // Resource has not Changed (ETag Matched)
+		return http.StatusNotModified, "Not Modified"
+	case 1005: // Request Accepted (Processed in the Background)
+		return http.StatusAccepted, "Accepted"
 	case 1099:
 		return http.StatusOK, "Contact System Administrator."
 	case 1998: // TODO Set Proper Error Code
@@ -69,6 +73,8 @@ func codesProcessingErrors(code int) (int, string) {
 		return http.StatusBadRequest, "User Session Active"
 	case 3004: // Session Not Registered
 		return http.StatusBadRequest, "Not a Registered User Session"
+	case 3005: // Invalid Account Recovery Key
+		return http.StatusBadRequest, "Invalid Account Recovery Key"
 	case 3010: // User is not Associated with a Company
 		return http.StatusBadRequest, "Session User is not a Company User"
 	case 3011: // User is Not Company Admin
@@ -152,6 +158,54 @@ func codesObjectErrors(code int) (int, string) {
 		return http.StatusBadRequest, "Store Access Blocked"
 	case 4204: // Store Read Only
 		return http.StatusBadRequest, "Store Read Only Mode"
+	case 4205: // Invalid Store Key
+		return http.StatusBadRequest, "Invalid Store Key"
+	case 4206: // Store Key not Escrowed
+		return http.StatusBadRequest, "Store Key not Escrowed"
+	case 4207: // Store Key Rotation in Progress
+		return http.StatusBadRequest, "Store Key Rotation in Progress"
+	case 4208: // User has no Share Key
+		return http.StatusBadRequest, "User has no Share Key"
+	case 4209: // User already Registered with Store
+		return http.StatusBadRequest, "User already Registered with Store"
+	case 4210: // Store Session Operations Used Up
+		return http.StatusBadRequest, "Store Session Exhausted"
+	case 4211: // Store Requires Credentials with Every Request
+		return http.StatusBadRequest, "Store Credentials Required"
+	case 4212: // Store Object Revision Does not Exist
+		return http.StatusBadRequest, "Object Revision does not exist"
+	case 4213: // Store Object not in Trash
+		return http.StatusBadRequest, "Object is not in Trash"
+	case 4214: // Folder Moved or Copied into Itself (or a Sub-Folder)
+		return http.StatusBadRequest, "Folder cannot be Moved or Copied into itself"
+	case 4215: // Store Attachments Quota Exceeded
+		return http.StatusBadRequest, "Store Attachment Quota Exceeded"
+	case 4216: // Store Object has no Attachment (or Empty Upload)
+		return http.StatusBadRequest, "Attachment has no Content"
+	case 4217: // Store Object Attachment Failed Verification
+		return http.StatusBadRequest, "Attachment failed Integrity Check"
+	case 4218: // Invalid Object Tags
+		return http.StatusBadRequest, "Invalid Tags"
+	case 4219: // Store Object Modified (Entity Tag does not Match 'If-Match')
+		return http.StatusPreconditionFailed, "Object was Modified"
+	case 4220: // Store Object Update (or Delete) without 'If-Match'
+		return http.StatusPreconditionRequired, "Object Version Required (If-Match)"
+	case 4221: // Import File could not be Parsed
+		return http.StatusBadRequest, "Invalid Import File"
+	case 4222: // Import File Larger than Allowed
+		return http.StatusRequestEntityTooLarge, "Import File too Large"
+	case 4223: // Store Import (Action) does not Exist
+		return http.StatusBadRequest, "Import does not exist"
+	case 4224: // Archive Passphrase Missing (or too Short)
+		return http.StatusBadRequest, "Archive Passphrase Required"
+	case 4225: // Archive could not be Decrypted with Passphrase
+		return http.StatusBadRequest, "Invalid Archive Passphrase"
+	case 4226: // Archive could not be Read (Corrupt, Truncated or Unsupported)
+		return http.StatusBadRequest, "Invalid Archive"
+	case 4227: // Store Archive Export Larger than Allowed
+		return http.StatusRequestEntityTooLarge, "Store too Large to Archive"
+	case 4228: // Store Users without Share Key (Rotated Key can't be Delivered)
+		return http.StatusConflict, "Store Users can't Receive Rotated Key"
 	case 4299: // Action not Permitted
 		return http.StatusBadRequest, "Access Denied"
 	// 4300 - 4399 : Invitation Related Error
@@ -166,6 +220,10 @@ func codesObjectErrors(code int) (int, string) {
 	// 4400 - 4499 : Invitation Related Error
 	case 4400:
 		return http.StatusBadRequest, "Template does not exist"
+	case 4401:
+		return http.StatusBadRequest, "Template not registered with Object"
+	case 4402:
+		return http.StatusBadRequest, "Object Values do not match Template"
 		// 4500 - 4599 : Request Related Error
 	case 4500:
 		return http.StatusBadRequest, "Request does not exist"
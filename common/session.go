@@ -20,17 +20,47 @@ import (
 	"time"
 )
 
+/* NOTE: Store Session Modes
+ * DEFAULT:     Store Key is kept in the session until the session expires
+ * OPERATIONS:  Store Key is kept in the session for a limited number of
+ *              operations (the session expires when they are used up)
+ * TRANSACTION: Store Key is NEVER kept in the session (the user's credentials
+ *              are required with every store request)
+ *
+ * The operation count is kept (server side) by the Store Session Registry,
+ * the count in the session is only a copy, so a replayed session cookie can't
+ * reset it.
+ */
+const (
+	STORE_SESSION_DEFAULT     uint8 = 0
+	STORE_SESSION_OPERATIONS  uint8 = 1
+	STORE_SESSION_TRANSACTION uint8 = 2
+)
+
+// Default Store Session Idle Timeout (Minutes)
+const STORE_SESSION_IDLE_DEFAULT uint16 = 5
+
+/* NOTE: Store Session Lifetime
+ * The idle timeout, maximum lifetime and whether a status check may extend
+ * the session, are taken from the store (or organization) policy when the
+ * store is opened, and are kept in the session (a policy change applies to
+ * sessions opened after the change).
+ */
+const storeSessionFlagFixed = 0x01 // Status Check can't Extend Session
+
 type StoreSession struct {
 	store      uint64 // LOCAL Key ID
-	key        []byte // Store Encryption Key
+	key        []byte // Store Encryption Key (NOT SET in Transaction Mode)
 	expiration int64  // UTC Unix Expiration Time
 	extend_by  uint16 // Default Session Expiration in Minutes
+	mode       uint8  // Store Session Mode
+	operations uint16 // Operations Left (Operations Mode - Copy of Registry Count)
+	deadline   int64  // UTC Unix Time Session can't be Extended Past (0 - No Limit)
+	fixed      bool   // Status Check can't Extend Session
+	org        uint64 // Global Organization ID (0 if not Known)
+	id         string // Store Session Registry ID (see StoreSessionRegistry)
 }
 
-/* TODO: Add Single Use Store Session
- * this could be used in stores that require high security (i.e. per transaction validation)
- */
-
 func ImportStoreSession(i string, valid_for uint16) (*StoreSession, error) {
 	if valid_for == 0 {
 		return nil, errors.New("Invalid Value for 'valid_for'")
@@ -60,10 +90,17 @@ func NewStoreSession(store uint64, key []byte, valid_for uint16) (*StoreSession,
 		return nil, errors.New("Invalid Value for 'valid_for'")
 	}
 
+	// Store Session Registry ID
+	id, e := NewStoreSessionID()
+	if e != nil {
+		return nil, e
+	}
+
 	o := &StoreSession{
 		store:     store,
 		key:       key,
 		extend_by: valid_for,
+		id:        id,
 	}
 
 	// Calculate Expiration
@@ -72,13 +109,77 @@ func NewStoreSession(store uint64, key []byte, valid_for uint16) (*StoreSession,
 }
 
 func (o *StoreSession) IsValid() bool {
-	return o.store > 0 && len(o.key) > 0 && o.expiration > 0 && o.extend_by > 0
+	if o.store == 0 || o.expiration <= 0 || o.extend_by == 0 {
+		return false
+	}
+
+	// Store Key is Only Missing in Transaction Mode
+	if o.mode == STORE_SESSION_TRANSACTION {
+		return len(o.key) == 0
+	}
+	return len(o.key) > 0 && o.mode <= STORE_SESSION_OPERATIONS
 }
 
 func (o *StoreSession) IsExpired() bool {
-	if o.IsValid() {
+	if o.IsValid() && !o.IsExhausted() {
 		et := time.Now().Unix()
-		return o.expiration < et
+		return o.expiration < et || (o.deadline > 0 && o.deadline < et)
+	}
+	return true
+}
+
+// IsExhausted Have all the Operations Allowed by the Session been Used?
+func (o *StoreSession) IsExhausted() bool {
+	return o.mode == STORE_SESSION_OPERATIONS && o.operations == 0
+}
+
+// SetMode Set Store Session Mode (operations is only used in Operations Mode)
+func (o *StoreSession) SetMode(mode uint8, operations uint16) error {
+	switch mode {
+	case STORE_SESSION_DEFAULT:
+		o.operations = 0
+	case STORE_SESSION_OPERATIONS:
+		if operations == 0 {
+			return errors.New("Invalid Value for 'operations'")
+		}
+		o.operations = operations
+	case STORE_SESSION_TRANSACTION:
+		// Store Key is not Kept in Session
+		o.key = nil
+		o.operations = 0
+	default:
+		return errors.New("Invalid Store Session Mode")
+	}
+
+	o.mode = mode
+	return nil
+}
+
+func (o *StoreSession) Mode() uint8 {
+	return o.mode
+}
+
+// IsTransactional Does every Request Require the User's Credentials?
+func (o *StoreSession) IsTransactional() bool {
+	return o.mode == STORE_SESSION_TRANSACTION
+}
+
+// Operations Operations Left in Session (Operations Mode)
+func (o *StoreSession) Operations() uint16 {
+	return o.operations
+}
+
+// Use Count an Operation against the Registered Session (FALSE if Closed or no Operations Left)
+func (o *StoreSession) Use() bool {
+	left, ok := StoreSessions().Use(o.id)
+	if !ok {
+		o.operations = 0
+		return false
+	}
+
+	// Are Operations Limited?
+	if o.mode == STORE_SESSION_OPERATIONS { // YES: Keep Copy of Registry Count
+		o.operations = left
 	}
 	return true
 }
@@ -87,6 +188,19 @@ func (o *StoreSession) Store() uint64 {
 	return o.store
 }
 
+// ID Store Session Registry ID (Empty for Sessions Created before the Registry)
+func (o *StoreSession) ID() string {
+	return o.id
+}
+
+func (o *StoreSession) Organization() uint64 {
+	return o.org
+}
+
+func (o *StoreSession) SetOrganization(org uint64) {
+	o.org = org
+}
+
 func (o *StoreSession) StoreHex() string {
 	return fmt.Sprintf("%x", o.store)
 }
@@ -117,6 +231,16 @@ func (o *StoreSession) ExpireTime() *time.Time {
 	return &t
 }
 
+// Deadline UTC Unix Time Session can't be Extended Past (0 - No Limit)
+func (o *StoreSession) Deadline() int64 {
+	return o.deadline
+}
+
+// CanExtendOnCheck Can a Store Status Check Extend the Session?
+func (o *StoreSession) CanExtendOnCheck() bool {
+	return !o.fixed
+}
+
 func (o *StoreSession) Extend(d uint16) int64 {
 	// Are we using a Different Extension Period?
 	by := d
@@ -126,7 +250,12 @@ func (o *StoreSession) Extend(d uint16) int64 {
 
 	// Calculate Expiration
 	o.expiration = time.Now().Unix()
-	o.expiration += int64(by * 60)
+	o.expiration += int64(by) * 60
+
+	// Limit Expiration to Session Lifetime
+	if o.deadline > 0 && o.expiration > o.deadline {
+		o.expiration = o.deadline
+	}
 	return o.expiration
 }
 
@@ -137,11 +266,37 @@ func (o *StoreSession) SetExtendBy(by uint16) (uint16, error) {
 		return 0, errors.New("Invalid Value for 'valid_for'")
 	}
 
+	o.extend_by = by
 	return current, nil
 }
 
+// SetLifetime Maximum Session Lifetime in Minutes, from Now (0 - No Limit)
+func (o *StoreSession) SetLifetime(minutes uint16) {
+	if minutes == 0 {
+		o.deadline = 0
+		return
+	}
+
+	o.deadline = time.Now().Unix() + int64(minutes)*60
+
+	// Limit Current Expiration
+	if o.expiration > o.deadline {
+		o.expiration = o.deadline
+	}
+}
+
+// SetExtendOnCheck Allow (or not) a Store Status Check to Extend the Session
+func (o *StoreSession) SetExtendOnCheck(extend bool) {
+	o.fixed = !extend
+}
+
 func (o *StoreSession) Export() (string, error) {
-	return fmt.Sprintf("/%s/%s/%s/", o.StoreHex(), o.KeyHex(), o.ExpireHex()), nil
+	var flags uint8
+	if o.fixed {
+		flags |= storeSessionFlagFixed
+	}
+
+	return fmt.Sprintf("/%s/%s/%s/%x/%x/%x/%x/%x/%x/%s/", o.StoreHex(), o.KeyHex(), o.ExpireHex(), o.mode, o.operations, o.extend_by, o.deadline, flags, o.org, o.id), nil
 }
 
 func (o *StoreSession) Import(v string) error {
@@ -157,7 +312,7 @@ func (o *StoreSession) Import(v string) error {
 
 	// Do we have valid number of fields?
 	parts := strings.Split(s, "/")
-	if len(parts) != 3 {
+	if len(parts) != 3 && len(parts) != 5 && len(parts) != 8 && len(parts) != 10 {
 		return errors.New("Not a valid import value in 'v'")
 	}
 
@@ -176,9 +331,62 @@ func (o *StoreSession) Import(v string) error {
 		return errors.New("Import Value 'v' contains an Invalid Expiration Timestamp")
 	}
 
+	// Session Mode (Default Mode if Basic Format)
+	var mode, operations uint64
+	if len(parts) >= 5 {
+		mode, e = strconv.ParseUint(parts[3], 16, 8)
+		if e != nil || mode > uint64(STORE_SESSION_TRANSACTION) {
+			return errors.New("Import Value 'v' contains an Invalid Session Mode")
+		}
+
+		operations, e = strconv.ParseUint(parts[4], 16, 16)
+		if e != nil {
+			return errors.New("Import Value 'v' contains an Invalid Operation Count")
+		}
+	}
+
+	// Session Lifetime (Import Defaults if not Set)
+	var deadline int64
+	var flags uint64
+	if len(parts) >= 8 {
+		idle, e := strconv.ParseUint(parts[5], 16, 16)
+		if e != nil || idle == 0 {
+			return errors.New("Import Value 'v' contains an Invalid Idle Timeout")
+		}
+		o.extend_by = uint16(idle)
+
+		deadline, e = strconv.ParseInt(parts[6], 16, 64)
+		if e != nil || deadline < 0 {
+			return errors.New("Import Value 'v' contains an Invalid Deadline")
+		}
+
+		flags, e = strconv.ParseUint(parts[7], 16, 8)
+		if e != nil {
+			return errors.New("Import Value 'v' contains Invalid Flags")
+		}
+	}
+
+	// Registry Information
+	var org uint64
+	var id string
+	if len(parts) == 10 {
+		org, e = strconv.ParseUint(parts[8], 16, 64)
+		if e != nil {
+			return errors.New("Import Value 'v' contains an Invalid Organization ID")
+		}
+
+		id = parts[9]
+	}
+
 	// Import StoreKey
 	o.store = u
 	o.key = bs
 	o.expiration = i
+	o.mode = uint8(mode)
+	o.operations = uint16(operations)
+	o.deadline = deadline
+	o.fixed = flags&storeSessionFlagFixed != 0
+	o.org = org
+	o.id = id
 	return nil
 }
@@ -0,0 +1,238 @@
+// cSpell:ignore paulo ferreira
+package common
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+/* NOTE: Store Session Registry
+ * Store sessions live in the user's (client side) session, so the server can't
+ * remove them. Every store session is registered (by a random ID) when the
+ * store is opened, and a store session that is not (or no longer) registered
+ * is treated as closed.
+ * This allows the server to close every store session that matches a user,
+ * store or organization (i.e. on logout, lock or block).
+ * The registry also keeps the operations left in operation limited sessions
+ * (see Use), as the client can replay an older session.
+ *
+ * IMPORTANT: The local registry is held in memory, so a server restart closes
+ * all store sessions, and it can't be used by servers behind a load balancer
+ * (the server uses the shared database registry, see orm.DBStoreSessionRegistry,
+ * unless configured as a single instance).
+ */
+type StoreSessionRegistry interface {
+	Register(id string, user uint64, store uint64, org uint64, operations uint16, expires int64) error
+	Touch(id string, expires int64) bool
+	Use(id string) (uint16, bool)
+	IsOpen(id string) bool
+	Close(id string) bool
+	CloseUser(user uint64) int
+	CloseStore(store uint64) int
+	CloseOrg(org uint64) int
+	CloseStoreUser(store uint64, user uint64) int
+	CloseOrgUser(org uint64, user uint64) int
+}
+
+var gStoreSessions StoreSessionRegistry = NewLocalStoreSessionRegistry()
+var gStoreSessionsLock sync.RWMutex
+
+// StoreSessions Store Session Registry
+func StoreSessions() StoreSessionRegistry {
+	gStoreSessionsLock.RLock()
+	defer gStoreSessionsLock.RUnlock()
+	return gStoreSessions
+}
+
+// SetStoreSessionRegistry Replace Store Session Registry (i.e. Shared Registry)
+func SetStoreSessionRegistry(r StoreSessionRegistry) error {
+	if r == nil {
+		return errors.New("Missing Store Session Registry")
+	}
+
+	gStoreSessionsLock.Lock()
+	defer gStoreSessionsLock.Unlock()
+	gStoreSessions = r
+	return nil
+}
+
+// NewStoreSessionID Random Store Session ID
+func NewStoreSessionID() (string, error) {
+	b := make([]byte, 16)
+	_, e := rand.Read(b)
+	if e != nil {
+		return "", e
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Registered Store Session
+type storeSessionEntry struct {
+	user       uint64 // Global User ID
+	store      uint64 // Global Store ID
+	org        uint64 // Global Organization ID (0 if not Known)
+	operations uint16 // Operations Left (if Limited)
+	limited    bool   // Are Operations Limited?
+	expires    int64  // UTC Unix Expiration Time
+}
+
+// In Memory Store Session Registry
+type LocalStoreSessionRegistry struct {
+	lock    sync.Mutex
+	entries map[string]*storeSessionEntry
+	purged  int64 // UTC Unix Time of Last Purge
+}
+
+func NewLocalStoreSessionRegistry() *LocalStoreSessionRegistry {
+	return &LocalStoreSessionRegistry{entries: make(map[string]*storeSessionEntry)}
+}
+
+// Register Store Session (operations == 0 - Operations not Limited)
+func (o *LocalStoreSessionRegistry) Register(id string, user uint64, store uint64, org uint64, operations uint16, expires int64) error {
+	if id == "" || user == 0 || store == 0 {
+		return errors.New("Invalid Store Session")
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	// Remove Expired Sessions
+	o.purge(time.Now().Unix())
+
+	o.entries[id] = &storeSessionEntry{
+		user:       user,
+		store:      store,
+		org:        org,
+		operations: operations,
+		limited:    operations > 0,
+		expires:    expires,
+	}
+	return nil
+}
+
+// Touch Update Expiration of Registered Session (FALSE if not Registered)
+func (o *LocalStoreSessionRegistry) Touch(id string, expires int64) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	entry, ok := o.entries[id]
+	if !ok || entry.expires < time.Now().Unix() {
+		return false
+	}
+
+	entry.expires = expires
+	return true
+}
+
+// Use Count an Operation against Registered Session (returns Operations Left, FALSE if Closed or none Left)
+func (o *LocalStoreSessionRegistry) Use(id string) (uint16, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	entry, ok := o.entries[id]
+	if !ok || entry.expires < time.Now().Unix() {
+		return 0, false
+	}
+
+	// Are Operations Limited?
+	if !entry.limited { // NO
+		return 0, true
+	}
+
+	// Any Operations Left?
+	if entry.operations == 0 { // NO
+		return 0, false
+	}
+
+	entry.operations--
+	return entry.operations, true
+}
+
+func (o *LocalStoreSessionRegistry) IsOpen(id string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	entry, ok := o.entries[id]
+	return ok && entry.expires >= time.Now().Unix()
+}
+
+func (o *LocalStoreSessionRegistry) Close(id string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	_, ok := o.entries[id]
+	delete(o.entries, id)
+	return ok
+}
+
+func (o *LocalStoreSessionRegistry) CloseUser(user uint64) int {
+	return o.closeIf(func(e *storeSessionEntry) bool {
+		return e.user == user
+	})
+}
+
+func (o *LocalStoreSessionRegistry) CloseStore(store uint64) int {
+	return o.closeIf(func(e *storeSessionEntry) bool {
+		return e.store == store
+	})
+}
+
+func (o *LocalStoreSessionRegistry) CloseOrg(org uint64) int {
+	return o.closeIf(func(e *storeSessionEntry) bool {
+		return e.org == org
+	})
+}
+
+func (o *LocalStoreSessionRegistry) CloseStoreUser(store uint64, user uint64) int {
+	return o.closeIf(func(e *storeSessionEntry) bool {
+		return e.store == store && e.user == user
+	})
+}
+
+func (o *LocalStoreSessionRegistry) CloseOrgUser(org uint64, user uint64) int {
+	return o.closeIf(func(e *storeSessionEntry) bool {
+		return e.org == org && e.user == user
+	})
+}
+
+func (o *LocalStoreSessionRegistry) closeIf(match func(e *storeSessionEntry) bool) int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	count := 0
+	for id, entry := range o.entries {
+		if match(entry) {
+			delete(o.entries, id)
+			count++
+		}
+	}
+	return count
+}
+
+func (o *LocalStoreSessionRegistry) purge(now int64) {
+	// Purged in the Last Minute?
+	if now-o.purged < 60 { // YES: Skip
+		return
+	}
+
+	o.purged = now
+	for id, entry := range o.entries {
+		if entry.expires < now {
+			delete(o.entries, id)
+		}
+	}
+}
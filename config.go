@@ -11,12 +11,22 @@ package main
  * along with this program.  If not, see <https://www.gnu.org/licenses/>.
  */
 
+// cSpell:ignore argon, keygen, wordlists, KEK
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/importer"
+	"github.com/objectvault/api-services/orm/keygen"
 )
 
 // CONTAINER for SERVER CONFIGURATION (GENERIC)
@@ -77,3 +87,293 @@ func loadConfiguration(path string) {
 		os.Exit(2)
 	}
 }
+
+// Configure Password Hashing (Argon2id) Cost
+func configurePasswordKDF() {
+	// Do we have a Configuration Object?
+	o, e := common.ConfigPropertyObject(Config, "security.argon2", nil, nil)
+	if e == nil && o == nil { // NO: Use Defaults
+		return
+	}
+
+	// Unset Properties use Defaults
+	d := orm.PasswordKDF()
+	t, e := common.ConfigPropertyUINT(o, "time", uint64(d.Time), e)
+	m, e := common.ConfigPropertyUINT(o, "memory", uint64(d.Memory), e)
+	p, e := common.ConfigPropertyUINT(o, "threads", uint64(d.Threads), e)
+	if e == nil {
+		if t > 0xFFFFFFFF || m > 0xFFFFFFFF || p > 0xFF {
+			e = errors.New("[security.argon2] Value out of Range")
+		} else {
+			e = orm.SetPasswordKDF(orm.Argon2Params{Time: uint32(t), Memory: uint32(m), Threads: uint8(p)})
+		}
+	}
+
+	if e != nil {
+		fmt.Printf("Error [%s]\n", e)
+		fmt.Println("ERROR: Invalid Password Hashing Configuration")
+		os.Exit(3)
+	}
+}
+
+// Configure Store Search (Searched Template Fields and Index Cache)
+func configureSearch() {
+	// Do we have a Configuration Object?
+	o, e := common.ConfigPropertyObject(Config, "search", nil, nil)
+	if e == nil && o == nil { // NO: Use Defaults
+		return
+	}
+
+	// Searched Fields (Field Name : Rank Weight)
+	var f map[string]interface{}
+	f, e = common.ConfigPropertyObject(o, "fields", nil, e)
+	if e == nil && f != nil {
+		fields := []orm.StoreSearchField{}
+		for name, v := range f {
+			w, ok := v.(float64)
+			if !ok || w < 1 || w > 0xFFFF {
+				e = fmt.Errorf("[search.fields.%s] Weight has to be between 1 and 65535", name)
+				break
+			}
+			fields = append(fields, orm.StoreSearchField{Name: name, Weight: uint16(w)})
+		}
+
+		// Highest Weight First (Stable Order for Results)
+		sort.Slice(fields, func(i, j int) bool {
+			if fields[i].Weight != fields[j].Weight {
+				return fields[i].Weight > fields[j].Weight
+			}
+			return fields[i].Name < fields[j].Name
+		})
+
+		if e == nil {
+			e = orm.SetStoreSearchFields(fields)
+		}
+	}
+
+	// Minutes an Unused Index is Cached
+	idle, e := common.ConfigPropertyUINT(o, "idle", uint64(orm.StoreSearchIdle()/time.Minute), e)
+	if e == nil {
+		e = orm.SetStoreSearchIdle(time.Duration(idle) * time.Minute)
+	}
+
+	if e != nil {
+		fmt.Printf("Error [%s]\n", e)
+		fmt.Println("ERROR: Invalid Search Configuration")
+		os.Exit(3)
+	}
+}
+
+// Configure Store Imports (Templates by Entry Kind and Limits)
+func configureImport() {
+	// Do we have a Configuration Object?
+	o, e := common.ConfigPropertyObject(Config, "import", nil, nil)
+	if e == nil && o == nil { // NO: Use Defaults
+		return
+	}
+
+	// Templates Used to Import Entry Kinds (Kind : Template Name)
+	var t map[string]interface{}
+	t, e = common.ConfigPropertyObject(o, "templates", nil, e)
+	if e == nil && t != nil {
+		for kind, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				e = fmt.Errorf("[import.templates.%s] Template Name has to be a String", kind)
+				break
+			}
+
+			e = importer.SetKindTemplate(kind, name)
+			if e != nil {
+				break
+			}
+		}
+	}
+
+	// Maximum Number of Entries Imported while the Client Waits
+	n, e := common.ConfigPropertyUINT(o, "sync-entries", uint64(importer.SyncEntries()), e)
+	if e == nil {
+		e = importer.SetSyncEntries(int(n))
+	}
+
+	// Maximum Size of Import Files (in KiB)
+	size, e := common.ConfigPropertyUINT(o, "max-size", uint64(importer.MaxSize()/1024), e)
+	if e == nil {
+		e = importer.SetMaxSize(int64(size) * 1024)
+	}
+
+	// Maximum Size of Store Archives Exported or Restored (in KiB)
+	size, e = common.ConfigPropertyUINT(o, "archive-max-size", uint64(orm.StoreArchiveMaxSize()/1024), e)
+	if e == nil {
+		e = orm.SetStoreArchiveMaxSize(int64(size) * 1024)
+	}
+
+	if e != nil {
+		fmt.Printf("Error [%s]\n", e)
+		fmt.Println("ERROR: Invalid Import Configuration")
+		os.Exit(3)
+	}
+}
+
+func configureTools() {
+	// Do we have a Configuration Object?
+	o, e := common.ConfigPropertyObject(Config, "tools", nil, nil)
+	if e == nil && o == nil { // NO: Use Defaults
+		return
+	}
+
+	// Passphrase Word Lists (Name : Path to File with Words Separated by White Space)
+	var w map[string]interface{}
+	w, e = common.ConfigPropertyObject(o, "wordlists", nil, e)
+	if e == nil && w != nil {
+		for name, v := range w {
+			path, ok := v.(string)
+			if !ok {
+				e = fmt.Errorf("[tools.wordlists.%s] Word List Path has to be a String", name)
+				break
+			}
+
+			var b []byte
+			b, e = os.ReadFile(path)
+			if e != nil {
+				break
+			}
+
+			e = keygen.SetWordlist(name, strings.Fields(string(b)))
+			if e != nil {
+				break
+			}
+		}
+	}
+
+	if e != nil {
+		fmt.Printf("Error [%s]\n", e)
+		fmt.Println("ERROR: Invalid Tools Configuration")
+		os.Exit(3)
+	}
+}
+
+// Store Session Registry Types
+const STORE_SESSIONS_DATABASE = "database"
+const STORE_SESSIONS_LOCAL = "local"
+
+/* NOTE: Store Session Registry
+ * Store sessions are registered in the database (shared by every server
+ * instance) by default. The in memory registry ("local") only works with a
+ * single server instance, so the server refuses to start with it, unless
+ * "session.registry.single-instance" is set.
+ */
+func configureStoreSessions() {
+	t, e := common.ConfigPropertyString(Config, "session.registry.type", STORE_SESSIONS_DATABASE, nil)
+	if e == nil {
+		switch t {
+		case STORE_SESSIONS_DATABASE:
+			var dbm *orm.DBSessionManager
+			dbm, e = databaseManager()
+			if e == nil {
+				var r *orm.DBStoreSessionRegistry
+				r, e = orm.NewDBStoreSessionRegistry(dbm)
+				if e == nil {
+					e = common.SetStoreSessionRegistry(r)
+				}
+			}
+		case STORE_SESSIONS_LOCAL:
+			single, ok := common.ConfigProperty(Config, "session.registry.single-instance", false).(bool)
+			if !ok || !single {
+				e = errors.New("[session.registry] Local Registry requires 'single-instance'")
+			} else {
+				e = common.SetStoreSessionRegistry(common.NewLocalStoreSessionRegistry())
+			}
+		default:
+			e = fmt.Errorf("[session.registry.type] Invalid Registry Type [%s]", t)
+		}
+	}
+
+	if e != nil {
+		fmt.Printf("Error [%s]\n", e)
+		fmt.Println("ERROR: Invalid Store Session Registry Configuration")
+		os.Exit(3)
+	}
+}
+
+/* NOTE: Key Encryption Key
+ * The KEK (wraps server secrets) is loaded once at startup, and the server
+ * refuses to start if it is configured, but can't be loaded.
+ */
+func configureKEK() orm.KEK {
+	kek, e := loadKEK()
+	if e != nil {
+		fmt.Printf("Error [%s]\n", e)
+		fmt.Println("ERROR: Invalid Key Encryption Key Configuration")
+		os.Exit(3)
+	}
+
+	return kek
+}
+
+func loadKEK() (orm.KEK, error) {
+	// Do we have a KEK Configuration Object?
+	o, e := common.ConfigPropertyObject(Config, "keys.kek", nil, nil)
+	if e != nil {
+		return nil, e
+	}
+
+	if o == nil { // NO: Server Secrets will not be Wrapped
+		log.Println("[loadKEK] No Key Encryption Key Configured")
+		return nil, nil
+	}
+
+	// Create KEK based on Type
+	var kek orm.KEK
+	t, e := common.ConfigPropertyString(o, "type", "file", nil)
+	switch t {
+	case "file": // Key File
+		var path string
+		path, e = common.ConfigPropertyString(o, "path", "", e)
+		if e == nil {
+			kek, e = orm.NewFileKEK(path)
+		}
+	case "env": // Environment Variable
+		var name string
+		name, e = common.ConfigPropertyString(o, "variable", "OV_KEK", e)
+		if e == nil {
+			kek, e = orm.NewEnvKEK(name)
+		}
+	case "kms": // Local KMS Stand-In
+		kek, e = localKMSKEK(o, e)
+	default:
+		e = errors.New("[keys.kek.type] Unknown KEK Type")
+	}
+
+	if e != nil {
+		return nil, e
+	}
+	return kek, nil
+}
+
+func localKMSKEK(o map[string]interface{}, nested error) (orm.KEK, error) {
+	id, e := common.ConfigPropertyString(o, "key", "", nested)
+	keys, e := common.ConfigPropertyObject(o, "keys", nil, e)
+	if e != nil {
+		return nil, e
+	}
+
+	// Load KMS Keys
+	kms := orm.NewLocalKMS()
+	for k, v := range keys {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("[keys.kek.keys] Key is not a string")
+		}
+
+		key, e := hex.DecodeString(s)
+		if e == nil {
+			e = kms.AddKey(k, key)
+		}
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	return orm.NewKMSKEK(kms, id)
+}
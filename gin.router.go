@@ -11,7 +11,7 @@ package main
  * along with this program.  If not, see <https://www.gnu.org/licenses/>.
  */
 
-// cSpell:ignore amqp, objs, pkginvites, pkgme, pkgorg, pkpwd, pkgsession, pkgstore, pkgsystem, sharded
+// cSpell:ignore amqp, objs, pkginvites, pkgme, pkgorg, pkpwd, pkgsession, pkgstore, pkgsystem, pkgtools, sharded
 
 import (
 	"github.com/objectvault/api-services/common"
@@ -26,6 +26,7 @@ import (
 	pkgsession "github.com/objectvault/api-services/requests/handlers/session"
 	pkgstore "github.com/objectvault/api-services/requests/handlers/store"
 	pkgsystem "github.com/objectvault/api-services/requests/handlers/system"
+	pkgtools "github.com/objectvault/api-services/requests/handlers/tools"
 
 	"github.com/gin-gonic/gin"
 )
@@ -74,28 +75,34 @@ func queueConnection() (*queue.AMQPServerConnection, error) {
 	return gQConnection, nil
 }
 
-func initializeGinSession(c *gin.Context) {
-	dbm, err := databaseManager()
-	if err != nil {
-		panic(err)
-	}
+// initializeGinSession Set Request Context Values (KEK is Loaded at Startup)
+func initializeGinSession(kek orm.KEK) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dbm, err := databaseManager()
+		if err != nil {
+			panic(err)
+		}
 
-	mq, err := queueConnection()
-	if err != nil {
-		panic(err)
-	}
+		mq, err := queueConnection()
+		if err != nil {
+			panic(err)
+		}
 
-	c.Set("dbm", dbm)
-	c.Set("mq-connection", mq)
+		c.Set("dbm", dbm)
+		c.Set("kek", kek)
+		c.Set("mq-connection", mq)
+	}
 }
 
 // GIN Router
-func ginRouter(r *gin.Engine) *gin.Engine {
+func ginRouter(r *gin.Engine, kek orm.KEK) *gin.Engine {
+	initialize := initializeGinSession(kek)
+
 	// SESSION
-	r.GET("/session", initializeGinSession, pkgsession.Hello) // IMPLEMENTED
+	r.GET("/session", initialize, pkgsession.Hello) // IMPLEMENTED
 
 	// API Version 1 Interface //
-	v1 := r.Group("/1", initializeGinSession) // *gin.RouterGroup
+	v1 := r.Group("/1", initialize) // *gin.RouterGroup
 	{
 		// SESSION MANAGEMENT //
 		session := v1.Group("/session")
@@ -108,6 +115,7 @@ func ginRouter(r *gin.Engine) *gin.Engine {
 		password := v1.Group("/password")
 		{
 			password.DELETE("/:email", pkgpwd.Recover)
+			password.GET("/:guid", pkgpwd.ResetCheck)
 			password.POST("/:guid", pkgpwd.Reset)
 		}
 
@@ -211,6 +219,8 @@ func ginRouter(r *gin.Engine) *gin.Engine {
 			organization.DELETE("/store/:store", pkgorg.DeleteStore)
 			organization.PUT("/store/:store", pkgorg.PutStoreProfile) // IMPLEMENTED: Needs Testing
 			organization.POST("/store/:store/open", pkgstore.OpenStore)
+			organization.GET("/store/:store/escrow", pkgorg.GetOrgStoreEscrow)
+			organization.POST("/store/:store/recover", pkgorg.PostOrgStoreRecover)
 			organization.GET("/store/:store/lock", pkgorg.GetOrgStoreLockState)          // IMPLEMENTED
 			organization.PUT("/store/:store/lock/:bool", pkgorg.PutOrgStoreLockState)    // IMPLEMENTED
 			organization.GET("/store/:store/block", pkgorg.GetOrgStoreBlockState)        // IMPLEMENTED
@@ -258,6 +268,9 @@ func ginRouter(r *gin.Engine) *gin.Engine {
 			store.GET("/open", pkgstore.IsStoreOpen)          // IMPLEMENTED
 			store.DELETE("/close", pkgstore.DeleteCloseStore) // IMPLEMENTED
 
+			// STORE KEY
+			store.POST("/rotate-key", pkgstore.PostRotateStoreKey)
+
 			// STORE INVITATION
 			// LIST: Use GET /invites
 
@@ -273,6 +286,7 @@ func ginRouter(r *gin.Engine) *gin.Engine {
 			store.PUT("/users/roles", pkgstore.PutStoreUsersRoles)
 
 			// SINGLE USER MANAGEMENT
+			store.POST("/user/:user", pkgstore.PostStoreUser)                      // Share Store with Existing User
 			store.GET("/user/:user", pkgstore.GetStoreUser)                        // IMPLEMENTED
 			store.DELETE("/user/:user", pkgstore.DeleteStoreUser)                  // IMPLEMENTED: Tested 20230901
 			store.GET("/user/:user/lock", pkgstore.GetStoreUserLock)               // IMPLEMENTED: Needs Testing
@@ -287,14 +301,49 @@ func ginRouter(r *gin.Engine) *gin.Engine {
 
 			// STORE OBJECT MANAGEMENT //
 			// MASS OBJECT
-			store.GET("/objs/:parent", pkgstore.GetStoreObjects) // IMPLEMENTED
-			store.DELETE("/objs", pkgstore.DeleteStoreObjects)
+			store.GET("/tree", pkgstore.GetStoreTree)                       // IMPLEMENTED: Needs Testing (Supports ETag / If-None-Match)
+			store.GET("/search", pkgstore.GetStoreSearch)                   // IMPLEMENTED: Needs Testing (Store has to be Open)
+			store.GET("/tags", pkgstore.GetStoreTags)                       // IMPLEMENTED: Needs Testing (Store has to be Open, if Tags are not Indexed)
+			store.PUT("/tags/index", pkgstore.PutStoreTagsIndex)            // IMPLEMENTED: Needs Testing (Rebuilds, or Removes, Plaintext Tag Index)
+			store.GET("/objs/:parent", pkgstore.GetStoreObjects)            // IMPLEMENTED
+			store.DELETE("/objs", pkgstore.DeleteStoreObjects)              // IMPLEMENTED: Needs Testing (Moves Objects to Trash)
+			store.PUT("/objs/:parent/move", pkgstore.PutStoreObjectsMove)   // IMPLEMENTED: Needs Testing (':parent' is the Target Folder)
+			store.POST("/objs/:parent/copy", pkgstore.PostStoreObjectsCopy) // IMPLEMENTED: Needs Testing (Optional '?store=' Target Store)
+			store.POST("/objs/tags", pkgstore.PostStoreObjectsTags)         // IMPLEMENTED: Needs Testing (Add Tags to Objects)
+			store.DELETE("/objs/tags", pkgstore.DeleteStoreObjectsTags)     // IMPLEMENTED: Needs Testing (Remove Tags from Objects)
+
+			// IMPORT
+			store.POST("/import/:parent", pkgstore.PostStoreImport) // IMPLEMENTED: Needs Testing (KeePass XML, Bitwarden JSON or 1Password/LastPass CSV '?format=')
+			store.GET("/import/:guid", pkgstore.GetStoreImport)     // IMPLEMENTED: Needs Testing (Background Import State and Report)
+
+			// EXPORT (Encrypted Archive)
+			store.GET("/export", pkgstore.GetStoreExport)                    // IMPLEMENTED: Needs Testing (Passphrase in 'X-Archive-Passphrase')
+			store.POST("/import/archive/:parent", pkgstore.PostStoreRestore) // IMPLEMENTED: Needs Testing (Restore Archive '?conflict=skip|overwrite|duplicate')
 
 			// OBJECT
-			store.GET("/obj/:object", pkgstore.GetStoreObject)             // IMPLEMENTED
-			store.POST("/obj/:parent", pkgstore.PostStoreObjectJSON)       // IMPLEMENTED
-			store.PUT("/obj/:parent/:object", pkgstore.PutStoreObjectJSON) // IMPLEMENTED
-			store.DELETE("/obj/:object", pkgstore.DeleteStoreObject)
+			store.GET("/obj/:object", pkgstore.GetStoreObject)                    // IMPLEMENTED
+			store.POST("/obj/:parent", pkgstore.PostStoreObjectJSON)              // IMPLEMENTED
+			store.PUT("/obj/:parent/:object", pkgstore.PutStoreObjectJSON)        // IMPLEMENTED
+			store.DELETE("/obj/:object", pkgstore.DeleteStoreObject)              // IMPLEMENTED: Needs Testing (Moves Object to Trash)
+			store.PUT("/obj/:parent/:object/move", pkgstore.PutStoreObjectMove)   // IMPLEMENTED: Needs Testing (':parent' is the Target Folder)
+			store.POST("/obj/:parent/:object/copy", pkgstore.PostStoreObjectCopy) // IMPLEMENTED: Needs Testing (Optional '?store=' Target Store)
+
+			// OBJECT HISTORY
+			store.GET("/obj/:object/history", pkgstore.GetStoreObjectHistory)                        // IMPLEMENTED: Needs Testing
+			store.GET("/obj/:object/history/:rev", pkgstore.GetStoreObjectRevision)                  // IMPLEMENTED: Needs Testing
+			store.POST("/obj/:parent/history/:rev/restore", pkgstore.PostStoreObjectRevisionRestore) // IMPLEMENTED: Needs Testing (':parent' is the Object)
+
+			// OBJECT ATTACHMENT
+			store.GET("/obj/:object/attachment", pkgstore.GetStoreObjectAttachment)          // IMPLEMENTED: Needs Testing (Content is Verified before Download)
+			store.GET("/obj/:object/attachment/info", pkgstore.GetStoreObjectAttachmentInfo) // IMPLEMENTED: Needs Testing
+			store.PUT("/obj/:parent/attachment", pkgstore.PutStoreObjectAttachment)          // IMPLEMENTED: Needs Testing (':parent' is the Object)
+			store.DELETE("/obj/:object/attachment", pkgstore.DeleteStoreObjectAttachment)    // IMPLEMENTED: Needs Testing
+
+			// TRASH
+			store.GET("/trash", pkgstore.GetStoreTrash)                          // IMPLEMENTED: Needs Testing
+			store.POST("/trash/:object/restore", pkgstore.PostStoreTrashRestore) // IMPLEMENTED: Needs Testing
+			store.DELETE("/trash/:object", pkgstore.DeleteStoreTrashObject)      // IMPLEMENTED: Needs Testing (Purge Object)
+			store.DELETE("/trash", pkgstore.DeleteStoreTrash)                    // IMPLEMENTED: Needs Testing (Purge Trash)
 
 			// STORE TEMPLATE MANAGEMENT //
 			store.GET("/templates", pkgstore.ListStoreTemplates)   // IMPLEMENTED - REQUIRED: List Permission to Store
@@ -328,6 +377,14 @@ func ginRouter(r *gin.Engine) *gin.Engine {
 			// STORE
 			self.GET("/stores", pkgme.GetMyStores) // IMPLEMENTED
 			self.DELETE("/store/:store", pkgme.DeleteMeFromStore)
+			self.DELETE("/stores/open", pkgme.DeleteMyOpenStores) // Close all Open Stores
+		}
+
+		tools := v1.Group("/tools")
+		{
+			// PASSWORD Generation and Strength
+			tools.POST("/password", pkgtools.PostGeneratePassword)
+			tools.POST("/password/strength", pkgtools.PostPasswordStrength)
 		}
 	}
 
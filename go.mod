@@ -13,6 +13,7 @@ require (
 	github.com/objectvault/goginrpf v0.0.7
 	github.com/objectvault/queue-interface v0.0.10
 	github.com/pjacferreira/sqlf v1.1.2-pf
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
 )
 
 require (
@@ -33,7 +34,6 @@ require (
 	github.com/rabbitmq/amqp091-go v1.8.0 // indirect
 	github.com/ugorji/go/codec v1.2.7 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
-	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e // indirect
 	golang.org/x/net v0.0.0-20220614195744-fb05da6f9022 // indirect
 	golang.org/x/sys v0.0.0-20220614162138-6c1b26c55098 // indirect
 	golang.org/x/text v0.3.7 // indirect
@@ -82,6 +82,24 @@ func main() {
 	// Load Configuration File
 	loadConfiguration(*sConfPath)
 
+	// Configure Password Hashing Cost
+	configurePasswordKDF()
+
+	// Configure Store Search
+	configureSearch()
+
+	// Configure Store Imports
+	configureImport()
+
+	// Configure Tools (Password Generator Word Lists)
+	configureTools()
+
+	// Configure Store Session Registry (Shared by Server Instances)
+	configureStoreSessions()
+
+	// Load Key Encryption Key (Wraps Server Secrets)
+	kek := configureKEK()
+
 	// After everything is Done Make Sure to Close Everything
 	defer func() {
 		fmt.Println("EXIT: Close All Connections")
@@ -94,7 +112,13 @@ func main() {
 	}
 
 	// Establish Routes
-	ginRouter(r)
+	ginRouter(r, kek)
+
+	// Purge Expired Objects from Store Trash
+	startTrashPurge()
+
+	// Mark Store Imports Interrupted by a Server Stop
+	cleanupStoreImports()
 
 	// Run Web Server //
 	// BUILD Listen Address from Server Configuration //
@@ -18,7 +18,7 @@ import (
 	"log"
 	"time"
 
-	"github.com/gofrs/uuid"
+	"github.com/objectvault/api-services/orm/keygen"
 	"github.com/objectvault/api-services/orm/mysql"
 	"github.com/objectvault/common/maps"
 	"github.com/pjacferreira/sqlf"
@@ -54,8 +54,6 @@ func NewAction(t string, creator uint64) *Action {
 		creator: &creator,
 	}
 
-	// Set UUID
-	o.GUID()
 	return o
 }
 
@@ -83,6 +81,59 @@ func (o *Action) IsValid() bool {
 	return o.guid != "" && o.atype != "" && o.creator != nil
 }
 
+// PendingActionsByCreator List GUIDs of Unprocessed Actions, of a Specific Type, Created by User
+func PendingActionsByCreator(db *sql.DB, atype string, creator uint64) ([]string, error) {
+	// Query Results Values
+	var guid string
+	var list []string
+
+	// Create SQL Statement
+	s := sqlf.From("actions").
+		Select("guid").To(&guid).
+		Where("type = ? and creator = ? and state <> ?", atype, creator, STATE_PROCESSED).
+		OrderBy("created")
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		list = append(list, guid)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return list, nil
+}
+
+// StaleActions List GUIDs of Unprocessed Actions, of a Specific Type, not Updated in the Last 'idle' Seconds
+func StaleActions(db *sql.DB, atype string, idle uint32) ([]string, error) {
+	// Query Results Values
+	var guid string
+	var list []string
+
+	// Create SQL Statement (NOTE: Compared in the Database, "modified" is Served in Local Time)
+	s := sqlf.From("actions").
+		Select("guid").To(&guid).
+		Where("type = ? and state <> ?", atype, STATE_PROCESSED).
+		Where("modified < NOW() - INTERVAL ? SECOND", idle).
+		OrderBy("created")
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		list = append(list, guid)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return list, nil
+}
+
 // ByGUID Finds Entry By GUID
 func (o *Action) ByGUID(db *sql.DB, guid string) error {
 	// Reset Entry
@@ -137,6 +188,10 @@ func (o *Action) ByGUID(db *sql.DB, guid string) error {
 		if created.Valid {
 			o.created = mysql.MySQLTimeStampToGoTime(created.String)
 		}
+
+		// Loaded Maps are not Modified
+		o.parameters.ClearModified(nil)
+		o.properties.ClearModified(nil)
 		o.stored = true
 	}
 
@@ -144,12 +199,7 @@ func (o *Action) ByGUID(db *sql.DB, guid string) error {
 }
 
 func (o *Action) GUID() string {
-	if o.guid == "" {
-		u, _ := uuid.NewV4()
-		o.guid = u.String()
-	}
 	return o.guid
-
 }
 
 func (o *Action) Parent() string {
@@ -200,7 +250,7 @@ func (o *Action) SetParent(guid string) (string, error) {
 
 func (o *Action) SetState(state uint16) error {
 	// Current State
-	current := state
+	current := o.state
 
 	// State Changed?
 	if current != state { // YES - Update
@@ -215,6 +265,10 @@ func (o *Action) SetStateQueued() error {
 	return o.SetState(STATE_QUEUED)
 }
 
+func (o *Action) SetStateProcessing() error {
+	return o.SetState(STATE_PROCESSING)
+}
+
 func (o *Action) SetStateProcessed() error {
 	return o.SetState(STATE_PROCESSED)
 }
@@ -255,6 +309,14 @@ func (o *Action) Flush(db sqlf.Executor, force bool) error {
 	// Is New Entry?
 	var e error
 	if o.IsNew() { // YES: Create
+		// Do we have a GUID?
+		if o.guid == "" { // NO: Create One (Set on Create)
+			o.guid, e = keygen.GUID()
+			if e != nil {
+				return e
+			}
+		}
+
 		// Execute Insert
 		s := sqlf.InsertInto("actions").
 			Set("guid", o.GUID()).
@@ -293,12 +355,24 @@ func (o *Action) Flush(db sqlf.Executor, force bool) error {
 			Set("state", o.state).
 			Where("guid = ?", o.guid)
 
+		// Have Action Parameters Changed?
+		if o.parameters.IsModified() { // YES
+			s.Set("params", o.parameters.Export())
+		}
+
+		// Have Action Properties Changed?
+		if o.properties.IsModified() { // YES
+			s.Set("props", o.properties.Export())
+		}
+
 		_, e = s.ExecAndClose(context.TODO(), db)
 	}
 
 	if e == nil {
 		o.stored = true
 		o.dirty = false
+		o.parameters.ClearModified(nil)
+		o.properties.ClearModified(nil)
 	}
 	return e
 }
@@ -13,4 +13,5 @@ package action
 
 const STATE_REGISTERED = 0x0000 // DEFAULT: Action Registered
 const STATE_QUEUED = 0x0010     // Action Queued
+const STATE_PROCESSING = 0x0020 // Action Being Processed
 const STATE_PROCESSED = 0x00ff  // Action Processed
@@ -0,0 +1,160 @@
+// cSpell:ignore bitwarden, paulo ferreira
+package importer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/* NOTE: Bitwarden JSON
+ * {
+ *   "encrypted": false,
+ *   "folders": [ { "id": "...", "name": "Parent/Child" }, ... ],
+ *   "items": [ {
+ *     "type": 1 (Login) | 2 (Secure Note) | 3 (Card) | 4 (Identity),
+ *     "folderId": "...", "name": "...", "notes": "...",
+ *     "login": { "username": "...", "password": "...", "totp": "...", "uris": [ { "uri": "..." } ] },
+ *     "card": { "cardholderName": "...", "brand": "...", "number": "...", "expMonth": "...", "expYear": "...", "code": "..." },
+ *     "identity": { "<field>": "...", ... },
+ *     "fields": [ { "name": "...", "value": "..." } ]
+ *   } ]
+ * }
+ * Nested folders are named with their full path ('/' separated).
+ * Identities are imported as notes (with the identity fields as custom
+ * fields).
+ */
+
+type bitwardenFile struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []struct {
+		Type     int     `json:"type"`
+		FolderID *string `json:"folderId"`
+		Name     string  `json:"name"`
+		Notes    *string `json:"notes"`
+		Login    *struct {
+			Username *string `json:"username"`
+			Password *string `json:"password"`
+			TOTP     *string `json:"totp"`
+			URIs     []struct {
+				URI *string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Card *struct {
+			Cardholder *string `json:"cardholderName"`
+			Brand      *string `json:"brand"`
+			Number     *string `json:"number"`
+			ExpMonth   *string `json:"expMonth"`
+			ExpYear    *string `json:"expYear"`
+			Code       *string `json:"code"`
+		} `json:"card"`
+		Identity map[string]interface{} `json:"identity"`
+		Fields   []struct {
+			Name  *string `json:"name"`
+			Value *string `json:"value"`
+		} `json:"fields"`
+	} `json:"items"`
+}
+
+// Bitwarden Item Types
+const bitwardenLogin = 1
+const bitwardenNote = 2
+const bitwardenCard = 3
+const bitwardenIdentity = 4
+
+func parseBitwarden(r io.Reader) ([]*Entry, error) {
+	f := &bitwardenFile{}
+	e := json.NewDecoder(r).Decode(f)
+	if e != nil {
+		return nil, e
+	}
+
+	if f.Encrypted {
+		return nil, errors.New("Encrypted Bitwarden Exports are not Supported")
+	}
+
+	// Folder Paths by Folder ID
+	folders := map[string][]string{}
+	for _, folder := range f.Folders {
+		folders[folder.ID] = splitPath(folder.Name, "/")
+	}
+
+	entries := []*Entry{}
+	for _, item := range f.Items {
+		entry := &Entry{
+			Folder: []string{},
+			Title:  item.Name,
+		}
+
+		if item.FolderID != nil {
+			if path, ok := folders[*item.FolderID]; ok {
+				entry.Folder = path
+			}
+		}
+
+		switch item.Type {
+		case bitwardenLogin:
+			entry.Kind = KIND_LOGIN
+			if item.Login != nil {
+				entry.SetField(FIELD_USERNAME, str(item.Login.Username))
+				entry.SetField(FIELD_PASSWORD, str(item.Login.Password))
+				entry.SetField(FIELD_TOTP, str(item.Login.TOTP))
+				for _, u := range item.Login.URIs {
+					entry.SetField(FIELD_URL, str(u.URI))
+				}
+			}
+		case bitwardenNote:
+			entry.Kind = KIND_NOTE
+		case bitwardenCard:
+			entry.Kind = KIND_CARD
+			if item.Card != nil {
+				entry.SetField(FIELD_CARDHOLDER, str(item.Card.Cardholder))
+				entry.SetField(FIELD_BRAND, str(item.Card.Brand))
+				entry.SetField(FIELD_NUMBER, str(item.Card.Number))
+				entry.SetField(FIELD_EXPIRY_MONTH, str(item.Card.ExpMonth))
+				entry.SetField(FIELD_EXPIRY_YEAR, str(item.Card.ExpYear))
+				entry.SetField(FIELD_CODE, str(item.Card.Code))
+			}
+		case bitwardenIdentity:
+			entry.Kind = KIND_NOTE
+			for name, v := range item.Identity {
+				if v != nil {
+					entry.SetField(name, fmt.Sprint(v))
+				}
+			}
+		default:
+			return nil, fmt.Errorf("Unknown Bitwarden Item Type [%d]", item.Type)
+		}
+
+		entry.SetField(FIELD_NOTES, str(item.Notes))
+		for _, field := range item.Fields {
+			entry.SetField(str(field.Name), str(field.Value))
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
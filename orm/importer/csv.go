@@ -0,0 +1,139 @@
+// cSpell:ignore otpauth, paulo ferreira
+package importer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+/* NOTE: 1Password / LastPass CSV
+ * The first row names the columns, and columns are matched by name (case
+ * insensitive), so the column order doesn't matter:
+ * 1Password: Title, Url, Username, Password, OTPAuth, Favorite, Archived, Tags, Notes
+ * LastPass:  url, username, password, totp, extra, name, grouping, fav
+ * LastPass secure notes have the url 'http://sn', and nested groups are
+ * separated with '\'.
+ * Columns that are not known are imported as custom fields.
+ */
+
+// Column Names (Lower Case) for Entry Properties and Standard Fields
+var csvColumns = map[string]string{
+	"title":    "title",
+	"name":     "title",
+	"grouping": "folder",
+	"folder":   "folder",
+	"tags":     "tags",
+	"url":      FIELD_URL,
+	"website":  FIELD_URL,
+	"username": FIELD_USERNAME,
+	"login":    FIELD_USERNAME,
+	"password": FIELD_PASSWORD,
+	"otpauth":  FIELD_TOTP,
+	"totp":     FIELD_TOTP,
+	"notes":    FIELD_NOTES,
+	"extra":    FIELD_NOTES,
+	"fav":      "",
+	"favorite": "",
+	"archived": "",
+}
+
+// URL of LastPass Secure Notes
+const lastpassNoteURL = "http://sn"
+
+func parseCSV(r io.Reader) ([]*Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	// Column Names
+	header, e := reader.Read()
+	if e != nil {
+		if e == io.EOF {
+			return nil, errors.New("CSV File is Empty")
+		}
+		return nil, e
+	}
+
+	columns := make([]string, len(header))
+	known := false
+	for i, h := range header {
+		h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		if name, ok := csvColumns[strings.ToLower(h)]; ok {
+			columns[i] = name
+			known = known || name == "title" || name == FIELD_PASSWORD
+		} else {
+			columns[i] = h
+		}
+	}
+
+	// Does the Header Name the Columns?
+	if !known { // NO
+		return nil, errors.New("CSV File has no Header Row")
+	}
+
+	entries := []*Entry{}
+	for {
+		row, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+
+		entry := &Entry{
+			Folder: []string{},
+		}
+
+		note := false
+		for i, v := range row {
+			if i >= len(columns) {
+				break
+			}
+
+			switch columns[i] {
+			case "":
+				continue
+			case "title":
+				entry.Title = v
+			case "folder":
+				entry.Folder = splitPath(v, "\\")
+			case "tags":
+				entry.Tags = splitTags(v)
+			case FIELD_URL:
+				if strings.TrimSpace(v) == lastpassNoteURL {
+					note = true
+					continue
+				}
+				entry.SetField(FIELD_URL, v)
+			default:
+				entry.SetField(columns[i], v)
+			}
+		}
+
+		// Skip Empty Rows
+		if entry.Title == "" && len(entry.Fields) == 0 {
+			continue
+		}
+
+		entry.Kind = kindFromFields(entry)
+		if note {
+			entry.Kind = KIND_NOTE
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
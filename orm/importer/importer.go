@@ -0,0 +1,234 @@
+// cSpell:ignore keepass, paulo ferreira
+package importer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+/* NOTE: Password Manager Imports
+ * Export files, of other password managers, are parsed into a list of
+ * entries, independent of the source format. Every entry has a kind (login,
+ * note or card), a folder path, a title and a set of fields, using the
+ * standard field names below (fields that have no standard name keep the
+ * name used in the source).
+ * Each kind is imported with a template (by default, the template with the
+ * same name as the kind), and fields are only mapped if the template model
+ * defines a field with the same name.
+ */
+
+// Supported Formats
+const FORMAT_KEEPASS = "keepass"     // KeePass 2 XML
+const FORMAT_BITWARDEN = "bitwarden" // Bitwarden JSON (Unencrypted)
+const FORMAT_CSV = "csv"             // 1Password or LastPass CSV
+
+// Entry Kinds
+const KIND_LOGIN = "login"
+const KIND_NOTE = "note"
+const KIND_CARD = "card"
+
+// Standard Field Names
+const FIELD_USERNAME = "username"
+const FIELD_PASSWORD = "password"
+const FIELD_URL = "url"
+const FIELD_NOTES = "notes"
+const FIELD_TOTP = "totp"
+const FIELD_CARDHOLDER = "cardholder"
+const FIELD_BRAND = "brand"
+const FIELD_NUMBER = "number"
+const FIELD_EXPIRY_MONTH = "expiry_month"
+const FIELD_EXPIRY_YEAR = "expiry_year"
+const FIELD_CODE = "code"
+
+// Entry Imported from Source
+type Entry struct {
+	Index      int               // Position in Source (Starts at 1)
+	Kind       string            // Entry Kind
+	Folder     []string          // Folder Path (Empty: Import Parent)
+	Title      string            // Entry Title
+	Fields     map[string]string // Field Values by Field Name
+	Tags       []string          // Entry Tags (as in Source)
+	Unreadable []string          // Fields that could not be Read (Encrypted in Source)
+}
+
+// SetField Set Field Value (Empty Values are Ignored)
+func (e *Entry) SetField(name string, v string) {
+	v = strings.TrimSpace(v)
+	if name == "" || v == "" {
+		return
+	}
+
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+
+	// Does the Field Already have a Value?
+	if current, ok := e.Fields[name]; ok && current != v { // YES: Keep Both
+		e.Fields[name] = current + "\n" + v
+		return
+	}
+
+	e.Fields[name] = v
+}
+
+// Import Settings
+var settingsLock sync.RWMutex
+var kindTemplates = map[string]string{
+	KIND_LOGIN: KIND_LOGIN,
+	KIND_NOTE:  KIND_NOTE,
+	KIND_CARD:  KIND_CARD,
+}
+var syncEntries = 100
+var maxSize int64 = 10 * 1024 * 1024
+
+// IsFormat Is Import Format Supported?
+func IsFormat(format string) bool {
+	switch format {
+	case FORMAT_KEEPASS, FORMAT_BITWARDEN, FORMAT_CSV:
+		return true
+	}
+	return false
+}
+
+// SetKindTemplate Set Template Used to Import Entry Kind
+func SetKindTemplate(kind string, template string) error {
+	template = strings.ToLower(strings.TrimSpace(template))
+	if template == "" {
+		return errors.New("Template Name is Invalid")
+	}
+
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+
+	if _, ok := kindTemplates[kind]; !ok {
+		return fmt.Errorf("Unknown Entry Kind [%s]", kind)
+	}
+
+	kindTemplates[kind] = template
+	return nil
+}
+
+// KindTemplate Template Used to Import Entry Kind
+func KindTemplate(kind string) string {
+	settingsLock.RLock()
+	defer settingsLock.RUnlock()
+	return kindTemplates[kind]
+}
+
+// SetSyncEntries Set Maximum Number of Entries Imported while the Client Waits (Larger Imports Run as Actions)
+func SetSyncEntries(n int) error {
+	if n < 1 {
+		return errors.New("Number of Entries has to be at least 1")
+	}
+
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+	syncEntries = n
+	return nil
+}
+
+// SyncEntries Maximum Number of Entries Imported while the Client Waits
+func SyncEntries() int {
+	settingsLock.RLock()
+	defer settingsLock.RUnlock()
+	return syncEntries
+}
+
+// SetMaxSize Set Maximum Size (in Bytes) of Import Files
+func SetMaxSize(n int64) error {
+	if n < 1 {
+		return errors.New("Maximum Size has to be at least 1 Byte")
+	}
+
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+	maxSize = n
+	return nil
+}
+
+// MaxSize Maximum Size (in Bytes) of Import Files
+func MaxSize() int64 {
+	settingsLock.RLock()
+	defer settingsLock.RUnlock()
+	return maxSize
+}
+
+// Parse Parse Export File into Entries
+func Parse(format string, r io.Reader) ([]*Entry, error) {
+	var entries []*Entry
+	var e error
+	switch format {
+	case FORMAT_KEEPASS:
+		entries, e = parseKeePass(r)
+	case FORMAT_BITWARDEN:
+		entries, e = parseBitwarden(r)
+	case FORMAT_CSV:
+		entries, e = parseCSV(r)
+	default:
+		return nil, fmt.Errorf("Unsupported Import Format [%s]", format)
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	// Number Entries and Make sure they have a Title
+	for i, entry := range entries {
+		entry.Index = i + 1
+		entry.Title = strings.TrimSpace(entry.Title)
+		if entry.Title == "" {
+			entry.Title = fmt.Sprintf("Imported %s %d", entry.Kind, entry.Index)
+		}
+	}
+
+	return entries, nil
+}
+
+// splitPath Folder Path Elements (Empty Elements are Removed)
+func splitPath(path string, separator string) []string {
+	folders := []string{}
+	for _, f := range strings.Split(path, separator) {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			folders = append(folders, f)
+		}
+	}
+	return folders
+}
+
+// splitTags Tags from a Separated List
+func splitTags(tags string) []string {
+	list := []string{}
+	for _, t := range strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ',' || r == ';'
+	}) {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// kindFromFields Entries without Login Fields are Notes
+func kindFromFields(e *Entry) string {
+	for _, f := range []string{FIELD_USERNAME, FIELD_PASSWORD, FIELD_URL, FIELD_TOTP} {
+		if _, ok := e.Fields[f]; ok {
+			return KIND_LOGIN
+		}
+	}
+	return KIND_NOTE
+}
@@ -0,0 +1,146 @@
+// cSpell:ignore keepass, chardata, paulo ferreira
+package importer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+/* NOTE: KeePass 2 XML
+ * <KeePassFile>
+ *   <Meta><RecycleBinUUID>...</RecycleBinUUID></Meta>
+ *   <Root>
+ *     <Group>                                  Database (Root Group)
+ *       <UUID>...</UUID><Name>...</Name>
+ *       <Entry>
+ *         <String><Key>Title</Key><Value>...</Value></String>
+ *         ...
+ *         <Tags>tag;tag</Tags>
+ *         <History><Entry>...</Entry></History>  Previous Versions (Ignored)
+ *       </Entry>
+ *       <Group>...</Group>                     Folders
+ *     </Group>
+ *   </Root>
+ * </KeePassFile>
+ * Only files exported as plain XML can be imported: values protected with
+ * the database's inner stream (Protected="True") are skipped.
+ * The root group is the import parent, and the recycle bin is not imported.
+ */
+
+type keepassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		RecycleBin string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+type keepassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Text      string `xml:",chardata"`
+			Protected string `xml:"Protected,attr"`
+		} `xml:"Value"`
+	} `xml:"String"`
+	Tags string `xml:"Tags"`
+}
+
+// Standard KeePass Fields
+var keepassFields = map[string]string{
+	"title":                  "",
+	"username":               FIELD_USERNAME,
+	"password":               FIELD_PASSWORD,
+	"url":                    FIELD_URL,
+	"notes":                  FIELD_NOTES,
+	"otp":                    FIELD_TOTP,
+	"totp seed":              FIELD_TOTP,
+	"timeotp-secret-base32":  FIELD_TOTP,
+	"keepassxc_totp_setting": "",
+}
+
+func parseKeePass(r io.Reader) ([]*Entry, error) {
+	f := &keepassFile{}
+	e := xml.NewDecoder(r).Decode(f)
+	if e != nil {
+		return nil, e
+	}
+
+	if len(f.Root.Groups) == 0 {
+		return nil, errors.New("KeePass File has no Root Group")
+	}
+
+	entries := []*Entry{}
+	for _, g := range f.Root.Groups {
+		entries = keepassGroupEntries(entries, &g, []string{}, f.Meta.RecycleBin)
+	}
+	return entries, nil
+}
+
+func keepassGroupEntries(entries []*Entry, g *keepassGroup, path []string, bin string) []*Entry {
+	for _, ke := range g.Entries {
+		entry := &Entry{
+			Folder: path,
+			Tags:   splitTags(ke.Tags),
+		}
+
+		for _, s := range ke.Strings {
+			// Is the Value Encrypted with the Inner Stream?
+			if strings.EqualFold(s.Value.Protected, "true") { // YES: Can't be Read
+				entry.Unreadable = append(entry.Unreadable, s.Key)
+				continue
+			}
+
+			// Is it a Standard Field?
+			name, standard := keepassFields[strings.ToLower(s.Key)]
+			switch {
+			case strings.EqualFold(s.Key, "title"):
+				entry.Title = s.Value.Text
+			case standard:
+				entry.SetField(name, s.Value.Text)
+			default: // NO: Custom Field
+				entry.SetField(s.Key, s.Value.Text)
+			}
+		}
+
+		entry.Kind = kindFromFields(entry)
+		entries = append(entries, entry)
+	}
+
+	for _, child := range g.Groups {
+		// Is it the Recycle Bin?
+		if bin != "" && child.UUID == bin { // YES: Skip
+			continue
+		}
+
+		name := strings.TrimSpace(child.Name)
+		folder := append(append([]string{}, path...), name)
+		if name == "" { // Unnamed Group: Entries go into Parent Folder
+			folder = path
+		}
+		entries = keepassGroupEntries(entries, &child, folder, bin)
+	}
+
+	return entries
+}
@@ -13,14 +13,13 @@ package orm
 
 import (
 	"context"
-	"crypto/sha1"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/objectvault/api-services/orm/keygen"
 	"github.com/objectvault/api-services/orm/mysql"
 	"github.com/pjacferreira/sqlf"
 )
@@ -178,8 +177,12 @@ func (o *Invitation) Key() *uint64 {
 	return o.key
 }
 
-func (o *Invitation) KeyPick() []byte {
-	return o.key_pick
+func (o *Invitation) KeyPick(kek KEK) ([]byte, error) {
+	if len(o.key_pick) == 0 {
+		return nil, errors.New("Invitation has no Key Pick")
+	}
+
+	return UnwrapWithKEK(kek, o.key_pick)
 }
 
 func (o *Invitation) Expiration() *time.Time {
@@ -265,7 +268,7 @@ func (o *Invitation) SetMessage(m string) (string, error) {
 	return current, nil
 }
 
-func (o *Invitation) SetKey(id uint64, pick []byte) error {
+func (o *Invitation) SetKey(kek KEK, id uint64, pick []byte) error {
 	if !o.IsNew() {
 		return errors.New("Registered Invitation is immutable")
 	}
@@ -274,8 +277,14 @@ func (o *Invitation) SetKey(id uint64, pick []byte) error {
 		return errors.New("Key Missing Lock")
 	}
 
+	// Wrap Key Pick with Server KEK
+	wrapped, e := WrapWithKEK(kek, pick)
+	if e != nil {
+		return e
+	}
+
 	o.key = &id
-	o.key_pick = pick
+	o.key_pick = wrapped
 	return nil
 }
 
@@ -387,7 +396,10 @@ func (o *Invitation) Flush(db sqlf.Executor, force bool) error {
 		}
 
 		// Create Unique ID
-		o.uid = o.createUID()
+		o.uid, e = o.createUID()
+		if e != nil {
+			return e
+		}
 
 		// Execute Insert
 		s := sqlf.InsertInto("invites").
@@ -435,10 +447,9 @@ func (o *Invitation) Flush(db sqlf.Executor, force bool) error {
 	return e
 }
 
-func (o *Invitation) createUID() string {
-	key := fmt.Sprintf("%X:%X:%s:%X", *o.creator, *o.object, o.invitee_email, time.Now().Unix())
-	hash := fmt.Sprintf("%x", sha1.Sum([]byte(key)))
-	return hash
+func (o *Invitation) createUID() (string, error) {
+	// NOTE: Random 160 bit Token (Same Format as the Previous SHA1 Based UID)
+	return keygen.HexToken(keygen.TOKEN_SIZE)
 }
 
 func (o *Invitation) reset() {
@@ -0,0 +1,272 @@
+// cSpell:ignore paulo ferreira
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// cSpell:ignore argon, argon2id, cypherbytes, kdf, hmac
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+/* NOTE: User Password Validation Cipher Text
+ * The client sends a hash of the user's password, which was used directly as
+ * the AES Key for the user's validation cipher text (so the cost of an offline
+ * attack against the cipher text was that of a single SHA-256).
+ * The AES Key is now derived from the hash with Argon2id, using a per-user
+ * salt and stored parameters.
+ *
+ * Cipher Text Format:
+ * [0]         KDF ID (Argon2id)
+ * [1:5]       Time Cost (Iterations)
+ * [5:9]       Memory Cost (KiB)
+ * [9]         Parallelism (Threads)
+ * [10:26]     Salt
+ * [26:]       NONCE + CIPHERTEXT (Header is Additional Data)
+ *
+ * Cipher texts without the header (legacy) are still accepted, and are
+ * upgraded the next time the user logs in.
+ *
+ * Secrets wrapped with the user's password (store keys, the share private
+ * key and the previous password hashes of a store keys re-wrap) use a key
+ * derived, with the same KDF, from the hash and the user's password cipher
+ * text header (see PasswordKey).
+ */
+const PASSWORD_KDF_ARGON2ID = 0xA2
+
+const kdfSaltSize = 16
+const kdfHeaderSize = 10 + kdfSaltSize
+const kdfMaxTime = 64
+const kdfMaxMemory = 4 * 1024 * 1024
+
+// Argon2id Cost Parameters
+type Argon2Params struct {
+	Time    uint32 // Number of Iterations
+	Memory  uint32 // Memory in KiB
+	Threads uint8  // Degree of Parallelism
+}
+
+// Default Parameters (RFC 9106 Second Recommended Option)
+var passwordKDF = Argon2Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+var passwordKDFLock sync.RWMutex
+
+// SetPasswordKDF Set Argon2id Parameters for New (or Upgraded) Cipher Texts
+func SetPasswordKDF(p Argon2Params) error {
+	if p.Time == 0 || p.Time > kdfMaxTime {
+		return errors.New("Argon2 Time Cost has to be > 0 and <= 64")
+	}
+
+	if p.Threads == 0 {
+		return errors.New("Argon2 Parallelism has to be > 0")
+	}
+
+	if p.Memory < 8*uint32(p.Threads) || p.Memory > kdfMaxMemory {
+		return errors.New("Argon2 Memory Cost has to be >= 8KiB per Thread and <= 4GiB")
+	}
+
+	passwordKDFLock.Lock()
+	defer passwordKDFLock.Unlock()
+	passwordKDF = p
+	return nil
+}
+
+// PasswordKDF Current Argon2id Parameters
+func PasswordKDF() Argon2Params {
+	passwordKDFLock.RLock()
+	defer passwordKDFLock.RUnlock()
+	return passwordKDF
+}
+
+// sealPasswordCheck Encrypt Plain Bytes with Key Derived from Password Hash
+func sealPasswordCheck(hash []byte, plain []byte) ([]byte, error) {
+	p := PasswordKDF()
+
+	// Create Header
+	header := make([]byte, kdfHeaderSize)
+	header[0] = PASSWORD_KDF_ARGON2ID
+	binary.BigEndian.PutUint32(header[1:5], p.Time)
+	binary.BigEndian.PutUint32(header[5:9], p.Memory)
+	header[9] = p.Threads
+
+	// Random Salt
+	_, e := io.ReadFull(rand.Reader, header[10:])
+	if e != nil {
+		return nil, e
+	}
+
+	// Encrypt Plain Bytes with Derived Key
+	cypherbytes, e := gcmEncryptAAD(deriveKeyArgon2(hash, header), plain, header)
+	if e != nil {
+		return nil, e
+	}
+
+	return append(header, cypherbytes...), nil
+}
+
+// openPasswordCheck Decrypt Cipher Text (Legacy or Argon2id) with Password Hash
+func openPasswordCheck(hash []byte, cbs []byte) ([]byte, error) {
+	// Argon2id Cipher Text?
+	if isPasswordCheckArgon2(cbs) { // MAYBE
+		header := cbs[:kdfHeaderSize]
+		plain, e := gcmDecryptAAD(deriveKeyArgon2(hash, header), cbs[kdfHeaderSize:], header)
+		if e == nil {
+			return plain, nil
+		}
+	}
+
+	// Legacy Cipher Text (Hash is the AES Key)
+	return toPlainBytes(hash, cbs)
+}
+
+// isPasswordCheckCurrent Is Cipher Text using the Current Argon2id Parameters?
+func isPasswordCheckCurrent(cbs []byte) bool {
+	if !isPasswordCheckArgon2(cbs) {
+		return false
+	}
+
+	p := PasswordKDF()
+	return binary.BigEndian.Uint32(cbs[1:5]) >= p.Time &&
+		binary.BigEndian.Uint32(cbs[5:9]) >= p.Memory &&
+		cbs[9] == p.Threads
+}
+
+func isPasswordCheckArgon2(cbs []byte) bool {
+	if len(cbs) <= kdfHeaderSize || cbs[0] != PASSWORD_KDF_ARGON2ID {
+		return false
+	}
+
+	// Sanity Check Parameters (Don't Allow Stored Values to Exhaust Resources)
+	t := binary.BigEndian.Uint32(cbs[1:5])
+	m := binary.BigEndian.Uint32(cbs[5:9])
+	return t > 0 && t <= kdfMaxTime && m > 0 && m <= kdfMaxMemory && cbs[9] > 0
+}
+
+func deriveKeyArgon2(hash []byte, header []byte) []byte {
+	t := binary.BigEndian.Uint32(header[1:5])
+	m := binary.BigEndian.Uint32(header[5:9])
+	return argon2.IDKey(hash, header[10:kdfHeaderSize], t, m, header[9], 32)
+}
+
+/* NOTE: Password Wrapped Secrets
+ * The wrapping key is derived with Argon2id, from the password hash and the
+ * header (parameters and salt) of the user's password cipher text, and then
+ * separated (HMAC) from the key that protects the password cipher text.
+ *
+ * Wrapped Value Format:
+ * [0:26]      KDF Header (as in the Password Cipher Text)
+ * [26:]       NONCE + CIPHERTEXT (Header is Additional Data)
+ *
+ * Values wrapped directly with the password hash (legacy) are still accepted,
+ * and are re-wrapped when the user logs in (see IsCurrent).
+ */
+const passwordWrapLabel = "objectvault:password-wrap"
+
+// PasswordKey Key Wrapping Key Derived from a User's Password Hash
+type PasswordKey struct {
+	hash   []byte            // Password Hash (Legacy Values)
+	header []byte            // KDF Header for New Values
+	keys   map[string][]byte // Derived Keys by KDF Header
+}
+
+// NewPasswordKey Wrapping Key for Password Hash and User's Password Cipher Text
+func NewPasswordKey(hash []byte, check []byte) *PasswordKey {
+	k := &PasswordKey{
+		hash: hash,
+		keys: map[string][]byte{},
+	}
+
+	// Does the User's Cipher Text have a KDF Header?
+	if isPasswordCheckArgon2(check) { // YES: Use its Parameters and Salt
+		k.header = check[:kdfHeaderSize]
+	}
+	return k
+}
+
+// NewPasswordKeyHex Wrapping Key for HEX Password Hash and User's Password Cipher Text
+func NewPasswordKeyHex(hash string, check []byte) (*PasswordKey, error) {
+	h, e := hex.DecodeString(hash)
+	if e != nil {
+		return nil, e
+	}
+
+	return NewPasswordKey(h, check), nil
+}
+
+// Wrap Encrypt Bytes with Derived Key
+func (k *PasswordKey) Wrap(plain []byte) ([]byte, error) {
+	// Do we have a User KDF Header?
+	if k.header == nil { // NO: Create One (Current Parameters, Random Salt)
+		p := PasswordKDF()
+
+		header := make([]byte, kdfHeaderSize)
+		header[0] = PASSWORD_KDF_ARGON2ID
+		binary.BigEndian.PutUint32(header[1:5], p.Time)
+		binary.BigEndian.PutUint32(header[5:9], p.Memory)
+		header[9] = p.Threads
+
+		_, e := io.ReadFull(rand.Reader, header[10:])
+		if e != nil {
+			return nil, e
+		}
+		k.header = header
+	}
+
+	cypherbytes, e := gcmEncryptAAD(k.key(k.header), plain, k.header)
+	if e != nil {
+		return nil, e
+	}
+
+	wrapped := make([]byte, 0, len(k.header)+len(cypherbytes))
+	wrapped = append(wrapped, k.header...)
+	return append(wrapped, cypherbytes...), nil
+}
+
+// Unwrap Decrypt Bytes Wrapped with Derived Key (or, Legacy, with Password Hash)
+func (k *PasswordKey) Unwrap(cbs []byte) ([]byte, error) {
+	// Wrapped with Derived Key?
+	if isPasswordCheckArgon2(cbs) { // MAYBE
+		header := cbs[:kdfHeaderSize]
+		plain, e := gcmDecryptAAD(k.key(header), cbs[kdfHeaderSize:], header)
+		if e == nil {
+			return plain, nil
+		}
+	}
+
+	// Legacy Value (Hash is the AES Key)
+	return toPlainBytes(k.hash, cbs)
+}
+
+// IsCurrent Is Value Wrapped with the User's Current KDF Header?
+func (k *PasswordKey) IsCurrent(cbs []byte) bool {
+	return k.header != nil && len(cbs) > kdfHeaderSize && bytes.Equal(cbs[:kdfHeaderSize], k.header)
+}
+
+func (k *PasswordKey) key(header []byte) []byte {
+	// Already Derived?
+	key, ok := k.keys[string(header)]
+	if !ok { // NO: Derive (Separated from Password Cipher Text Key)
+		mac := hmac.New(sha256.New, deriveKeyArgon2(k.hash, header))
+		mac.Write([]byte(passwordWrapLabel))
+		key = mac.Sum(nil)
+		k.keys[string(header)] = key
+	}
+
+	return key
+}
@@ -0,0 +1,263 @@
+// cSpell:ignore paulo ferreira, argon, kdf
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+// useTestKDF Cheap Argon2id Parameters (Restored when the Test Ends)
+func useTestKDF(t *testing.T) {
+	p := PasswordKDF()
+	e := SetPasswordKDF(Argon2Params{Time: 1, Memory: 64, Threads: 1})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() {
+		SetPasswordKDF(p)
+	})
+}
+
+func testPasswordHash(password string) []byte {
+	h := sha256.Sum256([]byte(password))
+	return h[:]
+}
+
+func TestPasswordCheck(t *testing.T) {
+	useTestKDF(t)
+
+	hash := testPasswordHash("password")
+	plain := []byte("validation text")
+
+	cbs, e := sealPasswordCheck(hash, plain)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !isPasswordCheckArgon2(cbs) || !isPasswordCheckCurrent(cbs) {
+		t.Fatal("Cipher Text should be Argon2id with the Current Parameters")
+	}
+
+	decrypted, e := openPasswordCheck(hash, cbs)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(decrypted, plain) {
+		t.Fatalf("Decrypted [%s], Expected [%s]", decrypted, plain)
+	}
+
+	// Wrong Password
+	if _, e := openPasswordCheck(testPasswordHash("wrong"), cbs); e == nil {
+		t.Fatal("Wrong Password Hash should Fail")
+	}
+
+	// Tampered Header (Salt)
+	tampered := append([]byte{}, cbs...)
+	tampered[kdfHeaderSize-1] ^= 0x01
+	if _, e := openPasswordCheck(hash, tampered); e == nil {
+		t.Fatal("Tampered Header should Fail")
+	}
+
+	// Stronger Parameters make Existing Cipher Texts Outdated
+	e = SetPasswordKDF(Argon2Params{Time: 2, Memory: 64, Threads: 1})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if isPasswordCheckCurrent(cbs) {
+		t.Fatal("Cipher Text should not be Current after Parameters Change")
+	}
+}
+
+func TestPasswordCheckLegacy(t *testing.T) {
+	hash := testPasswordHash("password")
+	plain := []byte("validation text")
+
+	cbs, e := toCypherBytes(hash, plain)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	decrypted, e := openPasswordCheck(hash, cbs)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(decrypted, plain) {
+		t.Fatalf("Decrypted [%s], Expected [%s]", decrypted, plain)
+	}
+
+	if isPasswordCheckCurrent(cbs) {
+		t.Fatal("Legacy Cipher Text should not be Current")
+	}
+}
+
+func TestPasswordCheckLimits(t *testing.T) {
+	useTestKDF(t)
+
+	cbs, e := sealPasswordCheck(testPasswordHash("password"), []byte("validation text"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Time Cost above Maximum
+	tampered := append([]byte{}, cbs...)
+	tampered[1] = 0xFF
+	if isPasswordCheckArgon2(tampered) {
+		t.Fatal("Time Cost above Maximum should be Refused")
+	}
+
+	// Memory Cost above Maximum
+	tampered = append([]byte{}, cbs...)
+	tampered[5] = 0xFF
+	if isPasswordCheckArgon2(tampered) {
+		t.Fatal("Memory Cost above Maximum should be Refused")
+	}
+
+	// Invalid Parameters
+	if SetPasswordKDF(Argon2Params{Time: 0, Memory: 64, Threads: 1}) == nil {
+		t.Fatal("Zero Time Cost should Fail")
+	}
+	if SetPasswordKDF(Argon2Params{Time: 1, Memory: 64, Threads: 0}) == nil {
+		t.Fatal("Zero Parallelism should Fail")
+	}
+	if SetPasswordKDF(Argon2Params{Time: 1, Memory: 8, Threads: 2}) == nil {
+		t.Fatal("Less than 8KiB per Thread should Fail")
+	}
+}
+
+func TestPasswordKey(t *testing.T) {
+	useTestKDF(t)
+
+	hash := testPasswordHash("password")
+	check, e := sealPasswordCheck(hash, []byte("validation text"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	secret := []byte("store key")
+	wrapped, e := NewPasswordKey(hash, check).Wrap(secret)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Wrapped with the User's KDF Header
+	k := NewPasswordKey(hash, check)
+	if !k.IsCurrent(wrapped) {
+		t.Fatal("Wrapped Value should be Current")
+	}
+
+	plain, e := k.Unwrap(wrapped)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(plain, secret) {
+		t.Fatalf("Unwrapped [%s], Expected [%s]", plain, secret)
+	}
+
+	// Wrapping Key is Separated from the Password Check Key
+	if _, e := gcmDecryptAAD(deriveKeyArgon2(hash, check[:kdfHeaderSize]), wrapped[kdfHeaderSize:], wrapped[:kdfHeaderSize]); e == nil {
+		t.Fatal("Password Check Key should not Unwrap Value")
+	}
+
+	// Wrong Password
+	if _, e := NewPasswordKey(testPasswordHash("wrong"), check).Unwrap(wrapped); e == nil {
+		t.Fatal("Wrong Password Hash should Fail")
+	}
+
+	// Tampered Cipher Text
+	tampered := append([]byte{}, wrapped...)
+	tampered[len(tampered)-1] ^= 0x01
+	if _, e := k.Unwrap(tampered); e == nil {
+		t.Fatal("Tampered Value should Fail")
+	}
+
+	// Tampered Header (Additional Data)
+	tampered = append([]byte{}, wrapped...)
+	tampered[kdfHeaderSize-1] ^= 0x01
+	if _, e := k.Unwrap(tampered); e == nil {
+		t.Fatal("Tampered Header should Fail")
+	}
+
+	// New Password Cipher Text (New Salt) makes Value Outdated
+	check, e = sealPasswordCheck(hash, []byte("validation text"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if NewPasswordKey(hash, check).IsCurrent(wrapped) {
+		t.Fatal("Value should not be Current for a New Password Cipher Text")
+	}
+}
+
+func TestPasswordKeyLegacy(t *testing.T) {
+	useTestKDF(t)
+
+	hash := testPasswordHash("password")
+	secret := []byte("store key")
+
+	// Value Wrapped Directly with the Password Hash
+	legacy, e := gcmEncrypt(hash, secret)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// User with a Legacy Password Cipher Text
+	check, e := toCypherBytes(hash, []byte("validation text"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	k := NewPasswordKey(hash, check)
+	plain, e := k.Unwrap(legacy)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(plain, secret) {
+		t.Fatalf("Unwrapped [%s], Expected [%s]", plain, secret)
+	}
+
+	if k.IsCurrent(legacy) {
+		t.Fatal("Legacy Value should not be Current")
+	}
+
+	// Without a User KDF Header, Wrap Creates One
+	wrapped, e := k.Wrap(secret)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !isPasswordCheckArgon2(wrapped) || !k.IsCurrent(wrapped) {
+		t.Fatal("Wrapped Value should have a KDF Header")
+	}
+
+	plain, e = k.Unwrap(wrapped)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(plain, secret) {
+		t.Fatalf("Unwrapped [%s], Expected [%s]", plain, secret)
+	}
+}
+
+func TestPasswordKeyHex(t *testing.T) {
+	if _, e := NewPasswordKeyHex("not hex", nil); e == nil {
+		t.Fatal("Invalid HEX Hash should Fail")
+	}
+}
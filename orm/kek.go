@@ -0,0 +1,278 @@
+// cSpell:ignore paulo ferreira
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// cSpell:ignore kek, keks
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+/* NOTE: Server Key Encryption Key (KEK)
+ * Server side secrets (i.e. invitation key ciphertexts and key picks) are
+ * wrapped with a Key Encryption Key that is NOT stored in the database, so
+ * that a database dump, on its own, can't be used to unlock them.
+ *
+ * Wrapped Value Format:
+ * [0]         KEK Envelope Version
+ * [1]         KEK ID Length (N)
+ * [2:2+N]     KEK ID
+ * [2+N:]      Wrapped Bytes
+ *
+ * Values without a KEK Envelope (written before a KEK was configured) are
+ * returned as is. Values wrapped with a KEK that is not configured fail to
+ * unwrap (they are NOT returned as is).
+ * KEK IDs are printable ASCII, so a legacy value is only mistaken for an
+ * envelope if its first bytes happen to match the envelope header AND an ID.
+ */
+const KEK_ENVELOPE_V1 = 0xCE
+
+// Key Encryption Key
+type KEK interface {
+	ID() string
+	Wrap(plain []byte) ([]byte, error)
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// Key Management Service Client (Keys Never Leave the Service)
+type KMSClient interface {
+	Encrypt(keyID string, plain []byte) ([]byte, error)
+	Decrypt(keyID string, cipher []byte) ([]byte, error)
+}
+
+// WrapWithKEK Wrap Bytes with KEK (if no KEK, bytes are returned as is)
+func WrapWithKEK(kek KEK, plain []byte) ([]byte, error) {
+	// Do we have a KEK?
+	if kek == nil { // NO: Nothing to Wrap With
+		return plain, nil
+	}
+
+	id := kek.ID()
+	if id == "" || len(id) > 255 || strings.IndexFunc(id, func(c rune) bool { return c < 0x21 || c > 0x7E }) >= 0 {
+		return nil, errors.New("Invalid KEK ID")
+	}
+
+	wrapped, e := kek.Wrap(plain)
+	if e != nil {
+		return nil, e
+	}
+
+	// Build Envelope
+	b := make([]byte, 0, 2+len(id)+len(wrapped))
+	b = append(b, KEK_ENVELOPE_V1, byte(len(id)))
+	b = append(b, id...)
+	b = append(b, wrapped...)
+	return b, nil
+}
+
+// UnwrapWithKEK Unwrap Bytes Previously Wrapped with WrapWithKEK
+func UnwrapWithKEK(kek KEK, b []byte) ([]byte, error) {
+	// Is Value Wrapped?
+	id, wrapped := splitKEKEnvelope(b)
+	if wrapped == nil { // NO: Legacy Value
+		return b, nil
+	}
+
+	// Was the Value Wrapped with our KEK?
+	if kek == nil || kek.ID() != id { // NO: Can't Unwrap
+		return nil, fmt.Errorf("Value Wrapped with Unknown KEK [%s]", id)
+	}
+
+	return kek.Unwrap(wrapped)
+}
+
+func splitKEKEnvelope(b []byte) (string, []byte) {
+	if len(b) < 3 || b[0] != KEK_ENVELOPE_V1 {
+		return "", nil
+	}
+
+	l := int(b[1])
+	if l == 0 || len(b) <= 2+l {
+		return "", nil
+	}
+
+	// KEK ID has to be Printable ASCII
+	id := b[2 : 2+l]
+	for _, c := range id {
+		if c < 0x21 || c > 0x7E {
+			return "", nil
+		}
+	}
+
+	return string(id), b[2+l:]
+}
+
+// Local KEK (Key Held in Server Memory)
+type localKEK struct {
+	id  string
+	key []byte
+}
+
+func NewLocalKEK(id string, key []byte) (KEK, error) {
+	if len(key) != 32 {
+		return nil, errors.New("KEK has to be 32 Bytes")
+	}
+
+	// Default ID : Fingerprint of Key
+	if id == "" {
+		h := sha256.Sum256(key)
+		id = hex.EncodeToString(h[:4])
+	}
+
+	return &localKEK{id: id, key: key}, nil
+}
+
+// NewFileKEK Load KEK from File (32 Raw Bytes or 64 Hex Characters)
+func NewFileKEK(path string) (KEK, error) {
+	b, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	key, e := kekFromBytes(b)
+	if e != nil {
+		return nil, fmt.Errorf("KEK File [%s]: %v", path, e)
+	}
+
+	return NewLocalKEK("", key)
+}
+
+// NewEnvKEK Load KEK from Environment Variable (64 Hex Characters)
+func NewEnvKEK(name string) (KEK, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("KEK Environment Variable [%s] not Set", name)
+	}
+
+	key, e := hex.DecodeString(strings.TrimSpace(v))
+	if e != nil || len(key) != 32 {
+		return nil, fmt.Errorf("KEK Environment Variable [%s] is not a 256 bit Hex Key", name)
+	}
+
+	return NewLocalKEK("", key)
+}
+
+func kekFromBytes(b []byte) ([]byte, error) {
+	// Raw Key?
+	if len(b) == 32 { // YES
+		return b, nil
+	}
+
+	// Hex Key
+	key, e := hex.DecodeString(string(bytes.TrimSpace(b)))
+	if e != nil || len(key) != 32 {
+		return nil, errors.New("Not a 256 bit Key")
+	}
+	return key, nil
+}
+
+func (k *localKEK) ID() string {
+	return k.id
+}
+
+func (k *localKEK) Wrap(plain []byte) ([]byte, error) {
+	return gcmEncryptAAD(k.key, plain, []byte(k.id))
+}
+
+func (k *localKEK) Unwrap(wrapped []byte) ([]byte, error) {
+	return gcmDecryptAAD(k.key, wrapped, []byte(k.id))
+}
+
+// KMS KEK (Wrapping Delegated to Key Management Service)
+type kmsKEK struct {
+	client KMSClient
+	keyID  string
+}
+
+func NewKMSKEK(client KMSClient, keyID string) (KEK, error) {
+	if client == nil {
+		return nil, errors.New("Missing KMS Client")
+	}
+
+	if keyID == "" {
+		return nil, errors.New("Missing KMS Key ID")
+	}
+
+	return &kmsKEK{client: client, keyID: keyID}, nil
+}
+
+func (k *kmsKEK) ID() string {
+	return k.keyID
+}
+
+func (k *kmsKEK) Wrap(plain []byte) ([]byte, error) {
+	return k.client.Encrypt(k.keyID, plain)
+}
+
+func (k *kmsKEK) Unwrap(wrapped []byte) ([]byte, error) {
+	return k.client.Decrypt(k.keyID, wrapped)
+}
+
+// Local Stand-In for a Key Management Service
+type LocalKMS struct {
+	lock sync.RWMutex
+	keys map[string][]byte
+}
+
+func NewLocalKMS() *LocalKMS {
+	return &LocalKMS{keys: make(map[string][]byte)}
+}
+
+func (k *LocalKMS) AddKey(keyID string, key []byte) error {
+	if keyID == "" {
+		return errors.New("Missing KMS Key ID")
+	}
+
+	if len(key) != 32 {
+		return errors.New("KMS Key has to be 32 Bytes")
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.keys[keyID] = key
+	return nil
+}
+
+func (k *LocalKMS) Encrypt(keyID string, plain []byte) ([]byte, error) {
+	key, e := k.key(keyID)
+	if e != nil {
+		return nil, e
+	}
+
+	return gcmEncryptAAD(key, plain, []byte(keyID))
+}
+
+func (k *LocalKMS) Decrypt(keyID string, cipher []byte) ([]byte, error) {
+	key, e := k.key(keyID)
+	if e != nil {
+		return nil, e
+	}
+
+	return gcmDecryptAAD(key, cipher, []byte(keyID))
+}
+
+func (k *LocalKMS) key(keyID string) ([]byte, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown KMS Key [%s]", keyID)
+	}
+	return key, nil
+}
@@ -0,0 +1,204 @@
+// cSpell:ignore paulo ferreira, kek, kms
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectvault/api-services/orm/keygen"
+)
+
+func testKEKs(t *testing.T) []KEK {
+	key, e := keygen.Key()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	local, e := NewLocalKEK("", key)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	kms := NewLocalKMS()
+	e = kms.AddKey("test-key", key)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	remote, e := NewKMSKEK(kms, "test-key")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return []KEK{local, remote}
+}
+
+func TestKEK(t *testing.T) {
+	plain := []byte("invitation key")
+
+	for _, kek := range testKEKs(t) {
+		wrapped, e := WrapWithKEK(kek, plain)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if id, _ := splitKEKEnvelope(wrapped); id != kek.ID() {
+			t.Fatalf("Envelope KEK ID [%s], Expected [%s]", id, kek.ID())
+		}
+
+		unwrapped, e := UnwrapWithKEK(kek, wrapped)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if !bytes.Equal(unwrapped, plain) {
+			t.Fatalf("Unwrapped [%s], Expected [%s]", unwrapped, plain)
+		}
+
+		// Tampered Cipher Text
+		tampered := append([]byte{}, wrapped...)
+		tampered[len(tampered)-1] ^= 0x01
+		if _, e := UnwrapWithKEK(kek, tampered); e == nil {
+			t.Fatalf("KEK [%s]: Tampered Value should Fail", kek.ID())
+		}
+
+		// Without a KEK, a Wrapped Value can't be Unwrapped
+		if _, e := UnwrapWithKEK(nil, wrapped); e == nil {
+			t.Fatalf("KEK [%s]: Unwrap without KEK should Fail", kek.ID())
+		}
+	}
+}
+
+func TestKEKMismatch(t *testing.T) {
+	keks := testKEKs(t)
+	plain := []byte("invitation key")
+
+	// Value Wrapped with Another KEK
+	wrapped, e := WrapWithKEK(keks[0], plain)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := UnwrapWithKEK(keks[1], wrapped); e == nil {
+		t.Fatal("Value Wrapped with Other KEK should Fail")
+	}
+
+	// Same ID, Different Key (ID is Additional Data, Key Fails)
+	other, e := keygen.Key()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	impostor, e := NewLocalKEK(keks[0].ID(), other)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := UnwrapWithKEK(impostor, wrapped); e == nil {
+		t.Fatal("Value Wrapped with Other Key should Fail")
+	}
+}
+
+func TestKEKLegacy(t *testing.T) {
+	kek := testKEKs(t)[0]
+	legacy := []byte("legacy key bytes")
+
+	// Values without an Envelope are Returned as Is
+	plain, e := UnwrapWithKEK(kek, legacy)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(plain, legacy) {
+		t.Fatalf("Unwrapped [%s], Expected [%s]", plain, legacy)
+	}
+
+	// Envelope Header with a Non Printable ID is not an Envelope
+	legacy = []byte{KEK_ENVELOPE_V1, 0x01, 0x00, 0xAA}
+	if _, wrapped := splitKEKEnvelope(legacy); wrapped != nil {
+		t.Fatal("Non Printable KEK ID should not be an Envelope")
+	}
+
+	// Without a KEK, Values are not Wrapped
+	wrapped, e := WrapWithKEK(nil, legacy)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(wrapped, legacy) {
+		t.Fatal("Value should not be Wrapped without a KEK")
+	}
+}
+
+func TestKEKSources(t *testing.T) {
+	key, e := keygen.Key()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := NewLocalKEK("", key[:16]); e == nil {
+		t.Fatal("Short KEK should Fail")
+	}
+
+	// File KEK (Raw and HEX)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "kek.bin")
+	hexed := filepath.Join(dir, "kek.hex")
+	if e := os.WriteFile(raw, key, 0600); e != nil {
+		t.Fatal(e)
+	}
+	if e := os.WriteFile(hexed, []byte(hex.EncodeToString(key)+"\n"), 0600); e != nil {
+		t.Fatal(e)
+	}
+
+	a, e := NewFileKEK(raw)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	b, e := NewFileKEK(hexed)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Environment KEK
+	t.Setenv("TEST_ORM_KEK", hex.EncodeToString(key))
+	c, e := NewEnvKEK("TEST_ORM_KEK")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Same Key, Same Fingerprint ID (Values Interchangeable)
+	wrapped, e := WrapWithKEK(a, []byte("invitation key"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for _, kek := range []KEK{b, c} {
+		if _, e := UnwrapWithKEK(kek, wrapped); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	// Invalid Sources
+	t.Setenv("TEST_ORM_KEK", "not hex")
+	if _, e := NewEnvKEK("TEST_ORM_KEK"); e == nil {
+		t.Fatal("Invalid Environment KEK should Fail")
+	}
+	if _, e := NewFileKEK(filepath.Join(dir, "missing")); e == nil {
+		t.Fatal("Missing KEK File should Fail")
+	}
+}
@@ -0,0 +1,120 @@
+// cSpell:ignore keygen, paulo ferreira
+package keygen
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KEY_SIZE AES-256 Key Size in Bytes
+const KEY_SIZE = 32
+
+// TOKEN_SIZE Default Size (in Bytes) of Random Tokens (i.e. Invitation UID)
+const TOKEN_SIZE = 20
+
+// Bytes Generate n Cryptographically Secure Random Bytes
+func Bytes(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, errors.New("Invalid Number of Random Bytes")
+	}
+
+	b := make([]byte, n)
+	_, e := io.ReadFull(rand.Reader, b)
+	if e != nil {
+		return nil, e
+	}
+
+	return b, nil
+}
+
+// Key Generate a Random AES-256 Key
+func Key() ([]byte, error) {
+	return Bytes(KEY_SIZE)
+}
+
+// HexToken Generate Random Token with n Bytes of Entropy (Returned as Lower Case HEX String)
+func HexToken(n int) (string, error) {
+	b, e := Bytes(n)
+	if e != nil {
+		return "", e
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// GUID Generate a Random (Version 4) UUID String
+func GUID() (string, error) {
+	b, e := Bytes(16)
+	if e != nil {
+		return "", e
+	}
+
+	// Set Version (4) and Variant (RFC 4122) Bits
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// String Generate Random String of length Characters, Selected Uniformly from set
+func String(set string, length int) (string, error) {
+	setLength := len(set)
+	if setLength == 0 || setLength > 256 {
+		return "", errors.New("Invalid Character Set")
+	}
+
+	if length <= 0 {
+		return "", errors.New("Invalid String Length")
+	}
+
+	// Number of Bits Required to Index Set
+	bits := uint(0)
+	for (1 << bits) < setLength {
+		bits++
+	}
+	mask := byte((1 << bits) - 1)
+
+	b := make([]byte, length)
+	buffer := make([]byte, length)
+	for i := 0; i < length; {
+		// Fill Buffer with Random Bytes
+		_, e := io.ReadFull(rand.Reader, buffer)
+		if e != nil {
+			return "", e
+		}
+
+		for _, r := range buffer {
+			// Next Set Index
+			idx := int(r & mask)
+
+			// idx outside of set?
+			if idx >= setLength { // YES: Reject (Avoids Bias towards Start of Set)
+				continue
+			}
+
+			// Set Next Random Character
+			b[i] = set[idx]
+			i++
+
+			// Have Enough Characters?
+			if i == length { // YES
+				break
+			}
+		}
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,165 @@
+// cSpell:ignore keygen, paulo ferreira
+package keygen
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Version 4, RFC 4122 Variant UUID
+var guidFormat = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestGUIDFormat(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		g, e := GUID()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if !guidFormat.MatchString(g) {
+			t.Fatalf("GUID [%s] is not a Version 4 UUID", g)
+		}
+	}
+}
+
+func TestGUIDUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 10000; i++ {
+		g, e := GUID()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if seen[g] {
+			t.Fatalf("GUID [%s] Repeated", g)
+		}
+		seen[g] = true
+	}
+}
+
+func TestHexToken(t *testing.T) {
+	for _, n := range []int{1, 16, TOKEN_SIZE, 64} {
+		s, e := HexToken(n)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if len(s) != 2*n {
+			t.Fatalf("Token [%s] has Length %d, Expected %d", s, len(s), 2*n)
+		}
+
+		if strings.Trim(s, "0123456789abcdef") != "" {
+			t.Fatalf("Token [%s] is not Lower Case HEX", s)
+		}
+	}
+
+	// Invalid Sizes
+	for _, n := range []int{0, -1} {
+		_, e := HexToken(n)
+		if e == nil {
+			t.Fatalf("Token with %d Bytes should Fail", n)
+		}
+	}
+}
+
+func TestHexTokenUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 10000; i++ {
+		s, e := HexToken(TOKEN_SIZE)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if seen[s] {
+			t.Fatalf("Token [%s] Repeated", s)
+		}
+		seen[s] = true
+	}
+}
+
+func TestString(t *testing.T) {
+	sets := []string{
+		"ab",
+		"0123456789",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	}
+
+	for _, set := range sets {
+		for _, length := range []int{1, 8, 100} {
+			s, e := String(set, length)
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if len(s) != length {
+				t.Fatalf("String [%s] has Length %d, Expected %d", s, len(s), length)
+			}
+
+			if strings.Trim(s, set) != "" {
+				t.Fatalf("String [%s] has Characters not in Set [%s]", s, set)
+			}
+		}
+	}
+
+	// Invalid Parameters
+	if _, e := String("", 10); e == nil {
+		t.Fatal("Empty Set should Fail")
+	}
+	if _, e := String(strings.Repeat("a", 257), 10); e == nil {
+		t.Fatal("Set with more than 256 Characters should Fail")
+	}
+	if _, e := String("abc", 0); e == nil {
+		t.Fatal("Zero Length should Fail")
+	}
+}
+
+func TestStringDistribution(t *testing.T) {
+	// Set Size not a Power of 2 (Modulo Bias would Favor the First Characters)
+	set := "0123456789"
+	samples := 100000
+
+	s, e := String(set, samples)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Every Character should be within 5% of the Expected Count (~ 16 Standard Deviations)
+	expected := samples / len(set)
+	for _, c := range set {
+		n := strings.Count(s, string(c))
+		if n < expected*95/100 || n > expected*105/100 {
+			t.Fatalf("Character [%c] Selected %d Times, Expected ~%d", c, n, expected)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	a, e := Key()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	b, e := Key()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if len(a) != KEY_SIZE || len(b) != KEY_SIZE {
+		t.Fatalf("Key Size has to be %d Bytes", KEY_SIZE)
+	}
+
+	if string(a) == string(b) {
+		t.Fatal("Keys Repeated")
+	}
+}
@@ -0,0 +1,295 @@
+// cSpell:ignore keygen, paulo ferreira, wordlist, wordlists
+package keygen
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+/* NOTE: Password and Passphrase Generation
+ * Passwords are built from the selected character classes (minus excluded
+ * characters), with at least one character of every selected class.
+ * Passphrases are built from words, picked from a word list (the built-in
+ * list, or lists loaded from the configuration), and optionally capitalized
+ * and with a digit added to one of the words.
+ * All random choices use crypto/rand, and are uniform (no modulo bias).
+ * The entropy returned is that of the generator (what an attacker that knows
+ * the options has to search), not an estimate of the generated value.
+ */
+
+// Character Classes
+const CHARS_LOWER = "abcdefghijklmnopqrstuvwxyz"
+const CHARS_UPPER = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const CHARS_DIGITS = "0123456789"
+const CHARS_SYMBOLS = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+
+// Characters that are Easily Confused (when Read or Typed)
+const CHARS_AMBIGUOUS = "Il1O0o|"
+
+// Password Length Limits
+const PASSWORD_MIN_LENGTH = 4
+const PASSWORD_MAX_LENGTH = 256
+
+// Passphrase Limits
+const PASSPHRASE_MIN_WORDS = 3
+const PASSPHRASE_MAX_WORDS = 20
+const PASSPHRASE_MAX_SEPARATOR = 3
+
+// Name of Built-in Word List
+const WORDLIST_DEFAULT = "default"
+
+// Minimum Number of (Unique) Words in a Word List
+const WORDLIST_MIN_WORDS = 64
+
+// PasswordOptions Password Generator Options
+type PasswordOptions struct {
+	Length           int    // Number of Characters
+	Lower            bool   // Use Lower Case Letters
+	Upper            bool   // Use Upper Case Letters
+	Digits           bool   // Use Digits
+	Symbols          bool   // Use Symbols
+	Exclude          string // Characters not to Use
+	ExcludeAmbiguous bool   // Don't Use Easily Confused Characters
+}
+
+// PassphraseOptions Passphrase Generator Options
+type PassphraseOptions struct {
+	Words      int    // Number of Words
+	Separator  string // Separator between Words
+	Capitalize bool   // Capitalize Words
+	Digit      bool   // Add Digit to a Word
+	Wordlist   string // Name of Word List
+}
+
+// Word Lists by Name
+var wordlists = map[string][]string{
+	WORDLIST_DEFAULT: strings.Fields(defaultWordlist),
+}
+var wordlistsLock sync.RWMutex
+
+// SetWordlist Add (or Replace) Word List Available to Passphrases
+func SetWordlist(name string, words []string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return errors.New("Word List has no Name")
+	}
+
+	// Unique Words (Lower Case)
+	list := []string{}
+	seen := map[string]bool{}
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" || seen[w] {
+			continue
+		}
+
+		seen[w] = true
+		list = append(list, w)
+	}
+
+	if len(list) < WORDLIST_MIN_WORDS {
+		return fmt.Errorf("Word List [%s] has less than %d Words", name, WORDLIST_MIN_WORDS)
+	}
+
+	wordlistsLock.Lock()
+	defer wordlistsLock.Unlock()
+	wordlists[name] = list
+	dictionary = nil // Rebuilt on Next Strength Estimate
+	return nil
+}
+
+// Wordlist Word List by Name (nil if it doesn't Exist)
+func Wordlist(name string) []string {
+	wordlistsLock.RLock()
+	defer wordlistsLock.RUnlock()
+	return wordlists[strings.ToLower(name)]
+}
+
+// classes Character Classes Selected (Excluded Characters Removed)
+func (o *PasswordOptions) classes() ([]string, error) {
+	exclude := o.Exclude
+	if o.ExcludeAmbiguous {
+		exclude += CHARS_AMBIGUOUS
+	}
+
+	classes := []string{}
+	for _, c := range []struct {
+		use   bool
+		chars string
+	}{{o.Lower, CHARS_LOWER}, {o.Upper, CHARS_UPPER}, {o.Digits, CHARS_DIGITS}, {o.Symbols, CHARS_SYMBOLS}} {
+		if !c.use {
+			continue
+		}
+
+		chars := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(exclude, r) {
+				return -1
+			}
+			return r
+		}, c.chars)
+
+		if chars == "" {
+			return nil, errors.New("All Characters of a Class are Excluded")
+		}
+		classes = append(classes, chars)
+	}
+
+	if len(classes) == 0 {
+		return nil, errors.New("No Character Class Selected")
+	}
+
+	if o.Length < PASSWORD_MIN_LENGTH || o.Length > PASSWORD_MAX_LENGTH || o.Length < len(classes) {
+		return nil, fmt.Errorf("Password Length has to be between %d and %d", PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH)
+	}
+
+	return classes, nil
+}
+
+// Validate Can Passwords be Generated with the Options?
+func (o *PasswordOptions) Validate() error {
+	_, e := o.classes()
+	return e
+}
+
+// Entropy Bits of Entropy of Generated Passwords
+func (o *PasswordOptions) Entropy() float64 {
+	classes, e := o.classes()
+	if e != nil {
+		return 0
+	}
+
+	pool := 0
+	for _, c := range classes {
+		pool += len(c)
+	}
+	return float64(o.Length) * math.Log2(float64(pool))
+}
+
+// Password Generate Password (At Least One Character of Every Selected Class)
+func Password(o *PasswordOptions) (string, error) {
+	classes, e := o.classes()
+	if e != nil {
+		return "", e
+	}
+
+	all := strings.Join(classes, "")
+	b := make([]byte, o.Length)
+	for {
+		for i := range b {
+			n, e := randomIndex(len(all))
+			if e != nil {
+				return "", e
+			}
+			b[i] = all[n]
+		}
+
+		// Are all Classes Used?
+		if hasAllClasses(b, classes) { // YES
+			return string(b), nil
+		}
+		// NO: Try Again (Keeps Characters Uniformly Distributed)
+	}
+}
+
+func hasAllClasses(b []byte, classes []string) bool {
+	for _, c := range classes {
+		if !strings.ContainsAny(string(b), c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate Can Passphrases be Generated with the Options?
+func (o *PassphraseOptions) Validate() error {
+	if o.Words < PASSPHRASE_MIN_WORDS || o.Words > PASSPHRASE_MAX_WORDS {
+		return fmt.Errorf("Passphrase has to have between %d and %d Words", PASSPHRASE_MIN_WORDS, PASSPHRASE_MAX_WORDS)
+	}
+
+	if utf8.RuneCountInString(o.Separator) > PASSPHRASE_MAX_SEPARATOR {
+		return fmt.Errorf("Passphrase Separator has to have at most %d Characters", PASSPHRASE_MAX_SEPARATOR)
+	}
+
+	if Wordlist(o.Wordlist) == nil {
+		return fmt.Errorf("Word List [%s] does not Exist", o.Wordlist)
+	}
+
+	return nil
+}
+
+// Entropy Bits of Entropy of Generated Passphrases
+func (o *PassphraseOptions) Entropy() float64 {
+	if o.Validate() != nil {
+		return 0
+	}
+
+	bits := float64(o.Words) * math.Log2(float64(len(Wordlist(o.Wordlist))))
+	if o.Digit { // Digit and Word it is Added to
+		bits += math.Log2(10) + math.Log2(float64(o.Words))
+	}
+	return bits
+}
+
+// Passphrase Generate Passphrase
+func Passphrase(o *PassphraseOptions) (string, error) {
+	e := o.Validate()
+	if e != nil {
+		return "", e
+	}
+
+	list := Wordlist(o.Wordlist)
+	words := make([]string, o.Words)
+	for i := range words {
+		n, e := randomIndex(len(list))
+		if e != nil {
+			return "", e
+		}
+
+		words[i] = list[n]
+		if o.Capitalize {
+			r, size := utf8.DecodeRuneInString(words[i])
+			words[i] = strings.ToUpper(string(r)) + words[i][size:]
+		}
+	}
+
+	// Add Digit to Random Word?
+	if o.Digit { // YES
+		w, e := randomIndex(len(words))
+		if e != nil {
+			return "", e
+		}
+
+		d, e := randomIndex(10)
+		if e != nil {
+			return "", e
+		}
+		words[w] += CHARS_DIGITS[d : d+1]
+	}
+
+	return strings.Join(words, o.Separator), nil
+}
+
+// randomIndex Uniform Random Integer in [0, n)
+func randomIndex(n int) (int, error) {
+	v, e := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if e != nil {
+		return 0, e
+	}
+	return int(v.Int64()), nil
+}
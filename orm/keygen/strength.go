@@ -0,0 +1,429 @@
+// cSpell:ignore keygen, paulo ferreira, qwerty, zxcvbn, wordlist, wordlists
+package keygen
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+/* NOTE: Password Strength Estimation (zxcvbn Style)
+ * A password is scored by the (estimated) number of guesses an attacker,
+ * that tries the most likely patterns first, would need to find it.
+ * Every part of the password that matches a pattern (common password,
+ * dictionary word, personal information given by the client, repeated
+ * characters, sequence, keyboard pattern or year) costs the guesses needed
+ * to find it as that pattern, and the rest of the password costs brute force
+ * guesses (by the character classes it uses). The estimate is the cheapest
+ * way to cover the whole password.
+ * Capitals and common substitutions ('@' for 'a', '0' for 'o', ...) in
+ * matched words only add a few guesses.
+ *
+ * Score (Guesses):
+ * 0: < 10^3  (Too Guessable)
+ * 1: < 10^6  (Very Guessable)
+ * 2: < 10^8  (Somewhat Guessable)
+ * 3: < 10^10 (Safely Unguessable)
+ * 4: >= 10^10 (Very Unguessable)
+ */
+
+// Maximum Number of Characters Evaluated
+const PASSWORD_STRENGTH_MAX_LENGTH = 256
+
+// Passwords Shorter than this get a Warning
+const PASSWORD_RECOMMENDED_LENGTH = 12
+
+// Lowest Score Considered Strong (No Pattern Feedback)
+const PASSWORD_STRONG_SCORE = 3
+
+// PasswordStrength Strength Estimate
+type PasswordStrength struct {
+	Score       int      `json:"score"`         // 0 (Too Guessable) to 4 (Very Unguessable)
+	Entropy     float64  `json:"entropy"`       // Estimated Bits (log2 of Guesses)
+	Guesses     float64  `json:"guesses_log10"` // Estimated Guesses (log10)
+	Length      int      `json:"length"`        // Number of Characters
+	Warnings    []string `json:"warnings"`      // Weaknesses Found
+	Suggestions []string `json:"suggestions"`   // How to Improve
+}
+
+// Score Thresholds in Bits (~ 10^3, 10^6, 10^8, 10^10 Guesses)
+var strengthScores = []float64{10, 20, 27, 34}
+
+// Patterns (in Feedback Order)
+const patternCommon = "common"
+const patternInput = "input"
+const patternWord = "word"
+const patternRepeat = "repeat"
+const patternSequence = "sequence"
+const patternKeyboard = "keyboard"
+const patternYear = "year"
+
+var strengthFeedback = []struct {
+	pattern    string
+	warning    string
+	suggestion string
+}{
+	{patternCommon, "Contains a Commonly Used Password", "Avoid Common Passwords (even with Substitutions like '@' for 'a')"},
+	{patternInput, "Contains Personal Information", "Avoid Names, Email Addresses and other Personal Information"},
+	{patternWord, "Single Dictionary Words are Easy to Guess", "Add More Words, or Use Less Common Words"},
+	{patternRepeat, "Repeated Characters are Easy to Guess", "Avoid Repeated Characters"},
+	{patternSequence, "Sequences like 'abc' or '123' are Easy to Guess", "Avoid Sequences"},
+	{patternKeyboard, "Keyboard Patterns like 'qwerty' are Easy to Guess", "Avoid Keyboard Patterns"},
+	{patternYear, "Years are Easy to Guess", "Avoid Dates and Years Associated with You"},
+}
+
+// Keyboard Rows (and Columns)
+var keyboardPatterns = []string{
+	"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "!@#$%^&*()",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p",
+}
+
+// Common Substitutions (l33t)
+var substitutions = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '9': 'g',
+	'1': 'i', '!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't',
+	'+': 't', '2': 'z',
+}
+
+// Most Common Passwords (by Rank)
+var commonPasswords = strings.Fields(`
+123456 password 123456789 12345678 12345 qwerty 1234567 111111 123123
+abc123 1234567890 password1 iloveyou 000000 1234 qwerty123 1q2w3e4r
+dragon sunshine princess letmein 654321 monkey 1qaz2wsx 123321
+qwertyuiop superman asdfghjkl welcome football baseball master shadow
+michael jennifer hunter charlie jordan trustno1 killer batman starwars
+freedom whatever qazwsx passw0rd admin login hello secret solo ninja
+mustang access flower lovely 666666 121212 7777777 888888 999999
+987654321 123qwe zaq12wsx aa123456 donald password123 biteme computer
+cheese soccer hockey ranger thomas tigger robert daniel andrew pepper
+ginger summer winter internet samsung google apple orange chocolate
+butterfly purple banana cookie matrix silver diamond maggie buster
+harley hannah jessica ashley bailey nicole blink182 liverpool arsenal
+chelsea junior taylor yankees dallas austin merlin corvette mercedes
+changeme default guest root toor test test123 pass 1111 0000 11111111
+`)
+
+// dictionary Words of all Word Lists (Bits to Guess), Rebuilt when Lists Change
+var dictionary map[string]float64
+
+// strengthMatch Part of Password Matching a Pattern
+type strengthMatch struct {
+	start   int     // First Character
+	end     int     // Character after Last
+	bits    float64 // log2 of Guesses
+	pattern string  // Pattern Matched
+}
+
+// EvaluatePassword Estimate Password Strength (inputs: User Information, i.e. Name or Email)
+func EvaluatePassword(password string, inputs []string) *PasswordStrength {
+	runes := []rune(password)
+	if len(runes) > PASSWORD_STRENGTH_MAX_LENGTH {
+		runes = runes[:PASSWORD_STRENGTH_MAX_LENGTH]
+	}
+
+	// Lower Case and Un-Substituted Versions (Same Length)
+	lower := make([]rune, len(runes))
+	plain := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+		plain[i] = lower[i]
+		if s, ok := substitutions[lower[i]]; ok {
+			plain[i] = s
+		}
+	}
+
+	// Matches by Last Character
+	ends := make([][]*strengthMatch, len(runes)+1)
+	for _, m := range strengthMatches(runes, lower, plain, inputs) {
+		ends[m.end] = append(ends[m.end], m)
+	}
+
+	// Cheapest Way to Cover the Password (Brute Force or Pattern Matches)
+	brute := math.Log2(float64(characterPool(runes)))
+	best := make([]float64, len(runes)+1)
+	via := make([]*strengthMatch, len(runes)+1)
+	for j := 1; j <= len(runes); j++ {
+		best[j] = best[j-1] + brute
+		for _, m := range ends[j] {
+			if best[m.start]+m.bits < best[j] {
+				best[j] = best[m.start] + m.bits
+				via[j] = m
+			}
+		}
+	}
+
+	// Patterns Used
+	patterns := map[string]bool{}
+	for j := len(runes); j > 0; {
+		if via[j] == nil {
+			j--
+			continue
+		}
+
+		patterns[via[j].pattern] = true
+		j = via[j].start
+	}
+
+	bits := best[len(runes)]
+	s := &PasswordStrength{
+		Entropy:     math.Round(bits*10) / 10,
+		Guesses:     math.Round(bits*math.Log10(2)*100) / 100,
+		Length:      len(runes),
+		Warnings:    []string{},
+		Suggestions: []string{},
+	}
+
+	for _, threshold := range strengthScores {
+		if bits >= threshold {
+			s.Score++
+		}
+	}
+
+	// Feedback (Personal Information is Always Reported)
+	for _, f := range strengthFeedback {
+		if patterns[f.pattern] && (s.Score < PASSWORD_STRONG_SCORE || f.pattern == patternInput) {
+			s.Warnings = append(s.Warnings, f.warning)
+			s.Suggestions = append(s.Suggestions, f.suggestion)
+		}
+	}
+
+	if len(runes) < PASSWORD_RECOMMENDED_LENGTH {
+		s.Warnings = append(s.Warnings, "Password is Short")
+	}
+
+	if s.Score < PASSWORD_STRONG_SCORE {
+		s.Suggestions = append(s.Suggestions, "Use a Longer Password, or a Passphrase of Several Random Words")
+	}
+
+	return s
+}
+
+func strengthMatches(runes []rune, lower []rune, plain []rune, inputs []string) []*strengthMatch {
+	matches := []*strengthMatch{}
+	n := len(runes)
+
+	// Dictionaries (Common Passwords, Words and Personal Information)
+	dictionaries := []struct {
+		pattern string
+		words   map[string]float64
+	}{
+		{patternCommon, commonDictionary()},
+		{patternWord, wordDictionary()},
+		{patternInput, inputDictionary(inputs)},
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 3; j <= n && j-i <= 32; j++ {
+			l := string(lower[i:j])
+			p := string(plain[i:j])
+			for _, d := range dictionaries {
+				if b, ok := d.words[l]; ok {
+					matches = append(matches, &strengthMatch{i, j, b + capitalBits(runes[i:j]), d.pattern})
+				} else if b, ok := d.words[p]; ok {
+					matches = append(matches, &strengthMatch{i, j, b + capitalBits(runes[i:j]) + substitutionBits(lower[i:j], plain[i:j]), d.pattern})
+				}
+			}
+		}
+	}
+
+	// Repeated Characters
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n && lower[j] == lower[i]; j++ {
+			if j-i+1 >= 3 {
+				matches = append(matches, &strengthMatch{i, j + 1, math.Log2(float64(classSizes[characterClass(runes[i])])) + math.Log2(float64(j-i+1)), patternRepeat})
+			}
+		}
+	}
+
+	// Sequences (Ascending or Descending, in the Same Class)
+	for i := 0; i+1 < n; i++ {
+		delta := lower[i+1] - lower[i]
+		class := characterClass(lower[i])
+		if (delta != 1 && delta != -1) || characterClass(lower[i+1]) != class {
+			continue
+		}
+
+		for j := i + 1; j < n && lower[j]-lower[j-1] == delta && characterClass(lower[j]) == class; j++ {
+			if j-i+1 >= 3 {
+				bits := math.Log2(float64(classSizes[class])) + math.Log2(float64(j-i+1))
+				if delta < 0 {
+					bits++
+				}
+				matches = append(matches, &strengthMatch{i, j + 1, bits, patternSequence})
+			}
+		}
+	}
+
+	// Keyboard Patterns (Forwards or Backwards)
+	keyboardBits := math.Log2(float64(len(keyboardPatterns) * 2 * 10))
+	for i := 0; i < n; i++ {
+		for j := i + 4; j <= n; j++ {
+			k := string(lower[i:j])
+			if !isKeyboardPattern(k) {
+				break
+			}
+			matches = append(matches, &strengthMatch{i, j, keyboardBits + math.Log2(float64(j-i)), patternKeyboard})
+		}
+	}
+
+	// Years (1900 - 2099)
+	for i := 0; i+4 <= n; i++ {
+		y := string(runes[i : i+4])
+		if (strings.HasPrefix(y, "19") || strings.HasPrefix(y, "20")) && strings.Trim(y, CHARS_DIGITS) == "" {
+			matches = append(matches, &strengthMatch{i, i + 4, math.Log2(200), patternYear})
+		}
+	}
+
+	return matches
+}
+
+func commonDictionary() map[string]float64 {
+	d := make(map[string]float64, len(commonPasswords))
+	for rank, p := range commonPasswords {
+		if _, ok := d[p]; !ok {
+			d[p] = math.Max(1, math.Log2(float64(rank+1)))
+		}
+	}
+	return d
+}
+
+// wordDictionary Words of all Word Lists (Cheapest Bits if in more than One List)
+func wordDictionary() map[string]float64 {
+	wordlistsLock.Lock()
+	defer wordlistsLock.Unlock()
+
+	if dictionary == nil {
+		dictionary = map[string]float64{}
+		for _, list := range wordlists {
+			bits := math.Log2(float64(len(list)))
+			for _, w := range list {
+				if b, ok := dictionary[w]; !ok || bits < b {
+					dictionary[w] = bits
+				}
+			}
+		}
+	}
+
+	return dictionary
+}
+
+// inputDictionary User Information and its Parts (i.e. Email Name and Domain)
+func inputDictionary(inputs []string) map[string]float64 {
+	words := []string{}
+	for _, input := range inputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		words = append(words, input)
+		words = append(words, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	d := map[string]float64{}
+	bits := math.Log2(float64(len(words) + 1))
+	for _, w := range words {
+		if len([]rune(w)) >= 3 {
+			d[w] = bits
+		}
+	}
+	return d
+}
+
+// capitalBits Guesses Added by Capitals (First, All or Some Letters)
+func capitalBits(word []rune) float64 {
+	upper := 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 0
+	case upper == len(word) || (upper == 1 && unicode.IsUpper(word[0])):
+		return 1
+	default:
+		return float64(upper)
+	}
+}
+
+// substitutionBits Guesses Added by Substitutions (One Bit per Substituted Character)
+func substitutionBits(lower []rune, plain []rune) float64 {
+	bits := 0.0
+	for i := range lower {
+		if lower[i] != plain[i] {
+			bits++
+		}
+	}
+	return bits
+}
+
+func isKeyboardPattern(k string) bool {
+	for _, row := range keyboardPatterns {
+		if strings.Contains(row, k) || strings.Contains(reverse(row), k) {
+			return true
+		}
+	}
+	return false
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+// characterPool Number of Possible Characters (by Classes Used)
+func characterPool(runes []rune) int {
+	used := map[int]bool{}
+	pool := 0
+	for _, r := range runes {
+		c := characterClass(r)
+		if !used[c] {
+			used[c] = true
+			pool += classSizes[c]
+		}
+	}
+
+	if pool == 0 {
+		return 1
+	}
+	return pool
+}
+
+// Character Classes
+const classLower = 0
+const classUpper = 1
+const classDigit = 2
+const classSymbol = 3
+const classOther = 4
+
+// Number of Characters by Class
+var classSizes = []int{26, 26, 10, 33, 100}
+
+func characterClass(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return classLower
+	case r >= 'A' && r <= 'Z':
+		return classUpper
+	case r >= '0' && r <= '9':
+		return classDigit
+	case r < 128:
+		return classSymbol
+	default:
+		return classOther
+	}
+}
@@ -0,0 +1,82 @@
+// cSpell:disable
+package keygen
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Built-in Passphrase Word List (Short, Common and Easy to Type Words)
+const defaultWordlist = `
+able acid acorn actor adapt admit adult agent agree ahead aim air alarm
+album alert alien alley alpha amber amount anchor angle ankle apple
+april apron arena armor army arrow art ash aspen atlas atom attic audio
+autumn avoid awake award axis baby bacon badge bagel baker balm bamboo
+banana band banjo bank barn basil basin basket beach beam bean bear
+beard beast bed beef bell belt bench berry bike bird bison blade blank
+blast blaze blend blimp block bloom blue blush board boat body bolt
+bonus book boot border bottle bowl box brain brake branch brass bread
+brick bridge brief brook broom brush bubble bucket buddy bugle bunny
+burst butter button buzz cabin cable cactus cake camel camera camp
+canal candy canoe canvas canyon cape card cargo carpet carrot cart
+castle cat cedar cello chair chalk charm chart cheese cherry chess
+chest chief chili chip choir cider cinema circle citrus city clam clay
+cliff clock cloud clover coach coast cobra cocoa coconut code coffee
+coin comet coral cord corn cotton couch crab craft crane crater crayon
+cream creek crisp crow crown crumb crust cube cup curve cycle daisy
+dance dart dawn deck deer delta denim desert desk dial diary diner
+dingo disk diver dock dog dolphin dome donkey door dough dove dragon
+drama dream drift drill drum duck dune dust eagle earth easel echo
+eclipse edge eel egg elbow elder elk elm ember emerald engine envoy
+epic equal error essay ether exit fable fabric falcon fan farm feast
+feather fence fern ferry fiber field fig film finch fire fish flag
+flame flask fleet flint flock flora flour flute foam focus fog folk
+forest forge fork fort fossil fox frame frost fruit fudge funnel gadget
+galaxy game garden garlic gate gauge gecko gem ghost giant ginger
+giraffe glacier glass globe glove glue goat gold golf goose gorilla
+gown grain grape graph grass gravel gravy green grid grill grove guard
+guest guitar gull gust habit hammer hamster harbor harp hat hawk hazel
+heart hedge helmet herb hero heron hill hinge hippo hobby honey hook
+hope horn horse hotel hound house hull human humor husky hut ice icon
+igloo image index ink inlet iris iron island ivory ivy jacket jade
+jaguar jam jar jazz jeans jelly jet jewel jigsaw job jockey joke judge
+juice jungle jury kale kayak kettle key kid kiln kite kitten kiwi knee
+knife knot koala ladder lake lamb lamp lance lane laser latch lava lawn
+layer leaf lemon lens level lever lid lilac lily lime linen lion liquid
+lizard llama lobby lobster lock locket lodge logic loop lotus lunar
+lunch lyric macro magnet mango manor maple marble market mask mason
+meadow medal melon memo menu metal meteor mile milk mill mint mirror
+mist mitten model mole monk moon moose moss moth motor mouse mug mule
+mural muse music nail napkin nature navy nectar needle nest net nickel
+night noble noodle north nose note novel nugget nut nylon oak oasis oat
+ocean octave olive omega onion opal opera orange orbit orchid organ
+otter oven owl oxygen oyster paddle page paint palace palm panda panel
+panther paper parade park parrot pasta patch path peach peanut pear
+pearl pebble pecan pedal pelican pen pencil pepper piano pickle pier
+pigeon pillow pilot pine pipe pirate pizza planet plank plate plaza
+plum poem polar pond pony poppy porch potato pottery prairie prism puma
+pump puppy puzzle pyramid quail quartz queen quest quill quilt quiz
+rabbit raccoon radar radio raft rain raisin ramp ranch raven razor reef
+relay rhino ribbon rice ridge ring river road robin robot rocket rodeo
+roof rope rose rover ruby rug ruler rust saddle safari sage sail salad
+salmon salt sand satin sauce scarf scone scout sea seal season seed
+shadow shark sheep shelf shell shield ship shirt shoe shore shovel
+shrimp signal silk silver siren skate sketch ski skunk sky sled slope
+sloth snail snake snow soap sock sofa soil solar sonic soup spark spice
+spider spoon spring spruce squid stable stage stamp star steam steel
+stem stone storm stove straw stream studio sugar summit sun swan
+sweater swing syrup table taco tail tango tank tape target tea teapot
+tennis tent thorn thread thunder ticket tiger timber toast token tomato
+tonic tool topaz torch tower toy track tractor trail train tree trophy
+trout truck tulip tuna tunnel turkey turtle tuxedo twig umbra uncle
+unicorn unit urban valley valve vanilla vapor vase velvet vendor venus
+verse vest violet violin visor vista voice volcano voyage waffle wagon
+walnut walrus wand water wave wax whale wheat wheel whistle willow
+window wing winter wizard wolf wombat wood wool world wren yacht yard
+yarn yeast yogurt yoke zebra zenith zero zinc zipper zone
+`
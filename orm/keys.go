@@ -13,13 +13,13 @@ package orm
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/objectvault/api-services/orm/keygen"
 	"github.com/objectvault/api-services/orm/mysql"
 	"github.com/pjacferreira/sqlf"
 )
@@ -54,12 +54,12 @@ func KeyGetLastID(db sqlf.Executor, creator uint64) (uint32, error) {
 	return id, nil
 }
 
-func NewKey(creator uint64, bytes []byte, exp time.Time) ([]byte, *Key, error) {
+func NewKey(kek KEK, creator uint64, bytes []byte, exp time.Time) ([]byte, *Key, error) {
 	// Create a Key Object
 	k := &Key{}
 
 	// Create Cypher Text
-	key, e := k.EncryptKey(creator, bytes)
+	key, e := k.EncryptKey(kek, creator, bytes)
 	if e != nil { // ERROR
 		return nil, nil, e
 	}
@@ -136,14 +136,20 @@ func (o *Key) ID() uint32 {
 	return *o.id
 }
 
-func (o *Key) DecryptKey(key []byte) ([]byte, error) {
+func (o *Key) DecryptKey(kek KEK, key []byte) ([]byte, error) {
 	// Do we have a Password Set?
 	if len(o.ciphertext) == 0 { // NO
 		return nil, nil
 	}
 
+	// Unwrap Cipher Text with Server KEK
+	cypherbytes, e := UnwrapWithKEK(kek, o.ciphertext)
+	if e != nil {
+		return nil, e
+	}
+
 	// Convert String to Byte Array
-	return gcmDecrypt(key, o.ciphertext)
+	return gcmDecrypt(key, cypherbytes)
 }
 
 func (o *Key) Expiration() *time.Time {
@@ -205,7 +211,7 @@ func (o *Key) setCreator(id uint64) (uint64, error) {
 	return current, nil
 }
 
-func (o *Key) EncryptKey(creator uint64, bytes []byte) ([]byte, error) {
+func (o *Key) EncryptKey(kek KEK, creator uint64, bytes []byte) ([]byte, error) {
 	_, e := o.setCreator(creator)
 	if e != nil {
 		return nil, e
@@ -222,6 +228,12 @@ func (o *Key) EncryptKey(creator uint64, bytes []byte) ([]byte, error) {
 		return nil, e
 	}
 
+	// Wrap Cipher Text with Server KEK
+	cypherbytes, e = WrapWithKEK(kek, cypherbytes)
+	if e != nil {
+		return nil, e
+	}
+
 	o.ciphertext = cypherbytes
 
 	// Return Encryption Key
@@ -297,11 +309,6 @@ func (o *Key) generateEncryptionKey() ([]byte, error) {
 		return nil, errors.New("Not Ready")
 	}
 
-	// Generate a Pseudo Random String to Hash
-	rs := RandomAlphaNumericPunctuationString(128) // Random String to make things harder
-	hashString := fmt.Sprintf("%d:%s:%d", o.creator, rs, time.Now().UTC().UnixNano())
-	hash := sha256.Sum256([]byte(hashString))
-
-	// Create HASH of Plain Text
-	return hash[:], nil
+	// Cryptographically Secure Random Key
+	return keygen.Key()
 }
@@ -1,8 +1,11 @@
 package mysql
 
 import (
+	"errors"
 	"fmt"
 	"time"
+
+	driver "github.com/go-sql-driver/mysql"
 )
 
 /*
@@ -27,6 +30,12 @@ func MySQLtoBool(v uint8) bool {
 	return v != 0
 }
 
+// IsDuplicateKey Did a Statement Fail because of a Duplicate Key (ER_DUP_ENTRY)?
+func IsDuplicateKey(e error) bool {
+	var me *driver.MySQLError
+	return errors.As(e, &me) && me.Number == 1062
+}
+
 /* IMPORTANT NOTE:
  * MySQL stores timestamps in UTC, but serves them in local time, as set
  * on the MySQL Server HOST NODE.
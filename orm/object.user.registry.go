@@ -14,14 +14,15 @@ package orm
 // cSpell:ignore cypherbytes, ciphertext, plainbytes, userid
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 
+	"github.com/objectvault/api-services/orm/keygen"
 	"github.com/objectvault/api-services/orm/query"
 	"github.com/pjacferreira/sqlf"
 )
@@ -29,16 +30,40 @@ import (
 // Org User Registry Definition
 type ObjectUserRegistry struct {
 	States
-	dirty      bool    // Is Entry Dirty?
-	stored     bool    // Is Entry Stored in Database
-	object     *uint64 // KEY: GLOBAL Object ID
-	user       *uint64 // KEY: GLOBAL User ID
-	username   string  // User Name (ALIAS)
-	state      uint16  // User State in Object
-	roles      S_Roles // User Roles in Object
-	ciphertext []byte  // Encrypted Store Key
+	dirty      bool            // Is Entry Dirty?
+	stored     bool            // Is Entry Stored in Database
+	object     *uint64         // KEY: GLOBAL Object ID
+	user       *uint64         // KEY: GLOBAL User ID
+	username   string          // User Name (ALIAS)
+	state      uint16          // User State in Object
+	roles      S_Roles         // User Roles in Object
+	ciphertext []byte          // Store Key (Wrapped with User Password Key)
+	recovery   []byte          // (OPTIONAL) Store Key Sealed to User Account Recovery Key
+	rekey      []byte          // (OPTIONAL) Rotated Store Key (Sealed to User's Share Key) not yet Applied
+	shared     []byte          // (OPTIONAL) Store Key Sealed to User's Share Key (Pending Acceptance)
+	original   *objectUserKeys // Key Columns as Loaded (or Last Saved)
 }
 
+/* NOTE: Concurrent Key Column Updates
+ * Store key rotation, pending key application, share acceptance and the
+ * background re-wrap / upgrade jobs all update the key columns of the same
+ * entry. An update only writes the key columns that were modified, and only
+ * if they still hold the values that were loaded, so that an update based on
+ * a stale copy fails (ErrObjectUserRegistryModified), rather than overwriting
+ * (i.e. losing) a rotated store key.
+ */
+type objectUserKeys struct {
+	ciphertext []byte
+	recovery   []byte
+	rekey      []byte
+	shared     []byte
+}
+
+var ErrObjectUserRegistryModified = errors.New("Registry Entry Modified by Another Request")
+
+// Size of a Rotated Store Key Sealed to the User's Share Key: EPHEMERAL KEY (32) + KEY (32) + TAG (16)
+const storeKeySealedSize = 80
+
 func ObjectUsersDeleteAll(db *sql.DB, object uint64) (uint64, error) {
 	// Create SQL Statement
 	s := sqlf.DeleteFrom("registry_object_users").
@@ -83,6 +108,33 @@ func ObjectUserDelete(db *sql.DB, object uint64, user uint64) (bool, error) {
 	return true, nil
 }
 
+// ObjectUserIDs Global IDs of all Users Registered with Object
+func ObjectUserIDs(db *sql.DB, object uint64) ([]uint64, error) {
+	var ids []uint64
+
+	// Query Results Values
+	var id uint64
+
+	// Create SQL Statement
+	s := sqlf.From("registry_object_users").
+		Select("id_user").To(&id).
+		Where("id_object = ?", object).
+		OrderBy("id_user")
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		ids = append(ids, id)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return ids, nil
+}
+
 func ObjectUsersCount(db *sql.DB, object uint64, q query.TQueryConditions) (uint64, error) {
 	// Query Results Values
 	var count uint64
@@ -310,12 +362,15 @@ func (o *ObjectUserRegistry) ByKey(db *sql.DB, object uint64, user uint64) error
 	o.dirty = false
 
 	// Execute Query
-	var ciphertext, csv sql.NullString
+	var ciphertext, recovery, rekey, shared, csv sql.NullString
 	e := sqlf.From("registry_object_users").
 		Select("username").To(&o.username).
 		Select("state").To(&o.state).
 		Select("roles").To(&csv).
 		Select("ciphertext").To(&ciphertext).
+		Select("recovery").To(&recovery).
+		Select("rekey").To(&rekey).
+		Select("shared").To(&shared).
 		Where("id_object = ? and id_user = ?", object, user).
 		QueryRowAndClose(context.TODO(), db)
 
@@ -342,6 +397,25 @@ func (o *ObjectUserRegistry) ByKey(db *sql.DB, object uint64, user uint64) error
 			o.ciphertext = []byte(s)
 		}
 
+		// Do we have a recovery cipher?
+		if recovery.Valid { // YES: Save it
+			s := recovery.String
+			o.recovery = []byte(s)
+		}
+
+		// Do we have rotated store keys pending?
+		if rekey.Valid { // YES: Save it
+			s := rekey.String
+			o.rekey = []byte(s)
+		}
+
+		// Do we have a shared store key pending acceptance?
+		if shared.Valid { // YES: Save it
+			s := shared.String
+			o.shared = []byte(s)
+		}
+
+		o.original = o.currentKeys()
 		o.stored = true // Registered Entry
 	}
 
@@ -540,19 +614,9 @@ func (o *ObjectUserRegistry) ClearStates(states uint16) {
 }
 
 // STORE KEY
-func (o *ObjectUserRegistry) StoreKey(hexCypher string) ([]byte, error) {
-	// Convert User Hash to byte Array
-	key, e := hex.DecodeString(hexCypher)
-	if e != nil {
-		return nil, e
-	}
-
-	return o.StoreKeyBytes(key)
-}
-
-func (o *ObjectUserRegistry) StoreKeyBytes(key []byte) ([]byte, error) {
+func (o *ObjectUserRegistry) StoreKey(k *PasswordKey) ([]byte, error) {
 	// Decrypt Store Key
-	plainbytes, e := toPlainBytes(key, o.ciphertext)
+	plainbytes, e := k.Unwrap(o.ciphertext)
 	if e != nil {
 		return nil, e
 	}
@@ -560,36 +624,259 @@ func (o *ObjectUserRegistry) StoreKeyBytes(key []byte) ([]byte, error) {
 	return plainbytes, nil
 }
 
-func (o *ObjectUserRegistry) SetStoreKey(hexCypher string, key []byte) error {
+func (o *ObjectUserRegistry) SetStoreKey(k *PasswordKey, key []byte) error {
 	if o.IsNew() {
-		// Convert User Hash to byte Array
-		cypher, e := hex.DecodeString(hexCypher)
+		// Wrap Store Key with User Password Key
+		cypherbytes, e := k.Wrap(key)
 		if e != nil {
 			return e
 		}
 
-		// Encrypt Store Key using User Password Hash
-		cypherbytes, e := toCypherBytes(cypher, key)
+		o.ciphertext = cypherbytes
+		o.dirty = true
+		return nil
+	}
+
+	return errors.New("Store KEY is immutable")
+}
+
+// ReplaceStoreKey Replace Store Key (i.e. Recovered from Escrow) on an Existing Entry
+func (o *ObjectUserRegistry) ReplaceStoreKey(k *PasswordKey, key []byte) error {
+	// Wrap Store Key with User Password Key
+	cypherbytes, e := k.Wrap(key)
+	if e != nil {
+		return e
+	}
+
+	o.ciphertext = cypherbytes
+	o.rekey = nil // Key is Current
+	o.dirty = true
+	return nil
+}
+
+// RewrapStoreKey Re-Wrap Store Key with New User Password Key (returns true if Entry Modified)
+func (o *ObjectUserRegistry) RewrapStoreKey(k *PasswordKey, olds ...*PasswordKey) (bool, error) {
+	// Is Store Key Already Wrapped with New Password?
+	_, e := k.Unwrap(o.ciphertext)
+	if e == nil { // YES: Upgrade (if Required)
+		return o.UpgradeStoreKey(k)
+	}
+
+	// Try Previous Passwords (Most Recent First)
+	for _, old := range olds {
+		key, e := o.StoreKey(old)
+		if e != nil { // NOT this Password: Try Next
+			continue
+		}
+
+		// Wrap Store Key with New User Password Key
+		cypherbytes, e := k.Wrap(key)
 		if e != nil {
-			return e
+			return false, e
 		}
 
 		o.ciphertext = cypherbytes
 		o.dirty = true
-		return nil
+		return true, nil
 	}
 
-	return errors.New("Store KEY is immutable")
+	return false, errors.New("Unable to Unlock Store KEY")
+}
+
+// UpgradeStoreKey Re-Wrap Store Key with User's Current Password Key (returns true if Entry Modified)
+func (o *ObjectUserRegistry) UpgradeStoreKey(k *PasswordKey) (bool, error) {
+	// Is Store Key Wrapped with Current Password Key (or Pending Share)?
+	if len(o.ciphertext) == 0 || k.IsCurrent(o.ciphertext) { // YES: Nothing to Do
+		return false, nil
+	}
+
+	key, e := o.StoreKey(k)
+	if e != nil {
+		return false, e
+	}
+
+	cypherbytes, e := k.Wrap(key)
+	if e != nil {
+		return false, e
+	}
+
+	o.ciphertext = cypherbytes
+	o.dirty = true
+	return true, nil
+}
+
+// HasRecoveryKey Is the Store Key Sealed to the User's Account Recovery Key?
+func (o *ObjectUserRegistry) HasRecoveryKey() bool {
+	return len(o.recovery) > 0
+}
+
+// SealRecoveryKey Seal Store Key to User's Account Recovery Public Key
+func (o *ObjectUserRegistry) SealRecoveryKey(k *PasswordKey, public []byte) error {
+	// Get Store Key
+	key, e := o.StoreKey(k)
+	if e != nil {
+		return e
+	}
+
+	return o.SealRecoveryKeyBytes(public, key)
+}
+
+// SealRecoveryKeyBytes Seal Known Store Key (i.e. Rotated Key) to User's Account Recovery Public Key
+func (o *ObjectUserRegistry) SealRecoveryKeyBytes(public []byte, key []byte) error {
+	// Seal Store Key
+	sealed, e := SealToPublicKey(public, key)
+	if e != nil {
+		return e
+	}
+
+	o.recovery = sealed
+	o.dirty = true
+	return nil
+}
+
+// RecoverStoreKey Re-encrypt Store Key with New User Password Hash, using HEX Account Recovery Key
+func (o *ObjectUserRegistry) RecoverStoreKey(hexRecovery string, k *PasswordKey) error {
+	// Is Store Key Sealed to Recovery Key?
+	if !o.HasRecoveryKey() { // NO: Store Key Lost
+		return errors.New("Store KEY has no Recovery")
+	}
+
+	// Convert Recovery Key to byte Array
+	private, e := hex.DecodeString(hexRecovery)
+	if e != nil {
+		return e
+	}
+
+	// Open Sealed Store Key
+	key, e := OpenSealed(private, o.recovery)
+	if e != nil {
+		return e
+	}
+
+	// Wrap Store Key with New User Password Key
+	cypherbytes, e := k.Wrap(key)
+	if e != nil {
+		return e
+	}
+
+	o.ciphertext = cypherbytes
+	o.rekey = nil // Recovery Key is Re-Sealed on Store Key Rotation
+	o.dirty = true
+	return nil
+}
+
+// HasPendingStoreKey Has the Store Key been Rotated since the User last Unlocked it?
+func (o *ObjectUserRegistry) HasPendingStoreKey() bool {
+	return len(o.rekey) > 0
+}
+
+// SealPendingStoreKey Seal Rotated Store Key to User's Share Public Key (Applied the Next Time the User Unlocks the Store)
+func (o *ObjectUserRegistry) SealPendingStoreKey(public []byte, key []byte) error {
+	sealed, e := SealToPublicKey(public, key)
+	if e != nil {
+		return e
+	}
+
+	// NOTE: Latest Rotation Replaces any Pending Rotation (Sealed Key is Current)
+	o.rekey = sealed
+	o.dirty = true
+	return nil
 }
 
-func (o *ObjectUserRegistry) CreateStoreKey(hexCypher string) error {
+// ApplyPendingStoreKey Open Rotated Store Key (with User's Share Private Key) and Wrap it with User Password Key
+func (o *ObjectUserRegistry) ApplyPendingStoreKey(k *PasswordKey, private []byte) ([]byte, error) {
+	// Unlock Store Key Wrapped with User Password Key (Verifies Credentials)
+	key, e := o.StoreKey(k)
+	if e != nil {
+		return nil, e
+	}
+
+	// Any Rotations Pending?
+	if !o.HasPendingStoreKey() { // NO: Key is Current
+		return key, nil
+	}
+
+	// NOTE: Rotated Key is Sealed to the User's Share Key
+	if len(o.rekey) != storeKeySealedSize {
+		return nil, errors.New("Invalid Pending Store KEY")
+	}
+
+	if len(private) == 0 {
+		return nil, errors.New("Missing Share Private KEY")
+	}
+
+	key, e = OpenSealed(private, o.rekey)
+	if e != nil {
+		return nil, e
+	}
+
+	// Wrap Latest Store Key with User Password Key
+	cypherbytes, e := k.Wrap(key)
+	if e != nil {
+		return nil, e
+	}
+
+	o.ciphertext = cypherbytes
+	o.rekey = nil
+	o.dirty = true
+	return key, nil
+}
+
+// IsPendingShare Was the Store Shared with the User, who has not yet Accepted it?
+func (o *ObjectUserRegistry) IsPendingShare() bool {
+	return len(o.shared) > 0 && len(o.ciphertext) == 0
+}
+
+// ShareStoreKey Seal Store Key to User's Share Public Key (Accepted Later by the User)
+func (o *ObjectUserRegistry) ShareStoreKey(public []byte, key []byte) error {
+	// Does the User Already have the Store Key?
+	if len(o.ciphertext) > 0 { // YES: Nothing to Share
+		return errors.New("Store KEY already Set")
+	}
+
+	sealed, e := SealToPublicKey(public, key)
+	if e != nil {
+		return e
+	}
+
+	o.shared = sealed
+	o.dirty = true
+	return nil
+}
+
+// AcceptSharedStoreKey Open Shared Store Key with User's Share Private Key and Wrap it with User Password Key
+func (o *ObjectUserRegistry) AcceptSharedStoreKey(private []byte, k *PasswordKey) ([]byte, error) {
+	if !o.IsPendingShare() {
+		return nil, errors.New("No Shared Store KEY")
+	}
+
+	// Open Sealed Store Key
+	key, e := OpenSealed(private, o.shared)
+	if e != nil {
+		return nil, e
+	}
+
+	// Wrap Store Key with User Password Key
+	cypherbytes, e := k.Wrap(key)
+	if e != nil {
+		return nil, e
+	}
+
+	// NOTE: Rotations while Pending Re-Seal the Shared Key
+	o.ciphertext = cypherbytes
+	o.shared = nil
+	o.dirty = true
+	return key, nil
+}
+
+func (o *ObjectUserRegistry) CreateStoreKey(k *PasswordKey) error {
 	// Create a Random Store Key
 	key, e := o.generateCipherText()
 	if e != nil {
 		return e
 	}
 
-	return o.SetStoreKey(hexCypher, key)
+	return o.SetStoreKey(k, key)
 }
 
 func (o *ObjectUserRegistry) Flush(db sqlf.Executor, force bool) error {
@@ -605,6 +892,7 @@ func (o *ObjectUserRegistry) Flush(db sqlf.Executor, force bool) error {
 
 	// Is New Entry?
 	var s *sqlf.Stmt
+	conditional := false
 	if o.IsNew() { // YES: Create
 		if !o.IsValid() {
 			return errors.New("Invalid Registry Entry")
@@ -619,6 +907,18 @@ func (o *ObjectUserRegistry) Flush(db sqlf.Executor, force bool) error {
 		if o.ciphertext != nil {
 			s.Set("ciphertext", o.ciphertext)
 		}
+
+		if o.recovery != nil {
+			s.Set("recovery", o.recovery)
+		}
+
+		if o.rekey != nil {
+			s.Set("rekey", o.rekey)
+		}
+
+		if o.shared != nil {
+			s.Set("shared", o.shared)
+		}
 	} else { // NO: Update
 		if !o.hasKey() {
 			return errors.New("Missing or Invalid Registry Key")
@@ -634,10 +934,8 @@ func (o *ObjectUserRegistry) Flush(db sqlf.Executor, force bool) error {
 			s.Set("username", o.username)
 		}
 
-		// Is Cipher Changed?
-		if o.ciphertext != nil {
-			s.Set("ciphertext", o.ciphertext)
-		}
+		// Modified Key Columns (Only if Unchanged since Loaded)
+		conditional = o.setKeyColumns(s)
 	}
 
 	// Do we have Roles to Set?
@@ -664,12 +962,83 @@ func (o *ObjectUserRegistry) Flush(db sqlf.Executor, force bool) error {
 	}
 
 	// Execute Statement
-	_, e := s.ExecAndClose(context.TODO(), db)
-	if e == nil {
-		o.stored = true
-		o.dirty = false
+	r, e := s.ExecAndClose(context.TODO(), db)
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	// Were the Key Columns Modified by Another Request?
+	if conditional {
+		// NOTE: A Modified Key Column Always Changes the Row, if the Conditions Match
+		c, e := r.RowsAffected()
+		if e != nil {
+			log.Printf("query error: %v\n", e)
+			return e
+		}
+
+		if c == 0 { // YES: Entry has to be Reloaded
+			return ErrObjectUserRegistryModified
+		}
+	}
+
+	o.original = o.currentKeys()
+	o.stored = true
+	o.dirty = false
+	return nil
+}
+
+// setKeyColumns Set Modified Key Columns in Update (returns true if Update is Conditional)
+func (o *ObjectUserRegistry) setKeyColumns(s *sqlf.Stmt) bool {
+	// Entry Loaded without Key Columns? (i.e. List Entry)
+	if o.original == nil { // YES: Only Set Values can be Written
+		if o.ciphertext != nil {
+			s.Set("ciphertext", o.ciphertext)
+		}
+		if o.recovery != nil {
+			s.Set("recovery", o.recovery)
+		}
+		if o.rekey != nil {
+			s.Set("rekey", o.rekey)
+		}
+		if o.shared != nil {
+			s.Set("shared", o.shared)
+		}
+		return false
+	}
+
+	conditional := false
+	columns := []struct {
+		name     string
+		value    []byte
+		original []byte
+	}{
+		{"ciphertext", o.ciphertext, o.original.ciphertext},
+		{"recovery", o.recovery, o.original.recovery},
+		{"rekey", o.rekey, o.original.rekey},
+		{"shared", o.shared, o.original.shared},
+	}
+
+	for _, c := range columns {
+		if bytes.Equal(c.value, c.original) && (c.value == nil) == (c.original == nil) { // Not Modified
+			continue
+		}
+
+		s.Set(c.name, c.value)
+		s.Where(c.name+" <=> ?", c.original)
+		conditional = true
+	}
+	return conditional
+}
+
+// currentKeys Copy of Key Columns
+func (o *ObjectUserRegistry) currentKeys() *objectUserKeys {
+	return &objectUserKeys{
+		ciphertext: o.ciphertext,
+		recovery:   o.recovery,
+		rekey:      o.rekey,
+		shared:     o.shared,
 	}
-	return e
 }
 
 func (o *ObjectUserRegistry) hasKey() bool {
@@ -683,6 +1052,10 @@ func (o *ObjectUserRegistry) reset() {
 	o.username = ""
 	o.state = 0
 	o.ciphertext = nil
+	o.recovery = nil
+	o.rekey = nil
+	o.shared = nil
+	o.original = nil
 	o.RemoveAllRoles()
 
 	// Mark State as Unregistered
@@ -697,11 +1070,6 @@ func (o *ObjectUserRegistry) generateCipherText() ([]byte, error) {
 		return nil, errors.New("Not Ready")
 	}
 
-	// Generate a Pseudo Random String to Hash
-	rs := RandomAlphaNumericPunctuationString(128) // Random String to make things harder
-	hashString := fmt.Sprintf("%d:%s:%d", o.user, rs, o.object)
-	hash := sha256.Sum256([]byte(hashString))
-
-	// Create HASH of Plain Text
-	return hash[:], nil
+	// Cryptographically Secure Random Store Key
+	return keygen.Key()
 }
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/objectvault/api-services/orm/mysql"
+	"github.com/objectvault/common/maps"
 	"github.com/pjacferreira/sqlf"
 )
 
@@ -46,23 +47,25 @@ func GetShardOrgID(db sqlf.Executor, alias string) (uint32, error) {
 
 // Organization Object Definition
 type Organization struct {
-	dirty          bool       // Is Entry Dirty?
-	updateRegistry bool       // Do we need to Update the Registry?
-	stored         bool       // Is Entry Stored in Database
-	id             *uint32    // LOCAL Organization ID
-	alias          string     // Organization Alias
-	name           *string    // Organization Name (Can be NULL)
-	creator        *uint64    // Global User ID of Creator
-	created        *time.Time // Created TimeStamp
-	modifier       *uint64    // Global User ID of Last Modifier
-	modified       *time.Time // Modification TimeStamp
+	dirty          bool            // Is Entry Dirty?
+	updateRegistry bool            // Do we need to Update the Registry?
+	stored         bool            // Is Entry Stored in Database
+	id             *uint32         // LOCAL Organization ID
+	alias          string          // Organization Alias
+	name           *string         // Organization Name (Can be NULL)
+	escrow         []byte          // Store Key Escrow X25519 Public Key (Can be NULL)
+	settings       maps.MapWrapper // Organization Settings (Database "object" field)
+	creator        *uint64         // Global User ID of Creator
+	created        *time.Time      // Created TimeStamp
+	modifier       *uint64         // Global User ID of Last Modifier
+	modified       *time.Time      // Modification TimeStamp
 }
 
 // TODO Implement Delete (Both From Within an Entry and Without a Structure)
 
 // IsDirty Have the Object Properties Changed since last Serialization?
 func (o *Organization) IsDirty() bool {
-	return o.dirty
+	return o.dirty || o.settings.IsModified()
 }
 
 func (o *Organization) UpdateRegistry() bool {
@@ -99,7 +102,6 @@ func (o *Organization) ByID(db *sql.DB, id uint32) error {
 	o.reset()
 
 	// Execute Query
-	// TODO Process Database "object" field
 	var name sql.NullString
 	var object sql.NullString
 	var created sql.NullString
@@ -108,6 +110,7 @@ func (o *Organization) ByID(db *sql.DB, id uint32) error {
 	e := sqlf.From("orgs").
 		Select("orgname").To(&o.alias).
 		Select("name").To(&name).
+		Select("escrow_key").To(&o.escrow).
 		Select("object").To(&object).
 		Select("creator").To(&o.creator).
 		Select("created").To(&created).
@@ -139,7 +142,13 @@ func (o *Organization) ByID(db *sql.DB, id uint32) error {
 			}
 		}
 
-		// TODO Deal with Object
+		if object.Valid {
+			e = o.settings.Import(object.String)
+			if e != nil {
+				log.Printf("organization settings: %v\n", e)
+			}
+			o.settings.ClearModified(nil)
+		}
 		o.stored = true
 	}
 
@@ -158,13 +167,13 @@ func (o *Organization) ByAlias(db *sql.DB, alias string) error {
 	o.reset()
 
 	// Execute Query
-	// TODO Process Database "object" field
 	var name sql.NullString
 	var object sql.NullString
 	var creator sql.NullInt32
 	e := sqlf.From("orgs").
 		Select("id").To(o.id).
 		Select("name").To(&name).
+		Select("escrow_key").To(&o.escrow).
 		Select("creator").To(&creator).
 		Select("object").To(&object).
 		Where("orgname = ?", alias).
@@ -182,7 +191,14 @@ func (o *Organization) ByAlias(db *sql.DB, alias string) error {
 		if name.Valid {
 			o.name = &name.String
 		}
-		// TODO Deal with Object, Creator/ed, Modifier/de
+		if object.Valid {
+			e = o.settings.Import(object.String)
+			if e != nil {
+				log.Printf("organization settings: %v\n", e)
+			}
+			o.settings.ClearModified(nil)
+		}
+		// TODO Deal with Creator/ed, Modifier/de
 		o.stored = true
 	}
 
@@ -209,6 +225,21 @@ func (o *Organization) Name() string {
 	return *o.name
 }
 
+// HasEscrowKey Are Store Keys Escrowed for the Organization?
+func (o *Organization) HasEscrowKey() bool {
+	return len(o.escrow) > 0
+}
+
+// EscrowKey Store Key Escrow X25519 Public Key
+func (o *Organization) EscrowKey() []byte {
+	return o.escrow
+}
+
+// Settings Organization Settings (i.e. Store Session Policy)
+func (o *Organization) Settings() *maps.MapWrapper {
+	return &o.settings
+}
+
 func (o *Organization) Creator() uint64 {
 	if o.creator == nil {
 		return 0
@@ -291,6 +322,31 @@ func (o *Organization) SetName(name string) (string, error) {
 	return current, nil
 }
 
+// SetEscrowKey Set (or Clear, if nil) the Store Key Escrow X25519 Public Key
+func (o *Organization) SetEscrowKey(public []byte) ([]byte, error) {
+	// Current State
+	current := o.escrow
+
+	// Clear Escrow Key?
+	if len(public) == 0 { // YES
+		if len(current) > 0 {
+			o.escrow = nil
+			o.dirty = true
+		}
+		return current, nil
+	}
+
+	// Validate Public Key
+	if len(public) != 32 {
+		return current, errors.New("Invalid Escrow Public Key")
+	}
+
+	// New State
+	o.escrow = public
+	o.dirty = true
+	return current, nil
+}
+
 func (o *Organization) SetCreator(id uint64) error {
 	// Is Record New?
 	if o.IsNew() { // YES
@@ -330,12 +386,20 @@ func (o *Organization) Flush(db sqlf.Executor, force bool) error {
 			return errors.New("Creation User not Set")
 		}
 
-		// Execute Insert
-		_, e = sqlf.InsertInto("orgs").
+		// Create SQL Statement
+		s := sqlf.InsertInto("orgs").
 			Set("orgname", o.alias).
 			Set("name", o.name).
-			Set("creator", o.creator).
-			ExecAndClose(context.TODO(), db)
+			Set("escrow_key", o.escrow).
+			Set("creator", o.creator)
+
+		// Do we have Organization Settings?
+		if !o.settings.IsEmpty() { // YES
+			s.Set("object", o.settings.Export())
+		}
+
+		// Execute Insert
+		_, e = s.ExecAndClose(context.TODO(), db)
 
 		// Error Occurred?
 		if e == nil { // NO: Get New Org's ID
@@ -355,17 +419,31 @@ func (o *Organization) Flush(db sqlf.Executor, force bool) error {
 			return errors.New("Modification User not Set")
 		}
 
-		_, e = sqlf.Update("orgs").
+		// Create SQL Statement
+		s := sqlf.Update("orgs").
 			Set("orgname", o.alias).
 			Set("name", o.name).
+			Set("escrow_key", o.escrow).
 			Set("modifier", o.modifier).
-			Where("id = ?", o.id).
-			ExecAndClose(context.TODO(), db)
+			Where("id = ?", o.id)
+
+		// Have Organization Settings Changed?
+		if o.settings.IsModified() { // YES
+			if o.settings.IsEmpty() {
+				s.Set("object", nil)
+			} else {
+				s.Set("object", o.settings.Export())
+			}
+		}
+
+		// Execute Statement
+		_, e = s.ExecAndClose(context.TODO(), db)
 	}
 
 	if e == nil {
 		o.stored = true
 		o.dirty = false
+		o.settings.ClearModified(nil)
 	}
 	return e
 }
@@ -375,6 +453,8 @@ func (o *Organization) reset() {
 	o.id = nil
 	o.alias = ""
 	o.name = nil
+	o.escrow = nil
+	o.settings.Reset()
 	o.creator = nil
 	o.created = nil
 	o.modifier = nil
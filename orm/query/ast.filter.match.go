@@ -0,0 +1,175 @@
+package query
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+)
+
+/* NOTE: In Memory Filters
+ * Values that can't be filtered by the database (i.e. encrypted titles) are
+ * filtered after being loaded, using the same filter AST.
+ * Comparisons follow the database: strings are compared case insensitive and
+ * CONTAINS uses the filter wildcards ('*'), like LIKE does.
+ */
+
+// TFieldValue Value of (ORM) Field for In Memory Filter
+type TFieldValue = func(field string) interface{}
+
+// HasField Does the Filter Reference the (ORM) Field?
+func (a *SQLFWhere) HasField(field string) bool {
+	if a.filter == nil || a.filter.F == nil {
+		return false
+	}
+
+	return a.hasField(a.filter.F, field)
+}
+
+// Match Does the Value (given by Field) Pass the Filter?
+func (a *SQLFWhere) Match(value TFieldValue) (bool, error) {
+	// Do we have a Filter?
+	if a.filter == nil || a.filter.F == nil { // NO: Everything Matches
+		return true, nil
+	}
+
+	return a.matchFunction(a.filter.F, value)
+}
+
+func (a *SQLFWhere) hasField(f *ast.Function, field string) bool {
+	for _, p := range f.Parameters {
+		switch n := p.(type) {
+		case *ast.Function:
+			if a.hasField(n, field) {
+				return true
+			}
+		case *ast.Value:
+			// Field Operators have the Field as the 1st Parameter
+			if n == f.Parameters[0] && a.mapField(n.V.Literal) == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *SQLFWhere) matchFunction(f *ast.Function, value TFieldValue) (bool, error) {
+	// ASSUMPTION: Filter has been run through Syntax Checker so AST is Correct
+	fname := f.Name.Literal
+	switch fname {
+	case "NOT":
+		m, e := a.matchFunction((f.Parameters[0]).(*ast.Function), value)
+		return !m, e
+	case "AND", "OR":
+		m1, e := a.matchFunction((f.Parameters[0]).(*ast.Function), value)
+		if e != nil {
+			return false, e
+		}
+
+		// Is the Result Known from the 1st Parameter?
+		if (fname == "AND" && !m1) || (fname == "OR" && m1) { // YES
+			return m1, nil
+		}
+		return a.matchFunction((f.Parameters[1]).(*ast.Function), value)
+	}
+
+	// Field Operators
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value)
+
+	// Is Valid Field?
+	field := a.mapField(pv1.V.Literal)
+	if field == "" { // NO
+		return false, fmt.Errorf("Invalid Field [%s]", pv1.V.Literal)
+	}
+
+	// Do we have a Field Value?
+	fv := a.mapValue(field, pv2.V.Literal)
+	if fv == nil { // NO: Abort
+		return false, fmt.Errorf("Invalid Field [%s] Value [%s]", pv1.V.Literal, pv2.V.Literal)
+	}
+
+	v := value(field)
+	switch fname {
+	case "EQ", "IN":
+		return CompareValues(v, fv) == 0, nil
+	case "NEQ":
+		return CompareValues(v, fv) != 0, nil
+	case "GT":
+		return CompareValues(v, fv) > 0, nil
+	case "GTE":
+		return CompareValues(v, fv) >= 0, nil
+	case "LT":
+		return CompareValues(v, fv) < 0, nil
+	case "LTE":
+		return CompareValues(v, fv) <= 0, nil
+	case "CONTAINS":
+		return matchWildcards(fmt.Sprint(v), fmt.Sprint(fv)), nil
+	default:
+		return false, fmt.Errorf("Unsupported Function [%s]", fname)
+	}
+}
+
+// CompareValues Compare Field Value with Filter (or other Field) Value (Strings are Case Insensitive)
+func CompareValues(v interface{}, fv interface{}) int {
+	// Is the Field Value a Number?
+	if n, ok := toFloat(v); ok { // YES: Compare as Numbers (if Possible)
+		if m, e := strconv.ParseFloat(fmt.Sprint(fv), 64); e == nil {
+			switch {
+			case n < m:
+				return -1
+			case n > m:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(strings.ToLower(fmt.Sprint(v)), strings.ToLower(fmt.Sprint(fv)))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func matchWildcards(v string, pattern string) bool {
+	// Filter Wildcards were Replaced by '�' when Parsed
+	parts := strings.Split(pattern, "�")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+
+	re, e := regexp.Compile("(?is)^" + strings.Join(parts, ".*") + "$")
+	if e != nil {
+		return false
+	}
+	return re.MatchString(v)
+}
@@ -18,8 +18,7 @@ import (
 	"log"
 	"time"
 
-	"github.com/gofrs/uuid"
-
+	"github.com/objectvault/api-services/orm/keygen"
 	"github.com/objectvault/api-services/orm/mysql"
 	"github.com/objectvault/common/maps"
 	"github.com/pjacferreira/sqlf"
@@ -48,8 +47,6 @@ func NewRequest(t string, creator uint64) *Request {
 		creator: &creator,
 	}
 
-	// Set UUID
-	o.GUID()
 	return o
 }
 
@@ -159,10 +156,6 @@ func (o *Request) ID() uint32 {
 }
 
 func (o *Request) GUID() string {
-	if o.guid == "" {
-		u, _ := uuid.NewV4()
-		o.guid = u.String()
-	}
 	return o.guid
 }
 
@@ -313,6 +306,14 @@ func (o *Request) Flush(db sqlf.Executor, force bool) error {
 	// Is New Entry?
 	var e error
 	if o.IsNew() { // YES: Create
+		// Do we have a GUID?
+		if o.guid == "" { // NO: Create One (Set on Create)
+			o.guid, e = keygen.GUID()
+			if e != nil {
+				return e
+			}
+		}
+
 		// Execute Insert
 		s := sqlf.InsertInto("requests").
 			Set("guid", o.GUID()).
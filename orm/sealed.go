@@ -0,0 +1,95 @@
+// cSpell:ignore ferreira, paulo
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// cSpell:ignore curve, nacl
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/objectvault/api-services/orm/keygen"
+)
+
+/* NOTE: Sealed Boxes
+ * Used when a value has to be encrypted by the server for a party whose
+ * private key the server does not hold (i.e. account recovery keys). Sealed
+ * Boxes are compatible with libsodium's crypto_box_seal.
+ */
+
+// NewX25519KeyPair Create a Random X25519 Key Pair
+func NewX25519KeyPair() ([]byte, []byte, error) {
+	private, e := keygen.Key()
+	if e != nil {
+		return nil, nil, e
+	}
+
+	public, e := X25519PublicKey(private)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	return public, private, nil
+}
+
+// X25519PublicKey Calculate Public Key for X25519 Private Key
+func X25519PublicKey(private []byte) ([]byte, error) {
+	if len(private) != curve25519.ScalarSize {
+		return nil, errors.New("Invalid Private Key")
+	}
+
+	return curve25519.X25519(private, curve25519.Basepoint)
+}
+
+// IsX25519KeyPair Does the Private Key Match the Public Key?
+func IsX25519KeyPair(public, private []byte) bool {
+	p, e := X25519PublicKey(private)
+	if e != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(p, public) == 1
+}
+
+// SealToPublicKey Encrypt Bytes so that they can only be Opened by the Holder of the Private Key
+func SealToPublicKey(public []byte, bytes []byte) ([]byte, error) {
+	if len(public) != curve25519.PointSize {
+		return nil, errors.New("Invalid Public Key")
+	}
+
+	var recipient [32]byte
+	copy(recipient[:], public)
+	return box.SealAnonymous(nil, bytes, &recipient, rand.Reader)
+}
+
+// OpenSealed Decrypt Bytes Sealed to Public Key of Private Key
+func OpenSealed(private []byte, sealed []byte) ([]byte, error) {
+	public, e := X25519PublicKey(private)
+	if e != nil {
+		return nil, e
+	}
+
+	var pub, priv [32]byte
+	copy(pub[:], public)
+	copy(priv[:], private)
+
+	bytes, ok := box.OpenAnonymous(nil, sealed, &pub, &priv)
+	if !ok {
+		return nil, errors.New("Failed to Open Sealed Bytes")
+	}
+
+	return bytes, nil
+}
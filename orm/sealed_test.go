@@ -0,0 +1,102 @@
+// cSpell:ignore paulo ferreira
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestX25519KeyPair(t *testing.T) {
+	public, private, e := NewX25519KeyPair()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !IsX25519KeyPair(public, private) {
+		t.Fatal("Key Pair should Match")
+	}
+
+	other, _, e := NewX25519KeyPair()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if IsX25519KeyPair(other, private) {
+		t.Fatal("Private Key should not Match Other Public Key")
+	}
+
+	if _, e := X25519PublicKey(private[:16]); e == nil {
+		t.Fatal("Short Private Key should Fail")
+	}
+}
+
+func TestSealed(t *testing.T) {
+	public, private, e := NewX25519KeyPair()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	plain := []byte("recovery key")
+	sealed, e := SealToPublicKey(public, plain)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	opened, e := OpenSealed(private, sealed)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Equal(opened, plain) {
+		t.Fatalf("Opened [%s], Expected [%s]", opened, plain)
+	}
+
+	// Sealed Boxes use an Ephemeral Key (Same Bytes, Different Box)
+	again, e := SealToPublicKey(public, plain)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if bytes.Equal(again, sealed) {
+		t.Fatal("Sealed Boxes Repeated")
+	}
+
+	// Wrong Private Key
+	_, other, e := NewX25519KeyPair()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := OpenSealed(other, sealed); e == nil {
+		t.Fatal("Wrong Private Key should Fail")
+	}
+
+	// Tampered Ephemeral Public Key and Cipher Text
+	for _, i := range []int{0, len(sealed) - 1} {
+		tampered := append([]byte{}, sealed...)
+		tampered[i] ^= 0x01
+		if _, e := OpenSealed(private, tampered); e == nil {
+			t.Fatalf("Sealed Box Tampered at Byte %d should Fail", i)
+		}
+	}
+
+	// Truncated
+	if _, e := OpenSealed(private, sealed[:len(sealed)-1]); e == nil {
+		t.Fatal("Truncated Sealed Box should Fail")
+	}
+
+	// Invalid Public Key
+	if _, e := SealToPublicKey(public[:16], plain); e == nil {
+		t.Fatal("Short Public Key should Fail")
+	}
+}
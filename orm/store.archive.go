@@ -0,0 +1,371 @@
+// cSpell:ignore argon, argon2id, cypherbytes, kdf, ovar, paulo ferreira
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Store Archives
+ * A store archive is a JSON document (StoreArchive) encrypted with a key
+ * derived (Argon2id) from a passphrase chosen by the user, so that it can be
+ * kept outside of the vault, and restored into any store.
+ *
+ * Archive Format:
+ * [0:4]       Magic ('OVAR')
+ * [4]         Archive Version
+ * [5:31]      KDF Header (Same Layout as User Password Check, see kdf.go)
+ * [31:]       Frames: [Length (uint32)] [NONCE + CIPHERTEXT]
+ *
+ * The JSON document is split into frames of (at most) STORE_ARCHIVE_FRAME_SIZE
+ * bytes, so that archives can be written and read as streams. Every frame is
+ * bound (AES-GCM additional data) to the archive header, its position and to
+ * whether it is the last frame, so frames can't be reordered, and a
+ * truncated archive is detected.
+ *
+ * The same maximum size applies to export and restore (see
+ * StoreArchiveMaxSize), so every exported archive can be restored: an export
+ * is refused if its estimated size is too large, and fails (without its last
+ * frame) if it grows past the maximum while being written.
+ */
+
+const STORE_ARCHIVE_MAGIC = "OVAR"
+const STORE_ARCHIVE_V1 = 0x01
+
+// Plain Bytes per Frame
+const STORE_ARCHIVE_FRAME_SIZE = 64 * 1024
+
+// Store Archive Document Format and Version
+const STORE_ARCHIVE_FORMAT = "objectvault.store"
+const STORE_ARCHIVE_FORMAT_VERSION = 1
+
+const storeArchiveHeaderSize = 5 + kdfHeaderSize
+
+// Maximum Archive KDF Costs (Archive Headers are Untrusted)
+const storeArchiveMaxTime = 8
+const storeArchiveMaxMemory = 256 * 1024 // 256MiB
+const storeArchiveMaxThreads = 16
+
+// Archive Errors
+var ErrStoreArchiveInvalid = errors.New("Invalid (or Corrupt) Store Archive")
+var ErrStoreArchivePassphrase = errors.New("Invalid Store Archive Passphrase")
+var ErrStoreArchiveTooLarge = errors.New("Store Archive Larger than Allowed")
+
+// Maximum Size of Archives Exported or Accepted for Restore (Default: 64MiB)
+var storeArchiveMaxSize int64 = 64 * 1024 * 1024
+var storeArchiveLock sync.RWMutex
+
+// StoreArchive Archive Document
+type StoreArchive struct {
+	Format    string                 `json:"format"`
+	Version   int                    `json:"version"`
+	Store     string                 `json:"store"`
+	Exported  string                 `json:"exported"`
+	Templates []StoreArchiveTemplate `json:"templates"`
+	Objects   []*StoreArchiveObject  `json:"objects"`
+}
+
+// StoreArchiveTemplate Template Registered with Archived Store
+type StoreArchiveTemplate struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+// StoreArchiveObject Archived Store Object (Values in Plain Text)
+type StoreArchiveObject struct {
+	ID         string                  `json:"id"`
+	Parent     string                  `json:"parent"`
+	Type       uint8                   `json:"type"`
+	Title      string                  `json:"title"`
+	Object     *StoreTemplateObject    `json:"object,omitempty"`
+	Attachment *StoreArchiveAttachment `json:"attachment,omitempty"`
+}
+
+// StoreArchiveAttachment Archived Attachment Content
+type StoreArchiveAttachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"` // Base64 in JSON
+}
+
+// SetStoreArchiveMaxSize Set Maximum Size (in Bytes) of Archives Exported or Accepted for Restore
+func SetStoreArchiveMaxSize(max int64) error {
+	if max <= 0 {
+		return errors.New("Archive Maximum Size has to be > 0")
+	}
+
+	storeArchiveLock.Lock()
+	defer storeArchiveLock.Unlock()
+	storeArchiveMaxSize = max
+	return nil
+}
+
+// StoreArchiveMaxSize Maximum Size (in Bytes) of Archives Exported or Accepted for Restore
+func StoreArchiveMaxSize() int64 {
+	storeArchiveLock.RLock()
+	defer storeArchiveLock.RUnlock()
+	return storeArchiveMaxSize
+}
+
+// StoreArchiveEstimate Estimated Archive Size (in Bytes) of Store Objects (Outside of Trash) and Attachments
+func StoreArchiveEstimate(db *sql.DB, store uint32) (int64, error) {
+	// Query Results Values
+	var objects sql.NullInt64
+
+	// Encrypted Values (~ Size of Plain JSON Values)
+	e := sqlf.From("objects").
+		Select("SUM(LENGTH(title) + LENGTH(object))").To(&objects).
+		Where("id_store = ? and trash IS NULL", store).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	// Attachments are Base64 Encoded in Archive
+	attachments, e := StoreAttachmentsUsage(db, store)
+	if e != nil {
+		return 0, e
+	}
+
+	return objects.Int64 + int64(attachments)*4/3, nil
+}
+
+// storeArchiveWriter Encrypts Written Bytes into Archive Frames
+type storeArchiveWriter struct {
+	w      io.Writer // Archive Destination
+	key    []byte    // Derived Key
+	header []byte    // Archive Header
+	index  uint64    // Next Frame Position
+	buffer []byte    // Plain Bytes not yet Written
+	closed bool      // Last Frame Written?
+	size   int64     // Archive Bytes Written
+	max    int64     // Maximum Archive Size
+}
+
+// NewStoreArchiveWriter Write Archive Header and Return Writer for Archive Document (Close Writes the Last Frame)
+func NewStoreArchiveWriter(w io.Writer, passphrase []byte) (io.WriteCloser, error) {
+	p := PasswordKDF()
+
+	// Create Header
+	header := make([]byte, storeArchiveHeaderSize)
+	copy(header, STORE_ARCHIVE_MAGIC)
+	header[4] = STORE_ARCHIVE_V1
+	kdf := header[5:]
+	kdf[0] = PASSWORD_KDF_ARGON2ID
+	binary.BigEndian.PutUint32(kdf[1:5], p.Time)
+	binary.BigEndian.PutUint32(kdf[5:9], p.Memory)
+	kdf[9] = p.Threads
+
+	// Random Salt
+	_, e := io.ReadFull(rand.Reader, kdf[10:])
+	if e != nil {
+		return nil, e
+	}
+
+	_, e = w.Write(header)
+	if e != nil {
+		return nil, e
+	}
+
+	return &storeArchiveWriter{
+		w:      w,
+		key:    deriveKeyArgon2(passphrase, kdf),
+		header: header,
+		buffer: make([]byte, 0, 2*STORE_ARCHIVE_FRAME_SIZE),
+		size:   int64(len(header)),
+		max:    StoreArchiveMaxSize(),
+	}, nil
+}
+
+func (aw *storeArchiveWriter) Write(p []byte) (int, error) {
+	if aw.closed {
+		return 0, errors.New("Store Archive Closed")
+	}
+
+	aw.buffer = append(aw.buffer, p...)
+
+	// Only Write Full Frames that are Known not to be the Last
+	for len(aw.buffer) > STORE_ARCHIVE_FRAME_SIZE {
+		e := aw.frame(aw.buffer[:STORE_ARCHIVE_FRAME_SIZE], false)
+		if e != nil {
+			return 0, e
+		}
+		aw.buffer = append(aw.buffer[:0], aw.buffer[STORE_ARCHIVE_FRAME_SIZE:]...)
+	}
+
+	return len(p), nil
+}
+
+// Close Write Last Frame (Doesn't Close the Archive Destination)
+func (aw *storeArchiveWriter) Close() error {
+	if aw.closed {
+		return nil
+	}
+
+	aw.closed = true
+	return aw.frame(aw.buffer, true)
+}
+
+func (aw *storeArchiveWriter) frame(plain []byte, last bool) error {
+	cypherbytes, e := gcmEncryptAAD(aw.key, plain, storeArchiveFrameAAD(aw.header, aw.index, last))
+	if e != nil {
+		return e
+	}
+	aw.index++
+
+	// Would the Archive be too Large to Restore?
+	aw.size += int64(4 + len(cypherbytes))
+	if aw.size > aw.max { // YES: Archive Left without Last Frame
+		aw.closed = true
+		return ErrStoreArchiveTooLarge
+	}
+
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(cypherbytes)))
+	_, e = aw.w.Write(append(length, cypherbytes...))
+	return e
+}
+
+// storeArchiveReader Decrypts Archive Frames
+type storeArchiveReader struct {
+	r      io.Reader // Archive Source
+	key    []byte    // Derived Key
+	header []byte    // Archive Header
+	index  uint64    // Next Frame Position
+	buffer []byte    // Plain Bytes not yet Read
+	last   bool      // Last Frame Read?
+}
+
+// NewStoreArchiveReader Read Archive Header and Return Reader for Archive Document
+func NewStoreArchiveReader(r io.Reader, passphrase []byte) (io.Reader, error) {
+	header := make([]byte, storeArchiveHeaderSize)
+	_, e := io.ReadFull(r, header)
+	if e != nil {
+		return nil, ErrStoreArchiveInvalid
+	}
+
+	// Is it a Store Archive (with Usable KDF Parameters)?
+	kdf := header[5:]
+	if !bytes.Equal(header[:4], []byte(STORE_ARCHIVE_MAGIC)) || header[4] != STORE_ARCHIVE_V1 || !isStoreArchiveKDF(kdf) { // NO
+		return nil, ErrStoreArchiveInvalid
+	}
+
+	return &storeArchiveReader{
+		r:      r,
+		key:    deriveKeyArgon2(passphrase, kdf),
+		header: header,
+	}, nil
+}
+
+func (ar *storeArchiveReader) Read(p []byte) (int, error) {
+	for len(ar.buffer) == 0 {
+		if ar.last {
+			return 0, io.EOF
+		}
+
+		e := ar.frame()
+		if e != nil {
+			return 0, e
+		}
+	}
+
+	n := copy(p, ar.buffer)
+	ar.buffer = ar.buffer[n:]
+	return n, nil
+}
+
+func (ar *storeArchiveReader) frame() error {
+	length := make([]byte, 4)
+	_, e := io.ReadFull(ar.r, length)
+	if e != nil { // Truncated Archive
+		return ErrStoreArchiveInvalid
+	}
+
+	// Is the Frame Length Valid?
+	size := binary.BigEndian.Uint32(length)
+	if size > STORE_ARCHIVE_FRAME_SIZE+64 { // NO
+		return ErrStoreArchiveInvalid
+	}
+
+	cypherbytes := make([]byte, size)
+	_, e = io.ReadFull(ar.r, cypherbytes)
+	if e != nil { // Truncated Archive
+		return ErrStoreArchiveInvalid
+	}
+
+	// Is this the Last Frame?
+	last := true
+	plain, e := gcmDecryptAAD(ar.key, cypherbytes, storeArchiveFrameAAD(ar.header, ar.index, true))
+	if e != nil { // NO: or Wrong Key
+		last = false
+		plain, e = gcmDecryptAAD(ar.key, cypherbytes, storeArchiveFrameAAD(ar.header, ar.index, false))
+	}
+
+	if e != nil {
+		// Failure on the First Frame is (Most Likely) a Wrong Passphrase
+		if ar.index == 0 {
+			return ErrStoreArchivePassphrase
+		}
+		return ErrStoreArchiveInvalid
+	}
+
+	// Is there Data after the Last Frame?
+	if last {
+		n, _ := ar.r.Read(make([]byte, 1))
+		if n > 0 { // YES
+			return ErrStoreArchiveInvalid
+		}
+	}
+
+	ar.index++
+	ar.last = last
+	ar.buffer = plain
+	return nil
+}
+
+// isStoreArchiveKDF Sanity Check KDF Parameters (Don't Allow Archives to Exhaust Resources)
+func isStoreArchiveKDF(kdf []byte) bool {
+	if kdf[0] != PASSWORD_KDF_ARGON2ID {
+		return false
+	}
+
+	// NOTE: Costs are Limited to the Current Parameters (or the Fixed Maximums, if Larger)
+	p := PasswordKDF()
+	t := binary.BigEndian.Uint32(kdf[1:5])
+	m := binary.BigEndian.Uint32(kdf[5:9])
+	return t > 0 && (t <= p.Time || t <= storeArchiveMaxTime) &&
+		m > 0 && (m <= p.Memory || m <= storeArchiveMaxMemory) &&
+		kdf[9] > 0 && (kdf[9] <= p.Threads || kdf[9] <= storeArchiveMaxThreads)
+}
+
+func storeArchiveFrameAAD(header []byte, index uint64, last bool) []byte {
+	aad := make([]byte, len(header)+9)
+	copy(aad, header)
+	binary.BigEndian.PutUint64(aad[len(header):], index)
+	if last {
+		aad[len(aad)-1] = 1
+	}
+	return aad
+}
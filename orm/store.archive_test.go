@@ -0,0 +1,213 @@
+// cSpell:ignore paulo ferreira, kdf
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// testArchive Archive of Document (Returns Archive Bytes)
+func testArchive(t *testing.T, document []byte, passphrase string) []byte {
+	var b bytes.Buffer
+	w, e := NewStoreArchiveWriter(&b, []byte(passphrase))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := w.Write(document); e != nil {
+		t.Fatal(e)
+	}
+
+	if e := w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	return b.Bytes()
+}
+
+func readTestArchive(archive []byte, passphrase string) ([]byte, error) {
+	r, e := NewStoreArchiveReader(bytes.NewReader(archive), []byte(passphrase))
+	if e != nil {
+		return nil, e
+	}
+	return io.ReadAll(r)
+}
+
+func TestStoreArchive(t *testing.T) {
+	useTestKDF(t)
+
+	// Empty, Single Frame, Exactly One Frame and Multiple Frames
+	for _, size := range []int{0, 100, STORE_ARCHIVE_FRAME_SIZE, 2*STORE_ARCHIVE_FRAME_SIZE + 1} {
+		document := bytes.Repeat([]byte("x"), size)
+		archive := testArchive(t, document, "passphrase")
+
+		plain, e := readTestArchive(archive, "passphrase")
+		if e != nil {
+			t.Fatalf("Document of %d Bytes: %v", size, e)
+		}
+
+		if !bytes.Equal(plain, document) {
+			t.Fatalf("Document of %d Bytes: Read %d Bytes", size, len(plain))
+		}
+	}
+}
+
+func TestStoreArchivePassphrase(t *testing.T) {
+	useTestKDF(t)
+
+	archive := testArchive(t, []byte("{}"), "passphrase")
+	if _, e := readTestArchive(archive, "wrong"); e != ErrStoreArchivePassphrase {
+		t.Fatalf("Wrong Passphrase Error [%v], Expected [%v]", e, ErrStoreArchivePassphrase)
+	}
+}
+
+func TestStoreArchiveTampered(t *testing.T) {
+	useTestKDF(t)
+
+	document := bytes.Repeat([]byte("x"), 2*STORE_ARCHIVE_FRAME_SIZE+1)
+	archive := testArchive(t, document, "passphrase")
+
+	// Frame Boundaries
+	first := storeArchiveHeaderSize
+	second := first + 4 + int(binary.BigEndian.Uint32(archive[first:]))
+	third := second + 4 + int(binary.BigEndian.Uint32(archive[second:]))
+
+	// Truncated (Last Frame Missing)
+	if _, e := readTestArchive(archive[:third], "passphrase"); e != ErrStoreArchiveInvalid {
+		t.Fatalf("Truncated Archive Error [%v], Expected [%v]", e, ErrStoreArchiveInvalid)
+	}
+
+	// Truncated (Inside Last Frame)
+	if _, e := readTestArchive(archive[:len(archive)-1], "passphrase"); e != ErrStoreArchiveInvalid {
+		t.Fatalf("Truncated Frame Error [%v], Expected [%v]", e, ErrStoreArchiveInvalid)
+	}
+
+	// Data after Last Frame
+	if _, e := readTestArchive(append(append([]byte{}, archive...), 0x00), "passphrase"); e != ErrStoreArchiveInvalid {
+		t.Fatalf("Trailing Data Error [%v], Expected [%v]", e, ErrStoreArchiveInvalid)
+	}
+
+	// Reordered Frames
+	reordered := append([]byte{}, archive[:first]...)
+	reordered = append(reordered, archive[second:third]...)
+	reordered = append(reordered, archive[first:second]...)
+	reordered = append(reordered, archive[third:]...)
+	if _, e := readTestArchive(reordered, "passphrase"); e == nil {
+		t.Fatal("Reordered Frames should Fail")
+	}
+
+	// Tampered Frame
+	tampered := append([]byte{}, archive...)
+	tampered[second+4+20] ^= 0x01
+	if _, e := readTestArchive(tampered, "passphrase"); e != ErrStoreArchiveInvalid {
+		t.Fatalf("Tampered Frame Error [%v], Expected [%v]", e, ErrStoreArchiveInvalid)
+	}
+
+	// Tampered Header (Bound to Every Frame)
+	tampered = append([]byte{}, archive...)
+	tampered[storeArchiveHeaderSize-1] ^= 0x01
+	if _, e := readTestArchive(tampered, "passphrase"); e == nil {
+		t.Fatal("Tampered Header should Fail")
+	}
+
+	// Not an Archive
+	if _, e := readTestArchive([]byte("not an archive"), "passphrase"); e != ErrStoreArchiveInvalid {
+		t.Fatalf("Invalid Archive Error [%v], Expected [%v]", e, ErrStoreArchiveInvalid)
+	}
+}
+
+func TestStoreArchiveKDFLimits(t *testing.T) {
+	useTestKDF(t)
+
+	archive := testArchive(t, []byte("{}"), "passphrase")
+	kdf := archive[5:storeArchiveHeaderSize]
+
+	// Costs up to the Fixed Maximums are Accepted (even if Larger than Current Parameters)
+	limits := append([]byte{}, kdf...)
+	binary.BigEndian.PutUint32(limits[1:5], storeArchiveMaxTime)
+	binary.BigEndian.PutUint32(limits[5:9], storeArchiveMaxMemory)
+	limits[9] = storeArchiveMaxThreads
+	if !isStoreArchiveKDF(limits) {
+		t.Fatal("KDF Costs at the Fixed Maximums should be Accepted")
+	}
+
+	// Costs above the Fixed Maximums (and the Current Parameters) are Refused
+	for _, i := range []int{1, 5, 9} {
+		tampered := append([]byte{}, archive...)
+		k := tampered[5:storeArchiveHeaderSize]
+		switch i {
+		case 1:
+			binary.BigEndian.PutUint32(k[1:5], storeArchiveMaxTime+1)
+		case 5:
+			binary.BigEndian.PutUint32(k[5:9], storeArchiveMaxMemory+1)
+		case 9:
+			k[9] = storeArchiveMaxThreads + 1
+		}
+
+		if _, e := NewStoreArchiveReader(bytes.NewReader(tampered), []byte("passphrase")); e != ErrStoreArchiveInvalid {
+			t.Fatalf("KDF Cost at Byte %d: Error [%v], Expected [%v]", i, e, ErrStoreArchiveInvalid)
+		}
+	}
+
+	// Larger Current Parameters Raise the Limit
+	e := SetPasswordKDF(Argon2Params{Time: storeArchiveMaxTime + 1, Memory: 64, Threads: 1})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	binary.BigEndian.PutUint32(limits[1:5], storeArchiveMaxTime+1)
+	if !isStoreArchiveKDF(limits) {
+		t.Fatal("KDF Time Cost at the Current Parameters should be Accepted")
+	}
+
+	// Zero Costs are Refused
+	zero := append([]byte{}, kdf...)
+	binary.BigEndian.PutUint32(zero[1:5], 0)
+	if isStoreArchiveKDF(zero) {
+		t.Fatal("Zero KDF Time Cost should be Refused")
+	}
+}
+
+func TestStoreArchiveMaxSize(t *testing.T) {
+	useTestKDF(t)
+
+	max := StoreArchiveMaxSize()
+	t.Cleanup(func() {
+		SetStoreArchiveMaxSize(max)
+	})
+
+	if e := SetStoreArchiveMaxSize(STORE_ARCHIVE_FRAME_SIZE); e != nil {
+		t.Fatal(e)
+	}
+
+	var b bytes.Buffer
+	w, e := NewStoreArchiveWriter(&b, []byte("passphrase"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Archive Grows Past Maximum
+	_, e = w.Write(bytes.Repeat([]byte("x"), 2*STORE_ARCHIVE_FRAME_SIZE))
+	if e == nil {
+		e = w.Close()
+	}
+
+	if e != ErrStoreArchiveTooLarge {
+		t.Fatalf("Archive too Large Error [%v], Expected [%v]", e, ErrStoreArchiveTooLarge)
+	}
+
+	if SetStoreArchiveMaxSize(0) == nil {
+		t.Fatal("Zero Maximum Size should Fail")
+	}
+}
@@ -0,0 +1,513 @@
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Store Object Attachments
+ * An attachment object (OBJECT_TYPE_ATTACHMENT) keeps its descriptive values
+ * (title, notes) in the object cipher, like any other object. Its content is
+ * kept in separately encrypted chunks ("store_object_chunks") described by an
+ * encrypted manifest ("store_object_attachments").
+ * Every upload writes a new generation of chunks. The manifest only switches
+ * to the new generation once all of its chunks are stored, after which the
+ * chunks of other generations are removed.
+ * Chunks are bound (AES-GCM additional data) to the store, object, generation,
+ * position and to whether they are the last chunk, so they can't be reordered,
+ * moved to another object or truncated without failing authentication. The
+ * manifest keeps the SHA-256 of the content, which is verified on download.
+ */
+
+// Maximum Plain Bytes per Chunk (Encrypted Chunk has to fit a BLOB Column)
+const ATTACHMENT_CHUNK_SIZE = 60 * 1024
+
+// Envelope Template Names (Manifest and Chunk Ciphers)
+const attachmentManifestTemplate = "attachment.manifest"
+const attachmentChunkTemplate = "attachment.chunk"
+
+// STORE Object Attachment (Manifest) Definition
+type StoreObjectAttachment struct {
+	stored     bool   // Is Entry Stored in Database
+	store      uint32 // KEY: SHARD Local Store ID
+	object     uint32 // KEY: Local Object ID
+	generation uint32 // Upload Generation of Chunks
+	name       string // MANIFEST: File Name
+	ctype      string // MANIFEST: Content (MIME) Type
+	size       uint64 // MANIFEST: Content Size in Bytes
+	chunks     uint32 // MANIFEST: Number of Chunks
+	digest     []byte // MANIFEST: SHA-256 of Content
+	cipher     []byte // Encrypted Manifest
+}
+
+// attachmentManifest Encrypted Manifest Contents
+type attachmentManifest struct {
+	Generation uint32 `json:"generation"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Size       uint64 `json:"size"`
+	Chunks     uint32 `json:"chunks"`
+	Digest     string `json:"sha256"`
+}
+
+// NewStoreObjectAttachment Manifest for a New Upload (Next Generation of Current Manifest, if Any)
+func NewStoreObjectAttachment(current *StoreObjectAttachment, store uint32, object uint32) *StoreObjectAttachment {
+	a := &StoreObjectAttachment{
+		store:      store,
+		object:     object,
+		generation: 1,
+	}
+
+	if current != nil && !current.IsNew() {
+		a.stored = true
+		a.generation = current.generation + 1
+	}
+
+	return a
+}
+
+// SealAttachmentChunk Encrypt Attachment Chunk (Bound to Object, Generation and Position)
+func SealAttachmentChunk(key []byte, store uint32, object uint32, generation uint32, index uint32, last bool, plain []byte) ([]byte, error) {
+	if len(plain) > ATTACHMENT_CHUNK_SIZE {
+		return nil, errors.New("Attachment Chunk too big")
+	}
+
+	header, e := objectEnvelopeHeader(key, attachmentChunkTemplate)
+	if e != nil {
+		return nil, e
+	}
+
+	encrypted, e := gcmEncryptAAD(key, plain, attachmentChunkAAD(header, store, object, generation, index, last))
+	if e != nil {
+		return nil, e
+	}
+
+	return append(header, encrypted...), nil
+}
+
+// OpenAttachmentChunk Decrypt Attachment Chunk
+func OpenAttachmentChunk(key []byte, store uint32, object uint32, generation uint32, index uint32, last bool, cbs []byte) ([]byte, error) {
+	header, template := objectEnvelopeSplit(cbs)
+	if header == nil || template != attachmentChunkTemplate {
+		return nil, errors.New("Invalid Attachment Chunk")
+	}
+
+	return gcmDecryptAAD(key, cbs[len(header):], attachmentChunkAAD(header, store, object, generation, index, last))
+}
+
+func attachmentChunkAAD(header []byte, store uint32, object uint32, generation uint32, index uint32, last bool) []byte {
+	b := bytes.NewBuffer(objectEnvelopeAAD(header, store, object))
+	binary.Write(b, binary.BigEndian, generation)
+	binary.Write(b, binary.BigEndian, index)
+	binary.Write(b, binary.BigEndian, last)
+	return b.Bytes()
+}
+
+// StoreAttachmentChunkInsert Save Encrypted Chunk (size is the Plain Size)
+func StoreAttachmentChunkInsert(db sqlf.Executor, store uint32, object uint32, generation uint32, index uint32, size int, data []byte) error {
+	_, e := sqlf.InsertInto("store_object_chunks").
+		Set("id_store", store).
+		Set("id_object", object).
+		Set("generation", generation).
+		Set("chunk", index).
+		Set("size", size).
+		Set("data", data).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+// StoreAttachmentChunkGet Encrypted Chunk (nil if it does not Exist)
+func StoreAttachmentChunkGet(db sqlf.Executor, store uint32, object uint32, generation uint32, index uint32) ([]byte, error) {
+	// Query Results Values
+	var data []byte
+
+	// Create SQL Statement
+	e := sqlf.From("store_object_chunks").
+		Select("data").To(&data).
+		Where("id_store = ? and id_object = ? and generation = ? and chunk = ?", store, object, generation, index).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e == sql.ErrNoRows { // YES: Missing Chunk
+		return nil, nil
+	} else if e != nil {
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return data, nil
+}
+
+// StoreAttachmentChunkUpdate Replace Encrypted Chunk (i.e. on Store Key Rotation)
+func StoreAttachmentChunkUpdate(db sqlf.Executor, store uint32, object uint32, generation uint32, index uint32, data []byte) error {
+	_, e := sqlf.Update("store_object_chunks").
+		Set("data", data).
+		Where("id_store = ? and id_object = ? and generation = ? and chunk = ?", store, object, generation, index).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+// StoreAttachmentChunksDelete Delete Chunks of a Single Generation (i.e. Failed Upload)
+func StoreAttachmentChunksDelete(db sqlf.Executor, store uint32, object uint32, generation uint32) error {
+	_, e := sqlf.DeleteFrom("store_object_chunks").
+		Where("id_store = ? and id_object = ? and generation = ?", store, object, generation).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+// StoreAttachmentChunksDeleteStale Delete Chunks of All Generations except the Current One
+func StoreAttachmentChunksDeleteStale(db sqlf.Executor, store uint32, object uint32, generation uint32) error {
+	_, e := sqlf.DeleteFrom("store_object_chunks").
+		Where("id_store = ? and id_object = ? and generation <> ?", store, object, generation).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+// StoreAttachmentDelete Delete Object Attachment (Manifest and Chunks)
+func StoreAttachmentDelete(db sqlf.Executor, store uint32, object uint32) error {
+	return deleteStoreAttachments(db, "id_store = ? and id_object = ?", store, object)
+}
+
+// StoreAttachmentsDeleteAll Delete All Attachments in Store
+func StoreAttachmentsDeleteAll(db sqlf.Executor, store uint32) error {
+	return deleteStoreAttachments(db, "id_store = ?", store)
+}
+
+// StoreAttachmentsUsage Bytes Used by Attachments in Store (Including Uploads in Progress)
+func StoreAttachmentsUsage(db sqlf.Executor, store uint32) (uint64, error) {
+	// Query Results Values
+	var usage sql.NullInt64
+
+	// Create SQL Statement
+	e := sqlf.From("store_object_chunks").
+		Select("SUM(size)").To(&usage).
+		Where("id_store = ?", store).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	return uint64(usage.Int64), nil
+}
+
+// StoreAttachmentsBatch Next Batch of Attachment Manifests (by Object ID) after Object ID
+func StoreAttachmentsBatch(db *sql.DB, store uint32, after uint32, limit uint) ([]*StoreObjectAttachment, error) {
+	var entries []*StoreObjectAttachment
+
+	// Query Results Values
+	var object uint32
+	var generation uint32
+	var cipher []byte
+
+	// Create SQL Statement
+	s := sqlf.From("store_object_attachments").
+		Select("id_object").To(&object).
+		Select("generation").To(&generation).
+		Select("manifest").To(&cipher).
+		Where("id_store = ? and id_object > ?", store, after).
+		OrderBy("id_object").
+		Limit(limit)
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		// Copy Cipher (New Memory Area)
+		cbs := make([]byte, len(cipher))
+		copy(cbs, cipher)
+
+		entries = append(entries, &StoreObjectAttachment{
+			stored:     true,
+			store:      store,
+			object:     object,
+			generation: generation,
+			cipher:     cbs,
+		})
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return entries, nil
+}
+
+// StoreAttachmentUpdateCipher Replace Encrypted Manifest (i.e. on Store Key Rotation)
+func StoreAttachmentUpdateCipher(db sqlf.Executor, store uint32, object uint32, cipher []byte) error {
+	_, e := sqlf.Update("store_object_attachments").
+		Set("manifest", cipher).
+		Where("id_store = ? and id_object = ?", store, object).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+// deleteStoreAttachments Delete Attachments (Manifest and Chunks) Matching Condition on (id_store, id_object)
+func deleteStoreAttachments(db sqlf.Executor, where string, args ...interface{}) error {
+	_, e := sqlf.DeleteFrom("store_object_chunks").
+		Where(where, args...).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	_, e = sqlf.DeleteFrom("store_object_attachments").
+		Where(where, args...).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+func (o *StoreObjectAttachment) IsNew() bool {
+	return !o.stored
+}
+
+func (o *StoreObjectAttachment) ByKey(db sqlf.Executor, store uint32, object uint32) error {
+	// Reset Entry
+	o.reset()
+
+	// Create SQL Statement
+	e := sqlf.From("store_object_attachments").
+		Select("generation").To(&o.generation).
+		Select("size").To(&o.size).
+		Select("manifest").To(&o.cipher).
+		Where("id_store = ? and id_object = ?", store, object).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e != nil {
+		if e == sql.ErrNoRows { // NO Attachment
+			o.reset()
+			return nil
+		}
+
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	o.store = store
+	o.object = object
+	o.stored = true
+	return nil
+}
+
+func (o *StoreObjectAttachment) Store() uint32 {
+	return o.store
+}
+
+func (o *StoreObjectAttachment) Object() uint32 {
+	return o.object
+}
+
+func (o *StoreObjectAttachment) Generation() uint32 {
+	return o.generation
+}
+
+func (o *StoreObjectAttachment) Name() string {
+	return o.name
+}
+
+func (o *StoreObjectAttachment) ContentType() string {
+	return o.ctype
+}
+
+func (o *StoreObjectAttachment) Size() uint64 {
+	return o.size
+}
+
+func (o *StoreObjectAttachment) Chunks() uint32 {
+	return o.chunks
+}
+
+func (o *StoreObjectAttachment) Digest() []byte {
+	return o.digest
+}
+
+func (o *StoreObjectAttachment) Cipher() []byte {
+	return o.cipher
+}
+
+func (o *StoreObjectAttachment) SetName(n string) {
+	o.name = n
+}
+
+func (o *StoreObjectAttachment) SetContentType(t string) {
+	o.ctype = t
+}
+
+// SetContent Content Information (Size, Number of Chunks and SHA-256)
+func (o *StoreObjectAttachment) SetContent(size uint64, chunks uint32, digest []byte) {
+	o.size = size
+	o.chunks = chunks
+	o.digest = digest
+}
+
+// Open Decrypt Manifest
+func (o *StoreObjectAttachment) Open(key []byte) error {
+	header, template := objectEnvelopeSplit(o.cipher)
+	if header == nil || template != attachmentManifestTemplate {
+		return errors.New("Invalid Attachment Manifest")
+	}
+
+	plain, e := gcmDecryptAAD(key, o.cipher[len(header):], objectEnvelopeAAD(header, o.store, o.object))
+	if e != nil {
+		return e
+	}
+
+	m := &attachmentManifest{}
+	e = json.Unmarshal(plain, m)
+	if e != nil {
+		return e
+	}
+
+	// Does the Manifest Match the Stored Generation?
+	if m.Generation != o.generation { // NO: Reject
+		return errors.New("Attachment Manifest Generation Mismatch")
+	}
+
+	digest, e := hex.DecodeString(m.Digest)
+	if e != nil {
+		return e
+	}
+
+	o.name = m.Name
+	o.ctype = m.Type
+	o.size = m.Size
+	o.chunks = m.Chunks
+	o.digest = digest
+	return nil
+}
+
+// Seal Encrypt Manifest
+func (o *StoreObjectAttachment) Seal(key []byte) error {
+	if o.store == 0 || o.object == 0 {
+		return errors.New("Missing Attachment Store or Object")
+	}
+
+	plain, e := json.Marshal(&attachmentManifest{
+		Generation: o.generation,
+		Name:       o.name,
+		Type:       o.ctype,
+		Size:       o.size,
+		Chunks:     o.chunks,
+		Digest:     hex.EncodeToString(o.digest),
+	})
+	if e != nil {
+		return e
+	}
+
+	header, e := objectEnvelopeHeader(key, attachmentManifestTemplate)
+	if e != nil {
+		return e
+	}
+
+	encrypted, e := gcmEncryptAAD(key, plain, objectEnvelopeAAD(header, o.store, o.object))
+	if e != nil {
+		return e
+	}
+
+	o.cipher = append(header, encrypted...)
+	return nil
+}
+
+// Flush Save (Sealed) Manifest, Switching the Attachment to the Manifest's Generation
+func (o *StoreObjectAttachment) Flush(db sqlf.Executor) error {
+	// Have DB Connection?
+	if db == nil { // NO: Abort
+		return errors.New("Missing Database Connection")
+	}
+
+	if o.cipher == nil {
+		return errors.New("Attachment Manifest not Sealed")
+	}
+
+	var e error
+	if o.IsNew() { // YES: Create
+		_, e = sqlf.InsertInto("store_object_attachments").
+			Set("id_store", o.store).
+			Set("id_object", o.object).
+			Set("generation", o.generation).
+			Set("size", o.size).
+			Set("manifest", o.cipher).
+			ExecAndClose(context.TODO(), db)
+	} else { // NO: Update
+		_, e = sqlf.Update("store_object_attachments").
+			Set("generation", o.generation).
+			Set("size", o.size).
+			Set("manifest", o.cipher).
+			Where("id_store = ? and id_object = ?", o.store, o.object).
+			ExecAndClose(context.TODO(), db)
+	}
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	o.stored = true
+	return nil
+}
+
+func (o *StoreObjectAttachment) reset() {
+	o.stored = false
+	o.store = 0
+	o.object = 0
+	o.generation = 0
+	o.name = ""
+	o.ctype = ""
+	o.size = 0
+	o.chunks = 0
+	o.digest = nil
+	o.cipher = nil
+}
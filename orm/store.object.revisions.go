@@ -0,0 +1,537 @@
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/objectvault/api-services/orm/mysql"
+	"github.com/objectvault/api-services/orm/query"
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Store Object Revisions
+ * Before a store object is updated, its current (encrypted) value is kept as
+ * a revision in the "store_object_revisions" table.
+ * The object cipher is bound to the store and object ID (not to the revision),
+ * so a revision can be decrypted with the store key and restored by copying
+ * the cipher back to the object.
+ */
+
+// STORE Object Revision Definition
+type StoreObjectRevision struct {
+	stored   bool       // Is Entry Stored in Database
+	store    uint32     // KEY: SHARD Local Store ID
+	object   uint32     // KEY: Local Object ID
+	revision uint32     // KEY: Object Revision (Sequential by Object)
+	title    string     // Object Title (at Revision)
+	sealed   string     // Object Title as Stored (if Sealed with Store Key)
+	cipher   []byte     // Encrypted Store Object (at Revision)
+	modifier uint64     // Global User ID of the User that Created the Revision Value
+	created  *time.Time // Revision TimeStamp
+}
+
+// NewStoreObjectRevision Revision from Current (Stored) Object Value
+func NewStoreObjectRevision(o *StoreObject) (*StoreObjectRevision, error) {
+	if o.IsNew() || o.Object() == nil {
+		return nil, errors.New("Object has no Stored Value")
+	}
+
+	// Copy Object Cipher (New Memory Area)
+	bytes := make([]byte, len(o.Object()))
+	copy(bytes, o.Object())
+
+	// Who set the Current Value?
+	modifier := o.Creator()
+	if o.Modifier() != nil {
+		modifier = *o.Modifier()
+	}
+
+	// Title is Kept as Stored (Sealed Titles are Bound to the Store, not the Object)
+	return &StoreObjectRevision{
+		store:    o.Store(),
+		object:   o.ID(),
+		title:    o.title,
+		sealed:   o.sealed,
+		cipher:   bytes,
+		modifier: modifier,
+	}, nil
+}
+
+// StoreObjectRevisionLast Last Revision Number for Object (0 if None)
+func StoreObjectRevisionLast(db sqlf.Executor, store uint32, object uint32) (uint32, error) {
+	// Query Results Values
+	var revision sql.NullInt64
+
+	// Create SQL Statement
+	e := sqlf.From("store_object_revisions").
+		Select("MAX(revision)").To(&revision).
+		Where("id_store = ? and id_object = ?", store, object).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Executing Query?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	if !revision.Valid {
+		return 0, nil
+	}
+	return uint32(revision.Int64), nil
+}
+
+// StoreObjectRevisionsPrune Remove Revisions Beyond Retention Count and/or Age in Days (0 - No Limit)
+func StoreObjectRevisionsPrune(db *sql.DB, store uint32, object uint32, count uint16, days uint16) error {
+	// Limit Number of Revisions?
+	if count > 0 { // YES
+		last, e := StoreObjectRevisionLast(db, store, object)
+		if e != nil {
+			return e
+		}
+
+		if last > uint32(count) {
+			_, e = sqlf.DeleteFrom("store_object_revisions").
+				Where("id_store = ? and id_object = ? and revision <= ?", store, object, last-uint32(count)).
+				ExecAndClose(context.TODO(), db)
+
+			// Error Occurred?
+			if e != nil { // YES
+				log.Printf("query error: %v\n", e)
+				return e
+			}
+		}
+	}
+
+	// Limit Age of Revisions?
+	if days > 0 { // YES
+		before := time.Now().UTC().AddDate(0, 0, -int(days))
+		_, e := sqlf.DeleteFrom("store_object_revisions").
+			Where("id_store = ? and id_object = ? and created < ?", store, object, mysql.GoTimeToMySQLTimeStamp(&before)).
+			ExecAndClose(context.TODO(), db)
+
+		// Error Occurred?
+		if e != nil { // YES
+			log.Printf("query error: %v\n", e)
+			return e
+		}
+	}
+
+	return nil
+}
+
+// StoreObjectRevisionsDelete Remove All Revisions of an Object
+func StoreObjectRevisionsDelete(db *sql.DB, store uint32, object uint32) error {
+	_, e := sqlf.DeleteFrom("store_object_revisions").
+		Where("id_store = ? and id_object = ?", store, object).
+		ExecAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+// StoreObjectRevisionsDeleteOrphans Remove Revisions of Deleted Objects (i.e. after Deleting a Folder)
+func StoreObjectRevisionsDeleteOrphans(db *sql.DB, store uint32) error {
+	_, e := sqlf.DeleteFrom("store_object_revisions").
+		Where("id_store = ? and id_object not in (select id from objects where id_store = ?)", store, store).
+		ExecAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+func StoreObjectRevisionsDeleteAll(db *sql.DB, store uint32) (uint64, error) {
+	// Create SQL Statement
+	s := sqlf.DeleteFrom("store_object_revisions").
+		Where("id_store = ?", store)
+
+	// Execute
+	r, e := s.ExecAndClose(context.TODO(), db)
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	// How many entries deleted?
+	c, e := r.RowsAffected()
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+	return uint64(c), nil
+}
+
+// StoreObjectRevisionsBatch Next Batch of Encrypted Revisions (by Object and Revision) after Object/Revision
+func StoreObjectRevisionsBatch(db *sql.DB, store uint32, object uint32, revision uint32, limit uint) ([]*StoreObjectRevision, error) {
+	var entries []*StoreObjectRevision
+
+	// Query Results Values
+	var id uint32
+	var rev uint32
+	var title string
+	var cipher []byte
+
+	// Create SQL Statement
+	s := sqlf.From("store_object_revisions").
+		Select("id_object").To(&id).
+		Select("revision").To(&rev).
+		Select("title").To(&title).
+		Select("object").To(&cipher).
+		Where("id_store = ? and (id_object > ? or (id_object = ? and revision > ?))", store, object, object, revision).
+		OrderBy("id_object", "revision").
+		Limit(limit)
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		// Save Cipher (New Memory Area)
+		bytes := make([]byte, len(cipher))
+		copy(bytes, cipher)
+
+		o := &StoreObjectRevision{
+			stored:   true,
+			store:    store,
+			object:   id,
+			revision: rev,
+			cipher:   bytes,
+		}
+		o.setStoredTitle(title)
+
+		entries = append(entries, o)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return entries, nil
+}
+
+// StoreObjectRevisionUpdateCipher Replace Encrypted Revision (i.e. on Store Key Rotation)
+func StoreObjectRevisionUpdateCipher(db sqlf.Executor, store uint32, object uint32, revision uint32, cipher []byte) error {
+	// Create SQL Statement
+	s := sqlf.Update("store_object_revisions").
+		Set("object", cipher).
+		Where("id_store = ? and id_object = ? and revision = ?", store, object, revision)
+
+	// Execute Statement
+	_, e := s.ExecAndClose(context.TODO(), db)
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	return nil
+}
+
+func CountStoreObjectRevisions(db *sql.DB, store uint32, object uint32) (uint64, error) {
+	// Query Results Values
+	var count uint64
+
+	// Create SQL Statement
+	e := sqlf.From("store_object_revisions").
+		Select("COUNT(*)").To(&count).
+		Where("id_store = ? and id_object = ?", store, object).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	return count, nil
+}
+
+// QueryStoreObjectRevisions List Object Revisions (Newest First, without Cipher)
+func QueryStoreObjectRevisions(db *sql.DB, store uint32, object uint32, q query.TQueryConditions, c bool) (query.TQueryResults, error) {
+	var list query.QueryResults = query.QueryResults{}
+	list.SetMaxLimit(100) // Hard Code Maximum Limit
+
+	// Set Query Page Limits
+	if q != nil {
+		// Set Offset From Query Conditions
+		if q.Offset() != nil {
+			list.SetOffset(*q.Offset())
+		}
+
+		if q.Limit() != nil {
+			list.SetLimit(*q.Limit())
+		}
+	}
+
+	// Query Results Values
+	var revision uint32
+	var title string
+	var modifier uint64
+	var created sql.NullString
+
+	// Create SQL Statement
+	s := sqlf.From("store_object_revisions").
+		Select("revision").To(&revision).
+		Select("title").To(&title).
+		Select("modifier").To(&modifier).
+		Select("created").To(&created).
+		Where("id_store = ? and id_object = ?", store, object).
+		OrderBy("revision DESC")
+	list.AppendSort("revision", true)
+
+	// Is OFFSET Set?
+	if list.Offset() > 0 { // YES: Use it
+		s.Offset(list.Offset())
+	}
+
+	// Is LIMIT Set?
+	if list.Limit() > 0 { // YES: Use it
+		s.Limit(list.Limit())
+	}
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		o := &StoreObjectRevision{
+			stored:   true,
+			store:    store,
+			object:   object,
+			revision: revision,
+			modifier: modifier,
+		}
+		o.setStoredTitle(title)
+
+		if created.Valid {
+			o.created = mysql.MySQLTimeStampToGoTime(created.String)
+		}
+
+		list.AppendValue(o)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	// Is Count of Entries Requested?
+	if c { // YES: Count Entries
+		count, e := CountStoreObjectRevisions(db, store, object)
+		if e != nil {
+			return nil, e
+		}
+
+		list.SetMaxCount(count)
+	}
+
+	return list, nil
+}
+
+func (o *StoreObjectRevision) IsNew() bool {
+	return !o.stored
+}
+
+// ByKey Finds Revision By Store / Object ID / Revision
+func (o *StoreObjectRevision) ByKey(db *sql.DB, store uint32, object uint32, revision uint32) error {
+	// Cleanup Entry
+	o.reset()
+
+	// Execute Query
+	var title string
+	var created sql.NullString
+	e := sqlf.From("store_object_revisions").
+		Select("title").To(&title).
+		Select("object").To(&o.cipher).
+		Select("modifier").To(&o.modifier).
+		Select("created").To(&created).
+		Where("id_store = ? and id_object = ? and revision = ?", store, object, revision).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Executing Query?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	// Did we retrieve an entry?
+	if e == nil { // YES
+		o.store = store
+		o.object = object
+		o.revision = revision
+		o.setStoredTitle(title)
+
+		if created.Valid {
+			o.created = mysql.MySQLTimeStampToGoTime(created.String)
+		}
+
+		o.stored = true // Registered Entry
+	}
+
+	return nil
+}
+
+func (o *StoreObjectRevision) Store() uint32 {
+	return o.store
+}
+
+func (o *StoreObjectRevision) Object() uint32 {
+	return o.object
+}
+
+func (o *StoreObjectRevision) Revision() uint32 {
+	return o.revision
+}
+
+// Title Object Title at Revision (Empty if Sealed and not Opened)
+func (o *StoreObjectRevision) Title() string {
+	return o.title
+}
+
+// HasSealedTitle Is the Stored Title Encrypted with the Store Key?
+func (o *StoreObjectRevision) HasSealedTitle() bool {
+	return o.sealed != ""
+}
+
+// SealedTitle Title as Stored (Empty if Plaintext)
+func (o *StoreObjectRevision) SealedTitle() string {
+	return o.sealed
+}
+
+// OpenTitle Decrypt Sealed Title with Store Key
+func (o *StoreObjectRevision) OpenTitle(key []byte) error {
+	// Is the Title Sealed (and not yet Opened)?
+	if o.sealed == "" || o.title != "" { // NO: Nothing to Do
+		return nil
+	}
+
+	t, e := OpenStoreTitle(key, o.store, o.sealed)
+	if e != nil {
+		return e
+	}
+
+	o.title = t
+	return nil
+}
+
+func (o *StoreObjectRevision) Cipher() []byte {
+	return o.cipher
+}
+
+func (o *StoreObjectRevision) Modifier() uint64 {
+	return o.modifier
+}
+
+func (o *StoreObjectRevision) Created() *time.Time {
+	return o.created
+}
+
+// Maximum Attempts to Allocate a Revision Number (Concurrent Updates of the Same Object)
+const revisionFlushAttempts = 5
+
+/* Flush Save Revision as the Next Revision of the Object (Revisions are Immutable)
+ * NOTE: The revision number is allocated (MAX + 1) outside a transaction, so
+ * a concurrent revision of the same object fails with a duplicate key, and the
+ * number is allocated again.
+ */
+func (o *StoreObjectRevision) Flush(db sqlf.Executor) error {
+	// Have DB Connection?
+	if db == nil { // NO: Abort
+		return errors.New("Missing Database Connection")
+	}
+
+	// Is Revision Stored?
+	if o.stored { // YES: Revisions are Immutable
+		return nil
+	}
+
+	if o.store == 0 || o.object == 0 || o.cipher == nil {
+		return errors.New("Revision Missing Store, Object or Value")
+	}
+
+	var last uint32
+	var e error
+	for i := 0; i < revisionFlushAttempts; i++ {
+		// Next Revision Number
+		last, e = StoreObjectRevisionLast(db, o.store, o.object)
+		if e != nil {
+			return e
+		}
+
+		// Execute Insert (Creation Time Stamp AUTO SET by MySQL)
+		_, e = sqlf.
+			InsertInto("store_object_revisions").
+			Set("id_store", o.store).
+			Set("id_object", o.object).
+			Set("revision", last+1).
+			Set("title", o.storedTitle()).
+			Set("object", o.cipher).
+			Set("modifier", o.modifier).
+			ExecAndClose(context.TODO(), db)
+
+		// Revision Number Taken by a Concurrent Update?
+		if !mysql.IsDuplicateKey(e) { // NO
+			break
+		}
+	}
+
+	// Error Occurred?
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	o.revision = last + 1
+	o.stored = true
+	return nil
+}
+
+func (o *StoreObjectRevision) reset() {
+	// Clean Entry
+	o.store = 0
+	o.object = 0
+	o.revision = 0
+	o.title = ""
+	o.sealed = ""
+	o.cipher = nil
+	o.modifier = 0
+	o.created = nil
+
+	// Mark State as Unregistered
+	o.stored = false
+}
+
+// setStoredTitle Set Title as Stored (Sealed Titles have to be Opened)
+func (o *StoreObjectRevision) setStoredTitle(t string) {
+	if IsSealedTitle(t) {
+		o.title = ""
+		o.sealed = t
+	} else {
+		o.title = t
+		o.sealed = ""
+	}
+}
+
+// storedTitle Title as Saved to the Database
+func (o *StoreObjectRevision) storedTitle() string {
+	if o.sealed != "" {
+		return o.sealed
+	}
+	return o.title
+}
@@ -0,0 +1,386 @@
+// cSpell:ignore paulo ferreira
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/objectvault/api-services/orm/query"
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Object Tags
+ * Tags are kept inside the encrypted object values (OBJECT_VALUE_TAGS), so
+ * they are available to any template, and are only readable with the store
+ * key.
+ * Stores can, optionally, keep a plaintext tag index (store_object_tags), so
+ * that tags can be listed, and objects filtered by tag, without opening the
+ * store. The index only reflects objects saved (or tagged) while the policy
+ * is enabled, and has to be rebuilt (with the store open) to include objects
+ * saved before, or removed once the policy is disabled.
+ */
+
+// Template Value that Holds Object Tags
+const OBJECT_VALUE_TAGS = "__tags"
+
+// Tag Limits
+const TAG_MAX_LENGTH = 64
+const TAGS_MAX_PER_OBJECT = 32
+
+// Tag (and Number of Objects Tagged)
+type StoreTagCount struct {
+	Tag   string
+	Count uint64
+}
+
+// NormalizeTag Trimmed Lower Case Tag
+func NormalizeTag(tag string) (string, error) {
+	t := strings.ToLower(strings.TrimSpace(tag))
+	if t == "" {
+		return "", errors.New("Empty Tag")
+	}
+
+	if utf8.RuneCountInString(t) > TAG_MAX_LENGTH {
+		return "", fmt.Errorf("Tag is Longer than %d Characters", TAG_MAX_LENGTH)
+	}
+
+	if strings.ContainsAny(t, ",\r\n\t") {
+		return "", errors.New("Tag contains Invalid Characters")
+	}
+
+	return t, nil
+}
+
+// NormalizeTags Unique, Sorted, Normalized Tags from a (JSON) List
+func NormalizeTags(v interface{}) ([]string, error) {
+	var list []string
+	switch l := v.(type) {
+	case nil:
+		return []string{}, nil
+	case []string:
+		list = l
+	case []interface{}:
+		for _, i := range l {
+			s, ok := i.(string)
+			if !ok {
+				return nil, errors.New("Tags has to be a List of Strings")
+			}
+			list = append(list, s)
+		}
+	default:
+		return nil, errors.New("Tags has to be a List of Strings")
+	}
+
+	unique := map[string]bool{}
+	tags := []string{}
+	for _, s := range list {
+		t, e := NormalizeTag(s)
+		if e != nil {
+			return nil, e
+		}
+
+		if !unique[t] {
+			unique[t] = true
+			tags = append(tags, t)
+		}
+	}
+
+	if len(tags) > TAGS_MAX_PER_OBJECT {
+		return nil, fmt.Errorf("Objects are Limited to %d Tags", TAGS_MAX_PER_OBJECT)
+	}
+
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Tags Object Tags (Invalid Values are Ignored)
+func (o *StoreTemplateObject) Tags() []string {
+	if o.values == nil {
+		return []string{}
+	}
+
+	tags, e := NormalizeTags(o.values[OBJECT_VALUE_TAGS])
+	if e != nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// SetTags Replace Object Tags (Empty List Removes Tags)
+func (o *StoreTemplateObject) SetTags(tags []string) error {
+	tags, e := NormalizeTags(tags)
+	if e != nil {
+		return e
+	}
+
+	if o.values == nil {
+		o.values = map[string]interface{}{}
+	}
+
+	if len(tags) == 0 {
+		delete(o.values, OBJECT_VALUE_TAGS)
+	} else {
+		o.values[OBJECT_VALUE_TAGS] = tags
+	}
+	return nil
+}
+
+// AddTags Add Tags to Object (TRUE if Tags Changed)
+func (o *StoreTemplateObject) AddTags(tags []string) (bool, error) {
+	current := o.Tags()
+	e := o.SetTags(append(append([]string{}, current...), tags...))
+	if e != nil {
+		return false, e
+	}
+
+	return !equalTags(current, o.Tags()), nil
+}
+
+// RemoveTags Remove Tags from Object (TRUE if Tags Changed)
+func (o *StoreTemplateObject) RemoveTags(tags []string) (bool, error) {
+	remove := map[string]bool{}
+	for _, t := range tags {
+		remove[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+
+	current := o.Tags()
+	keep := []string{}
+	for _, t := range current {
+		if !remove[t] {
+			keep = append(keep, t)
+		}
+	}
+
+	if len(keep) == len(current) {
+		return false, nil
+	}
+
+	return true, o.SetTags(keep)
+}
+
+// StoreObjectSetTags Replace Indexed Tags of Object
+func StoreObjectSetTags(db sqlf.Executor, store uint32, object uint32, tags []string) error {
+	e := StoreObjectTagsDelete(db, store, object)
+	if e != nil {
+		return e
+	}
+
+	for _, t := range tags {
+		_, e = sqlf.InsertInto("store_object_tags").
+			Set("id_store", store).
+			Set("id_object", object).
+			Set("tag", t).
+			ExecAndClose(context.TODO(), db)
+
+		if e != nil { // YES
+			log.Printf("query error: %v\n", e)
+			return e
+		}
+	}
+
+	return nil
+}
+
+// StoreObjectTagsDelete Remove Object from Tag Index
+func StoreObjectTagsDelete(db sqlf.Executor, store uint32, object uint32) error {
+	return deleteStoreTags(db, "id_store = ? and id_object = ?", store, object)
+}
+
+// StoreTagsDeleteAll Remove Store Tag Index
+func StoreTagsDeleteAll(db sqlf.Executor, store uint32) error {
+	return deleteStoreTags(db, "id_store = ?", store)
+}
+
+// QueryStoreTags Indexed Tags (of Objects Outside of Trash) with Number of Objects
+func QueryStoreTags(db *sql.DB, store uint32) ([]StoreTagCount, error) {
+	tags := []StoreTagCount{}
+
+	// Query Results Values
+	var tag string
+	var count uint64
+
+	// Create SQL Statement
+	s := sqlf.From("store_object_tags").
+		Select("tag").To(&tag).
+		Select("COUNT(*)").To(&count).
+		Where("id_store = ?", store).
+		Where("id_object in (select id from objects where id_store = ? and trash IS NULL)", store).
+		GroupBy("tag").
+		OrderBy("tag")
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		tags = append(tags, StoreTagCount{Tag: tag, Count: count})
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return tags, nil
+}
+
+// StoreObjectsTags Decrypt Store Objects (Outside of Trash) and Count Tags
+func StoreObjectsTags(db *sql.DB, store uint32, key []byte, batch uint, each func(o *StoreObject, tags []string) error) ([]StoreTagCount, error) {
+	counts := map[string]uint64{}
+
+	after := uint32(0)
+	for {
+		objs, e := StoreActiveObjectsBatch(db, store, after, batch)
+		if e != nil {
+			return nil, e
+		}
+
+		// Have we Reached the End?
+		if len(objs) == 0 { // YES
+			break
+		}
+
+		for _, o := range objs {
+			after = o.ID()
+
+			// Does the Object have Values?
+			if len(o.Object()) == 0 { // NO: Skip
+				continue
+			}
+
+			ot := &StoreTemplateObject{}
+			e = ot.DecryptObject(key, store, o.ID(), o.Object())
+			if e != nil {
+				return nil, e
+			}
+
+			tags := ot.Tags()
+			for _, t := range tags {
+				counts[t]++
+			}
+
+			if each != nil {
+				e = each(o, tags)
+				if e != nil {
+					return nil, e
+				}
+			}
+		}
+	}
+
+	tags := make([]StoreTagCount, 0, len(counts))
+	for t, c := range counts {
+		tags = append(tags, StoreTagCount{Tag: t, Count: c})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// applyTagsCondition Limit Objects to those with All Tags (in Tag Index)
+func applyTagsCondition(s *sqlf.Stmt, store uint32, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	args := []interface{}{store}
+	for _, t := range tags {
+		args = append(args, t)
+	}
+	args = append(args, len(tags))
+
+	s.Where("id in (select id_object from store_object_tags where id_store = ? and tag in (?"+strings.Repeat(", ?", len(tags)-1)+") group by id_object having count(distinct tag) = ?)", args...)
+}
+
+// deleteStoreTags Delete Tag Index Entries Matching Condition on (id_store, id_object)
+func deleteStoreTags(db sqlf.Executor, where string, args ...interface{}) error {
+	_, e := sqlf.DeleteFrom("store_object_tags").
+		Where(where, args...).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+func equalTags(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterStoreObjectsByTags Decrypt Objects in List (Page) and Keep those with All Tags (Count is not Changed)
+func FilterStoreObjectsByTags(db *sql.DB, page query.TQueryResults, key []byte, tags []string) (query.TQueryResults, error) {
+	var list query.QueryResults = query.QueryResults{}
+	list.SetMaxLimit(page.MaxLimit())
+	list.SetOffset(page.Offset())
+	list.SetLimit(page.Limit())
+	list.SetMaxCount(page.MaxCount())
+	for _, i := range page.Sorted() {
+		list.AppendSort(i.Field, i.Descending)
+	}
+
+	for _, v := range page.Items() {
+		o := v.(*StoreObject)
+
+		// Load Encrypted Values (Lists don't Include them)
+		full := &StoreObject{}
+		e := full.ByKey(db, o.Store(), o.ID())
+		if e != nil {
+			return nil, e
+		}
+
+		if full.IsNew() || len(full.Object()) == 0 {
+			continue
+		}
+
+		ot := &StoreTemplateObject{}
+		e = ot.DecryptObject(key, o.Store(), o.ID(), full.Object())
+		if e != nil {
+			return nil, e
+		}
+
+		// Does the Object have All Tags?
+		if hasTags(ot.Tags(), tags) { // YES
+			list.AppendValue(o)
+		}
+	}
+
+	return list, nil
+}
+
+func hasTags(tags []string, required []string) bool {
+	for _, r := range required {
+		i := sort.SearchStrings(tags, r)
+		if i >= len(tags) || tags[i] != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/objectvault/api-services/orm/query"
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Sealed Titles
+ * Stores can have their object titles (and folder names) encrypted with the
+ * store key. A sealed title is kept in the "title" column, of objects and
+ * revisions, as TITLE_SEALED_PREFIX + BASE64(NONCE + CIPHERTEXT).
+ * The cipher is bound to the store (not the object) so that titles can be
+ * sealed before the object ID is known and copied to revisions unchanged.
+ * Every seal uses a new nonce, so sealed titles can't be compared, sorted or
+ * filtered by the database: that has to be done after the titles are opened.
+ * Titles without the prefix are plaintext (i.e. stored before the store
+ * policy was enabled) and are sealed the next time the object is saved.
+ */
+
+// Prefix of Sealed Titles
+const TITLE_SEALED_PREFIX = "$t1$"
+
+// IsSealedTitle Is the (Stored) Title Encrypted?
+func IsSealedTitle(t string) bool {
+	return strings.HasPrefix(t, TITLE_SEALED_PREFIX)
+}
+
+// SealStoreTitle Encrypt Title with Store Key
+func SealStoreTitle(key []byte, store uint32, title string) (string, error) {
+	cbs, e := gcmEncryptAAD(key, []byte(title), storeTitleAAD(store))
+	if e != nil {
+		return "", e
+	}
+
+	return TITLE_SEALED_PREFIX + base64.RawStdEncoding.EncodeToString(cbs), nil
+}
+
+// OpenStoreTitle Decrypt (Stored) Title with Store Key (Plaintext Titles are Returned As Is)
+func OpenStoreTitle(key []byte, store uint32, title string) (string, error) {
+	if !IsSealedTitle(title) {
+		return title, nil
+	}
+
+	cbs, e := base64.RawStdEncoding.DecodeString(title[len(TITLE_SEALED_PREFIX):])
+	if e != nil {
+		return "", errors.New("Invalid Sealed Title")
+	}
+
+	plain, e := gcmDecryptAAD(key, cbs, storeTitleAAD(store))
+	if e != nil {
+		return "", e
+	}
+
+	return string(plain), nil
+}
+
+// QueryStoreParentSealedObjects List Folder Contents (with All Tags, if Given) with Titles Opened (if Store Key given), Title Sort and Filter are Applied to the Page
+func QueryStoreParentSealedObjects(db *sql.DB, store uint32, parent uint32, tags []string, key []byte, q query.TQueryConditions, c bool) (query.TQueryResults, error) {
+	// Conditions Applied by the Database
+	dq := &query.QueryConditions{}
+
+	// Conditions Applied to the Page
+	var filter *query.SQLFWhere
+	var sortBy []query.OrderBy
+
+	if q != nil {
+		if q.Offset() != nil {
+			dq.SetOffset(*q.Offset())
+		}
+
+		if q.Limit() != nil {
+			dq.SetLimit(*q.Limit())
+		}
+
+		// Does the Filter Apply to Titles?
+		if f := q.Filter(); f != nil {
+			if f.HasField("title") { // YES: Filter Page
+				filter = f
+			} else {
+				dq.SetFilter(f)
+			}
+		}
+
+		sortBy = q.Sort()
+	}
+
+	// DEFAULT: Sort by Title
+	if len(sortBy) == 0 {
+		sortBy = []query.OrderBy{{Field: "title"}}
+	}
+
+	for _, i := range sortBy {
+		if i.Field != "title" {
+			dq.AppendSort(i.Field, i.Descending)
+		}
+	}
+
+	// Pages have to be Stable (Sealed Titles can't be Sorted by the Database)
+	if len(dq.Sort()) == 0 {
+		dq.AppendSort("id", false)
+	}
+
+	page, e := QueryStoreParentTaggedObjects(db, store, parent, tags, dq, c)
+	if e != nil {
+		return nil, e
+	}
+
+	var list query.QueryResults = query.QueryResults{}
+	list.SetMaxLimit(100) // Hard Code Maximum Limit
+	list.SetOffset(page.Offset())
+	list.SetLimit(page.Limit())
+	for _, i := range sortBy {
+		list.AppendSort(i.Field, i.Descending)
+	}
+
+	// Open Titles and Apply Title Filter
+	var entries []*StoreObject
+	for _, v := range page.Items() {
+		o := v.(*StoreObject)
+
+		// Do we have the Store Key?
+		if key != nil { // YES: Open Title
+			e = o.OpenTitle(key)
+			if e != nil {
+				return nil, e
+			}
+		}
+
+		if filter != nil {
+			m, e := filter.Match(o.fieldValue)
+			if e != nil {
+				return nil, e
+			}
+
+			if !m {
+				continue
+			}
+		}
+
+		entries = append(entries, o)
+	}
+
+	// Apply Sort to Page
+	sort.SliceStable(entries, func(i, j int) bool {
+		for _, s := range sortBy {
+			r := query.CompareValues(entries[i].fieldValue(s.Field), entries[j].fieldValue(s.Field))
+			if r != 0 {
+				return (r < 0) != s.Descending
+			}
+		}
+		return false
+	})
+
+	for _, o := range entries {
+		list.AppendValue(o)
+	}
+
+	// Is Count of Entries Requested?
+	if c { // YES: Count (without Title Filter)
+		list.SetMaxCount(page.MaxCount())
+	}
+
+	return list, nil
+}
+
+// StoreObjectSealedTitlesBatch Next Batch of Objects (by ID) with Sealed Titles after Object ID
+func StoreObjectSealedTitlesBatch(db *sql.DB, store uint32, after uint32, limit uint) ([]*StoreObject, error) {
+	var entries []*StoreObject
+
+	// Query Results Values
+	var id uint32
+	var title string
+
+	// Create SQL Statement
+	s := sqlf.From("objects").
+		Select("id").To(&id).
+		Select("title").To(&title).
+		Where("id_store = ? and id > ?", store, after).
+		Where("title LIKE ?", TITLE_SEALED_PREFIX+"%").
+		OrderBy("id").
+		Limit(limit)
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		// Save Object ID (New Memory Area)
+		object_id := id
+
+		o := &StoreObject{
+			stored: true,
+			store:  store,
+			id:     &object_id,
+		}
+		o.setStoredTitle(title)
+
+		entries = append(entries, o)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return entries, nil
+}
+
+// StoreObjectUpdateTitle Replace Stored Title (i.e. on Store Key Rotation) without Modifying Metadata
+func StoreObjectUpdateTitle(db sqlf.Executor, store uint32, id uint32, title string) error {
+	// Create SQL Statement
+	s := sqlf.Update("objects").
+		Set("title", title).
+		Where("id_store = ? and id = ?", store, id)
+
+	// Execute Statement
+	_, e := s.ExecAndClose(context.TODO(), db)
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	return storeObjectsChanged(db, store)
+}
+
+// StoreObjectRevisionUpdateTitle Replace Stored Revision Title (i.e. on Store Key Rotation)
+func StoreObjectRevisionUpdateTitle(db sqlf.Executor, store uint32, object uint32, revision uint32, title string) error {
+	// Create SQL Statement
+	s := sqlf.Update("store_object_revisions").
+		Set("title", title).
+		Where("id_store = ? and id_object = ? and revision = ?", store, object, revision)
+
+	// Execute Statement
+	_, e := s.ExecAndClose(context.TODO(), db)
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	return nil
+}
+
+// storeTitleAAD Additional Authenticated Data for Sealed Titles (Bound to Store)
+func storeTitleAAD(store uint32) []byte {
+	aad := []byte("title:0000")
+	binary.BigEndian.PutUint32(aad[6:], store)
+	return aad
+}
@@ -12,6 +12,7 @@ package orm
  */
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
@@ -32,17 +33,27 @@ type StoreObject struct {
 	parent   uint32     // KEY: Parent Object ID (0 == ROOT)
 	id       *uint32    // KEY: SHARD UNIQUE Local Object ID
 	title    string     // Object Title
+	sealed   string     // Object Title as Stored (if Sealed with Store Key)
 	objtype  uint8      // Object Type
 	object   []byte     // Encrypted Store Object
+	loaded   []byte     // Encrypted Store Object as Loaded (or Last Saved)
 	creator  *uint64    // Global User ID of Creator
 	created  *time.Time // Created TimeStamp
 	modifier *uint64    // Global User ID of Last Modifier
 	modified *time.Time // Modification TimeStamp
+	trash    *uint32    // TRASH: Local ID of Trashed Object (Self or Trashed Ancestor Folder)
+	deleter  *uint64    // TRASH: Global User ID of Deleter
+	deleted  *time.Time // TRASH: Deletion TimeStamp
+	purge    *time.Time // TRASH: Scheduled Purge TimeStamp
 }
 
 // KNOWN OBJECT TYPES
 const OBJECT_TYPE_FOLDER = 0
 const OBJECT_TYPE_JSON = 1
+const OBJECT_TYPE_ATTACHMENT = 2
+
+// Object Updated (since Loaded) by Another Request
+var ErrStoreObjectModified = errors.New("Store Object Modified by Another Request")
 
 func ChildObjectFromParent(p *StoreObject) (*StoreObject, error) {
 	if p.Type() != OBJECT_TYPE_FOLDER {
@@ -67,6 +78,9 @@ func StoreObjectGetLocalID(db sqlf.Executor, store uint32, title string) (uint32
 		Select("id").To(&id).
 		Where("id_store = ?", store).
 		Where("title = ?", title).
+		Where("trash IS NULL").
+		OrderBy("id DESC").
+		Limit(1).
 		QueryRowAndClose(context.TODO(), db)
 
 		// Error Executing Query?
@@ -78,6 +92,137 @@ func StoreObjectGetLocalID(db sqlf.Executor, store uint32, title string) (uint32
 	return id, nil
 }
 
+// StoreObjectFirst Local ID of First Object (of any Type, all are Encrypted) in Store (0 if None)
+func StoreObjectFirst(db sqlf.Executor, store uint32) (uint32, error) {
+	// Query Results Values
+	var id uint32
+
+	// Create SQL Statement
+	e := sqlf.From("objects").
+		Select("id").To(&id).
+		Where("id_store = ?", store).
+		OrderBy("id").
+		Limit(1).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Executing Query?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	return id, nil
+}
+
+// StoreObjectsBatch Next Batch of Encrypted Store Objects (by ID) after Object ID
+func StoreObjectsBatch(db *sql.DB, store uint32, after uint32, limit uint) ([]*StoreObject, error) {
+	var entries []*StoreObject
+
+	// Query Results Values
+	var id uint32
+	var parent uint32
+	var title string
+	var objtype uint8
+	var object []byte
+
+	// Create SQL Statement
+	s := sqlf.From("objects").
+		Select("id_parent").To(&parent).
+		Select("id").To(&id).
+		Select("title").To(&title).
+		Select("type").To(&objtype).
+		Select("object").To(&object).
+		Where("id_store = ? and id > ?", store, after).
+		Where("object IS NOT NULL").
+		OrderBy("id").
+		Limit(limit)
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		// Save Object ID and Bytes (New Memory Area)
+		object_id := id
+		bytes := make([]byte, len(object))
+		copy(bytes, object)
+
+		o := &StoreObject{
+			stored:  true,
+			store:   store,
+			parent:  parent,
+			id:      &object_id,
+			objtype: objtype,
+			object:  bytes,
+		}
+		o.setStoredTitle(title)
+
+		entries = append(entries, o)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return entries, nil
+}
+
+// StoreObjectUpdateCipher Replace Encrypted Object (i.e. on Store Key Rotation) without Modifying Metadata
+func StoreObjectUpdateCipher(db sqlf.Executor, store uint32, id uint32, object []byte) error {
+	// Create SQL Statement
+	s := sqlf.Update("objects").
+		Set("object", object).
+		Where("id_store = ? and id = ?", store, id)
+
+	// Execute Statement
+	_, e := s.ExecAndClose(context.TODO(), db)
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	return storeObjectsChanged(db, store)
+}
+
+// StoreObjectInsertEncrypted Create Object and Save its Cipher (Bound to the New Object ID) in a Single Transaction
+func StoreObjectInsertEncrypted(db *sql.DB, o *StoreObject, encrypt func(id uint32) ([]byte, error)) error {
+	if !o.IsNew() {
+		return errors.New("Object Already Registered")
+	}
+
+	tx, e := db.BeginTx(context.TODO(), nil)
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	// Insert Object without Cipher (ID only Known after Insert)
+	o.SetObject([]byte{})
+	e = o.Flush(tx, true)
+	if e == nil {
+		var ebs []byte
+		ebs, e = encrypt(o.ID())
+		if e == nil {
+			e = StoreObjectUpdateCipher(tx, o.store, o.ID(), ebs)
+		}
+		if e == nil {
+			o.object = ebs
+			o.loaded = ebs
+			e = tx.Commit()
+		}
+	}
+
+	// Error Occurred?
+	if e != nil { // YES: Nothing was Created
+		tx.Rollback()
+		o.id = nil
+		o.stored = false
+		o.dirty = true
+		return e
+	}
+
+	return nil
+}
+
 func StoreObjectsDeleteAll(db *sql.DB, store uint32) (uint64, error) {
 	// Create SQL Statement
 	s := sqlf.DeleteFrom("objects").
@@ -96,7 +241,7 @@ func StoreObjectsDeleteAll(db *sql.DB, store uint32) (uint64, error) {
 		log.Printf("query error: %v\n", e)
 		return 0, e
 	}
-	return uint64(c), nil
+	return uint64(c), storeObjectsChanged(db, store)
 }
 
 func StoreObjectDeleteFolder(db *sql.DB, store uint32, folder uint32) error {
@@ -120,9 +265,10 @@ func StoreObjectDeleteFolder(db *sql.DB, store uint32, folder uint32) error {
 	// Error Occurred?
 	if e != nil { // YES
 		log.Printf("query error: %v\n", e)
+		return e
 	}
 
-	return nil
+	return storeObjectsChanged(db, store)
 }
 
 func StoreObjectDelete(db *sql.DB, store uint32, oid uint32) error {
@@ -134,9 +280,10 @@ func StoreObjectDelete(db *sql.DB, store uint32, oid uint32) error {
 	// Error Occurred?
 	if e != nil { // YES
 		log.Printf("query error: %v\n", e)
+		return e
 	}
 
-	return e
+	return storeObjectsChanged(db, store)
 }
 
 func StoreObjectCount(db *sql.DB, store uint32, q query.TQueryConditions) (uint64, error) {
@@ -177,6 +324,7 @@ func CountStoreParentObjectType(db *sql.DB, store uint32, parent uint32, otype u
 	e := sqlf.From("objects").
 		Select("COUNT(*)").To(&count).
 		Where("id_store = ? and id_parent = ? and type = ?", store, parent, otype).
+		Where("trash IS NULL").
 		QueryRowAndClose(context.TODO(), db)
 
 	// Error Occurred?
@@ -189,13 +337,21 @@ func CountStoreParentObjectType(db *sql.DB, store uint32, parent uint32, otype u
 }
 
 func CountStoreParentObject(db *sql.DB, store uint32, parent uint32, q query.TQueryConditions) (uint64, error) {
+	return countStoreParentObject(db, store, parent, nil, q)
+}
+
+func countStoreParentObject(db *sql.DB, store uint32, parent uint32, tags []string, q query.TQueryConditions) (uint64, error) {
 	// Query Results Values
 	var count uint64
 
 	// Create SQL Statement
 	s := sqlf.From("objects").
 		Select("COUNT(*)").To(&count).
-		Where("id_store = ? and id_parent = ?", store, parent)
+		Where("id_store = ? and id_parent = ?", store, parent).
+		Where("trash IS NULL")
+
+	// Limit to Tagged Objects
+	applyTagsCondition(s, store, tags)
 
 	// Apply Query Conditions
 	e := query.ApplyFilterConditions(s, q)
@@ -234,7 +390,8 @@ func QueryStoreObjects(db *sql.DB, store uint32, query string) ([]StoreObject, e
 		Select("id").To(&id).
 		Select("title").To(&title).
 		Select("type").To(&objtype).
-		Where("id_store = ?", store)
+		Where("id_store = ?", store).
+		Where("trash IS NULL")
 
 	// Execute Query
 	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
@@ -246,9 +403,9 @@ func QueryStoreObjects(db *sql.DB, store uint32, query string) ([]StoreObject, e
 			store:   store,
 			parent:  parent,
 			id:      &object_id,
-			title:   title,
 			objtype: objtype,
 		}
+		o.setStoredTitle(title)
 
 		if entries == nil {
 			entries = make([]StoreObject, 1)
@@ -268,6 +425,11 @@ func QueryStoreObjects(db *sql.DB, store uint32, query string) ([]StoreObject, e
 }
 
 func QueryStoreParentObjects(db *sql.DB, store uint32, parent uint32, q query.TQueryConditions, c bool) (query.TQueryResults, error) {
+	return QueryStoreParentTaggedObjects(db, store, parent, nil, q, c)
+}
+
+// QueryStoreParentTaggedObjects List Folder Contents with All Tags (in Tag Index)
+func QueryStoreParentTaggedObjects(db *sql.DB, store uint32, parent uint32, tags []string, q query.TQueryConditions, c bool) (query.TQueryResults, error) {
 	var list query.QueryResults = query.QueryResults{}
 	list.SetMaxLimit(100) // Hard Code Maximum Limit
 
@@ -293,7 +455,11 @@ func QueryStoreParentObjects(db *sql.DB, store uint32, parent uint32, q query.TQ
 		Select("id").To(&id).
 		Select("title").To(&title).
 		Select("type").To(&objtype).
-		Where("id_store = ? and id_parent = ?", store, parent)
+		Where("id_store = ? and id_parent = ?", store, parent).
+		Where("trash IS NULL")
+
+	// Limit to Tagged Objects
+	applyTagsCondition(s, store, tags)
 
 	// Is OFFSET Set?
 	if list.Offset() > 0 { // YES: Use it
@@ -350,9 +516,9 @@ func QueryStoreParentObjects(db *sql.DB, store uint32, parent uint32, q query.TQ
 			store:   store,
 			parent:  parent,
 			id:      &object_id,
-			title:   title,
 			objtype: objtype,
 		}
+		o.setStoredTitle(title)
 
 		list.AppendValue(&o)
 	})
@@ -365,7 +531,7 @@ func QueryStoreParentObjects(db *sql.DB, store uint32, parent uint32, q query.TQ
 
 	// Is Count of Entries Requested?
 	if c { // YES: Count Entries under Same Conditions
-		count, e := CountStoreParentObject(db, store, parent, q)
+		count, e := countStoreParentObject(db, store, parent, tags, q)
 		if e != nil {
 			return nil, e
 		}
@@ -391,20 +557,29 @@ func (o *StoreObject) ByID(db *sql.DB, id uint32) error {
 	o.reset()
 
 	// Execute Query
+	var title string
 	var object sql.NullString
 	var created sql.NullString
 	var modifier sql.NullInt64
 	var modified sql.NullString
+	var trash sql.NullInt64
+	var deleter sql.NullInt64
+	var deleted sql.NullString
+	var purge sql.NullString
 	e := sqlf.From("objects").
 		Select("id_store").To(&o.store).
 		Select("id_parent").To(&o.parent).
-		Select("title").To(&o.title).
+		Select("title").To(&title).
 		Select("type").To(&o.objtype).
 		Select("object").To(&object).
 		Select("creator").To(&o.creator).
 		Select("created").To(&created).
 		Select("modifier").To(&modifier).
 		Select("modified").To(&modified).
+		Select("trash").To(&trash).
+		Select("deleter").To(&deleter).
+		Select("deleted").To(&deleted).
+		Select("purge").To(&purge).
 		Where("id = ?", id).
 		QueryRowAndClose(context.TODO(), db)
 
@@ -417,10 +592,12 @@ func (o *StoreObject) ByID(db *sql.DB, id uint32) error {
 	// Did we retrieve an entry?
 	if e == nil { // YES
 		o.id = &id
+		o.setStoredTitle(title)
 
 		if object.Valid {
 			s := object.String
 			o.object = []byte(s)
+			o.loaded = o.object
 		}
 
 		if created.Valid {
@@ -433,6 +610,7 @@ func (o *StoreObject) ByID(db *sql.DB, id uint32) error {
 				o.modified = mysql.MySQLTimeStampToGoTime(created.String)
 			}
 		}
+		o.setTrash(trash, deleter, deleted, purge)
 
 		o.stored = true // Registered Entry
 	}
@@ -446,19 +624,28 @@ func (o *StoreObject) ByKey(db *sql.DB, store uint32, id uint32) error {
 	o.reset()
 
 	// Execute Query
+	var title string
 	var object sql.NullString
 	var created sql.NullString
 	var modifier sql.NullInt64
 	var modified sql.NullString
+	var trash sql.NullInt64
+	var deleter sql.NullInt64
+	var deleted sql.NullString
+	var purge sql.NullString
 	e := sqlf.From("objects").
 		Select("id_parent").To(&o.parent).
-		Select("title").To(&o.title).
+		Select("title").To(&title).
 		Select("type").To(&o.objtype).
 		Select("object").To(&object).
 		Select("creator").To(&o.creator).
 		Select("created").To(&created).
 		Select("modifier").To(&modifier).
 		Select("modified").To(&modified).
+		Select("trash").To(&trash).
+		Select("deleter").To(&deleter).
+		Select("deleted").To(&deleted).
+		Select("purge").To(&purge).
 		Where("id_store = ? and id = ?", store, id).
 		QueryRowAndClose(context.TODO(), db)
 
@@ -472,10 +659,12 @@ func (o *StoreObject) ByKey(db *sql.DB, store uint32, id uint32) error {
 	if e == nil { // YES
 		o.store = store
 		o.id = &id
+		o.setStoredTitle(title)
 
 		if object.Valid {
 			s := object.String
 			o.object = []byte(s)
+			o.loaded = o.object
 		}
 
 		if created.Valid {
@@ -488,6 +677,7 @@ func (o *StoreObject) ByKey(db *sql.DB, store uint32, id uint32) error {
 				o.modified = mysql.MySQLTimeStampToGoTime(created.String)
 			}
 		}
+		o.setTrash(trash, deleter, deleted, purge)
 
 		o.stored = true // Registered Entry
 	}
@@ -511,10 +701,21 @@ func (o *StoreObject) ID() uint32 {
 	return *o.id
 }
 
+// Title Object Title (Empty if Sealed and not Opened)
 func (o *StoreObject) Title() string {
 	return o.title
 }
 
+// HasSealedTitle Is the Stored Title Encrypted with the Store Key?
+func (o *StoreObject) HasSealedTitle() bool {
+	return o.sealed != ""
+}
+
+// SealedTitle Title as Stored (Empty if Plaintext)
+func (o *StoreObject) SealedTitle() string {
+	return o.sealed
+}
+
 func (o *StoreObject) Type() uint8 {
 	return o.objtype
 }
@@ -545,6 +746,31 @@ func (o *StoreObject) Modified() *time.Time {
 	return o.modified
 }
 
+// IsTrashed Is Object in Trash (Directly or Through a Trashed Folder)?
+func (o *StoreObject) IsTrashed() bool {
+	return o.trash != nil
+}
+
+// Trash Local ID of the Trash Entry (0 if not in Trash)
+func (o *StoreObject) Trash() uint32 {
+	if o.trash == nil {
+		return 0
+	}
+	return *o.trash
+}
+
+func (o *StoreObject) Deleter() *uint64 {
+	return o.deleter
+}
+
+func (o *StoreObject) Deleted() *time.Time {
+	return o.deleted
+}
+
+func (o *StoreObject) Purge() *time.Time {
+	return o.purge
+}
+
 func (o *StoreObject) SetStore(s uint32) (uint32, error) {
 	if o.IsNew() {
 		// Current State
@@ -573,12 +799,51 @@ func (o *StoreObject) SetTitle(t string) (string, error) {
 	// Current State
 	current := o.title
 
-	// New State
+	// Is the Title Reserved (Looks like a Sealed Title)?
+	if IsSealedTitle(t) { // YES
+		return current, errors.New("Object Title can't start with '" + TITLE_SEALED_PREFIX + "'")
+	}
+
+	// New State (Plaintext until Sealed)
 	o.title = t
+	o.sealed = ""
 	o.dirty = true
 	return current, nil
 }
 
+// OpenTitle Decrypt Sealed Title with Store Key
+func (o *StoreObject) OpenTitle(key []byte) error {
+	// Is the Title Sealed (and not yet Opened)?
+	if o.sealed == "" || o.title != "" { // NO: Nothing to Do
+		return nil
+	}
+
+	t, e := OpenStoreTitle(key, o.store, o.sealed)
+	if e != nil {
+		return e
+	}
+
+	o.title = t
+	return nil
+}
+
+// SealTitle Encrypt Title with Store Key (Title is Stored Sealed)
+func (o *StoreObject) SealTitle(key []byte) error {
+	// Is the Title Already Sealed?
+	if o.sealed != "" { // YES: Nothing to Do
+		return nil
+	}
+
+	t, e := SealStoreTitle(key, o.store, o.title)
+	if e != nil {
+		return e
+	}
+
+	o.sealed = t
+	o.dirty = true
+	return nil
+}
+
 func (o *StoreObject) SetType(t uint8) (uint8, error) {
 	if o.IsNew() {
 		// Current State
@@ -651,7 +916,7 @@ func (o *StoreObject) Flush(db sqlf.Executor, force bool) error {
 			InsertInto("objects").
 			Set("id_store", o.store).
 			Set("id_parent", o.parent).
-			Set("title", o.title).
+			Set("title", o.storedTitle()).
 			Set("type", o.objtype).
 			Set("creator", o.creator)
 
@@ -659,15 +924,16 @@ func (o *StoreObject) Flush(db sqlf.Executor, force bool) error {
 			s.Set("object", o.object)
 		}
 
-		_, e = s.ExecAndClose(context.TODO(), db)
+		var r sql.Result
+		r, e = s.ExecAndClose(context.TODO(), db)
 
 		// Error Occurred?
-		if e == nil { // NO: Get New Org's ID
-			// Error Occurred?
-			var id uint32
-			id, e = StoreObjectGetLocalID(db, o.store, o.title)
+		if e == nil { // NO: Get New Object's ID (From the Insert, Titles are not Unique)
+			var id int64
+			id, e = r.LastInsertId()
 			if e == nil { // NO: Set Object ID
-				o.id = &id
+				oid := uint32(id)
+				o.id = &oid
 			}
 		}
 	} else { // NO: Update
@@ -679,16 +945,39 @@ func (o *StoreObject) Flush(db sqlf.Executor, force bool) error {
 
 		// Create SQL Statement
 		s := sqlf.Update("objects").
-			Set("title", o.title).
+			Set("title", o.storedTitle()).
 			Set("modifier", o.modifier).
 			Set("object", o.object).
 			Where("id_store = ? and id = ?", o.store, o.id)
 
+		// Is the Object being Replaced?
+		conditional := o.loaded != nil && !bytes.Equal(o.loaded, o.object)
+		if conditional { // YES: Only if it still has the Loaded Value
+			s.Where("object = ?", o.loaded)
+		}
+
 		// Execute Statement
-		_, e = s.ExecAndClose(context.TODO(), db)
+		var r sql.Result
+		r, e = s.ExecAndClose(context.TODO(), db)
+
+		// Was the Object Modified by Another Request?
+		if e == nil && conditional {
+			// NOTE: A New Cipher Always Changes the Row, if the Condition Matches
+			var c int64
+			c, e = r.RowsAffected()
+			if e == nil && c == 0 { // YES
+				return ErrStoreObjectModified
+			}
+		}
 	}
 
+	// Increment Store Objects Version
 	if e == nil {
+		e = storeObjectsChanged(db, o.store)
+	}
+
+	if e == nil {
+		o.loaded = o.object
 		o.stored = true
 		o.dirty = false
 	}
@@ -699,9 +988,12 @@ func (o *StoreObject) isValidForInsert() string {
 	if o.store == 0 {
 		return "Object Missing Parent Store ID"
 	}
-	if o.title == "" {
+	if o.storedTitle() == "" {
 		return "Object Missing Title"
 	}
+	if o.sealed == "" && IsSealedTitle(o.title) {
+		return "Object Title is Reserved"
+	}
 	if o.creator == nil {
 		return "Object Missing Creation User ID"
 	}
@@ -721,6 +1013,9 @@ func (o *StoreObject) isValidForUpdate() string {
 	if o.modifier == nil {
 		return "Object Missing Modifier User ID"
 	}
+	if o.sealed == "" && IsSealedTitle(o.title) {
+		return "Object Title is Reserved"
+	}
 	return ""
 }
 
@@ -730,12 +1025,18 @@ func (o *StoreObject) reset() {
 	o.parent = 0
 	o.id = nil
 	o.title = ""
+	o.sealed = ""
 	o.objtype = 0
 	o.object = nil
+	o.loaded = nil
 	o.creator = nil
 	o.created = nil
 	o.modifier = nil
 	o.modified = nil
+	o.trash = nil
+	o.deleter = nil
+	o.deleted = nil
+	o.purge = nil
 
 	// Mark State as Unregistered
 	o.stored = false
@@ -743,3 +1044,61 @@ func (o *StoreObject) reset() {
 	// Mark Entry as Clean
 	o.dirty = false
 }
+
+// fieldValue Value of Field for In Memory Sort and Filter
+func (o *StoreObject) fieldValue(field string) interface{} {
+	switch field {
+	case "id":
+		return o.ID()
+	case "id_parent":
+		return o.parent
+	case "title":
+		return o.title
+	case "type":
+		return o.objtype
+	default:
+		return nil
+	}
+}
+
+// setStoredTitle Set Title as Stored (Sealed Titles have to be Opened)
+func (o *StoreObject) setStoredTitle(t string) {
+	if IsSealedTitle(t) {
+		o.title = ""
+		o.sealed = t
+	} else {
+		o.title = t
+		o.sealed = ""
+	}
+}
+
+// storedTitle Title as Saved to the Database
+func (o *StoreObject) storedTitle() string {
+	if o.sealed != "" {
+		return o.sealed
+	}
+	return o.title
+}
+
+func (o *StoreObject) setTrash(trash sql.NullInt64, deleter sql.NullInt64, deleted sql.NullString, purge sql.NullString) {
+	// Is Object in Trash?
+	if !trash.Valid { // NO
+		return
+	}
+
+	t := uint32(trash.Int64)
+	o.trash = &t
+
+	if deleter.Valid {
+		d := uint64(deleter.Int64)
+		o.deleter = &d
+	}
+
+	if deleted.Valid {
+		o.deleted = mysql.MySQLTimeStampToGoTime(deleted.String)
+	}
+
+	if purge.Valid {
+		o.purge = mysql.MySQLTimeStampToGoTime(purge.String)
+	}
+}
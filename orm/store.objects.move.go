@@ -0,0 +1,95 @@
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/pjacferreira/sqlf"
+)
+
+// StoreObjectMove Move Object (and Folder Contents) to Parent Folder (0 == ROOT)
+func StoreObjectMove(db *sql.DB, store uint32, id uint32, parent uint32, modifier uint64) error {
+	// Create SQL Statement
+	s := sqlf.Update("objects").
+		Set("id_parent", parent).
+		Set("modifier", modifier).
+		Where("id_store = ? and id = ? and trash IS NULL", store, id)
+
+	// Execute Statement
+	_, e := s.ExecAndClose(context.TODO(), db)
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	return storeObjectsChanged(db, store)
+}
+
+// StoreObjectIsAncestor Is Folder the Object, or one of the Object's Ancestor Folders?
+func StoreObjectIsAncestor(db *sql.DB, store uint32, folder uint32, id uint32) (bool, error) {
+	// Walk up the Folder Tree (Limited to the Number of Objects Visited, in Case of a Broken Tree)
+	visited := map[uint32]bool{}
+	for id != 0 {
+		if id == folder {
+			return true, nil
+		}
+
+		// Have we been here Before?
+		if visited[id] { // YES: Broken Tree
+			return false, errors.New("Store Object Tree contains a Cycle")
+		}
+		visited[id] = true
+
+		// Get Parent Folder
+		var parent uint32
+		e := sqlf.From("objects").
+			Select("id_parent").To(&parent).
+			Where("id_store = ? and id = ?", store, id).
+			QueryRowAndClose(context.TODO(), db)
+
+		// Error Occurred?
+		if e == sql.ErrNoRows { // YES: Object does not Exist
+			return false, nil
+		} else if e != nil {
+			log.Printf("query error: %v\n", e)
+			return false, e
+		}
+
+		id = parent
+	}
+
+	return folder == 0, nil
+}
+
+// StoreObjectChildren Objects Directly Contained in Folder (Excluding Trash)
+func StoreObjectChildren(db *sql.DB, store uint32, folder uint32) ([]*StoreObject, error) {
+	ids, e := storeObjectChildren(db, store, []interface{}{folder}, false)
+	if e != nil {
+		return nil, e
+	}
+
+	children := make([]*StoreObject, 0, len(ids))
+	for _, id := range ids {
+		o := &StoreObject{}
+		e = o.ByKey(db, store, id.(uint32))
+		if e != nil {
+			return nil, e
+		}
+
+		children = append(children, o)
+	}
+
+	return children, nil
+}
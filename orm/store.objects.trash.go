@@ -0,0 +1,405 @@
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/objectvault/api-services/orm/mysql"
+	"github.com/objectvault/api-services/orm/query"
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Store Trash
+ * Deleting a store object moves it to the store's trash: the object (and, for
+ * folders, every object below it) is marked with the ID of the deleted
+ * object ("trash"), the deleting user, the deletion time and the time after
+ * which it is purged.
+ * The trash lists only the deleted objects (trash == id). Restoring or purging
+ * a trash entry applies to every object marked with its ID.
+ * The purge time is set when the object is deleted, so a change in the store's
+ * retention period only applies to objects deleted after the change.
+ * Purging does not change the store's objects version (see StoreObjectsVersion),
+ * as objects in the trash are not part of the store's contents.
+ */
+
+// StoreObjectTrash Move Object (and Folder Contents) to Trash (retention in Days, 0 - Never Purge)
+func StoreObjectTrash(db *sql.DB, store uint32, id uint32, user uint64, retention uint16) (int, error) {
+	// NOTE: Folder Contents are Locked (Moves or Inserts Wait until they are Trashed)
+	tx, e := db.BeginTx(context.TODO(), nil)
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+	defer tx.Rollback()
+
+	// Collect Object and (Untrashed) Descendants
+	ids := []interface{}{id}
+	parents := []interface{}{id}
+	for len(parents) > 0 {
+		children, e := storeObjectChildren(tx, store, parents, true)
+		if e != nil {
+			return 0, e
+		}
+
+		ids = append(ids, children...)
+		parents = children
+	}
+
+	// Move to Trash
+	now := time.Now().UTC()
+	s := sqlf.Update("objects").
+		Set("trash", id).
+		Set("deleter", user).
+		Set("deleted", mysql.GoTimeToMySQLTimeStamp(&now))
+
+	// Schedule Purge?
+	if retention > 0 { // YES
+		purge := now.AddDate(0, 0, int(retention))
+		s.Set("purge", mysql.GoTimeToMySQLTimeStamp(&purge))
+	}
+
+	s.Where("id_store = ? and trash IS NULL", store).
+		Where("id").In(ids...)
+
+	_, e = s.ExecAndClose(context.TODO(), tx)
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	e = storeObjectsChanged(tx, store)
+	if e == nil {
+		e = tx.Commit()
+	}
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	return len(ids), nil
+}
+
+// StoreObjectRestore Restore Trash Entry (to the Store Root if its Folder no longer Exists)
+func StoreObjectRestore(db *sql.DB, store uint32, id uint32) error {
+	o := &StoreObject{}
+	e := o.ByKey(db, store, id)
+	if e != nil {
+		return e
+	}
+
+	// Is the Object a Trash Entry?
+	if o.IsNew() || o.Trash() != id { // NO
+		return errors.New("Object is not in Trash")
+	}
+
+	// Does the Parent Folder still Exist (outside of the Trash)?
+	if o.Parent() != 0 {
+		p := &StoreObject{}
+		e = p.ByKey(db, store, o.Parent())
+		if e != nil {
+			return e
+		}
+
+		if p.IsNew() || p.IsTrashed() { // NO: Restore to Root
+			_, e = sqlf.Update("objects").
+				Set("id_parent", 0).
+				Where("id_store = ? and id = ?", store, id).
+				ExecAndClose(context.TODO(), db)
+
+			if e != nil { // YES
+				log.Printf("query error: %v\n", e)
+				return e
+			}
+		}
+	}
+
+	// Restore Object and Folder Contents
+	_, e = sqlf.Update("objects").
+		SetExpr("trash", "NULL").
+		SetExpr("deleter", "NULL").
+		SetExpr("deleted", "NULL").
+		SetExpr("purge", "NULL").
+		Where("id_store = ? and trash = ?", store, id).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	return storeObjectsChanged(db, store)
+}
+
+// StoreObjectPurge Permanently Delete Trash Entry (Object, Folder Contents, Revisions, Attachments and Tags)
+func StoreObjectPurge(db *sql.DB, store uint32, id uint32) error {
+	// Delete Revisions
+	_, e := sqlf.DeleteFrom("store_object_revisions").
+		Where("id_store = ? and id_object in (select id from objects where id_store = ? and trash = ?)", store, store, id).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+
+	// Delete Attachments
+	e = deleteStoreAttachments(db, "id_store = ? and id_object in (select id from objects where id_store = ? and trash = ?)", store, store, id)
+	if e != nil {
+		return e
+	}
+
+	// Delete Tag Index Entries
+	e = deleteStoreTags(db, "id_store = ? and id_object in (select id from objects where id_store = ? and trash = ?)", store, store, id)
+	if e != nil {
+		return e
+	}
+
+	// Delete Objects
+	_, e = sqlf.DeleteFrom("objects").
+		Where("id_store = ? and trash = ?", store, id).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+
+	return e
+}
+
+// StoreTrashPurge Permanently Delete All Objects in Store Trash
+func StoreTrashPurge(db *sql.DB, store uint32) (uint64, error) {
+	// Delete Revisions
+	_, e := sqlf.DeleteFrom("store_object_revisions").
+		Where("id_store = ? and id_object in (select id from objects where id_store = ? and trash IS NOT NULL)", store, store).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	// Delete Attachments
+	e = deleteStoreAttachments(db, "id_store = ? and id_object in (select id from objects where id_store = ? and trash IS NOT NULL)", store, store)
+	if e != nil {
+		return 0, e
+	}
+
+	// Delete Tag Index Entries
+	e = deleteStoreTags(db, "id_store = ? and id_object in (select id from objects where id_store = ? and trash IS NOT NULL)", store, store)
+	if e != nil {
+		return 0, e
+	}
+
+	// Delete Objects
+	r, e := sqlf.DeleteFrom("objects").
+		Where("id_store = ? and trash IS NOT NULL", store).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	// How many entries deleted?
+	c, e := r.RowsAffected()
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+	return uint64(c), nil
+}
+
+// StoreTrashPurgeExpired Permanently Delete Objects (in All Stores of Shard) Past their Purge Time
+func StoreTrashPurgeExpired(db *sql.DB) (uint64, error) {
+	now := time.Now().UTC()
+	before := mysql.GoTimeToMySQLTimeStamp(&now)
+
+	// Delete Revisions
+	_, e := sqlf.DeleteFrom("store_object_revisions").
+		Where("(id_store, id_object) in (select id_store, id from objects where purge < ?)", before).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	// Delete Attachments
+	e = deleteStoreAttachments(db, "(id_store, id_object) in (select id_store, id from objects where purge < ?)", before)
+	if e != nil {
+		return 0, e
+	}
+
+	// Delete Tag Index Entries
+	e = deleteStoreTags(db, "(id_store, id_object) in (select id_store, id from objects where purge < ?)", before)
+	if e != nil {
+		return 0, e
+	}
+
+	// Delete Objects
+	r, e := sqlf.DeleteFrom("objects").
+		Where("purge < ?", before).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	// How many entries deleted?
+	c, e := r.RowsAffected()
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+	return uint64(c), nil
+}
+
+func CountStoreTrash(db *sql.DB, store uint32) (uint64, error) {
+	// Query Results Values
+	var count uint64
+
+	// Create SQL Statement
+	e := sqlf.From("objects").
+		Select("COUNT(*)").To(&count).
+		Where("id_store = ? and trash = id", store).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Occurred?
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+		return 0, e
+	}
+
+	return count, nil
+}
+
+// QueryStoreTrash List Trash Entries (Newest First)
+func QueryStoreTrash(db *sql.DB, store uint32, q query.TQueryConditions, c bool) (query.TQueryResults, error) {
+	var list query.QueryResults = query.QueryResults{}
+	list.SetMaxLimit(100) // Hard Code Maximum Limit
+
+	// Set Query Page Limits
+	if q != nil {
+		// Set Offset From Query Conditions
+		if q.Offset() != nil {
+			list.SetOffset(*q.Offset())
+		}
+
+		if q.Limit() != nil {
+			list.SetLimit(*q.Limit())
+		}
+	}
+
+	// Query Results Values
+	var id uint32
+	var parent uint32
+	var title string
+	var objtype uint8
+	var deleter sql.NullInt64
+	var deleted sql.NullString
+	var purge sql.NullString
+
+	// Create SQL Statement
+	s := sqlf.From("objects").
+		Select("id").To(&id).
+		Select("id_parent").To(&parent).
+		Select("title").To(&title).
+		Select("type").To(&objtype).
+		Select("deleter").To(&deleter).
+		Select("deleted").To(&deleted).
+		Select("purge").To(&purge).
+		Where("id_store = ? and trash = id", store).
+		OrderBy("deleted DESC", "id DESC")
+	list.AppendSort("deleted", true)
+
+	// Is OFFSET Set?
+	if list.Offset() > 0 { // YES: Use it
+		s.Offset(list.Offset())
+	}
+
+	// Is LIMIT Set?
+	if list.Limit() > 0 { // YES: Use it
+		s.Limit(list.Limit())
+	}
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		// Save Object ID (New Memory Area)
+		object_id := id
+
+		o := &StoreObject{
+			stored:  true,
+			store:   store,
+			parent:  parent,
+			id:      &object_id,
+			objtype: objtype,
+		}
+		o.setStoredTitle(title)
+		o.setTrash(sql.NullInt64{Int64: int64(id), Valid: true}, deleter, deleted, purge)
+
+		list.AppendValue(o)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	// Is Count of Entries Requested?
+	if c { // YES: Count Entries
+		count, e := CountStoreTrash(db, store)
+		if e != nil {
+			return nil, e
+		}
+
+		list.SetMaxCount(count)
+	}
+
+	return list, nil
+}
+
+// storeObjectChildren IDs of (Untrashed) Children of Parent Folders (lock - Lock Rows for Update, in Transaction)
+func storeObjectChildren(db sqlf.Executor, store uint32, parents []interface{}, lock bool) ([]interface{}, error) {
+	var children []interface{}
+
+	// Query Results Values
+	var id uint32
+
+	// Create SQL Statement
+	s := sqlf.From("objects").
+		Select("id").To(&id).
+		Where("id_store = ? and trash IS NULL", store).
+		Where("id_parent").In(parents...)
+
+	if lock {
+		s.Clause("FOR UPDATE")
+	}
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		children = append(children, id)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return children, nil
+}
@@ -0,0 +1,523 @@
+// cSpell:ignore paulo ferreira
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Store Search Index
+ * Object values are encrypted, so the database can't search them. To search a
+ * store, the objects (outside of the trash) are decrypted in batches and the
+ * configured template fields are extracted into a search index.
+ * The index is cached, per store session, so that following searches don't
+ * have to decrypt the whole store again. The cached index is encrypted with
+ * the store key (bound to the store) and is only kept while the store session
+ * is open and in use.
+ * Each index has a version (the store's objects version, a counter that every
+ * write to the store's objects increments, including cipher and title only
+ * rewrites) so an index is rebuilt, rather than used, once the store contents
+ * change.
+ *
+ * IMPORTANT: The cache is held in memory, so servers behind a load balancer
+ * each build (and keep) their own index.
+ */
+
+// Title Field (Object Title, not a Template Value)
+const SEARCH_FIELD_TITLE = "title"
+
+// Store Search Field (and Rank Weight)
+type StoreSearchField struct {
+	Name   string // Template Field Name (or SEARCH_FIELD_TITLE)
+	Weight uint16 // Rank Weight
+}
+
+// Default Search Fields
+var storeSearchFields = []StoreSearchField{
+	{Name: SEARCH_FIELD_TITLE, Weight: 10},
+	{Name: "username", Weight: 6},
+	{Name: "url", Weight: 4},
+	{Name: OBJECT_VALUE_TAGS, Weight: 3},
+	{Name: "notes", Weight: 1},
+}
+
+// Default Cached Index Idle Time
+var storeSearchIdle = 15 * time.Minute
+var storeSearchLock sync.RWMutex
+
+// SetStoreSearchFields Set Template Fields Searched (and their Rank Weight)
+func SetStoreSearchFields(fields []StoreSearchField) error {
+	if len(fields) == 0 {
+		return errors.New("Search requires at least one Field")
+	}
+
+	for _, f := range fields {
+		if f.Name == "" || f.Weight == 0 {
+			return errors.New("Search Fields require a Name and a Weight > 0")
+		}
+	}
+
+	storeSearchLock.Lock()
+	defer storeSearchLock.Unlock()
+	storeSearchFields = append([]StoreSearchField{}, fields...)
+	return nil
+}
+
+// StoreSearchFields Current Search Fields
+func StoreSearchFields() []StoreSearchField {
+	storeSearchLock.RLock()
+	defer storeSearchLock.RUnlock()
+	return append([]StoreSearchField{}, storeSearchFields...)
+}
+
+// SetStoreSearchIdle Set Time an Unused Index is Kept in the Cache
+func SetStoreSearchIdle(idle time.Duration) error {
+	if idle < time.Minute {
+		return errors.New("Search Index Idle Time has to be >= 1 Minute")
+	}
+
+	storeSearchLock.Lock()
+	defer storeSearchLock.Unlock()
+	storeSearchIdle = idle
+	return nil
+}
+
+// StoreSearchIdle Time an Unused Index is Kept in the Cache
+func StoreSearchIdle() time.Duration {
+	storeSearchLock.RLock()
+	defer storeSearchLock.RUnlock()
+	return storeSearchIdle
+}
+
+// Indexed Store Object
+type StoreSearchEntry struct {
+	ID       uint32            `json:"i"`
+	Parent   uint32            `json:"p"`
+	Type     uint8             `json:"y"`
+	Title    string            `json:"t"`
+	Template string            `json:"m,omitempty"`
+	Fields   map[string]string `json:"f,omitempty"` // Searched Values (Lower Case)
+}
+
+// Search Match
+type StoreSearchResult struct {
+	Entry  *StoreSearchEntry
+	Score  uint32   // Rank (Higher is Better)
+	Fields []string // Fields that Matched
+}
+
+// Store Search Index
+type StoreSearchIndex struct {
+	store   uint32             // Store Local ID
+	version string             // Store Contents Version
+	entries []StoreSearchEntry // Indexed Objects
+}
+
+func (i *StoreSearchIndex) Store() uint32 {
+	return i.store
+}
+
+func (i *StoreSearchIndex) Version() string {
+	return i.version
+}
+
+func (i *StoreSearchIndex) Count() int {
+	return len(i.entries)
+}
+
+// StoreObjectsVersion Version of Store Contents (Changes if Objects are Created, Modified or Deleted)
+func StoreObjectsVersion(db sqlf.Executor, store uint32) (string, error) {
+	// Query Results Values
+	var version uint64
+
+	// Create SQL Statement
+	e := sqlf.From("store_objects_versions").
+		Select("version").To(&version).
+		Where("id_store = ?", store).
+		QueryRowAndClose(context.TODO(), db)
+
+	// Error Executing Query?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return "", e
+	}
+
+	return fmt.Sprintf("%d", version), nil
+}
+
+// storeObjectsChanged Increment Store Objects Version (Called on Every Write to the Store's Objects)
+func storeObjectsChanged(db sqlf.Executor, store uint32) error {
+	_, e := sqlf.InsertInto("store_objects_versions").
+		Set("id_store", store).
+		Set("version", 1).
+		Clause("ON DUPLICATE KEY UPDATE version = version + 1").
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil { // YES
+		log.Printf("query error: %v\n", e)
+	}
+	return e
+}
+
+// BuildStoreSearchIndex Decrypt Store Objects (Outside of Trash) and Index Search Fields
+func BuildStoreSearchIndex(db *sql.DB, store uint32, key []byte, version string, fields []StoreSearchField, batch uint) (*StoreSearchIndex, error) {
+	index := &StoreSearchIndex{
+		store:   store,
+		version: version,
+	}
+
+	after := uint32(0)
+	for {
+		objs, e := StoreActiveObjectsBatch(db, store, after, batch)
+		if e != nil {
+			return nil, e
+		}
+
+		// Have we Reached the End?
+		if len(objs) == 0 { // YES
+			break
+		}
+
+		for _, o := range objs {
+			after = o.ID()
+
+			// Open Title
+			e = o.OpenTitle(key)
+			if e != nil {
+				return nil, e
+			}
+
+			entry := StoreSearchEntry{
+				ID:     o.ID(),
+				Parent: o.Parent(),
+				Type:   o.Type(),
+				Title:  o.Title(),
+				Fields: map[string]string{},
+			}
+
+			// Does the Object have Values?
+			var values map[string]interface{}
+			if len(o.Object()) > 0 { // YES: Decrypt
+				ot := &StoreTemplateObject{}
+				e = ot.DecryptObject(key, store, o.ID(), o.Object())
+				if e != nil {
+					return nil, e
+				}
+
+				entry.Template = ot.Template()
+				values, _ = ot.Values().(map[string]interface{})
+			}
+
+			for _, f := range fields {
+				var v string
+				if f.Name == SEARCH_FIELD_TITLE {
+					v = entry.Title
+				} else if values != nil {
+					v = searchValue(values[f.Name])
+				}
+
+				if v != "" {
+					entry.Fields[f.Name] = strings.ToLower(v)
+				}
+			}
+
+			index.entries = append(index.entries, entry)
+		}
+	}
+
+	return index, nil
+}
+
+// Search Index Entries that Match all Terms (Ranked)
+func (i *StoreSearchIndex) Search(terms []string, fields []StoreSearchField, limit int) []StoreSearchResult {
+	results := []StoreSearchResult{}
+	for n := range i.entries {
+		entry := &i.entries[n]
+
+		// Every Term has to Match (at least) One Field
+		r := StoreSearchResult{Entry: entry}
+		matched := map[string]bool{}
+		for _, t := range terms {
+			score := uint32(0)
+			for _, f := range fields {
+				s := searchScore(entry.Fields[f.Name], t)
+				if s > 0 {
+					score += s * uint32(f.Weight)
+					matched[f.Name] = true
+				}
+			}
+
+			// Did the Term Match?
+			if score == 0 { // NO: Skip Entry
+				r.Score = 0
+				break
+			}
+			r.Score += score
+		}
+
+		if r.Score == 0 {
+			continue
+		}
+
+		// Matched Fields (in Field Order)
+		for _, f := range fields {
+			if matched[f.Name] {
+				r.Fields = append(r.Fields, f.Name)
+			}
+		}
+
+		results = append(results, r)
+	}
+
+	// Best Matches First (Ties by Title and ID)
+	sort.Slice(results, func(a, b int) bool {
+		ra, rb := results[a], results[b]
+		if ra.Score != rb.Score {
+			return ra.Score > rb.Score
+		}
+
+		ta, tb := strings.ToLower(ra.Entry.Title), strings.ToLower(rb.Entry.Title)
+		if ta != tb {
+			return ta < tb
+		}
+		return ra.Entry.ID < rb.Entry.ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// Seal Encrypt Index with Store Key
+func (i *StoreSearchIndex) Seal(key []byte) ([]byte, error) {
+	plain, e := json.Marshal(i.entries)
+	if e != nil {
+		return nil, e
+	}
+
+	return gcmEncryptAAD(key, plain, storeSearchAAD(i.store))
+}
+
+// OpenStoreSearchIndex Decrypt Sealed Index with Store Key
+func OpenStoreSearchIndex(key []byte, store uint32, version string, cbs []byte) (*StoreSearchIndex, error) {
+	plain, e := gcmDecryptAAD(key, cbs, storeSearchAAD(store))
+	if e != nil {
+		return nil, e
+	}
+
+	index := &StoreSearchIndex{
+		store:   store,
+		version: version,
+	}
+
+	e = json.Unmarshal(plain, &index.entries)
+	if e != nil {
+		return nil, e
+	}
+
+	return index, nil
+}
+
+// Cached (Sealed) Index
+type storeSearchCacheEntry struct {
+	store   uint32    // Store Local ID
+	version string    // Store Contents Version
+	sealed  []byte    // Encrypted Index
+	used    time.Time // Last Use
+}
+
+// Cached Indexes by Store Session ID
+var storeSearchCache = map[string]*storeSearchCacheEntry{}
+var storeSearchCacheLock sync.Mutex
+
+// CachedStoreSearchIndex Cached Index for Store Session (nil if not Cached, or Outdated)
+func CachedStoreSearchIndex(session string, key []byte, store uint32, version string) *StoreSearchIndex {
+	storeSearchCacheLock.Lock()
+	defer storeSearchCacheLock.Unlock()
+
+	// Remove Unused Indexes
+	purgeStoreSearchCache(time.Now())
+
+	// Is the Index Cached (and Current)?
+	c, ok := storeSearchCache[session]
+	if !ok || c.store != store || c.version != version { // NO
+		return nil
+	}
+
+	index, e := OpenStoreSearchIndex(key, store, version, c.sealed)
+	if e != nil { // Not Usable: Remove it
+		delete(storeSearchCache, session)
+		return nil
+	}
+
+	c.used = time.Now()
+	return index
+}
+
+// CacheStoreSearchIndex Cache (Sealed) Index for Store Session
+func CacheStoreSearchIndex(session string, key []byte, index *StoreSearchIndex) error {
+	sealed, e := index.Seal(key)
+	if e != nil {
+		return e
+	}
+
+	storeSearchCacheLock.Lock()
+	defer storeSearchCacheLock.Unlock()
+
+	storeSearchCache[session] = &storeSearchCacheEntry{
+		store:   index.store,
+		version: index.version,
+		sealed:  sealed,
+		used:    time.Now(),
+	}
+	return nil
+}
+
+func purgeStoreSearchCache(now time.Time) {
+	idle := StoreSearchIdle()
+	for id, c := range storeSearchCache {
+		// Unused, or Store Session Closed?
+		if now.Sub(c.used) > idle || !common.StoreSessions().IsOpen(id) { // YES: Remove
+			delete(storeSearchCache, id)
+		}
+	}
+}
+
+// StoreActiveObjectsBatch Next Batch of Store Objects (Outside of Trash) after Object ID
+func StoreActiveObjectsBatch(db *sql.DB, store uint32, after uint32, limit uint) ([]*StoreObject, error) {
+	var entries []*StoreObject
+
+	// Query Results Values
+	var id uint32
+	var parent uint32
+	var title string
+	var objtype uint8
+	var object []byte
+
+	// Create SQL Statement
+	s := sqlf.From("objects").
+		Select("id_parent").To(&parent).
+		Select("id").To(&id).
+		Select("title").To(&title).
+		Select("type").To(&objtype).
+		Select("object").To(&object).
+		Where("id_store = ? and id > ?", store, after).
+		Where("trash IS NULL").
+		OrderBy("id").
+		Limit(limit)
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		// Save Object ID and Bytes (New Memory Area)
+		object_id := id
+		var bytes []byte
+		if len(object) > 0 {
+			bytes = make([]byte, len(object))
+			copy(bytes, object)
+		}
+
+		o := &StoreObject{
+			stored:  true,
+			store:   store,
+			parent:  parent,
+			id:      &object_id,
+			objtype: objtype,
+			object:  bytes,
+		}
+		o.setStoredTitle(title)
+
+		entries = append(entries, o)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return entries, nil
+}
+
+// searchValue Template Value as Searchable Text
+func searchValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []interface{}: // i.e. Tags
+		parts := make([]string, 0, len(t))
+		for _, i := range t {
+			if s := searchValue(i); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, " ")
+	case map[string]interface{}: // Nested Values aren't Searched
+		return ""
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// searchScore Rank of Term in (Lower Case) Value: 4 - Equal, 3 - Prefix, 2 - Word Prefix, 1 - Contains
+func searchScore(v string, term string) uint32 {
+	p := strings.Index(v, term)
+	switch {
+	case p < 0:
+		return 0
+	case v == term:
+		return 4
+	case p == 0:
+		return 3
+	}
+
+	// Does the Term Start a Word?
+	for p >= 0 {
+		if !isSearchWordRune(v[p-1]) {
+			return 2
+		}
+
+		n := strings.Index(v[p+1:], term)
+		if n < 0 {
+			break
+		}
+		p += n + 1
+	}
+	return 1
+}
+
+func isSearchWordRune(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b >= 0x80
+}
+
+// storeSearchAAD Additional Authenticated Data for Cached Search Indexes (Bound to Store)
+func storeSearchAAD(store uint32) []byte {
+	aad := []byte("search:0000")
+	binary.BigEndian.PutUint32(aad[7:], store)
+	return aad
+}
@@ -0,0 +1,267 @@
+// cSpell:ignore paulo ferreira, sqlf
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pjacferreira/sqlf"
+)
+
+/* NOTE: Shared Store Session Registry
+ * Store sessions are registered in the global shard (table
+ * "registry_store_sessions"), so that every server instance behind a load
+ * balancer sees the same sessions (and operation counts).
+ * Database errors are treated as closed sessions (fail closed).
+ */
+type DBStoreSessionRegistry struct {
+	dbm    *DBSessionManager
+	lock   sync.Mutex
+	purged int64 // UTC Unix Time of Last Purge
+}
+
+func NewDBStoreSessionRegistry(dbm *DBSessionManager) (*DBStoreSessionRegistry, error) {
+	if dbm == nil {
+		return nil, errors.New("Missing Database Manager")
+	}
+
+	return &DBStoreSessionRegistry{dbm: dbm}, nil
+}
+
+// Register Store Session (operations == 0 - Operations not Limited)
+func (o *DBStoreSessionRegistry) Register(id string, user uint64, store uint64, org uint64, operations uint16, expires int64) error {
+	if id == "" || user == 0 || store == 0 {
+		return errors.New("Invalid Store Session")
+	}
+
+	db, e := o.connect()
+	if e != nil {
+		return e
+	}
+
+	// Remove Expired Sessions
+	o.purge(db, time.Now().Unix())
+
+	_, e = sqlf.InsertInto("registry_store_sessions").
+		Set("id", id).
+		Set("id_user", user).
+		Set("id_store", store).
+		Set("id_org", org).
+		Set("operations", operations).
+		Set("limited", operations > 0).
+		Set("expires", expires).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+	return nil
+}
+
+// Touch Update Expiration of Registered Session (FALSE if not Registered)
+func (o *DBStoreSessionRegistry) Touch(id string, expires int64) bool {
+	ok := false
+	e := o.locked(id, func(tx *sql.Tx, _ uint16, _ bool) error {
+		_, e := sqlf.Update("registry_store_sessions").
+			Set("expires", expires).
+			Where("id = ?", id).
+			ExecAndClose(context.TODO(), tx)
+
+		ok = e == nil
+		return e
+	})
+
+	return e == nil && ok
+}
+
+// Use Count an Operation against Registered Session (returns Operations Left, FALSE if Closed or none Left)
+func (o *DBStoreSessionRegistry) Use(id string) (uint16, bool) {
+	var left uint16
+	ok := false
+	e := o.locked(id, func(tx *sql.Tx, operations uint16, limited bool) error {
+		// Are Operations Limited?
+		if !limited { // NO
+			ok = true
+			return nil
+		}
+
+		// Any Operations Left?
+		if operations == 0 { // NO
+			return nil
+		}
+
+		_, e := sqlf.Update("registry_store_sessions").
+			Set("operations", operations-1).
+			Where("id = ?", id).
+			ExecAndClose(context.TODO(), tx)
+
+		left = operations - 1
+		ok = e == nil
+		return e
+	})
+
+	if e != nil || !ok {
+		return 0, false
+	}
+	return left, true
+}
+
+/* locked Call Function with Registered (Open) Session Row Locked in a Transaction
+ * NOTE: The row is read (SELECT ... FOR UPDATE), rather than relying on the
+ * rows affected by an UPDATE, as MySQL only counts rows that actually change.
+ */
+func (o *DBStoreSessionRegistry) locked(id string, f func(tx *sql.Tx, operations uint16, limited bool) error) error {
+	db, e := o.connect()
+	if e != nil {
+		return e
+	}
+
+	tx, e := db.BeginTx(context.TODO(), nil)
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return e
+	}
+	defer tx.Rollback()
+
+	// Query Results Values
+	var operations uint16
+	var limited bool
+
+	e = sqlf.From("registry_store_sessions").
+		Select("operations").To(&operations).
+		Select("limited").To(&limited).
+		Where("id = ? and expires >= ?", id, time.Now().Unix()).
+		Clause("FOR UPDATE").
+		QueryRowAndClose(context.TODO(), tx)
+
+	// Is Session Registered?
+	if e == sql.ErrNoRows { // NO
+		return e
+	}
+
+	if e == nil {
+		e = f(tx, operations, limited)
+	}
+
+	if e == nil {
+		e = tx.Commit()
+	}
+
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+	}
+	return e
+}
+
+func (o *DBStoreSessionRegistry) IsOpen(id string) bool {
+	db, e := o.connect()
+	if e != nil {
+		return false
+	}
+
+	var count uint64
+	e = sqlf.From("registry_store_sessions").
+		Select("COUNT(*)").To(&count).
+		Where("id = ? and expires >= ?", id, time.Now().Unix()).
+		QueryRowAndClose(context.TODO(), db)
+
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return false
+	}
+	return count > 0
+}
+
+func (o *DBStoreSessionRegistry) Close(id string) bool {
+	return o.closeWhere("id = ?", id) > 0
+}
+
+func (o *DBStoreSessionRegistry) CloseUser(user uint64) int {
+	return o.closeWhere("id_user = ?", user)
+}
+
+func (o *DBStoreSessionRegistry) CloseStore(store uint64) int {
+	return o.closeWhere("id_store = ?", store)
+}
+
+func (o *DBStoreSessionRegistry) CloseOrg(org uint64) int {
+	return o.closeWhere("id_org = ?", org)
+}
+
+func (o *DBStoreSessionRegistry) CloseStoreUser(store uint64, user uint64) int {
+	return o.closeWhere("id_store = ? and id_user = ?", store, user)
+}
+
+func (o *DBStoreSessionRegistry) CloseOrgUser(org uint64, user uint64) int {
+	return o.closeWhere("id_org = ? and id_user = ?", org, user)
+}
+
+func (o *DBStoreSessionRegistry) closeWhere(where string, args ...interface{}) int {
+	db, e := o.connect()
+	if e != nil {
+		return 0
+	}
+
+	r, e := sqlf.DeleteFrom("registry_store_sessions").
+		Where(where, args...).
+		ExecAndClose(context.TODO(), db)
+
+	return int(o.affected(r, e))
+}
+
+func (o *DBStoreSessionRegistry) purge(db *sql.DB, now int64) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	// Purged in the Last Minute?
+	if now-o.purged < 60 { // YES: Skip
+		return
+	}
+
+	o.purged = now
+	_, e := sqlf.DeleteFrom("registry_store_sessions").
+		Where("expires < ?", now).
+		ExecAndClose(context.TODO(), db)
+
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+	}
+}
+
+func (o *DBStoreSessionRegistry) connect() (*sql.DB, error) {
+	// NOTE: Registry is Kept in the Global Shard
+	db, e := o.dbm.ConnectTo(0, 0)
+	if e != nil {
+		log.Printf("[DBStoreSessionRegistry] Connection Error: %v\n", e)
+	}
+	return db, e
+}
+
+func (o *DBStoreSessionRegistry) affected(r sql.Result, e error) int64 {
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return 0
+	}
+
+	c, e := r.RowsAffected()
+	if e != nil {
+		log.Printf("query error: %v\n", e)
+		return 0
+	}
+	return c
+}
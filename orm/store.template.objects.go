@@ -11,17 +11,40 @@ package orm
  */
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"strings"
 )
 
+/* NOTE: Object Cipher Envelope (Version 1)
+ * [0]         Envelope Version
+ * [1:5]       Key ID (First 4 Bytes of SHA-256 of the Store Key)
+ * [5]         Template Name Length (N)
+ * [6:6+N]     Template Name
+ * [6+N:]      NONCE + CIPHERTEXT
+ *
+ * The envelope header, the store ID and the object ID are passed to AES-GCM
+ * as additional data, so an encrypted object can't be moved to another
+ * object, store or template without failing authentication.
+ * Objects encrypted before the envelope existed (NONCE + CIPHERTEXT, no
+ * additional data) are still readable, and are upgraded the next time they
+ * are read, written or the store key is rotated (see IsLegacyCipher).
+ * Once every object in a store is in an envelope (new stores, or after a key
+ * rotation), the store is flagged, and legacy ciphers are refused
+ * (see DecryptEnvelope).
+ */
+const OBJECT_ENVELOPE_V1 = 0x01
+
 // Templated Object for Storage
 type StoreTemplateObject struct {
 	template string                 // TEMPLATE: Name
 	version  uint16                 // TEMPLATE: Version
 	title    string                 // OBJECT Title
 	values   map[string]interface{} // OBJECT TEMPLATED Values
+	legacy   bool                   // Decrypted from Legacy (Pre-Envelope) Cipher?
 }
 
 func (o *StoreTemplateObject) isValid() bool {
@@ -115,7 +138,16 @@ func (o *StoreTemplateObject) FromString(s string) error {
 	return nil
 }
 
-func (o *StoreTemplateObject) EncryptObject(key []byte) ([]byte, error) {
+func (o *StoreTemplateObject) IsLegacyCipher() bool {
+	return o.legacy
+}
+
+func (o *StoreTemplateObject) EncryptObject(key []byte, store uint32, id uint32) ([]byte, error) {
+	// Validate Incoming Parameters
+	if store == 0 || id == 0 {
+		return nil, errors.New("Missing Object Store or ID")
+	}
+
 	// Convert Object to JSON String
 	json, e := o.ToString()
 	if e != nil {
@@ -126,20 +158,39 @@ func (o *StoreTemplateObject) EncryptObject(key []byte) ([]byte, error) {
 		return nil, errors.New("Object too big")
 	}
 
+	// Create Envelope Header
+	header, e := objectEnvelopeHeader(key, o.template)
+	if e != nil {
+		return nil, e
+	}
+
 	// Encrypt the JSON String
-	encrypted, e := toCypherBytes(key, []byte(json))
+	encrypted, e := gcmEncryptAAD(key, []byte(json), objectEnvelopeAAD(header, store, id))
 	if e != nil {
 		return nil, e
 	}
 
+	encrypted = append(header, encrypted...)
 	if len(encrypted) > 65535 {
 		return nil, errors.New("Encrypted Object too big")
 	}
 
+	// Object Cipher is Current
+	o.legacy = false
 	return encrypted, nil
 }
 
-func (o *StoreTemplateObject) DecryptObject(key []byte, cbs []byte) error {
+// DecryptObject Decrypt Object Cipher (Envelope or Legacy)
+func (o *StoreTemplateObject) DecryptObject(key []byte, store uint32, id uint32, cbs []byte) error {
+	return o.decryptObject(key, store, id, cbs, true)
+}
+
+// DecryptEnvelope Decrypt Object Cipher (Envelope Only: Store has been Upgraded)
+func (o *StoreTemplateObject) DecryptEnvelope(key []byte, store uint32, id uint32, cbs []byte) error {
+	return o.decryptObject(key, store, id, cbs, false)
+}
+
+func (o *StoreTemplateObject) decryptObject(key []byte, store uint32, id uint32, cbs []byte, legacy bool) error {
 	// Validate Incoming Parameters
 	if len(key) == 0 {
 		return errors.New("Missing Decryption Key")
@@ -153,13 +204,84 @@ func (o *StoreTemplateObject) DecryptObject(key []byte, cbs []byte) error {
 		return errors.New("Encrypted Object too big")
 	}
 
-	// Decrypted Bytes is JSON String
+	// Is Object in an Envelope?
+	header, template := objectEnvelopeSplit(cbs)
+	if header != nil { // MAYBE: Try to Open Envelope
+		decrypted, e := gcmDecryptAAD(key, cbs[len(header):], objectEnvelopeAAD(header, store, id))
+		if e == nil {
+			e = o.FromString(string(decrypted))
+			if e != nil {
+				return e
+			}
+
+			// Does the Object Match the Envelope's Template?
+			if o.template != template { // NO: Reject
+				o.reset()
+				return errors.New("Object Template does not Match Envelope")
+			}
+
+			return nil
+		}
+	}
+
+	// Can the Object be a Legacy Cipher?
+	if !legacy { // NO
+		return errors.New("Invalid Object Envelope")
+	}
+
+	// Legacy Cipher (NONCE + CIPHERTEXT) : Decrypted Bytes is JSON String
 	decrypted, e := toPlainBytes(key, cbs)
 	if e != nil {
 		return e
 	}
 
-	return o.FromString(string(decrypted))
+	e = o.FromString(string(decrypted))
+	if e != nil {
+		return e
+	}
+
+	o.legacy = true
+	return nil
+}
+
+func objectKeyID(key []byte) []byte {
+	h := sha256.Sum256(key)
+	return h[:4]
+}
+
+func objectEnvelopeHeader(key []byte, template string) ([]byte, error) {
+	if template == "" || len(template) > 255 {
+		return nil, errors.New("Template Name is Invalid")
+	}
+
+	header := make([]byte, 0, 6+len(template))
+	header = append(header, OBJECT_ENVELOPE_V1)
+	header = append(header, objectKeyID(key)...)
+	header = append(header, byte(len(template)))
+	header = append(header, template...)
+	return header, nil
+}
+
+// objectEnvelopeSplit Returns Envelope Header and Template Name (nil if not a Valid Envelope)
+func objectEnvelopeSplit(cbs []byte) ([]byte, string) {
+	if len(cbs) < 6 || cbs[0] != OBJECT_ENVELOPE_V1 {
+		return nil, ""
+	}
+
+	l := int(cbs[5])
+	if l == 0 || len(cbs) < 6+l {
+		return nil, ""
+	}
+
+	return cbs[:6+l], string(cbs[6 : 6+l])
+}
+
+func objectEnvelopeAAD(header []byte, store uint32, id uint32) []byte {
+	var b bytes.Buffer
+	b.Write(header)
+	binary.Write(&b, binary.BigEndian, store)
+	binary.Write(&b, binary.BigEndian, id)
+	return b.Bytes()
 }
 
 func (o *StoreTemplateObject) MarshalJSON() ([]byte, error) {
@@ -198,25 +320,28 @@ func (o *StoreTemplateObject) UnmarshalJSON(b []byte) error {
 	}
 
 	// Typed Alias
-	m := i.(map[string]interface{})
+	m, ok := i.(map[string]interface{})
+	if !ok {
+		return errors.New("JSON is not an Object")
+	}
 
 	// Extract Template Information
-	t := m["template"]
-	if t == nil {
+	t, ok := m["template"].(map[string]interface{})
+	if !ok {
 		return errors.New("JSON Missing 'template' structure")
 	}
 
-	e = o.extractTemplate(t.(map[string]interface{}))
+	e = o.extractTemplate(t)
 	if e != nil {
 		return e
 	}
 
 	// Extract Values
-	v := m["values"]
-	if v == nil {
+	v, ok := m["values"].(map[string]interface{})
+	if !ok {
 		return errors.New("JSON Missing 'values' structure")
 	}
-	o.values = v.(map[string]interface{})
+	o.values = v
 
 	// Extract Header Properties
 	e = o.extractHeaderProps()
@@ -286,4 +411,5 @@ func (o *StoreTemplateObject) reset() {
 	o.version = 0
 	o.title = ""
 	o.values = nil
+	o.legacy = false
 }
@@ -0,0 +1,201 @@
+// cSpell:ignore paulo ferreira
+package orm
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/api-services/orm/keygen"
+)
+
+func testTemplateObject(t *testing.T, template string) *StoreTemplateObject {
+	o := &StoreTemplateObject{}
+	if _, e := o.SetTemplate(template); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := o.SetVersion(1); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := o.SetTitle("Test Object"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := o.SetValues(map[string]interface{}{"__title": "Test Object", "notes": "secret"}); e != nil {
+		t.Fatal(e)
+	}
+	return o
+}
+
+func testObjectKey(t *testing.T) []byte {
+	key, e := keygen.Key()
+	if e != nil {
+		t.Fatal(e)
+	}
+	return key
+}
+
+func TestObjectEnvelope(t *testing.T) {
+	key := testObjectKey(t)
+	cbs, e := testTemplateObject(t, "note").EncryptObject(key, 1, 2)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	header, template := objectEnvelopeSplit(cbs)
+	if header == nil || template != "note" {
+		t.Fatalf("Envelope Template [%s], Expected [note]", template)
+	}
+
+	for _, decrypt := range []string{"DecryptObject", "DecryptEnvelope"} {
+		o := &StoreTemplateObject{}
+		if decrypt == "DecryptObject" {
+			e = o.DecryptObject(key, 1, 2, cbs)
+		} else {
+			e = o.DecryptEnvelope(key, 1, 2, cbs)
+		}
+		if e != nil {
+			t.Fatalf("%s: %v", decrypt, e)
+		}
+
+		if o.Template() != "note" || o.Title() != "Test Object" || o.values["notes"] != "secret" {
+			t.Fatalf("%s: Decrypted Object does not Match", decrypt)
+		}
+
+		if o.IsLegacyCipher() {
+			t.Fatalf("%s: Envelope should not be a Legacy Cipher", decrypt)
+		}
+	}
+}
+
+func TestObjectEnvelopeAAD(t *testing.T) {
+	key := testObjectKey(t)
+	cbs, e := testTemplateObject(t, "note").EncryptObject(key, 1, 2)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Moved to Another Store or Object
+	for _, target := range [][2]uint32{{3, 2}, {1, 3}} {
+		o := &StoreTemplateObject{}
+		if e := o.DecryptObject(key, target[0], target[1], cbs); e == nil {
+			t.Fatalf("Object Moved to Store %d, ID %d should Fail", target[0], target[1])
+		}
+	}
+
+	// Wrong Key
+	o := &StoreTemplateObject{}
+	if e := o.DecryptObject(testObjectKey(t), 1, 2, cbs); e == nil {
+		t.Fatal("Wrong Key should Fail")
+	}
+
+	// Tampered Envelope Header (Template Name) and Cipher Text
+	for _, i := range []int{6, len(cbs) - 1} {
+		tampered := append([]byte{}, cbs...)
+		tampered[i] ^= 0x01
+		if e := o.DecryptObject(key, 1, 2, tampered); e == nil {
+			t.Fatalf("Envelope Tampered at Byte %d should Fail", i)
+		}
+	}
+}
+
+func TestObjectEnvelopeTemplate(t *testing.T) {
+	key := testObjectKey(t)
+
+	// Envelope Template Name doesn't Match Object (Encrypted with a Valid Header)
+	header, e := objectEnvelopeHeader(key, "note")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	json, e := testTemplateObject(t, "login").ToString()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	encrypted, e := gcmEncryptAAD(key, []byte(json), objectEnvelopeAAD(header, 1, 2))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	o := &StoreTemplateObject{}
+	if e := o.DecryptObject(key, 1, 2, append(header, encrypted...)); e == nil {
+		t.Fatal("Object Template not Matching Envelope should Fail")
+	}
+
+	if o.Template() != "" {
+		t.Fatal("Object should be Reset")
+	}
+}
+
+func TestObjectLegacyCipher(t *testing.T) {
+	key := testObjectKey(t)
+
+	// Legacy Cipher (NONCE + CIPHERTEXT, no Additional Data)
+	json, e := testTemplateObject(t, "note").ToString()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	legacy, e := gcmEncrypt(key, []byte(json))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	o := &StoreTemplateObject{}
+	if e := o.DecryptObject(key, 1, 2, legacy); e != nil {
+		t.Fatal(e)
+	}
+
+	if !o.IsLegacyCipher() || o.Template() != "note" {
+		t.Fatal("Object should be Decrypted from Legacy Cipher")
+	}
+
+	// Upgraded Stores Refuse Legacy Ciphers
+	if e := (&StoreTemplateObject{}).DecryptEnvelope(key, 1, 2, legacy); e == nil {
+		t.Fatal("Legacy Cipher should Fail in an Upgraded Store")
+	}
+
+	// Re-Encrypting Upgrades the Cipher
+	cbs, e := o.EncryptObject(key, 1, 2)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if o.IsLegacyCipher() {
+		t.Fatal("Object Cipher should be Current after Encryption")
+	}
+
+	if e := (&StoreTemplateObject{}).DecryptEnvelope(key, 1, 2, cbs); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestObjectEnvelopeInvalid(t *testing.T) {
+	key := testObjectKey(t)
+	o := testTemplateObject(t, "note")
+
+	if _, e := o.EncryptObject(key, 0, 2); e == nil {
+		t.Fatal("Missing Store should Fail")
+	}
+	if _, e := o.EncryptObject(key, 1, 0); e == nil {
+		t.Fatal("Missing Object ID should Fail")
+	}
+	if _, e := o.EncryptObject(key[:16], 1, 2); e == nil {
+		t.Fatal("Short Key should Fail")
+	}
+
+	if e := (&StoreTemplateObject{}).DecryptObject(nil, 1, 2, []byte{OBJECT_ENVELOPE_V1}); e == nil {
+		t.Fatal("Missing Key should Fail")
+	}
+	if e := (&StoreTemplateObject{}).DecryptObject(key, 1, 2, nil); e == nil {
+		t.Fatal("Missing Cipher should Fail")
+	}
+}
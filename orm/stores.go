@@ -15,31 +15,48 @@ package orm
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/binary"
 	"errors"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/objectvault/api-services/orm/mysql"
+	"github.com/objectvault/common/maps"
 	"github.com/pjacferreira/sqlf"
 )
 
 // Store Object Definition
 type Store struct {
-	dirty          bool       // Is Entry Dirty?
-	stored         bool       // Is Entry Stored in Database
-	updateRegistry bool       // Do we need to Update the Registry?
-	id             *uint32    // LOCAL Store ID
-	org            *uint64    // Global Organization ID store Belongs To
-	alias          string     // Store Alias
-	name           *string    // Store Name (Can be NULL)
-	creator        *uint64    // Global User ID of Creator
-	created        *time.Time // Created TimeStamp
-	modifier       *uint64    // Global User ID of Last Modifier
-	modified       *time.Time // Modification TimeStamp
+	dirty          bool            // Is Entry Dirty?
+	stored         bool            // Is Entry Stored in Database
+	updateRegistry bool            // Do we need to Update the Registry?
+	id             *uint32         // LOCAL Store ID
+	org            *uint64         // Global Organization ID store Belongs To
+	alias          string          // Store Alias
+	name           *string         // Store Name (Can be NULL)
+	escrow         []byte          // Store Key Sealed to Organization Escrow Key (Can be NULL)
+	keycheck       []byte          // Store Key Check Value (Can be NULL)
+	settings       maps.MapWrapper // Store Settings (Database "object" field)
+	creator        *uint64         // Global User ID of Creator
+	created        *time.Time      // Created TimeStamp
+	modifier       *uint64         // Global User ID of Last Modifier
+	modified       *time.Time      // Modification TimeStamp
 }
 
+/* NOTE: Store Key Check Value
+ * The store row keeps a check value (HMAC-SHA256 of a fixed label and the
+ * store ID, keyed with the store key), so that a store key provided from
+ * outside the store's registry (i.e. recovered from escrow) can be verified
+ * without depending on the store having encrypted objects.
+ * The check value does not reveal anything about the key.
+ */
+
+const storeKeyCheckLabel = "objectvault:store:key-check"
+
 func StoreMarkDeleted(db *sql.DB, user uint64, store uint32) (bool, error) {
 	// Create SQL Statement
 	s := sqlf.Update("stores").
@@ -108,7 +125,7 @@ func StoreGetLocalID(db sqlf.Executor, org uint64, alias string) (uint32, error)
 
 // IsDirty Have the Object Properties Changed since last Serialization?
 func (o *Store) IsDirty() bool {
-	return o.dirty
+	return o.dirty || o.settings.IsModified()
 }
 
 func (o *Store) UpdateRegistry() bool {
@@ -141,7 +158,6 @@ func (o *Store) ByID(db *sql.DB, id uint32) error {
 	o.reset()
 
 	// Execute Query
-	// TODO Process Database "object" field
 	var name sql.NullString
 	var object sql.NullString
 	var created sql.NullString
@@ -151,6 +167,8 @@ func (o *Store) ByID(db *sql.DB, id uint32) error {
 		Select("id_org").To(&o.org).
 		Select("storename").To(&o.alias).
 		Select("name").To(&name).
+		Select("escrow").To(&o.escrow).
+		Select("keycheck").To(&o.keycheck).
 		Select("object").To(&object).
 		Select("creator").To(&o.creator).
 		Select("created").To(&created).
@@ -181,7 +199,13 @@ func (o *Store) ByID(db *sql.DB, id uint32) error {
 				o.modified = mysql.MySQLTimeStampToGoTime(created.String)
 			}
 		}
-		// TODO Deal with Object
+		if object.Valid {
+			e = o.settings.Import(object.String)
+			if e != nil {
+				log.Printf("store settings: %v\n", e)
+			}
+			o.settings.ClearModified(nil)
+		}
 		o.stored = true
 	}
 
@@ -212,6 +236,8 @@ func (o *Store) ByAlias(db *sql.DB, alias string) error {
 	e := sqlf.From("stores").
 		Select("id").To(o.id).
 		Select("name").To(&name).
+		Select("escrow").To(&o.escrow).
+		Select("keycheck").To(&o.keycheck).
 		Select("creator").To(&creator).
 		Select("object").To(&object).
 		Where("storename = ?", alias).
@@ -229,7 +255,14 @@ func (o *Store) ByAlias(db *sql.DB, alias string) error {
 		if name.Valid {
 			o.name = &name.String
 		}
-		// TODO Deal with Object, Creator/ed, Modifier/de
+		if object.Valid {
+			e = o.settings.Import(object.String)
+			if e != nil {
+				log.Printf("store settings: %v\n", e)
+			}
+			o.settings.ClearModified(nil)
+		}
+		// TODO Deal with Creator/ed, Modifier/de
 		o.stored = true
 	}
 
@@ -265,6 +298,35 @@ func (o *Store) Name() string {
 	return *o.name
 }
 
+// HasEscrow Is the Store Key Sealed to the Organization's Escrow Key?
+func (o *Store) HasEscrow() bool {
+	return len(o.escrow) > 0
+}
+
+// Escrow Store Key Sealed to the Organization's Escrow Key
+func (o *Store) Escrow() []byte {
+	return o.escrow
+}
+
+// HasKeyCheck Can Store Keys be Verified against the Store's Key Check Value?
+func (o *Store) HasKeyCheck() bool {
+	return len(o.keycheck) > 0
+}
+
+// VerifyKey Is key the Current Store Key? (false if the Store has no Key Check Value)
+func (o *Store) VerifyKey(key []byte) bool {
+	if !o.HasKeyCheck() || o.id == nil {
+		return false
+	}
+
+	return hmac.Equal(o.keycheck, storeKeyCheck(*o.id, key))
+}
+
+// Settings Store Settings (i.e. Key Rotation Policy)
+func (o *Store) Settings() *maps.MapWrapper {
+	return &o.settings
+}
+
 func (o *Store) Creator() uint64 {
 	if o.creator == nil {
 		return 0
@@ -365,6 +427,34 @@ func (o *Store) SetName(name string) (string, error) {
 	return current, nil
 }
 
+// SealEscrow Seal Store Key to the Organization's Escrow Public Key
+func (o *Store) SealEscrow(public []byte, key []byte) error {
+	sealed, e := SealToPublicKey(public, key)
+	if e != nil {
+		return e
+	}
+
+	o.escrow = sealed
+	o.dirty = true
+	return nil
+}
+
+// SetKeyCheck Set Key Check Value for (New or Rotated) Store Key
+func (o *Store) SetKeyCheck(key []byte) error {
+	if o.id == nil {
+		return errors.New("Store has no ID")
+	}
+
+	// Is the Check Value Current?
+	if o.VerifyKey(key) { // YES: Nothing to Do
+		return nil
+	}
+
+	o.keycheck = storeKeyCheck(*o.id, key)
+	o.dirty = true
+	return nil
+}
+
 func (o *Store) SetCreator(id uint64) error {
 	// Is Record New?
 	if o.IsNew() { // YES
@@ -403,13 +493,22 @@ func (o *Store) Flush(db sqlf.Executor, force bool) error {
 			return errors.New("Creation User not Set")
 		}
 
-		// Execute Insert
-		_, e = sqlf.InsertInto("stores").
+		// Create SQL Statement
+		s := sqlf.InsertInto("stores").
 			Set("id_org", o.org).
 			Set("storename", o.alias).
 			Set("name", o.name).
-			Set("creator", o.creator).
-			ExecAndClose(context.TODO(), db)
+			Set("escrow", o.escrow).
+			Set("keycheck", o.keycheck).
+			Set("creator", o.creator)
+
+		// Do we have Store Settings?
+		if !o.settings.IsEmpty() { // YES
+			s.Set("object", o.settings.Export())
+		}
+
+		// Execute Insert
+		_, e = s.ExecAndClose(context.TODO(), db)
 
 		// Error Occurred?
 		if e == nil { // NO: Get New Store's ID
@@ -421,7 +520,7 @@ func (o *Store) Flush(db sqlf.Executor, force bool) error {
 			}
 		}
 	} else { // NO: Update
-		if o.hasKey() {
+		if !o.hasKey() {
 			return errors.New("Missing or Invalid Store Key")
 		}
 
@@ -432,6 +531,8 @@ func (o *Store) Flush(db sqlf.Executor, force bool) error {
 		// Create SQL Statement
 		s := sqlf.Update("stores").
 			Set("name", o.name).
+			Set("escrow", o.escrow).
+			Set("keycheck", o.keycheck).
 			Set("modifier", o.modifier).
 			Where("id = ?", o.id)
 
@@ -440,6 +541,15 @@ func (o *Store) Flush(db sqlf.Executor, force bool) error {
 			s.Set("storename", o.alias)
 		}
 
+		// Have Store Settings Changed?
+		if o.settings.IsModified() { // YES
+			if o.settings.IsEmpty() {
+				s.Set("object", nil)
+			} else {
+				s.Set("object", o.settings.Export())
+			}
+		}
+
 		// Execute Statement
 		_, e = s.ExecAndClose(context.TODO(), db)
 	}
@@ -447,6 +557,7 @@ func (o *Store) Flush(db sqlf.Executor, force bool) error {
 	if e == nil {
 		o.stored = true
 		o.dirty = false
+		o.settings.ClearModified(nil)
 	}
 	return e
 }
@@ -460,6 +571,9 @@ func (o *Store) reset() {
 	o.id = nil
 	o.alias = ""
 	o.name = nil
+	o.escrow = nil
+	o.keycheck = nil
+	o.settings.Reset()
 	o.creator = nil
 	o.created = nil
 	o.modifier = nil
@@ -472,3 +586,13 @@ func (o *Store) reset() {
 	o.dirty = false
 	o.updateRegistry = false
 }
+
+func storeKeyCheck(store uint32, key []byte) []byte {
+	id := make([]byte, 4)
+	binary.BigEndian.PutUint32(id, store)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(storeKeyCheckLabel))
+	mac.Write(id)
+	return mac.Sum(nil)
+}
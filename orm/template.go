@@ -164,6 +164,7 @@ func (o *Template) ByNameVersion(db *sql.DB, name string, version uint16) error
 	var description sql.NullString
 	var created sql.NullString
 	e := sqlf.From("templates").
+		Select("id").To(&o.id).
 		Select("title").To(&o.title).
 		Select("description").To(&description).
 		Select("model").To(&o.model).
@@ -131,6 +131,31 @@ func UserObjectsCount(db *sql.DB, user uint64, q query.TQueryConditions) (uint64
 	return count, nil
 }
 
+func UserObjectIDsByType(db *sql.DB, user uint64, ltype uint16) ([]uint64, error) {
+	// Query Results Values
+	var id uint64
+	var ids []uint64
+
+	// Create SQL Statement
+	s := sqlf.From("registry_user_objects").
+		Select("id_object").To(&id).
+		Where("id_user = ? and type = ?", user, ltype).
+		OrderBy("id_object")
+
+	// Execute Query
+	e := s.QueryAndClose(context.TODO(), db, func(row *sql.Rows) {
+		ids = append(ids, id)
+	})
+
+	// Error Occurred?
+	if e != nil && e != sql.ErrNoRows { // YES
+		log.Printf("query error: %v\n", e)
+		return nil, e
+	}
+
+	return ids, nil
+}
+
 // TODO Implement Delete (Both From Within an Entry and Without a Structure)
 func UserObjectsByTypeQuery(db *sql.DB, user uint64, ltype uint16, q query.TQueryConditions, c bool) (query.TQueryResults, error) {
 	var list query.QueryResults = query.QueryResults{}
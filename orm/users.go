@@ -14,16 +14,17 @@ package orm
 // cSpell:ignore cypherbytes, ciphertext, lastpwdchg, lastpwdchange, maxpwddays
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/objectvault/api-services/orm/keygen"
 	"github.com/objectvault/api-services/orm/mysql"
 	"github.com/pjacferreira/sqlf"
 )
@@ -69,6 +70,7 @@ func GetShardUserID(db sqlf.Executor, email string) (uint32, error) {
 
 /* NOTE: User Password has can not be salted as it used as the basis
  * for the decryption keys, and you can't retrieve the unsalted hash
+ * (the validation cipher text key is derived from it, see kdf.go)
  */
 
 // User Profile
@@ -82,6 +84,11 @@ type User struct {
 	email          string     // User Email
 	object         string     // JSON Object String
 	ciphertext     []byte     // COPY: User Cipher Text to Validate Password
+	recovery       []byte     // (OPTIONAL) Account Recovery Public Key (Private Key Held by User)
+	sharepublic    []byte     // (OPTIONAL) Store Sharing Public Key
+	shareprivate   []byte     // (OPTIONAL) Store Sharing Private Key (Wrapped with User Password Key)
+	sharerecovery  []byte     // (OPTIONAL) Store Sharing Private Key Sealed to Account Recovery Key (Kept on Password Reset)
+	shareplain     []byte     // TRANSIENT: Store Sharing Private Key Created on User Creation (Never Saved)
 	expires        *time.Time // Date Time Expires Password
 	lastpwdchange  *time.Time // Date Time of Last Password Change
 	maxpwddays     *uint16
@@ -133,6 +140,8 @@ func (o *User) ByID(db *sql.DB, id uint32) error {
 
 	// Execute Query
 	var ciphertext sql.NullString
+	var recovery sql.NullString
+	var sharepublic, shareprivate, sharerecovery sql.NullString
 	var expires sql.NullString
 	var lastpwdchange sql.NullString
 	var maxpwddays sql.NullInt32
@@ -148,6 +157,10 @@ func (o *User) ByID(db *sql.DB, id uint32) error {
 		Select("email").To(&o.email).
 		Select("object").To(&object).
 		Select("ciphertext").To(&ciphertext).
+		Select("recovery_key").To(&recovery).
+		Select("share_public").To(&sharepublic).
+		Select("share_private").To(&shareprivate).
+		Select("share_recovery").To(&sharerecovery).
 		Select("dt_expires").To(&expires).
 		Select("dt_lastpwdchg").To(&lastpwdchange).
 		Select("maxpwddays").To(&maxpwddays).
@@ -172,6 +185,17 @@ func (o *User) ByID(db *sql.DB, id uint32) error {
 			s := ciphertext.String
 			o.ciphertext = []byte(s)
 		}
+		if recovery.Valid {
+			s := recovery.String
+			o.recovery = []byte(s)
+		}
+		if sharepublic.Valid && shareprivate.Valid {
+			o.sharepublic = []byte(sharepublic.String)
+			o.shareprivate = []byte(shareprivate.String)
+			if sharerecovery.Valid {
+				o.sharerecovery = []byte(sharerecovery.String)
+			}
+		}
 		if expires.Valid {
 			o.expires = mysql.MySQLTimeStampToGoTime(expires.String)
 		}
@@ -212,6 +236,8 @@ func (o *User) ByUserName(db *sql.DB, username string) error {
 	// Execute Query
 	var id uint32
 	var ciphertext sql.NullString
+	var recovery sql.NullString
+	var sharepublic, shareprivate, sharerecovery sql.NullString
 	var expires sql.NullString
 	var lastpwdchange sql.NullString
 	var maxpwddays sql.NullInt32
@@ -227,6 +253,10 @@ func (o *User) ByUserName(db *sql.DB, username string) error {
 		Select("email").To(&o.email).
 		Select("object").To(&object).
 		Select("ciphertext").To(&ciphertext).
+		Select("recovery_key").To(&recovery).
+		Select("share_public").To(&sharepublic).
+		Select("share_private").To(&shareprivate).
+		Select("share_recovery").To(&sharerecovery).
 		Select("dt_expires").To(&expires).
 		Select("dt_lastpwdchg").To(&lastpwdchange).
 		Select("maxpwddays").To(&maxpwddays).
@@ -252,6 +282,17 @@ func (o *User) ByUserName(db *sql.DB, username string) error {
 			s := ciphertext.String
 			o.ciphertext = []byte(s)
 		}
+		if recovery.Valid {
+			s := recovery.String
+			o.recovery = []byte(s)
+		}
+		if sharepublic.Valid && shareprivate.Valid {
+			o.sharepublic = []byte(sharepublic.String)
+			o.shareprivate = []byte(shareprivate.String)
+			if sharerecovery.Valid {
+				o.sharerecovery = []byte(sharerecovery.String)
+			}
+		}
 		if expires.Valid {
 			o.expires = mysql.MySQLTimeStampToGoTime(expires.String)
 		}
@@ -295,6 +336,8 @@ func (o *User) ByEmail(db *sql.DB, email string) error {
 	// Execute Query
 	var id uint32
 	var ciphertext sql.NullString
+	var recovery sql.NullString
+	var sharepublic, shareprivate, sharerecovery sql.NullString
 	var expires sql.NullString
 	var lastpwdchange sql.NullString
 	var maxpwddays sql.NullInt32
@@ -310,6 +353,10 @@ func (o *User) ByEmail(db *sql.DB, email string) error {
 		Select("username").To(&o.username).
 		Select("object").To(&object).
 		Select("ciphertext").To(&ciphertext).
+		Select("recovery_key").To(&recovery).
+		Select("share_public").To(&sharepublic).
+		Select("share_private").To(&shareprivate).
+		Select("share_recovery").To(&sharerecovery).
 		Select("dt_expires").To(&expires).
 		Select("dt_lastpwdchg").To(&lastpwdchange).
 		Select("maxpwddays").To(&maxpwddays).
@@ -335,6 +382,17 @@ func (o *User) ByEmail(db *sql.DB, email string) error {
 			s := ciphertext.String
 			o.ciphertext = []byte(s)
 		}
+		if recovery.Valid {
+			s := recovery.String
+			o.recovery = []byte(s)
+		}
+		if sharepublic.Valid && shareprivate.Valid {
+			o.sharepublic = []byte(sharepublic.String)
+			o.shareprivate = []byte(shareprivate.String)
+			if sharerecovery.Valid {
+				o.sharerecovery = []byte(sharerecovery.String)
+			}
+		}
 		if expires.Valid {
 			o.expires = mysql.MySQLTimeStampToGoTime(expires.String)
 		}
@@ -516,11 +574,17 @@ func (o *User) SetHash(hash string) error {
 	}
 
 	// Did We Generate Cypher Text?
-	ct, e := toCypherBytes(h, pb)
+	ct, e := sealPasswordCheck(h, pb)
 	if e != nil { // NO: ABORT
 		return e
 	}
 
+	// Create Store Sharing Key Pair
+	e = o.createShareKey(NewPasswordKey(h, ct))
+	if e != nil {
+		return e
+	}
+
 	// New State
 	o.ciphertext = ct
 	o.dirty = true
@@ -528,34 +592,52 @@ func (o *User) SetHash(hash string) error {
 	return nil
 }
 
-func (o *User) ResetHash(hash string) error {
+// ResetHash Reset Password Hash (returns true if Store Sharing Key Pair was Kept, using the Account Recovery Key)
+func (o *User) ResetHash(hash string, recovery string) (bool, error) {
 	// Is Password Hash Valid
 	if hash == "" {
-		return errors.New("Missing Password Hash")
+		return false, errors.New("Missing Password Hash")
 	}
 
 	h, e := hex.DecodeString(hash)
 	if e != nil {
-		return e
+		return false, e
 	}
 
 	// Get Semi Random Text Bytes to Encrypt
 	pb, e := o.generatePlainText()
 	if e != nil {
-		return e
+		return false, e
 	}
 
 	// Did We Generate Cypher Text?
-	ct, e := toCypherBytes(h, pb)
+	ct, e := sealPasswordCheck(h, pb)
 	if e != nil { // NO: ABORT
-		return e
+		return false, e
+	}
+
+	// Can the Store Sharing Private Key be Recovered?
+	kept := false
+	k := NewPasswordKey(h, ct)
+	private, e := o.recoverShareKey(recovery)
+	if e == nil { // YES: Keep Key Pair (Pending Shares and Rotations are Sealed to it)
+		o.shareprivate, e = k.Wrap(private)
+		kept = e == nil
+	}
+
+	// Replace Store Sharing Key Pair (Private Key can't be Decrypted without Previous Hash)
+	if !kept {
+		e = o.createShareKey(k)
+		if e != nil {
+			return false, e
+		}
 	}
 
 	// New State
 	o.ciphertext = ct
 	o.dirty = true
 	o.updateRegistry = true
-	return nil
+	return kept, nil
 }
 
 func (o *User) UpdateHash(old, hash string) error {
@@ -581,24 +663,260 @@ func (o *User) UpdateHash(old, hash string) error {
 
 	// Decrypt Existing CipherText //
 	// Decrypt Bytes
-	pb, e := toPlainBytes(ho, o.ciphertext)
+	pb, e := openPasswordCheck(ho, o.ciphertext)
 	if e != nil {
 		return e
 	}
 
 	// Re-encrypt using Hash Hash
-	cb, e := toCypherBytes(hn, pb)
+	cb, e := sealPasswordCheck(hn, pb)
 	if e != nil { // NO: ABORT
 		return e
 	}
 
+	// Re-encrypt Store Sharing Private Key
+	if o.HasShareKey() {
+		private, e := NewPasswordKey(ho, o.ciphertext).Unwrap(o.shareprivate)
+		if e != nil {
+			return e
+		}
+
+		o.shareprivate, e = NewPasswordKey(hn, cb).Wrap(private)
+		if e != nil {
+			return e
+		}
+	}
+
+	// New State
+	o.ciphertext = cb
+	o.dirty = true
+	o.updateRegistry = true
+	return nil
+}
+
+// UpgradeHash Re-encrypt Cipher Text and Share Private Key with Current KDF Parameters, and Seal Share Private Key to Recovery Key (returns true if Modified)
+func (o *User) UpgradeHash(hash string) (bool, error) {
+	// Do we have a Cipher Text?
+	if len(o.ciphertext) == 0 { // NO: Nothing to Do
+		return false, nil
+	}
+
+	h, e := hex.DecodeString(hash)
+	if e != nil {
+		return false, e
+	}
+
+	// Is Cipher Text Current?
+	cb := o.ciphertext
+	if !isPasswordCheckCurrent(cb) { // NO: Re-encrypt with Current Parameters
+		// Decrypt Existing CipherText
+		pb, e := openPasswordCheck(h, o.ciphertext)
+		if e != nil {
+			return false, e
+		}
+
+		cb, e = sealPasswordCheck(h, pb)
+		if e != nil {
+			return false, e
+		}
+	}
+
+	// Is Share Private Key Wrapped with Current Cipher Text's KDF?
+	sp := o.shareprivate
+	sr := o.sharerecovery
+	k := NewPasswordKey(h, cb)
+	if o.HasShareKey() && (!k.IsCurrent(sp) || (o.HasRecoveryKey() && len(sr) == 0)) { // NO (or not Sealed to Recovery Key)
+		private, e := NewPasswordKey(h, o.ciphertext).Unwrap(sp)
+		if e != nil {
+			return false, e
+		}
+
+		if !k.IsCurrent(sp) {
+			sp, e = k.Wrap(private)
+			if e != nil {
+				return false, e
+			}
+		}
+
+		if o.HasRecoveryKey() && len(sr) == 0 {
+			sr, e = SealToPublicKey(o.recovery, private)
+			if e != nil {
+				return false, e
+			}
+		}
+	}
+
+	// Was Anything Modified?
+	if bytes.Equal(cb, o.ciphertext) && bytes.Equal(sp, o.shareprivate) && bytes.Equal(sr, o.sharerecovery) { // NO
+		return false, nil
+	}
+
 	// New State
 	o.ciphertext = cb
+	o.shareprivate = sp
+	o.sharerecovery = sr
+	o.dirty = true
+	o.updateRegistry = true
+	return true, nil
+}
+
+// HasShareKey Does the User have a Store Sharing Key Pair?
+func (o *User) HasShareKey() bool {
+	return len(o.sharepublic) > 0 && len(o.shareprivate) > 0
+}
+
+// ShareKey Store Sharing Public Key
+func (o *User) ShareKey() []byte {
+	return o.sharepublic
+}
+
+// ShareKeyPrivate Store Sharing Private Key (Decrypted with User Password Hash)
+func (o *User) ShareKeyPrivate(hash string) ([]byte, error) {
+	if !o.HasShareKey() {
+		return nil, errors.New("User has no Share Key")
+	}
+
+	h, e := hex.DecodeString(hash)
+	if e != nil {
+		return nil, e
+	}
+
+	return NewPasswordKey(h, o.ciphertext).Unwrap(o.shareprivate)
+}
+
+// CreateShareKey Create Store Sharing Key Pair for Existing User (if it doesn't have one)
+func (o *User) CreateShareKey(hash string) (bool, error) {
+	if o.HasShareKey() {
+		return false, nil
+	}
+
+	h, e := hex.DecodeString(hash)
+	if e != nil {
+		return false, e
+	}
+
+	e = o.createShareKey(NewPasswordKey(h, o.ciphertext))
+	if e != nil {
+		return false, e
+	}
+
 	o.dirty = true
 	o.updateRegistry = true
+	return true, nil
+}
+
+// createShareKey Create Key Pair, with Private Key Wrapped with Key Derived from User Password Hash
+func (o *User) createShareKey(k *PasswordKey) error {
+	public, private, e := NewX25519KeyPair()
+	if e != nil {
+		return e
+	}
+
+	cb, e := k.Wrap(private)
+	if e != nil {
+		return e
+	}
+
+	// Seal Private Key to Account Recovery Key (Allows Keeping it on Password Reset)
+	var sr []byte
+	if o.HasRecoveryKey() {
+		sr, e = SealToPublicKey(o.recovery, private)
+		if e != nil {
+			return e
+		}
+	}
+
+	o.sharepublic = public
+	o.shareprivate = cb
+	o.sharerecovery = sr
+	if o.IsNew() { // Allow Recovery Key Created after Password to Seal Private Key
+		o.shareplain = private
+	}
 	return nil
 }
 
+// HasShareRecovery Can the Store Sharing Private Key be Recovered with the Account Recovery Key?
+func (o *User) HasShareRecovery() bool {
+	return o.HasShareKey() && o.HasRecoveryKey() && len(o.sharerecovery) > 0
+}
+
+// recoverShareKey Open Store Sharing Private Key with HEX Account Recovery Private Key
+func (o *User) recoverShareKey(recovery string) ([]byte, error) {
+	if !o.HasShareRecovery() || recovery == "" {
+		return nil, errors.New("Share Key can't be Recovered")
+	}
+
+	key, e := hex.DecodeString(recovery)
+	if e != nil {
+		return nil, e
+	}
+
+	private, e := OpenSealed(key, o.sharerecovery)
+	if e != nil {
+		return nil, e
+	}
+
+	// Is it the Private Key for the Share Public Key?
+	if !IsX25519KeyPair(o.sharepublic, private) { // NO
+		return nil, errors.New("Share Key can't be Recovered")
+	}
+
+	return private, nil
+}
+
+// HasRecoveryKey Does the User have an Account Recovery Key?
+func (o *User) HasRecoveryKey() bool {
+	return len(o.recovery) > 0
+}
+
+// RecoveryKey Account Recovery Public Key
+func (o *User) RecoveryKey() []byte {
+	return o.recovery
+}
+
+// CreateRecoveryKey Create Account Recovery Key (returns HEX Private Key, which is NOT Stored)
+func (o *User) CreateRecoveryKey() (string, error) {
+	// Are we Creating a User?
+	if !o.IsNew() { // NO: Recovery Key can only be created with the User
+		return "", errors.New("Recovery Key has to be set on User Creation")
+	}
+
+	public, private, e := NewX25519KeyPair()
+	if e != nil {
+		return "", e
+	}
+
+	// Was Store Sharing Key Pair Created with User?
+	if len(o.shareplain) > 0 { // YES: Seal Private Key to Recovery Key
+		o.sharerecovery, e = SealToPublicKey(public, o.shareplain)
+		if e != nil {
+			return "", e
+		}
+	}
+
+	// New State
+	o.recovery = public
+	o.dirty = true
+	o.updateRegistry = true
+	return hex.EncodeToString(private), nil
+}
+
+// TestRecoveryKey Is HEX Private Key the User's Account Recovery Key?
+func (o *User) TestRecoveryKey(key string) bool {
+	// Does User have a Recovery Key?
+	if !o.HasRecoveryKey() { // NO: Never Match
+		return false
+	}
+
+	// Is Hex String?
+	k, e := hex.DecodeString(key)
+	if e != nil { // NO: Fail
+		return false
+	}
+
+	return IsX25519KeyPair(o.recovery, k)
+}
+
 func (o *User) TestPassword(password string) bool {
 	// Convert USer Password to HASH
 	hasher := sha256.Sum256([]byte(password))
@@ -648,13 +966,24 @@ func (o *User) Flush(db sqlf.Executor, force bool) error {
 		}
 
 		// CREATE USER RECORD: Execute Insert
-		_, e := sqlf.InsertInto("users").
+		s := sqlf.InsertInto("users").
 			Set("name", o.name).
 			Set("username", o.username).
 			Set("email", o.email).
 			Set("ciphertext", o.ciphertext).
-			Set("creator", o.creator).
-			ExecAndClose(context.TODO(), db)
+			Set("creator", o.creator)
+
+		if len(o.recovery) > 0 {
+			s.Set("recovery_key", o.recovery)
+		}
+
+		if o.HasShareKey() {
+			s.Set("share_public", o.sharepublic)
+			s.Set("share_private", o.shareprivate)
+			s.Set("share_recovery", o.sharerecovery)
+		}
+
+		_, e = s.ExecAndClose(context.TODO(), db)
 
 		// Error Occurred?
 		if e == nil { // NO: Get New User's ID
@@ -685,6 +1014,16 @@ func (o *User) Flush(db sqlf.Executor, force bool) error {
 			s.Set("ciphertext", o.ciphertext)
 		}
 
+		if len(o.recovery) > 0 {
+			s.Set("recovery_key", o.recovery)
+		}
+
+		if o.HasShareKey() {
+			s.Set("share_public", o.sharepublic)
+			s.Set("share_private", o.shareprivate)
+			s.Set("share_recovery", o.sharerecovery)
+		}
+
 		_, e = s.ExecAndClose(context.TODO(), db)
 	}
 
@@ -700,13 +1039,8 @@ func (o *User) generatePlainText() ([]byte, error) {
 		return nil, errors.New("Not Ready")
 	}
 
-	// Generate a Pseudo Random String to Hash
-	rs := RandomAlphaNumericPunctuationString(128) // Random String to make things harder
-	hashString := fmt.Sprintf("%s:%s:%s", o.username, rs, o.email)
-	hash := sha256.Sum256([]byte(hashString))
-
-	// Create Plain Text
-	return hash[:], nil
+	// Cryptographically Secure Random Plain Text
+	return keygen.Key()
 }
 
 func (o *User) testHash(hash []byte) bool {
@@ -721,7 +1055,7 @@ func (o *User) testHash(hash []byte) bool {
 	}
 
 	// Does HASH Decode Cypher Text?
-	_, e := toPlainBytes(hash, o.ciphertext)
+	_, e := openPasswordCheck(hash, o.ciphertext)
 	return e == nil
 }
 
@@ -733,6 +1067,11 @@ func (o *User) reset() {
 	o.email = ""
 	o.object = ""
 	o.ciphertext = nil
+	o.recovery = nil
+	o.sharepublic = nil
+	o.shareprivate = nil
+	o.sharerecovery = nil
+	o.shareplain = nil
 	o.expires = nil
 	o.lastpwdchange = nil
 	o.maxpwddays = nil
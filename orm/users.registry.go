@@ -37,6 +37,8 @@ type UserRegistry struct {
 	name       string  // User Long Name
 	state      uint16  // Global User State
 	ciphertext []byte  // User Cipher Text to Validate Password
+	recovery   []byte  // (OPTIONAL) Account Recovery Public Key
+	share      []byte  // (OPTIONAL) Store Sharing Public Key
 }
 
 func UserRegistryFromUser(u *User) (*UserRegistry, error) {
@@ -206,13 +208,15 @@ func (o *UserRegistry) ByID(db *sql.DB, id uint64) error {
 	o.reset()
 
 	// Execute Query
-	var ciphertext sql.NullString
+	var ciphertext, recovery, share sql.NullString
 	e := sqlf.From("registry_users").
 		Select("name").To(&o.name).
 		Select("username").To(&o.username).
 		Select("email").To(&o.email).
 		Select("state").To(&o.state).
 		Select("ciphertext").To(&ciphertext).
+		Select("recovery_key").To(&recovery).
+		Select("share_public").To(&share).
 		Where("id_user = ?", id).
 		QueryRowAndClose(context.TODO(), db)
 
@@ -229,6 +233,13 @@ func (o *UserRegistry) ByID(db *sql.DB, id uint64) error {
 			s := ciphertext.String
 			o.ciphertext = []byte(s)
 		}
+		if recovery.Valid {
+			s := recovery.String
+			o.recovery = []byte(s)
+		}
+		if share.Valid {
+			o.share = []byte(share.String)
+		}
 		o.stored = true
 	}
 
@@ -256,13 +267,15 @@ func (o *UserRegistry) ByUserName(db *sql.DB, username string) error {
 
 	// Execute Query
 	var id uint64
-	var ciphertext sql.NullString
+	var ciphertext, recovery, share sql.NullString
 	e := sqlf.From("registry_users").
 		Select("id_user").To(&id).
 		Select("name").To(&o.name).
 		Select("email").To(&o.email).
 		Select("state").To(&o.state).
 		Select("ciphertext").To(&ciphertext).
+		Select("recovery_key").To(&recovery).
+		Select("share_public").To(&share).
 		Where("username = ?", username).
 		QueryRowAndClose(context.TODO(), db)
 
@@ -281,6 +294,13 @@ func (o *UserRegistry) ByUserName(db *sql.DB, username string) error {
 			s := ciphertext.String
 			o.ciphertext = []byte(s)
 		}
+		if recovery.Valid {
+			s := recovery.String
+			o.recovery = []byte(s)
+		}
+		if share.Valid {
+			o.share = []byte(share.String)
+		}
 
 		o.stored = true
 	}
@@ -303,13 +323,15 @@ func (o *UserRegistry) ByEmail(db *sql.DB, email string) error {
 
 	// Execute Query
 	var id uint64
-	var ciphertext sql.NullString
+	var ciphertext, recovery, share sql.NullString
 	e := sqlf.From("registry_users").
 		Select("id_user").To(&id).
 		Select("name").To(&o.name).
 		Select("username").To(&o.username).
 		Select("state").To(&o.state).
 		Select("ciphertext").To(&ciphertext).
+		Select("recovery_key").To(&recovery).
+		Select("share_public").To(&share).
 		Where("email = ?", email).
 		QueryRowAndClose(context.TODO(), db)
 
@@ -322,6 +344,13 @@ func (o *UserRegistry) ByEmail(db *sql.DB, email string) error {
 			s := ciphertext.String
 			o.ciphertext = []byte(s)
 		}
+		if recovery.Valid {
+			s := recovery.String
+			o.recovery = []byte(s)
+		}
+		if share.Valid {
+			o.share = []byte(share.String)
+		}
 
 		o.stored = true
 	}
@@ -377,6 +406,26 @@ func (o *UserRegistry) IsReadOnly() bool {
 	return HasAllStates(o.state, STATE_READONLY)
 }
 
+// HasRecoveryKey Does the User have an Account Recovery Key?
+func (o *UserRegistry) HasRecoveryKey() bool {
+	return len(o.recovery) > 0
+}
+
+// RecoveryKey Account Recovery Public Key
+func (o *UserRegistry) RecoveryKey() []byte {
+	return o.recovery
+}
+
+// HasShareKey Does the User have a Store Sharing Key Pair?
+func (o *UserRegistry) HasShareKey() bool {
+	return len(o.share) > 0
+}
+
+// ShareKey Store Sharing Public Key
+func (o *UserRegistry) ShareKey() []byte {
+	return o.share
+}
+
 func (o *UserRegistry) SetID(id uint64) (uint64, error) {
 	if o.IsNew() {
 		// Current State
@@ -473,6 +522,8 @@ func (o *UserRegistry) UpdateRegistry(u *User) error {
 	o.email = u.Email()
 	o.name = u.Name()
 	o.ciphertext = u.ciphertext
+	o.recovery = u.recovery
+	o.share = u.sharepublic
 
 	if !o.IsNew() {
 		o.dirty = true
@@ -482,8 +533,9 @@ func (o *UserRegistry) UpdateRegistry(u *User) error {
 }
 
 func (o *UserRegistry) UpdatePassword(u *User) error {
-	// Update User Password Hash
+	// Update User Password Hash (and Store Sharing Key, Replaced on Password Reset)
 	o.ciphertext = u.ciphertext
+	o.share = u.sharepublic
 
 	if !o.IsNew() {
 		o.dirty = true
@@ -492,6 +544,21 @@ func (o *UserRegistry) UpdatePassword(u *User) error {
 	return nil
 }
 
+// PasswordKey Key Wrapping Key for User's Password Hash (Store Keys)
+func (o *UserRegistry) PasswordKey(hash []byte) *PasswordKey {
+	return NewPasswordKey(hash, o.ciphertext)
+}
+
+// PasswordKeyHex Key Wrapping Key for User's HEX Password Hash (Store Keys)
+func (o *UserRegistry) PasswordKeyHex(hash string) (*PasswordKey, error) {
+	return NewPasswordKeyHex(hash, o.ciphertext)
+}
+
+// IsHashCurrent Is Password Cipher Text using the Current KDF Parameters?
+func (o *UserRegistry) IsHashCurrent() bool {
+	return isPasswordCheckCurrent(o.ciphertext)
+}
+
 func (o *UserRegistry) TestPassword(password string) bool {
 	// Convert USer Password to HASH
 	hasher := sha256.Sum256([]byte(password))
@@ -548,6 +615,14 @@ func (o *UserRegistry) Flush(db sqlf.Executor, force bool) error {
 			Set("state", o.state).
 			Set("ciphertext", o.ciphertext)
 
+		if len(o.recovery) > 0 {
+			s.Set("recovery_key", o.recovery)
+		}
+
+		if len(o.share) > 0 {
+			s.Set("share_public", o.share)
+		}
+
 		_, e = s.ExecAndClose(context.TODO(), db)
 	} else { // NO: Update
 		// TODO: Create Special Update to Change User Password
@@ -561,6 +636,14 @@ func (o *UserRegistry) Flush(db sqlf.Executor, force bool) error {
 			s.Set("ciphertext", o.ciphertext)
 		}
 
+		if len(o.recovery) > 0 {
+			s.Set("recovery_key", o.recovery)
+		}
+
+		if len(o.share) > 0 {
+			s.Set("share_public", o.share)
+		}
+
 		_, e = s.
 			Where("id_user = ?", o.id).
 			ExecAndClose(context.TODO(), db)
@@ -585,7 +668,7 @@ func (o *UserRegistry) testHash(hash []byte) bool {
 	}
 
 	// Does HASH Decode Cypher Text?
-	_, e := toPlainBytes(hash, o.ciphertext)
+	_, e := openPasswordCheck(hash, o.ciphertext)
 	return e == nil
 }
 
@@ -597,6 +680,8 @@ func (o *UserRegistry) reset() {
 	o.name = ""
 	o.state = 0
 	o.ciphertext = nil
+	o.recovery = nil
+	o.share = nil
 
 	// Mark State as Unregistered
 	o.stored = false
@@ -15,10 +15,11 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	cryptorand "crypto/rand"
+	"encoding/hex"
 	"errors"
 	"io"
-	"math"
-	"math/rand"
+
+	"github.com/objectvault/api-services/orm/keygen"
 )
 
 // GLOBAL HELPERs //
@@ -30,36 +31,13 @@ const apBytes = aBytes + pBytes
 const anpBytes = aBytes + nBytes + pBytes
 
 func randomByteString(set string, length int) string {
-	b := make([]byte, length)
-	bits := int(math.Ceil(math.Log2(float64(len(set))))) // Maximum Number of Bits for Set Index
-	mask := int64(1) << (bits - 1)
-	maxLetters := 63 / bits
-	setLength := len(set)
-
-	for i, cache, remain := length-1, rand.Int63(), maxLetters; i >= 0; {
-		// Exhausted Random Bits?
-		if remain == 0 { // YES: Reload
-			cache, remain = rand.Int63(), maxLetters
-		}
-
-		// Next Set Index
-		idx := int(cache & mask)
-
-		// idx outside of set?
-		if idx > setLength { // YES: Loop back to start of set
-			idx = idx % setLength
-		}
-
-		// Set Next Random Character
-		b[i] = set[idx]
-
-		// Prepare for Next Cycle
-		i--
-		cache >>= bits
-		remain--
+	// Cryptographically Secure Random String
+	s, e := keygen.String(set, length)
+	if e != nil { // ERROR: System Random Source Failed
+		panic(e)
 	}
 
-	return string(b)
+	return s
 }
 
 func RandomAlphaString(n int) string {
@@ -74,7 +52,36 @@ func RandomAlphaNumericPunctuationString(n int) string {
 	return randomByteString(anpBytes, n)
 }
 
+// EncryptWithHash Encrypt Bytes using a (HEX) Password Hash as Key
+func EncryptWithHash(hexHash string, bytes []byte) ([]byte, error) {
+	key, e := hex.DecodeString(hexHash)
+	if e != nil {
+		return nil, e
+	}
+
+	return gcmEncrypt(key, bytes)
+}
+
+// DecryptWithHash Decrypt Bytes using a (HEX) Password Hash as Key
+func DecryptWithHash(hexHash string, cipherbytes []byte) ([]byte, error) {
+	key, e := hex.DecodeString(hexHash)
+	if e != nil {
+		return nil, e
+	}
+
+	return gcmDecrypt(key, cipherbytes)
+}
+
 func gcmDecrypt(key, cipherbytes []byte) ([]byte, error) {
+	return gcmDecryptAAD(key, cipherbytes, nil)
+}
+
+func gcmEncrypt(key []byte, bytes []byte) ([]byte, error) {
+	return gcmEncryptAAD(key, bytes, nil)
+}
+
+// gcmDecryptAAD Decrypt NONCE + CIPHERTEXT, Authenticating Additional Data
+func gcmDecryptAAD(key, cipherbytes []byte, aad []byte) ([]byte, error) {
 	// Create and Initialize Block Cypher //
 	block, e := aes.NewCipher(key)
 	if e != nil {
@@ -89,13 +96,16 @@ func gcmDecrypt(key, cipherbytes []byte) ([]byte, error) {
 
 	// Extract NONCE
 	nonceSize := aesGCM.NonceSize()
+	if len(cipherbytes) < nonceSize+aesGCM.Overhead() {
+		return nil, errors.New("Encrypted Bytes too Short")
+	}
 
 	// Extract the nonce from the encrypted data
 	nonce := cipherbytes[:nonceSize]
 	ciphertext := cipherbytes[nonceSize:]
 
 	// Decrypt the data
-	plainbytes, e := aesGCM.Open(nil, nonce, ciphertext, nil)
+	plainbytes, e := aesGCM.Open(nil, nonce, ciphertext, aad)
 	if e != nil {
 		return nil, e
 	}
@@ -103,7 +113,8 @@ func gcmDecrypt(key, cipherbytes []byte) ([]byte, error) {
 	return plainbytes, nil
 }
 
-func gcmEncrypt(key []byte, bytes []byte) ([]byte, error) {
+// gcmEncryptAAD Encrypt Bytes (returns NONCE + CIPHERTEXT), Authenticating Additional Data
+func gcmEncryptAAD(key []byte, bytes []byte, aad []byte) ([]byte, error) {
 	// NOTE: We use SHA256 HASH because it is 32 bytes long and can be user with AES-256
 	if len(key) != 32 {
 		return nil, errors.New("Encryption KEY not Strong Enough")
@@ -127,6 +138,6 @@ func gcmEncrypt(key []byte, bytes []byte) ([]byte, error) {
 		return nil, e
 	}
 
-	cipherbytes := aesGCM.Seal(nonce, nonce, bytes, nil)
+	cipherbytes := aesGCM.Seal(nonce, nonce, bytes, aad)
 	return cipherbytes, nil
 }
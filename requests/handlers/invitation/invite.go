@@ -137,9 +137,17 @@ func groupAcceptStoreInvite(parent rpf.GINProcessor, u *orm.UserRegistry, i *orm
 		func(r rpf.GINProcessor, c *gin.Context) {
 			inv := r.MustGet("invitation").(*orm.Invitation)
 
+			// Unwrap Key Pick with Server KEK
+			kek, _ := c.MustGet("kek").(orm.KEK)
+			pick, e := inv.KeyPick(kek)
+			if e != nil {
+				r.Abort(5900, nil)
+				return
+			}
+
 			// Extract Key ID and Pick
 			r.SetLocal("key-id", *inv.Key())
-			r.SetLocal("key-pick", inv.KeyPick())
+			r.SetLocal("key-pick", pick)
 		},
 		keys.DBGetKeyByID,
 		keys.KeyExtractBytes,
@@ -156,6 +164,7 @@ func groupAcceptStoreInvite(parent rpf.GINProcessor, u *orm.UserRegistry, i *orm
 		},
 		store.DBRegisterUserWithExistingStore,
 		store.DBRegisterStoreWithUser,
+		store.DBStoreEscrowSeal, // Seal Store Key to Organization Escrow Key
 		// Update Invitation //
 		invitation.DBInvitationAccepted,
 		// Update Session
@@ -241,6 +250,20 @@ func mixinCreateUserFromInvitation(c *rpf.ProcessChain) rpf.ProcessChain {
 				return nil
 			})
 
+			// OPTIONAL: Create Account Recovery Key (DEFAULT: Yes)
+			vmap.Optional("recovery", nil, xjson.F_xToBoolean, true, func(v interface{}) error {
+				if v.(bool) {
+					k, e := u.CreateRecoveryKey()
+					if e != nil {
+						return e
+					}
+
+					// IMPORTANT: Recovery Key is only Shown Once
+					r.SetLocal("recovery-key", k)
+				}
+				return nil
+			})
+
 			// Did we have an Error Processing the Map?
 			if vmap.Error != nil {
 				fmt.Println(vmap.Error)
@@ -283,6 +306,7 @@ func mixinCreateUserFromInvitation(c *rpf.ProcessChain) rpf.ProcessChain {
 		},
 		// RESPONSE //
 		user.ExportUserMe,
+		user.ExportRecoveryKey,
 		session.SaveSession, // Update Session Cookie
 	)
 }
@@ -19,7 +19,6 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 
-	"github.com/objectvault/api-services/common"
 	"github.com/objectvault/api-services/xjson"
 	rpf "github.com/objectvault/goginrpf"
 
@@ -205,14 +204,14 @@ func CreateStoreInvitation(c *gin.Context) {
 			bHash, _ := hex.DecodeString(hash)
 			r.SetLocal("user-credentials", bHash)
 		},
+		store.DBStoreSessionPolicy, // Store Session Mode Required by Store Policy
 		session.SessionStoreOpen,
 		func(r rpf.GINProcessor, c *gin.Context) {
 			// Get Invitation
 			i := r.MustGet("invitation").(*orm.Invitation)
-			ss := r.MustGet("store-session").(*common.StoreSession)
 
-			// Key Information
-			r.SetLocal("key-bytes", ss.Key())
+			// Key Information (NOTE: Store Key is not in the Session in Transaction Mode)
+			r.SetLocal("key-bytes", r.MustGet("store-key"))
 			r.SetLocal("key-expiration", i.Expiration())
 		},
 		keys.DBCreateKey,
@@ -223,8 +222,13 @@ func CreateStoreInvitation(c *gin.Context) {
 			key := r.MustGet("key-id").(uint64)
 			pick := r.MustGet("key-key").([]byte)
 
-			// Set Key Information
-			i.SetKey(key, pick)
+			// Set Key Information (Pick Wrapped with Server KEK)
+			kek, _ := c.MustGet("kek").(orm.KEK)
+			e := i.SetKey(kek, key, pick)
+			if e != nil {
+				r.Abort(5900, nil)
+				return
+			}
 		},
 		// Register Invitation
 		invitation.DBInsertInvitation,
@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/action"
 	"github.com/objectvault/api-services/requests/rpf/object"
 	"github.com/objectvault/api-services/requests/rpf/session"
 	"github.com/objectvault/api-services/requests/rpf/shared"
@@ -156,6 +157,8 @@ func ChangePassword(c *gin.Context) {
 		},
 		user.AssertCredentials, // See if User Password Correct
 		user.DBGetUserByID,     // GET User Object
+		// Create Store Keys Re-Wrap (Carries Over Pending Re-Wraps)
+		action.ActionCreateStoreKeysRewrap,
 		func(r rpf.GINProcessor, c *gin.Context) {
 			// Get Hashes
 			hash := r.MustGet("hash").(string)
@@ -184,7 +187,11 @@ func ChangePassword(c *gin.Context) {
 			}
 		},
 		user.DBUserUpdate,
+		// Register Store Keys Re-Wrap (Only once the New Password is Saved)
+		action.DBRegisterAction,
 		user.DBRegistryUserUpdate,
+		// Re-Wrap Store Keys in Background
+		action.ActionStartStoreKeysRewrap,
 	)
 
 	// Save Session
@@ -507,3 +514,28 @@ func DeleteMeFromStore(c *gin.Context) {
 	// Start Request Processing
 	request.Run()
 }
+
+func DeleteMyOpenStores(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("DELETE.ME.STORES.OPEN", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Validate Session Users Permission
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Is User Session?
+			gSessionUser := session.GroupGetSessionUser(r, true, false)
+			gSessionUser.Run()
+		},
+		// Close all Open Stores (All Clients)
+		session.CloseUserStoreSessions,
+		// Export Results //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			r.SetResponseDataValue("closed", r.MustGet("closed-stores"))
+		},
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
@@ -12,6 +12,7 @@ package org
  */
 
 import (
+	"encoding/hex"
 	"errors"
 	"strings"
 
@@ -159,6 +160,7 @@ func PostCreateStore(c *gin.Context) {
 		},
 		store.DBRegisterUserWithNewStore,
 		store.DBRegisterStoreWithUser,
+		store.DBStoreEscrowSeal, // Seal Store Key to Organization Escrow Key
 		// Request Response //
 		store.ExportStoreFull,
 		session.SaveSession, // Update Session Cookie
@@ -420,6 +422,7 @@ func PutOrgStoreLockState(c *gin.Context) {
 			}
 		},
 		org.DBOrgStoreUpdate,
+		session.RevokeStoreSessions, // Close Open Store Sessions if Locked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-store").(*orm.OrgStoreRegistry)
@@ -514,6 +517,7 @@ func PutOrgStoreBlockState(c *gin.Context) {
 			}
 		},
 		org.DBOrgStoreUpdate,
+		session.RevokeStoreSessions, // Close Open Store Sessions if Blocked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-store").(*orm.OrgStoreRegistry)
@@ -581,6 +585,7 @@ func PutOrgStoreState(c *gin.Context) {
 			registry.SetStates(uint16(states))
 		},
 		org.DBOrgStoreUpdate,
+		session.RevokeStoreSessions, // Close Open Store Sessions if Locked or Blocked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-store").(*orm.OrgStoreRegistry)
@@ -630,3 +635,117 @@ func DeleteOrgStoreState(c *gin.Context) {
 	// Start Request Processing
 	request.Run()
 }
+
+func GetOrgStoreEscrow(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("GET.ORG.STORE.ESCROW", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Required Roles : Organization Store Management with Modify Function
+			roles := []uint32{orm.Role(orm.CATEGORY_ORG|orm.SUBCATEGORY_STORE, orm.FUNCTION_MODIFY)}
+
+			// Initialize Request
+			store.GroupOrgStoreRequestInitialize(r, roles, true, false).
+				Run()
+		},
+		// Get Store
+		store.DBStoreGetByID,
+		store.AssertStoreEscrowed,
+		// Request Response //
+		store.ExportStoreEscrow,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostOrgStoreRecover(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.ORG.STORE.RECOVER", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Required Roles : Organization Store Management with Modify Function
+			roles := []uint32{orm.Role(orm.CATEGORY_ORG|orm.SUBCATEGORY_STORE, orm.FUNCTION_MODIFY)}
+
+			// Initialize Request
+			store.GroupOrgStoreRequestInitialize(r, roles, true, false).
+				Run()
+		},
+		store.AssertStoreNotDeleted,
+		shared.RequestExtractJSON,
+		// Extract Recovered Store Key and User Credentials //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Extract and Validate Post Parameters
+			m := r.MustGet("request-json").(xjson.T_xMap)
+			vmap := xjson.S_xJSONMap{Source: m}
+
+			// Store KEY (Opened Offline with the Organization Escrow Private Key)
+			vmap.Required("key", nil, func(v interface{}) (interface{}, error) {
+				v, e := xjson.F_xToTrimmedString(v)
+				if e != nil {
+					return nil, e
+				}
+
+				s := strings.ToLower(v.(string))
+				if !utils.IsValidKey256(s) {
+					return nil, errors.New("Value does not contain a valid store key")
+				}
+				return s, nil
+			}, func(v interface{}) error {
+				key, _ := hex.DecodeString(v.(string))
+				r.Set("store-key", key)
+				return nil
+			})
+
+			// Session User's Password Hash
+			vmap.Required("credentials", nil, func(v interface{}) (interface{}, error) {
+				v, e := xjson.F_xToTrimmedString(v)
+				if e != nil {
+					return nil, e
+				}
+
+				s := strings.ToLower(v.(string))
+				if !utils.IsValidPasswordHash(s) {
+					return nil, errors.New("Value does not contains a valid password hash")
+				}
+				return s, nil
+			}, func(v interface{}) error {
+				r.Set("hash", v.(string))
+				return nil
+			})
+
+			// Did we have an Error Processing the Map?
+			if vmap.Error != nil {
+				r.Abort(5202, nil)
+				return
+			}
+		},
+		user.DBGetUserByID,
+		user.AssertCredentials,
+		// Verify Recovered Store Key //
+		store.DBStoreGetByID,
+		store.AssertStoreEscrowed,
+		store.AssertStoreKey,
+		// Grant Session User Store Administrator Access //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Set Default ALL Roles
+			roles := []uint32{0x301FFFF, 0x302FFFF, 0x303FFFF, 0x304FFFF, 0x306FFFF, 0x307FFFF, 0x308FFFF}
+			r.SetLocal("register-roles", roles)
+			r.SetLocal("register-as-admin", true)
+		},
+		store.DBRegisterUserWithRecoveredStore,
+		// Request Response //
+		store.ExportStoreFull,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
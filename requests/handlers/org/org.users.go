@@ -402,6 +402,7 @@ func PutOrgUserLock(c *gin.Context) {
 			}
 		},
 		object.DBObjectUserFlush,
+		session.RevokeObjectUserStoreSessions, // Close Open Stores if Locked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
@@ -492,6 +493,7 @@ func PutOrgUserBlock(c *gin.Context) {
 			}
 		},
 		object.DBObjectUserFlush,
+		session.RevokeObjectUserStoreSessions, // Close Open Stores if Blocked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
@@ -571,6 +573,7 @@ func PutOrgUserState(c *gin.Context) {
 			registry.SetStates(uint16(states))
 		},
 		object.DBObjectUserFlush,
+		session.RevokeObjectUserStoreSessions, // Close Open Stores if Locked or Blocked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
@@ -66,6 +66,25 @@ func Reset(c *gin.Context) {
 				return nil
 			})
 
+			// OPTIONAL: Account Recovery Key (Required to Keep Access to Stores)
+			vmap.Optional("recovery", nil, func(v interface{}) (interface{}, error) {
+				v, e := xjson.F_xToTrimmedString(v)
+				if e != nil {
+					return nil, e
+				}
+
+				s := v.(string)
+				if !utils.IsValidRecoveryKey(s) {
+					return nil, errors.New("Value is not a valid recovery key")
+				}
+				return s, nil
+			}, nil, func(v interface{}) error {
+				if v != nil {
+					r.Set("recovery-key", v.(string))
+				}
+				return nil
+			})
+
 			// Did we have an Error Processing the Map?
 			if vmap.Error != nil {
 				fmt.Println(vmap.Error)
@@ -97,6 +116,12 @@ func Reset(c *gin.Context) {
 		user.DBRegistryUserFindByID,
 		user.AssertUserActive,
 		user.DBGetUserByID,
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Was an Account Recovery Key Provided?
+			if r.Has("recovery-key") { // YES: Validate it
+				user.AssertRecoveryKey(r, c)
+			}
+		},
 		func(r rpf.GINProcessor, c *gin.Context) {
 			// Get User Object
 			user := r.MustGet("user").(*orm.User)
@@ -105,12 +130,27 @@ func Reset(c *gin.Context) {
 			// New Password Hash
 			hash := r.MustGet("request-hash").(string)
 
+			// Account Recovery Key (Keeps Store Sharing Key Pair)
+			recovery := ""
+			if r.Has("recovery-key") {
+				recovery = r.MustGet("recovery-key").(string)
+			}
+
 			// Update Hash
-			user.ResetHash(hash)
+			kept, e := user.ResetHash(hash, recovery)
+			if e != nil {
+				fmt.Println(e)
+				r.Abort(5400 /* TODO: ERROR [Failed to Modify Password] */, nil)
+				return
+			}
+
+			r.SetLocal("user-share-kept", kept)
 			userReg.UpdateRegistry(user)
 		},
 		user.DBUserUpdate,
 		user.DBRegistryUserUpdate,
+		// Re-Wrap Recoverable Store Keys (and Report Lost Stores)
+		user.DBUserStoresRecover,
 		func(r rpf.GINProcessor, c *gin.Context) {
 			rr := r.MustGet("registry-request").(*ormrequest.RequestRegistry)
 			rr.SetState(ormrequest.STATE_CLOSED)
@@ -122,6 +162,40 @@ func Reset(c *gin.Context) {
 	request.Run()
 }
 
+// ResetCheck Report Stores that will be Lost (or Recovered) on Password Reset
+func ResetCheck(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("GET.PASSWORD", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Make sure we don't have an active session
+		session.AssertNoUserSession,
+		// Extract Route Parameter 'guid'
+		shared.ExtractGINParameterGUID,
+		/// RETRIEVE REQUEST //
+		pkgrequest.DBGetRegistryRequestByGUID,
+		func(r rpf.GINProcessor, c *gin.Context) {
+			r.Set("request-type", "password:reset")
+		},
+		pkgrequest.AssertRequestRegOfType,
+		pkgrequest.AssertRequestRegActive,
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request - Reference Object (i.e. the requesting user)
+			rr := r.MustGet("registry-request").(*ormrequest.RequestRegistry)
+			r.Set("user-id", rr.Object())
+		},
+		user.DBRegistryUserFindByID,
+		user.AssertUserActive,
+		user.DBGetUserByID,
+		// RESPONSE //
+		user.DBUserStoresRecoverable,
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
 func Recover(c *gin.Context) {
 	// Create Request
 	request := rpf.RootProcessor("DELETE.PASSWORD", c, 1000, shared.JSONResponse)
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/objectvault/api-services/requests/rpf/action"
 	"github.com/objectvault/api-services/requests/rpf/session"
 	"github.com/objectvault/api-services/requests/rpf/shared"
 	"github.com/objectvault/api-services/requests/rpf/user"
@@ -174,9 +175,14 @@ func Login(c *gin.Context) {
 		user.DBRegistryUserFind,  // Get User Registry
 		session.CloseUserSession, // Reset Session if Required
 		// Verify User State //
-		user.AssertUserActive,  // See if the account active
-		user.AssertUserBlocked, // See if Account Blocked by System Admin
-		user.AssertCredentials, // See if User Password Correct
+		user.AssertUserActive,     // See if the account active
+		user.AssertUserBlocked,    // See if Account Blocked by System Admin
+		user.AssertCredentials,    // See if User Password Correct
+		user.DBUserUpgradeHash,    // Upgrade Password Cipher Text to Current KDF
+		user.DBUserCreateShareKey, // Create Store Sharing Key Pair (Existing Users)
+		// Resume Incomplete Store Keys Re-Wrap (Password Change)
+		action.ActionResumeStoreKeysRewrap,
+		action.ActionUpgradeStoreKeys, // Re-Wrap Legacy Store Keys with Password Key
 		// Verify User Password //
 		/*
 			func(r rpf.GINProcessor, c *gin.Context) {
@@ -201,8 +207,9 @@ func Logout(c *gin.Context) {
 
 	// Request Processing Chain
 	request.Chain = rpf.ProcessChain{
-		session.CloseUserSession, // Clear Session Information
-		session.SaveSession,      // Update Session Cookie
+		session.CloseUserStoreSessions, // Close all Open Stores
+		session.CloseUserSession,       // Clear Session Information
+		session.SaveSession,            // Update Session Cookie
 	}
 
 	// Start Request Processing
@@ -0,0 +1,132 @@
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/entry"
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+	"github.com/objectvault/api-services/requests/rpf/store"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetStoreExport(c *gin.Context) {
+	// Create Request (NOTE: Archive is Streamed, JSON Response only on Error)
+	request := rpf.RootProcessor("GET.STORE.EXPORT", c, 1000, shared.StreamResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Read Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_READ_LIST)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Archive Passphrase (Request Header)
+		entry.ExtractHeaderArchivePassphrase,
+		// Archive Size (Export has to be Restorable)
+		entry.AssertStoreArchiveSize,
+		// Extend and Save Store Session (before Archive is Sent) //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+		// Send Archive //
+		entry.StreamStoreArchive,
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostStoreRestore(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.RESTORE", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Conflict Policy (Overwrites Require Update Function)
+		entry.ExtractURLParameterArchiveConflict,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Create Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_CREATE)}
+			if r.MustGet("request-archive-conflict").(string) == entry.ARCHIVE_CONFLICT_OVERWRITE {
+				roles = append(roles, orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE))
+			}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Make sure Parent Exists
+		entry.ExtractGINParameterParentID,
+		func(r rpf.GINProcessor, c *gin.Context) {
+			pid := r.MustGet("request-parent-id").(uint32)
+
+			if pid != 0 {
+				// Create Processing Group
+				group := &rpf.ProcessorGroup{}
+				group.Parent = r
+
+				// Set Object ID to Match
+				group.SetLocal("request-entry-id", pid)
+
+				// See if Object ID Exists and is Folder Object
+				group.Chain = rpf.ProcessChain{
+					entry.DBStoreObjectGetByID,
+					entry.AssertFolderObject,
+				}
+
+				group.Run()
+			}
+		},
+		// Decrypt Archive (Request Body)
+		entry.ExtractHeaderArchivePassphrase,
+		entry.ReadStoreArchive,
+		// Store Policies
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
+		store.DBStoreAttachmentsPolicy,
+		store.DBStoreRevisionPolicy,
+		// Restore Archive
+		entry.DBStoreArchiveRestore,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+		// Export Results //
+		entry.ExportStoreRestoreReport,
+	}
+
+	// Start Request Processing
+	request.Run()
+}
@@ -15,6 +15,7 @@ package store
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/objectvault/api-services/common"
@@ -116,8 +117,7 @@ func IsStoreOpen(c *gin.Context) {
 				return
 			}
 
-			// TODO: Make the Store Open Configurable
-			ss, e := common.ImportStoreSession(key.(string), 5)
+			ss, e := common.ImportStoreSession(key.(string), common.STORE_SESSION_IDLE_DEFAULT)
 			if e != nil {
 				s.Delete(skey)
 				r.Abort(5010 /* TODO: Invalid Session Store Key */, nil)
@@ -126,7 +126,7 @@ func IsStoreOpen(c *gin.Context) {
 
 			rif := rpf.NestedIF(r,
 				func(r rpf.ProcessorIF, c *gin.Context) {
-					if ss.IsExpired() { // NO:
+					if ss.IsExpired() || !common.StoreSessions().IsOpen(ss.ID()) { // NO:
 						r.ContinueFalse()
 					} else {
 						r.ContinueTrue()
@@ -135,8 +135,11 @@ func IsStoreOpen(c *gin.Context) {
 				func(r rpf.ProcessorIF, c *gin.Context) {
 					request.Append(
 						func(r rpf.GINProcessor, c *gin.Context) {
-							// Extend Store Session (BY: 1 Minute). This avoids situations in which the next request might fail if we were close to expiration period
-							ss.Extend(1)
+							// Can the Status Check Extend the Session (Store Policy)?
+							if ss.CanExtendOnCheck() && ss.ExpireUnix() < time.Now().Unix()+60 { // YES
+								// Extend Store Session (BY: 1 Minute). This avoids situations in which the next request might fail if we were close to expiration period
+								ss.Extend(1)
+							}
 
 							// Save Session and Key
 							r.SetLocal("store-session", ss)
@@ -186,11 +189,12 @@ func OpenStore(c *gin.Context) {
 		session.AssertNotSystemAdmin,
 		store.DBStoreUserGet,
 		// REQUEST Validation - POST Parameters //
-		/* TODO: PROBLEM: Currently if User Changes Password
-		 * All of the Stores Keys Have to be unsealed and re-sealed with
-		 * the new password, since it's used as the decryption key for store
-		 * passwords
-		 */
+		// NOTE: Store Keys are re-sealed, in the background, on Password Change
+		store.DBStoreUserAcceptShare,     // Accept Store Key Shared with User
+		store.DBStoreUserApplyPendingKey, // Apply Store Key Rotations
+		store.DBStoreKeyRotateIfPending,  // Rotate Store Key if Requested by Store Policy
+		store.DBStoreKeyCheckIfMissing,   // Key Check Value (Stores Created before it Existed)
+		store.DBStoreSessionPolicy,       // Store Session Mode Required by Store Policy
 		session.SessionStoreOpen,
 		session.SessionStoreSave,
 		func(r rpf.GINProcessor, c *gin.Context) {
@@ -213,19 +217,49 @@ func DeleteCloseStore(c *gin.Context) {
 	request.Chain = rpf.ProcessChain{
 		// Validate Basic Request Settings
 		store.ExtractGINParameterStore,
+		session.SessionStoreClose, // Remove Store Session (and Close it in Registry)
+		session.SaveSession,       // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostRotateStoreKey(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.ROTATE.KEY", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		user.ExtractFormParameterCredentials,
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
 		func(r rpf.GINProcessor, c *gin.Context) {
-			// Get Credentials
-			id := r.MustGet("request-store").(uint64)
-			sid := fmt.Sprintf("_s:%d", id)
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
 
-			// Get Session Store
-			session := sessions.Default(c)
+			// Required Roles : Store Configuration with Update Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_CONF, orm.FUNCTION_UPDATE)}
 
-			// Do we have a Store Key Cached?
-			key := session.Get(sid)
-			if key != nil { // YES: Remove it
-				session.Delete(sid)
-			}
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		session.AssertNotSystemAdmin,
+		store.ExtractURLParameterRemoveUnreachable,
+		// Rotate Store Key //
+		store.DBStoreGetByID,
+		store.DBStoreUserUnlockKey,
+		store.DBStoreKeyRotate,
+		store.DBStoreEscrowSeal, // Re-Seal Rotated Key for Organization
+		session.SessionStoreClose,
+		// Request Response //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			r.SetResponseDataValue("store", fmt.Sprintf(":%x", r.MustGet("request-store").(uint64)))
+			r.SetResponseDataValue("objects", r.MustGet("rotated-objects"))
+			r.SetResponseDataValue("users", r.MustGet("rotated-users"))
+			r.SetResponseDataValue("removed", r.MustGet("rotated-removed"))
 		},
 		session.SaveSession, // Update Session Cookie
 	}
@@ -0,0 +1,141 @@
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/importer"
+	"github.com/objectvault/api-services/requests/rpf/action"
+	"github.com/objectvault/api-services/requests/rpf/entry"
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+	"github.com/objectvault/api-services/requests/rpf/store"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PostStoreImport(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.IMPORT", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Create Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_CREATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Make sure Parent Exists
+		entry.ExtractGINParameterParentID,
+		func(r rpf.GINProcessor, c *gin.Context) {
+			pid := r.MustGet("request-parent-id").(uint32)
+
+			if pid != 0 {
+				// Create Processing Group
+				group := &rpf.ProcessorGroup{}
+				group.Parent = r
+
+				// Set Object ID to Match
+				group.SetLocal("request-entry-id", pid)
+
+				// See if Object ID Exists and is Folder Object
+				group.Chain = rpf.ProcessChain{
+					entry.DBStoreObjectGetByID,
+					entry.AssertFolderObject,
+				}
+
+				group.Run()
+			}
+		},
+		// Parse Import File (Request Body)
+		entry.ExtractURLParameterImportFormat,
+		entry.ReadStoreImportFile,
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
+		// Import Entries (Large Imports are Run in the Background)
+		func(r rpf.GINProcessor, c *gin.Context) {
+			entries := r.MustGet("store-import-entries").([]*importer.Entry)
+
+			// Create Processing Group
+			group := &rpf.ProcessorGroup{}
+			group.Parent = r
+
+			// Can the Client Wait for the Import?
+			if len(entries) <= importer.SyncEntries() { // YES
+				group.Chain = rpf.ProcessChain{
+					entry.DBStoreImport,
+					entry.ExportStoreImportReport,
+				}
+			} else {
+				group.Chain = rpf.ProcessChain{
+					action.ActionCreateStoreImport,
+					action.DBRegisterAction,
+					action.ActionStartStoreImport,
+					action.ExportStoreImportAction,
+				}
+			}
+
+			group.Run()
+		},
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func GetStoreImport(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("GET.STORE.IMPORT", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Create Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_CREATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Extract Route Parameter 'guid'
+		shared.ExtractGINParameterGUID,
+		action.DBGetStoreImportAction,
+		// Export Results //
+		action.ExportStoreImportAction,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
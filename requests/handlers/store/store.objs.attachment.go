@@ -0,0 +1,189 @@
+// cSpell:ignore objs
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/entry"
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+	"github.com/objectvault/api-services/requests/rpf/store"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetStoreObjectAttachment(c *gin.Context) {
+	// Create Request (NOTE: Content is Streamed, JSON Response only on Error)
+	request := rpf.RootProcessor("GET.STORE.OBJ.ATTACHMENT", c, 1000, shared.StreamResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Read Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_READ_LIST)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreObjectGetByID,
+		entry.AssertAttachmentObject,
+		// Verify Content before Sending
+		entry.DBStoreAttachmentGet,
+		entry.AssertStoreAttachmentIntegrity,
+		// Extend and Save Store Session (before Content is Sent) //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+		// Send Content //
+		entry.StreamStoreAttachment,
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func GetStoreObjectAttachmentInfo(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("GET.STORE.OBJ.ATTACHMENT.INFO", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Read Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_READ_LIST)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreObjectGetByID,
+		entry.AssertAttachmentObject,
+		entry.DBStoreAttachmentGet,
+		// Export Results //
+		entry.ExportStoreObjectAttachment,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PutStoreObjectAttachment(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("PUT.STORE.OBJ.ATTACHMENT", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Update Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters (NOTE: Route uses the ':parent' Wildcard of PUT /obj/:parent/:object)
+		entry.ExtractGINParameterParentAsEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreObjectGetByID,
+		entry.AssertAttachmentObject,
+		// Extract Optional Parameters
+		entry.ExtractURLParameterAttachmentName,
+		// Store Content (within Store Quota)
+		store.DBStoreAttachmentsPolicy,
+		entry.DBStoreAttachmentUpload,
+		// Export Results //
+		entry.ExportStoreObjectAttachment,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func DeleteStoreObjectAttachment(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("DELETE.STORE.OBJ.ATTACHMENT", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Update Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreObjectGetByID,
+		entry.AssertAttachmentObject,
+		// Remove Content (Object is Kept)
+		entry.DBStoreAttachmentDelete,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
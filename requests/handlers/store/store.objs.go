@@ -14,6 +14,7 @@ package store
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/objectvault/api-services/common"
@@ -72,10 +73,14 @@ func GetStoreObjects(c *gin.Context) {
 				gQuery.LocalToGlobal("query-conditions")
 			}
 		},
-		// Query System for List //
+		// Query System for List (Sealed Titles are Decrypted if the Store is Open) //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			r.SetLocal("store-parent-id", r.MustGet("request-parent-id"))
 		},
+		entry.ExtractURLParameterTags,
+		store.StoreKeyIfOpen,
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
 		entry.DBStoreObjectsList,
 		// Export Results //
 		entry.ExportStoreObjectList,
@@ -86,17 +91,39 @@ func GetStoreObjects(c *gin.Context) {
 	request.Run()
 }
 
-// TODO IMPLEMENT: MASS Delete (List) of Store Objects
 func DeleteStoreObjects(c *gin.Context) {
 	// Create Request
 	request := rpf.RootProcessor("DELETE.STORE.OBJS", c, 1000, shared.JSONResponse)
 
 	// Request Processing Chain
 	request.Chain = rpf.ProcessChain{
-		/* REQUEST VALIDATION */
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
 		func(r rpf.GINProcessor, c *gin.Context) {
-			r.Abort(5999, nil)
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Delete Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_DELETE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
 		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// PROCESS JSON Object //
+		shared.RequestExtractJSON, // Has to have a JSON Body
+		entry.ExtractJSONEntryIDs,
+		// Move to Trash
+		store.DBStoreTrashPolicy,
+		entry.DBStoreObjectsDelete,
+		entry.ExportStoreObjectIDs,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession,
 	}
 
 	// Start Request Processing
@@ -130,7 +157,16 @@ func GetStoreObject(c *gin.Context) {
 		entry.ExtractGINParameterEntryID,
 		entry.AssertNotRootFolder,
 		entry.DBStoreObjectGetByID,
+		store.DBStoreEnvelopePolicy,
 		entry.DecryptStoreObject,
+		entry.UpgradeStoreObjectCipher, // Legacy (Pre-Envelope) Objects
+		entry.SetStoreObjectETag,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+		// Client has Current Version?
+		shared.AssertModified,
 		// Export Results //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			obj := r.MustGet("store-object").(*orm.StoreObject)
@@ -138,16 +174,12 @@ func GetStoreObject(c *gin.Context) {
 			switch obj.Type() {
 			case orm.OBJECT_TYPE_FOLDER:
 				entry.ExportStoreObjectFolder(r, c)
-			case orm.OBJECT_TYPE_JSON:
+			case orm.OBJECT_TYPE_JSON, orm.OBJECT_TYPE_ATTACHMENT:
 				entry.ExportStoreObjectJSON(r, c)
 			default:
 				r.Abort(4998 /* TODO: Error [Unknown Object Type] */, nil)
 			}
 		},
-		// Extend and Save Store Session //
-		session.ExtendStoreSession,
-		session.SessionStoreSave,
-		session.SaveSession, // Update Session Cookie
 	}
 
 	// Start Request Processing
@@ -188,7 +220,7 @@ func PostStoreObjectJSON(c *gin.Context) {
 				group.Parent = r
 
 				// Set Object ID to Match
-				group.SetLocal("store-object-id", pid)
+				group.SetLocal("request-entry-id", pid)
 
 				// See if Object ID Exists and is Folder Object
 				group.Chain = rpf.ProcessChain{
@@ -229,6 +261,8 @@ func PostStoreObjectJSON(c *gin.Context) {
 				switch v.(string) {
 				case "folder":
 					o.SetType(orm.OBJECT_TYPE_FOLDER)
+				case "attachment":
+					o.SetType(orm.OBJECT_TYPE_ATTACHMENT)
 				default:
 					o.SetType(orm.OBJECT_TYPE_JSON)
 				}
@@ -279,6 +313,9 @@ func PostStoreObjectJSON(c *gin.Context) {
 				if s == "" {
 					return nil, errors.New("Object Missing Title")
 				}
+				if orm.IsSealedTitle(s) {
+					return nil, errors.New("Object Title can't start with '" + orm.TITLE_SEALED_PREFIX + "'")
+				}
 				return s, nil
 			}, func(v interface{}) error {
 				o.SetTitle(v.(string))
@@ -298,7 +335,6 @@ func PostStoreObjectJSON(c *gin.Context) {
 			r.SetLocal("store-object", o)
 			r.SetLocal("store-template-object", t)
 		},
-		entry.EncryptStoreObject,
 		// Set Object
 		func(r rpf.GINProcessor, c *gin.Context) {
 			o := r.MustGet("store-object").(*orm.StoreObject)
@@ -311,7 +347,14 @@ func PostStoreObjectJSON(c *gin.Context) {
 
 			r.SetLocal("store-parent-id", pid)
 		},
-		entry.DBStoreObjectInsert,
+		// Validate Values against Template Model
+		entry.AssertStoreObjectTemplate,
+		entry.AssertStoreObjectTags,
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
+		entry.DBStoreObjectInsertEncrypted,
+		entry.DBStoreObjectTagsIndex,
+		entry.SetStoreObjectETag,
 		// Export Results //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			obj := r.MustGet("store-object").(*orm.StoreObject)
@@ -319,7 +362,7 @@ func PostStoreObjectJSON(c *gin.Context) {
 			switch obj.Type() {
 			case orm.OBJECT_TYPE_FOLDER:
 				entry.ExportStoreObjectFolder(r, c)
-			case orm.OBJECT_TYPE_JSON:
+			case orm.OBJECT_TYPE_JSON, orm.OBJECT_TYPE_ATTACHMENT:
 				entry.ExportStoreObjectJSON(r, c)
 			default:
 				r.Abort(4998 /* TODO: Error [Unknown Object Type] */, nil)
@@ -341,6 +384,9 @@ func PutStoreObjectJSON(c *gin.Context) {
 
 	/* NOTE: Update Requires that all of the object information be resent
 	 * EVEN THE INFORMATION THAT HAS NOT CHANGED
+	 * If the request has an 'If-Match' header (required by store policy), it
+	 * has to match the current object version (ETag), or the request fails
+	 * with the current version of the object.
 	 */
 	// Request Processing Chain
 	request.Chain = rpf.ProcessChain{
@@ -372,7 +418,7 @@ func PutStoreObjectJSON(c *gin.Context) {
 				group.Parent = r
 
 				// Set Object ID to Match
-				group.SetLocal("store-object-id", pid)
+				group.SetLocal("request-entry-id", pid)
 
 				// See if Object ID Exists and is Folder Object
 				group.Chain = rpf.ProcessChain{
@@ -390,7 +436,14 @@ func PutStoreObjectJSON(c *gin.Context) {
 		entry.ExtractGINParameterEntryID,
 		entry.AssertNotRootFolder,
 		entry.DBStoreObjectGetByID,
+		store.DBStoreEnvelopePolicy,
 		entry.DecryptStoreObject,
+		// Assert Client Updates Current Version
+		store.DBStoreIfMatchPolicy,
+		entry.AssertStoreObjectUnmodified,
+		// Keep Current Value as Revision
+		store.DBStoreRevisionPolicy,
+		entry.StoreObjectKeepRevision,
 		// PROCESS JSON Object //
 		shared.RequestExtractJSON, // Has to have a JSON Body
 		func(r rpf.GINProcessor, c *gin.Context) {
@@ -420,6 +473,8 @@ func PutStoreObjectJSON(c *gin.Context) {
 				switch template {
 				case "folder":
 					ot = orm.OBJECT_TYPE_FOLDER
+				case "attachment":
+					ot = orm.OBJECT_TYPE_ATTACHMENT
 				default:
 					ot = orm.OBJECT_TYPE_JSON
 				}
@@ -434,6 +489,25 @@ func PutStoreObjectJSON(c *gin.Context) {
 				return nil
 			})
 
+			// Template Version (Optional: Keep Current Version)
+			vmap.Optional("template.version", nil, func(v interface{}) (interface{}, error) {
+				v, e := xjson.F_xToUint64(v)
+				if e != nil {
+					return nil, e
+				}
+
+				version := v.(uint64)
+				if version == 0 || version > math.MaxUint16 {
+					return nil, errors.New("Object has Invalid Template Version")
+				}
+				return uint16(version), nil
+			}, uint16(0), func(v interface{}) error {
+				if v.(uint16) != 0 {
+					t.SetVersion(v.(uint16))
+				}
+				return nil
+			})
+
 			// Object Template Values
 			vmap.Required("values", nil, func(v interface{}) (interface{}, error) {
 				if v == nil {
@@ -460,6 +534,9 @@ func PutStoreObjectJSON(c *gin.Context) {
 				if s == "" {
 					return nil, errors.New("Object Missing Title")
 				}
+				if orm.IsSealedTitle(s) {
+					return nil, errors.New("Object Title can't start with '" + orm.TITLE_SEALED_PREFIX + "'")
+				}
 				return s, nil
 			}, func(v interface{}) error {
 				o.SetTitle(v.(string))
@@ -474,6 +551,9 @@ func PutStoreObjectJSON(c *gin.Context) {
 				return
 			}
 		},
+		// Validate Values against Template Model
+		entry.AssertStoreObjectTemplate,
+		entry.AssertStoreObjectTags,
 		// Set Object
 		entry.EncryptStoreObject,
 		func(r rpf.GINProcessor, c *gin.Context) {
@@ -484,7 +564,12 @@ func PutStoreObjectJSON(c *gin.Context) {
 
 			r.SetLocal("store-parent-id", pid)
 		},
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
 		entry.DBStoreObjectUpdate,
+		entry.DBStoreObjectTagsIndex,
+		entry.DBStoreObjectSaveRevision,
+		entry.SetStoreObjectETag,
 		// Export Results //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			obj := r.MustGet("store-object").(*orm.StoreObject)
@@ -492,7 +577,7 @@ func PutStoreObjectJSON(c *gin.Context) {
 			switch obj.Type() {
 			case orm.OBJECT_TYPE_FOLDER:
 				entry.ExportStoreObjectFolder(r, c)
-			case orm.OBJECT_TYPE_JSON:
+			case orm.OBJECT_TYPE_JSON, orm.OBJECT_TYPE_ATTACHMENT:
 				entry.ExportStoreObjectJSON(r, c)
 			default:
 				r.Abort(4998 /* TODO: Error [Unknown Object Type] */, nil)
@@ -508,7 +593,6 @@ func PutStoreObjectJSON(c *gin.Context) {
 	request.Run()
 }
 
-// TODO IMPLEMENT: DELETE Store Object
 func DeleteStoreObject(c *gin.Context) {
 	// Create Request
 	request := rpf.RootProcessor("DELETE.STORE.OBJ", c, 1000, shared.JSONResponse)
@@ -522,8 +606,8 @@ func DeleteStoreObject(c *gin.Context) {
 			// Get Request Store
 			storeID := r.MustGet("request-store").(uint64)
 
-			// Required Roles : Store Access with Create Function
-			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE)}
+			// Required Roles : Store Access with Delete Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_DELETE)}
 
 			// Initialize Request
 			// TODO: Assert Store Unlocked
@@ -536,6 +620,11 @@ func DeleteStoreObject(c *gin.Context) {
 		entry.ExtractGINParameterEntryID,
 		entry.AssertNotRootFolder,
 		entry.DBStoreObjectGetByID,
+		// Assert Client Deletes Current Version
+		store.DBStoreIfMatchPolicy,
+		entry.AssertStoreObjectUnmodified,
+		// Move to Trash
+		store.DBStoreTrashPolicy,
 		entry.DBStoreObjectDelete,
 		entry.ExportStoreObjectRegistry,
 		// Extend and Save Store Session //
@@ -0,0 +1,177 @@
+// cSpell:ignore objs
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/entry"
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+	"github.com/objectvault/api-services/requests/rpf/store"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetStoreObjectHistory(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("GET.STORE.OBJ.HISTORY", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Read Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_READ_LIST)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreObjectGetByID,
+		entry.AssertNotFolderObject,
+		// Extract Query Parameters //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			gQuery := shared.GroupExtractQueryConditions(r, nil, func(f string) string {
+				switch f {
+				case "revision":
+					return "revision"
+				default: // Invalid Field
+					return ""
+				}
+			}, nil)
+
+			gQuery.Run()
+			if !r.IsFinished() { // YES
+				// Save Query Settings as Global
+				gQuery.LocalToGlobal("query-conditions")
+			}
+		},
+		// Query System for List //
+		entry.DBStoreObjectRevisionsList,
+		// Export Results //
+		entry.ExportStoreObjectRevisions,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func GetStoreObjectRevision(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("GET.STORE.OBJ.REVISION", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Read Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_READ_LIST)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.ExtractGINParameterRevision,
+		entry.DBStoreObjectGetByID,
+		entry.DBStoreObjectRevisionGet,
+		store.DBStoreEnvelopePolicy,
+		entry.DecryptStoreObjectRevision,
+		// Export Results //
+		entry.ExportStoreObjectRevision,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostStoreObjectRevisionRestore(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.OBJ.REVISION.RESTORE", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Update Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters (NOTE: Route uses the ':parent' Wildcard of POST /obj/:parent)
+		entry.ExtractGINParameterParentAsEntryID,
+		entry.AssertNotRootFolder,
+		entry.ExtractGINParameterRevision,
+		entry.DBStoreObjectGetByID,
+		entry.AssertNotFolderObject,
+		// Keep Current Value as Revision (Restore can be Undone)
+		store.DBStoreRevisionPolicy,
+		entry.StoreObjectKeepRevision,
+		// Restore Revision
+		entry.DBStoreObjectRevisionGet,
+		store.DBStoreEnvelopePolicy,
+		entry.DecryptStoreObjectRevision,
+		entry.RestoreStoreObjectRevision,
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
+		entry.DBStoreObjectUpdate,
+		entry.DBStoreObjectTagsIndex,
+		entry.DBStoreObjectSaveRevision,
+		// Export Results //
+		entry.ExportStoreObjectJSON,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
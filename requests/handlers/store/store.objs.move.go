@@ -0,0 +1,294 @@
+// cSpell:ignore objs
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/entry"
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+	"github.com/objectvault/api-services/requests/rpf/store"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PutStoreObjectMove(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("PUT.STORE.OBJ.MOVE", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Update Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Make sure Target Folder Exists
+		entry.ExtractGINParameterParentID,
+		assertTargetFolder,
+		// Extract Moved Object
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreObjectGetByID,
+		// Move Object
+		entry.StoreObjectIDToList,
+		entry.DBStoreObjectsMove,
+		// Export Results //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			o := r.MustGet("store-object").(*orm.StoreObject)
+			o.SetParent(r.MustGet("request-parent-id").(uint32))
+		},
+		entry.ExportStoreObjectRegistry,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PutStoreObjectsMove(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("PUT.STORE.OBJS.MOVE", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Update Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Make sure Target Folder Exists
+		entry.ExtractGINParameterParentID,
+		assertTargetFolder,
+		// PROCESS JSON Object //
+		shared.RequestExtractJSON, // Has to have a JSON Body
+		entry.ExtractJSONEntryIDs,
+		// Move Objects
+		entry.DBStoreObjectsMove,
+		entry.ExportStoreObjectIDs,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostStoreObjectCopy(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.OBJ.COPY", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Read Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_READ)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Make sure Target Store is Open and Target Folder Exists
+		store.DBStoreAttachmentsPolicy,
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
+		entry.ExtractURLParameterTargetStore,
+		openTargetStore,
+		entry.ExtractGINParameterParentID,
+		assertTargetFolder,
+		// Extract Copied Object
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreObjectGetByID,
+		// Copy Object
+		entry.StoreObjectIDToList,
+		entry.DBStoreObjectsCopy,
+		entry.ExportStoreObjectIDs,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostStoreObjectsCopy(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.OBJS.COPY", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Read Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_READ)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Make sure Target Store is Open and Target Folder Exists
+		store.DBStoreAttachmentsPolicy,
+		store.DBStoreTitlesPolicy,
+		store.DBStoreTagsPolicy,
+		entry.ExtractURLParameterTargetStore,
+		openTargetStore,
+		entry.ExtractGINParameterParentID,
+		assertTargetFolder,
+		// PROCESS JSON Object //
+		shared.RequestExtractJSON, // Has to have a JSON Body
+		entry.ExtractJSONEntryIDs,
+		// Copy Objects
+		entry.DBStoreObjectsCopy,
+		entry.ExportStoreObjectIDs,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+// openTargetStore Assert User can Create Objects in Target Store and that it is Open (if Copy to Another Store)
+func openTargetStore(r rpf.GINProcessor, c *gin.Context) {
+	// Required Roles : Target Store Access with Create Function
+	roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_CREATE)}
+
+	// Create Processing Group
+	group := &rpf.ProcessorGroup{}
+	group.Parent = r
+
+	// Copy to Another Store?
+	if !r.Has("request-target-store") { // NO: Copy is Created in Request Store
+		group.SetLocal("roles-required", roles)
+		group.Chain = rpf.ProcessChain{
+			store.AssertUserHasAllRolesInStore,
+		}
+
+		group.Run()
+		return
+	}
+
+	// Get Target Store and Session User
+	tsid := r.MustGet("request-target-store").(uint64)
+	uid := r.MustGet("user-id").(uint64)
+
+	// Store Steps apply to the Target Store
+	group.SetLocal("request-store", tsid)
+
+	group.Chain = rpf.ProcessChain{
+		// Check User has Permissions in Target Store
+		func(r rpf.GINProcessor, c *gin.Context) {
+			g := store.GroupAssertUserStorePermissions(r, uid, tsid, roles, true, false)
+			g.Run()
+
+			if !r.IsFinished() {
+				r.SetLocal("registry-store-user", g.MustGet("registry-store-user"))
+			}
+		},
+		// Assert Target Store is Open
+		store.AssertStoreOpen,
+		// Count Operation against Target Store Session
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		// Target Store Policies
+		store.DBTargetStorePolicy,
+	}
+
+	group.Run()
+	if !r.IsFinished() {
+		r.SetLocal("target-store", tsid)
+		r.SetLocal("target-store-key", group.MustGet("store-key"))
+		r.SetLocal("store-attachments-quota", group.MustGet("store-attachments-quota"))
+		r.SetLocal("store-titles-sealed", group.MustGet("store-titles-sealed"))
+		r.SetLocal("store-tags-indexed", group.MustGet("store-tags-indexed"))
+	}
+}
+
+// assertTargetFolder Assert Target Folder Exists (in Target Store, if Copy to Another Store)
+func assertTargetFolder(r rpf.GINProcessor, c *gin.Context) {
+	pid := r.MustGet("request-parent-id").(uint32)
+
+	// Is Target the Root Folder?
+	if pid == 0 { // YES: Always Exists
+		return
+	}
+
+	// Create Processing Group
+	group := &rpf.ProcessorGroup{}
+	group.Parent = r
+
+	// Set Object ID to Match
+	group.SetLocal("request-entry-id", pid)
+
+	// Is Target Folder in Another Store?
+	if r.Has("target-store") { // YES
+		group.SetLocal("request-store", r.MustGet("target-store"))
+		group.SetLocal("store-key", r.MustGet("target-store-key"))
+	}
+
+	// See if Object ID Exists and is Folder Object
+	group.Chain = rpf.ProcessChain{
+		entry.DBStoreObjectGetByID,
+		entry.AssertFolderObject,
+	}
+
+	group.Run()
+}
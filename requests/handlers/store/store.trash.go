@@ -0,0 +1,195 @@
+// cSpell:ignore objs
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/entry"
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+	"github.com/objectvault/api-services/requests/rpf/store"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetStoreTrash(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("GET.STORE.TRASH", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with List Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_LIST)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Query Parameters //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			gQuery := shared.GroupExtractQueryConditions(r, nil, func(f string) string {
+				switch f {
+				case "deleted":
+					return "deleted"
+				default: // Invalid Field
+					return ""
+				}
+			}, nil)
+
+			gQuery.Run()
+			if !r.IsFinished() { // YES
+				// Save Query Settings as Global
+				gQuery.LocalToGlobal("query-conditions")
+			}
+		},
+		// Query System for List //
+		entry.DBStoreTrashList,
+		// Export Results //
+		entry.ExportStoreTrashList,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostStoreTrashRestore(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.TRASH.RESTORE", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Update Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_UPDATE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreTrashGetByID,
+		// Restore Object (and Folder Contents)
+		entry.DBStoreTrashRestore,
+		// Export Results //
+		entry.ExportStoreObjectRegistry,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func DeleteStoreTrashObject(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("DELETE.STORE.TRASH.OBJ", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Delete Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_DELETE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Extract Required Parameters
+		entry.ExtractGINParameterEntryID,
+		entry.AssertNotRootFolder,
+		entry.DBStoreTrashGetByID,
+		// Permanently Delete Object (and Folder Contents)
+		entry.DBStoreTrashPurge,
+		// Export Results //
+		entry.ExportStoreObjectRegistry,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
+
+func DeleteStoreTrash(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("DELETE.STORE.TRASH", c, 1000, shared.JSONResponse)
+
+	// Request Processing Chain
+	request.Chain = rpf.ProcessChain{
+		// Extract Route Parameter 'store'
+		store.ExtractGINParameterStore,
+		// Validate Basic Request Settings
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Get Request Store
+			storeID := r.MustGet("request-store").(uint64)
+
+			// Required Roles : Store Access with Delete Function
+			roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_OBJECT, orm.FUNCTION_DELETE)}
+
+			// Initialize Request
+			store.GroupStoreRequestInitialize(r, storeID, roles).
+				Run()
+		},
+		// Assert Store is Open
+		store.AssertStoreOpen,
+		// Permanently Delete All Objects in Trash
+		entry.DBStoreTrashPurgeAll,
+		// Export Results //
+		func(r rpf.GINProcessor, c *gin.Context) {
+			r.SetResponseDataValue("purged", r.MustGet("purged-objects"))
+		},
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+		session.SaveSession, // Update Session Cookie
+	}
+
+	// Start Request Processing
+	request.Run()
+}
@@ -12,6 +12,7 @@ package store
  */
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/gin-contrib/sessions"
@@ -23,6 +24,7 @@ import (
 	"github.com/objectvault/api-services/requests/rpf/store"
 	"github.com/objectvault/api-services/requests/rpf/user"
 	"github.com/objectvault/api-services/requests/rpf/utils"
+	"github.com/objectvault/api-services/xjson"
 
 	rpf "github.com/objectvault/goginrpf"
 
@@ -230,6 +232,83 @@ func GetStoreUser(c *gin.Context) {
 	request.Run()
 }
 
+func PostStoreUser(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.STORE.USER", c, 1000, shared.JSONResponse)
+
+	// Required Roles : Store User Access with Create Function
+	roles := []uint32{orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_USER, orm.FUNCTION_CREATE)}
+
+	// Basic Request Validate
+	store.AddinGroupValidateStoreUserRequest(request, func(o string) interface{} {
+		if o == "roles" {
+			return roles
+		} else if o == "assert-if-self" {
+			return true
+		}
+
+		return nil
+	})
+
+	// Request Processing
+	request.Append(
+		// Store Key from Open Store Session
+		store.AssertStoreOpen,
+		// EXTRACT : Registration Options
+		shared.RequestExtractJSON,
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Extract and Validate JSON Message
+			m := r.MustGet("request-json").(xjson.T_xMap)
+			vmap := xjson.S_xJSONMap{Source: m}
+
+			// OPTIONAL: Store Roles (DEFAULT: Minimum Roles for Store Access)
+			vmap.Optional("roles", nil, xjson.F_xToTrimmedString, "50724865,50790403", func(v interface{}) error {
+				sr := &orm.S_Roles{}
+				sr.RolesFromCSV(v.(string))
+				r.SetLocal("register-roles", sr.Roles())
+				return nil
+			})
+
+			// OPTIONAL: Register User as Store Administrator
+			vmap.Optional("admin", nil, xjson.F_xToBoolean, false, func(v interface{}) error {
+				if v.(bool) {
+					r.SetLocal("register-as-admin", true)
+				}
+				return nil
+			})
+
+			// Did we have an Error Processing the Map?
+			if vmap.Error != nil {
+				fmt.Println(vmap.Error)
+				fmt.Println(vmap.StringSrc())
+				r.Abort(5202, nil)
+				return
+			}
+		},
+		// Get User to Share Store With
+		func(r rpf.GINProcessor, c *gin.Context) {
+			r.SetLocal("user", r.MustGet("request-user"))
+		},
+		user.DBRegistryUserFind,
+		user.AssertUserActive,
+		user.AssertUserBlocked,
+		store.AssertUserHasShareKey,
+		// Seal Store Key to User and Register Store with User
+		store.DBShareStoreWithUser,
+		store.DBRegisterStoreWithUser,
+		object.ExportRegistryObjUserBasic,
+		// Extend and Save Store Session //
+		session.ExtendStoreSession,
+		session.SessionStoreSave,
+	)
+
+	// Save Session
+	session.AddinSaveSession(request, nil)
+
+	// Start Request Processing
+	request.Run()
+}
+
 func DeleteStoreUser(c *gin.Context) {
 	/* IMPLEMENTATION NOTE:
 	 * It shouldn't be possible to delete last user from store, since
@@ -305,6 +384,13 @@ func DeleteStoreUser(c *gin.Context) {
 			registry.SetStates(orm.STATE_BLOCKED)
 		},
 		object.DBObjectUserFlush,
+		// Rotate Store Key, if Required by Store Policy
+		func(r rpf.GINProcessor, c *gin.Context) {
+			// Store Modifier is the Session User
+			session := sessions.Default(c)
+			r.SetLocal("user-id", session.Get("user-id"))
+		},
+		store.DBStoreKeyRotateOnRemove,
 	)
 
 	// Save Session
@@ -390,6 +476,7 @@ func PutStoreUserLock(c *gin.Context) {
 			}
 		},
 		object.DBObjectUserFlush,
+		session.RevokeObjectUserStoreSessions, // Close Open Store Sessions if Locked
 		// Request Response //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
@@ -476,6 +563,7 @@ func PutStoreUserBlock(c *gin.Context) {
 			}
 		},
 		object.DBObjectUserFlush,
+		session.RevokeObjectUserStoreSessions, // Close Open Store Sessions if Blocked
 		// Request Response //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
@@ -559,6 +647,7 @@ func PutStoreUserState(c *gin.Context) {
 			registry.SetStates(uint16(states))
 		},
 		object.DBObjectUserFlush,
+		session.RevokeObjectUserStoreSessions, // Close Open Store Sessions if Locked or Blocked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
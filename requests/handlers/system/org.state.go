@@ -118,6 +118,7 @@ func PutOrgLockState(c *gin.Context) {
 			}
 		},
 		org.DBRegistryOrgUpdate,
+		session.RevokeOrgStoreSessions, // Close Open Stores if Locked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-org").(*orm.OrgRegistry)
@@ -227,6 +228,7 @@ func PutOrgBlockState(c *gin.Context) {
 			}
 		},
 		org.DBRegistryOrgUpdate,
+		session.RevokeOrgStoreSessions, // Close Open Stores if Blocked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-org").(*orm.OrgRegistry)
@@ -129,6 +129,20 @@ func PostCreateUser(c *gin.Context) {
 				return nil
 			})
 
+			// OPTIONAL: Create Account Recovery Key (DEFAULT: Yes)
+			vmap.Optional("recovery", nil, xjson.F_xToBoolean, true, func(v interface{}) error {
+				if v.(bool) {
+					k, e := u.CreateRecoveryKey()
+					if e != nil {
+						return e
+					}
+
+					// IMPORTANT: Recovery Key is only Shown Once
+					r.SetLocal("recovery-key", k)
+				}
+				return nil
+			})
+
 			/*
 				// OPTIONAL: User Country
 				vmap.Optional("country", nil, xjson.F_xToString, nil, func(v interface{}) error {
@@ -182,6 +196,7 @@ func PostCreateUser(c *gin.Context) {
 		},
 		// RESPONSE //
 		user.ExportUserMe,
+		user.ExportRecoveryKey,
 		session.SaveSession, // Update Session Cookie
 	}
 
@@ -421,6 +436,7 @@ func PutUserLockState(c *gin.Context) {
 			}
 		},
 		user.DBRegistryUserUpdate,
+		session.RevokeUserStoreSessions, // Close Open Stores if Locked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-user").(*orm.UserRegistry)
@@ -528,6 +544,7 @@ func PutUserBlockState(c *gin.Context) {
 			}
 		},
 		user.DBRegistryUserUpdate,
+		session.RevokeUserStoreSessions, // Close Open Stores if Blocked
 		// CALCULATE RESPONSE //
 		func(r rpf.GINProcessor, c *gin.Context) {
 			registry := r.MustGet("registry-user").(*orm.UserRegistry)
@@ -0,0 +1,82 @@
+// cSpell:ignore ginrpf, gonic, paulo, ferreira
+package tools
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+	"github.com/objectvault/api-services/requests/rpf/tools"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PostGeneratePassword(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.TOOLS.PASSWORD", c, 1000, shared.JSONResponse)
+
+	// SESSION: We have an active session for user that is not blocked
+	session.AddinActiveUserSession(request, func(o string) interface{} {
+		if o == "assert-session-user-readonly" {
+			return false
+		}
+
+		return nil
+	})
+
+	// Request Process //
+	request.Append(
+		// GET JSON Body //
+		shared.RequestExtractJSON,
+		tools.ExtractJSONGeneratorOptions,
+		// Generate //
+		tools.GeneratePasswords,
+		// Save Session
+		session.SaveSession, // Update Session Cookie
+		// RESPONSE //
+		tools.ExportGeneratedPasswords,
+	)
+
+	// Start Request Processing
+	request.Run()
+}
+
+func PostPasswordStrength(c *gin.Context) {
+	// Create Request
+	request := rpf.RootProcessor("POST.TOOLS.PASSWORD.STRENGTH", c, 1000, shared.JSONResponse)
+
+	// SESSION: We have an active session for user that is not blocked
+	session.AddinActiveUserSession(request, func(o string) interface{} {
+		if o == "assert-session-user-readonly" {
+			return false
+		}
+
+		return nil
+	})
+
+	// Request Process //
+	request.Append(
+		// GET JSON Body //
+		shared.RequestExtractJSON,
+		tools.ExtractJSONPasswordStrength,
+		// Evaluate (Session User Information Included in Personal Inputs) //
+		tools.EvaluatePasswordStrength,
+		// Save Session
+		session.SaveSession, // Update Session Cookie
+		// RESPONSE //
+		tools.ExportPasswordStrength,
+	)
+
+	// Start Request Processing
+	request.Run()
+}
@@ -0,0 +1,257 @@
+// cSpell:ignore ferreira, paulo
+package action
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/action"
+	"github.com/objectvault/api-services/orm/importer"
+	"github.com/objectvault/api-services/requests/rpf/entry"
+	rpf "github.com/objectvault/goginrpf"
+)
+
+/* NOTE: Store Import Action
+ * Imports with more entries than the configured limit are run in the
+ * background, and the client is given the action GUID, to follow the
+ * import's progress and retrieve the report.
+ * The parsed entries and the store key are only kept in memory, by the
+ * process that received the import, so (unlike store key re-wraps) an
+ * interrupted import can't be resumed.
+ * The import saves its progress (entries processed) after every batch of
+ * entries, and stops if the store session that started it is closed.
+ * Imports that stopped saving their progress (i.e. the server was stopped)
+ * are marked as processed, with an error, when a server starts (see
+ * MarkStaleStoreImports). Their report is lost, but objects already imported
+ * are kept.
+ */
+
+const ACTION_STORE_IMPORT = "store:import"
+
+// Seconds without Progress before an Import is Considered Interrupted
+const STORE_IMPORT_STALE = 15 * 60
+
+var errStoreImportClosed = errors.New("Store Session Closed")
+
+func ActionCreateStoreImport(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	pid := r.MustGet("request-parent-id").(uint32)
+	format := r.MustGet("request-import-format").(string)
+	entries := r.MustGet("store-import-entries").([]*importer.Entry)
+
+	// User ID of Import Creator
+	uid := r.MustGet("user-id").(uint64)
+
+	// Create Action
+	oa := action.NewAction(ACTION_STORE_IMPORT, uid)
+
+	// Set Action Properties (IDs as Strings, to Survive JSON Round Trip)
+	props := oa.Properties()
+	props.Set("store", fmt.Sprintf(":%x", sid), true)
+	props.Set("parent", fmt.Sprintf(":%x", pid), true)
+	props.Set("format", format, true)
+	props.Set("entries", len(entries), true)
+
+	// Save Action
+	r.Set("action", oa)
+}
+
+func ActionStartStoreImport(r rpf.GINProcessor, c *gin.Context) {
+	// Get Action and Entries to Import
+	oa := r.MustGet("action").(*action.Action)
+	entries := r.MustGet("store-import-entries").([]*importer.Entry)
+	pid := r.MustGet("request-parent-id").(uint32)
+
+	// Create Importer (while Request Context is Available)
+	imp := entry.NewStoreImporterForRequest(r, c)
+	if imp == nil {
+		return
+	}
+
+	// Store Session that Started the Import (Import Stops if it is Closed)
+	ssid := r.MustGet("store-session-id").(string)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Run in Background
+	go RunStoreImport(dbm, oa.GUID(), ssid, imp, entries, pid)
+
+	// Request Accepted (Import not Complete)
+	r.SetResponseCode(1005)
+}
+
+// RunStoreImport Import Entries and Save Report in Action
+func RunStoreImport(dbm *orm.DBSessionManager, guid string, ssid string, imp *entry.StoreImporter, entries []*importer.Entry, parent uint32) {
+	e := runStoreImport(dbm, guid, ssid, imp, entries, parent)
+	if e != nil {
+		log.Printf("store import [%s]: %v\n", guid, e)
+	}
+}
+
+func runStoreImport(dbm *orm.DBSessionManager, guid string, ssid string, imp *entry.StoreImporter, entries []*importer.Entry, parent uint32) error {
+	// Get Connection to Global Registry (Always in Group 0: Shard 0)
+	db, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Get Action
+	oa := &action.Action{}
+	e = oa.ByGUID(db, guid)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	if !oa.IsValid() {
+		return errors.New("Action not Found")
+	}
+
+	// Mark Action as Being Processed
+	oa.SetStateProcessing()
+	e = oa.Flush(db, false)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Save Progress after every Batch (while the Store Session is Open)
+	props := oa.Properties()
+	imp.SetProgress(func(entries int) error {
+		if !common.StoreSessions().IsOpen(ssid) {
+			return errStoreImportClosed
+		}
+
+		props.Set("processed", entries, true)
+		return oa.Flush(db, false)
+	})
+
+	// Import Entries
+	reports, ie := imp.Import(entries, parent)
+
+	// Save Report and Mark Action Processed
+	props.Set("summary", &imp.Summary, true)
+	props.Set("report", reports, true)
+	if ie == errStoreImportClosed {
+		props.Set("error", "Import Interrupted (Store Closed)", true)
+	} else if ie != nil {
+		props.Set("error", "Import Interrupted (Database Error)", true)
+	}
+	oa.SetStateProcessed()
+	e = oa.Flush(db, false)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	return ie
+}
+
+// MarkStaleStoreImports Mark Imports without Recent Progress (Interrupted) as Processed with an Error
+func MarkStaleStoreImports(dbm *orm.DBSessionManager) (int, error) {
+	// Get Connection to Global Registry (Always in Group 0: Shard 0)
+	db, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		return 0, e
+	}
+
+	stale, e := action.StaleActions(db, ACTION_STORE_IMPORT, STORE_IMPORT_STALE)
+	if e != nil { // YES: Database Error
+		return 0, e
+	}
+
+	for _, guid := range stale {
+		oa := &action.Action{}
+		e = oa.ByGUID(db, guid)
+		if e != nil { // YES: Database Error
+			return 0, e
+		}
+
+		oa.Properties().Set("error", "Import Interrupted (Server Stopped)", true)
+		oa.SetStateProcessed()
+		e = oa.Flush(db, false)
+		if e != nil { // YES: Database Error
+			return 0, e
+		}
+	}
+
+	return len(stale), nil
+}
+
+// DBGetStoreImportAction Import Action (Only Visible to its Creator, in the Import Store)
+func DBGetStoreImportAction(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	guid := r.MustGet("request-guid").(string)
+	uid := r.MustGet("user-id").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Global Registry (Always in Group 0: Shard 0)
+	db, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Action
+	oa := &action.Action{}
+	e = oa.ByGUID(db, guid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Is it an Import, by the User, into the Store?
+	if !oa.IsValid() || oa.Type() != ACTION_STORE_IMPORT || oa.Creator() != uid || actionPropertyString(oa, "store") != fmt.Sprintf(":%x", sid) { // NO
+		r.Abort(4223, nil)
+		return
+	}
+
+	r.SetLocal("action", oa)
+}
+
+func ExportStoreImportAction(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	oa := r.MustGet("action").(*action.Action)
+
+	var state string
+	switch oa.State() {
+	case action.STATE_PROCESSING:
+		state = "processing"
+	case action.STATE_PROCESSED:
+		state = "processed"
+	default:
+		state = "pending"
+	}
+
+	data := gin.H{
+		"guid":  oa.GUID(),
+		"state": state,
+	}
+
+	// Copy Action Properties
+	for _, p := range []string{"parent", "format", "entries", "processed", "summary", "report", "error"} {
+		v, e := oa.Properties().Get(p)
+		if e == nil && v != nil {
+			data[p] = v
+		}
+	}
+
+	r.SetResponseDataValue("import", data)
+}
@@ -0,0 +1,466 @@
+// cSpell:ignore ferreira, paulo, rewrap
+package action
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/action"
+	rpf "github.com/objectvault/goginrpf"
+)
+
+/* NOTE: Store Keys Re-Wrap
+ * Store keys are wrapped with a key derived from the user's password hash
+ * (see orm.PasswordKey), so a password change requires every
+ * registry_object_users entry for the user, in every store shard, to be
+ * re-wrapped.
+ *
+ * The action is run in the background, and can be resumed (the next time
+ * the user logs in) if it is interrupted. To allow this, the previous password
+ * hash(es) are stored in the action, wrapped with a key derived from the new
+ * password hash, and are removed once the action has been completed.
+ *
+ * Entries wrapped with the password hash itself (legacy) are re-wrapped, with
+ * the derived key, the next time the user logs in (see RunStoreKeysUpgrade).
+ *
+ * Re-wrapping an entry is idempotent (entries already wrapped with the new
+ * hash are skipped), so the action can safely be run more than once.
+ */
+
+const ACTION_STORE_KEYS_REWRAP = "store:keys:rewrap"
+
+// Actions Running in this Process
+var rewrapRunning = map[string]bool{}
+var rewrapLock sync.Mutex
+
+// Users with Store Keys being Upgraded in this Process
+var upgradeRunning = map[uint64]bool{}
+var upgradeLock sync.Mutex
+
+func ActionCreateStoreKeysRewrap(r rpf.GINProcessor, c *gin.Context) {
+	// Get User and Password Hashes
+	uid := r.MustGet("user-id").(uint64)
+	hash := r.MustGet("hash").(string)
+	newHash := r.MustGet("new-hash").(string)
+
+	// Wrap Current Password Hash with Key Derived from New Password Hash
+	nk, e := orm.NewPasswordKeyHex(newHash, nil)
+	if e != nil {
+		r.Abort(5400, nil)
+		return
+	}
+
+	b, e := nk.Wrap([]byte(hash))
+	if e != nil {
+		r.Abort(5400, nil)
+		return
+	}
+	picks := []string{hex.EncodeToString(b)}
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Global Registry (Always in Group 0: Shard 0)
+	db, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the User have Incomplete Re-Wrap Actions?
+	pending, e := action.PendingActionsByCreator(db, ACTION_STORE_KEYS_REWRAP, uid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Carry Over Previous Password Hashes (New Action Supersedes Pending Actions)
+	for _, guid := range pending {
+		pa := &action.Action{}
+		e = pa.ByGUID(db, guid)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		for _, h := range actionRewrapHashes(pa, hash) {
+			b, e = nk.Wrap([]byte(h))
+			if e != nil {
+				r.Abort(5400, nil)
+				return
+			}
+			picks = append(picks, hex.EncodeToString(b))
+		}
+	}
+
+	// Create Action
+	oa := action.NewAction(ACTION_STORE_KEYS_REWRAP, uid)
+
+	// Set Action Properties
+	props := oa.Properties()
+	props.Set("picks", strings.Join(picks, ","), true)
+	if len(pending) > 0 {
+		props.Set("supersedes", strings.Join(pending, ","), true)
+	}
+
+	// Save Action
+	r.Set("action", oa)
+}
+
+func ActionStartStoreKeysRewrap(r rpf.GINProcessor, c *gin.Context) {
+	// Get Action and New Password Hash
+	oa := r.MustGet("action").(*action.Action)
+	newHash := r.MustGet("new-hash").(string)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Run in Background
+	go RunStoreKeysRewrap(dbm, oa.GUID(), newHash)
+}
+
+func ActionResumeStoreKeysRewrap(r rpf.GINProcessor, c *gin.Context) {
+	// Get User and Current Password Hash
+	user := r.MustGet("registry-user").(*orm.UserRegistry)
+	hash := r.MustGet("hash").(string)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// IMPORTANT: Failure to Resume should not Stop the Request (Retried on Next Login)
+	db, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		log.Printf("store keys rewrap: %v\n", e)
+		return
+	}
+
+	// Does the User have Incomplete Re-Wrap Actions?
+	pending, e := action.PendingActionsByCreator(db, ACTION_STORE_KEYS_REWRAP, user.ID())
+	if e != nil { // YES: Database Error
+		log.Printf("store keys rewrap: %v\n", e)
+		return
+	}
+
+	// Resume Actions in Background
+	for _, guid := range pending {
+		go RunStoreKeysRewrap(dbm, guid, hash)
+	}
+}
+
+// RunStoreKeysRewrap Re-Wrap all Store Keys for the Action's User with the Current Password Hash
+func RunStoreKeysRewrap(dbm *orm.DBSessionManager, guid string, hash string) {
+	// Is Action Already Running?
+	rewrapLock.Lock()
+	if rewrapRunning[guid] { // YES: Abort
+		rewrapLock.Unlock()
+		return
+	}
+	rewrapRunning[guid] = true
+	rewrapLock.Unlock()
+
+	defer func() {
+		rewrapLock.Lock()
+		delete(rewrapRunning, guid)
+		rewrapLock.Unlock()
+	}()
+
+	e := runStoreKeysRewrap(dbm, guid, hash)
+	if e != nil {
+		log.Printf("store keys rewrap [%s]: %v\n", guid, e)
+	}
+}
+
+func runStoreKeysRewrap(dbm *orm.DBSessionManager, guid string, hash string) error {
+	// Get Connection to Global Registry (Always in Group 0: Shard 0)
+	db, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Get Action
+	oa := &action.Action{}
+	e = oa.ByGUID(db, guid)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Action Still Pending?
+	if !oa.IsValid() || oa.State() == action.STATE_PROCESSED { // NO: Nothing to Do
+		return nil
+	}
+
+	// Previous Password Hashes
+	hashes := actionRewrapHashes(oa, hash)
+	if len(hashes) == 0 { // NONE: Action was not Created with this Password
+		return fmt.Errorf("Unable to Unlock Action for User [%x]", oa.Creator())
+	}
+
+	olds := make([]*orm.PasswordKey, 0, len(hashes))
+	for _, h := range hashes {
+		k, e := orm.NewPasswordKeyHex(h, nil)
+		if e != nil { // INVALID: Skip
+			continue
+		}
+		olds = append(olds, k)
+	}
+
+	// Get User's Registry Entry (KDF Parameters and Salt)
+	uid := oa.Creator()
+	ur := &orm.UserRegistry{}
+	e = ur.ByID(db, uid)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	k, e := ur.PasswordKeyHex(hash)
+	if e != nil {
+		return e
+	}
+
+	// Mark Action as Being Processed
+	oa.SetStateProcessing()
+	e = oa.Flush(db, false)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Get Connection to User's Shard
+	udb, e := dbm.Connect(uid)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// List User's Stores
+	stores, e := orm.UserObjectIDsByType(udb, uid, common.OTYPE_STORE)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Re-Wrap Store Keys
+	// NOTE: Database Errors Abort the Action (Leaving it Pending for Retry)
+	var failed []string
+	for _, sid := range stores {
+		// Get Connection to Store's Shard
+		sdb, e := dbm.Connect(sid)
+		if e != nil { // YES: Database Error
+			return e
+		}
+
+		// Get Store User Registry Entry
+		o := &orm.ObjectUserRegistry{}
+		e = o.ByKey(sdb, sid, uid)
+		if e != nil { // YES: Database Error
+			return e
+		}
+
+		// User Still Registered with Store?
+		if o.IsNew() { // NO: Skip
+			continue
+		}
+
+		// Re-Wrap Key
+		modified, e := o.RewrapStoreKey(k, olds...)
+		if e != nil { // FAILED: Key not Wrapped with Known Hash
+			failed = append(failed, fmt.Sprintf(":%x", sid))
+			continue
+		}
+
+		// Was the Entry Modified?
+		if modified { // YES: Save
+			e = o.Flush(sdb, false)
+			if e != nil { // YES: Database Error
+				return e
+			}
+		}
+	}
+
+	// Mark Action Processed and Remove Previous Hashes
+	props := oa.Properties()
+	props.Clear("picks")
+	props.Set("stores", len(stores), true)
+	if len(failed) > 0 {
+		props.Set("failed", strings.Join(failed, ","), true)
+	}
+	oa.SetStateProcessed()
+	e = oa.Flush(db, false)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Mark Superseded Actions as Processed
+	superseded := actionPropertyString(oa, "supersedes")
+	if superseded != "" {
+		for _, sguid := range strings.Split(superseded, ",") {
+			sa := &action.Action{}
+			e = sa.ByGUID(db, sguid)
+			if e != nil { // YES: Database Error
+				return e
+			}
+
+			// Action Exists and Still Pending?
+			if !sa.IsValid() || sa.State() == action.STATE_PROCESSED { // NO: Skip
+				continue
+			}
+
+			sa.Properties().Clear("picks")
+			sa.SetStateProcessed()
+			e = sa.Flush(db, false)
+			if e != nil { // YES: Database Error
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+func ActionUpgradeStoreKeys(r rpf.GINProcessor, c *gin.Context) {
+	// Get User and Current Password Hash
+	user := r.MustGet("registry-user").(*orm.UserRegistry)
+	hash := r.MustGet("hash").(string)
+
+	// Password Key (Registry Entry has Current KDF Parameters and Salt)
+	k, e := user.PasswordKeyHex(hash)
+	if e != nil {
+		log.Printf("store keys upgrade [%x]: %v\n", user.ID(), e)
+		return
+	}
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Run in Background (IMPORTANT: Failure is Retried on Next Login)
+	go RunStoreKeysUpgrade(dbm, user.ID(), k)
+}
+
+// RunStoreKeysUpgrade Re-Wrap User's Legacy Store Keys (Wrapped with Password Hash) with Password Key
+func RunStoreKeysUpgrade(dbm *orm.DBSessionManager, uid uint64, k *orm.PasswordKey) {
+	// Is Upgrade Already Running?
+	upgradeLock.Lock()
+	if upgradeRunning[uid] { // YES: Abort
+		upgradeLock.Unlock()
+		return
+	}
+	upgradeRunning[uid] = true
+	upgradeLock.Unlock()
+
+	defer func() {
+		upgradeLock.Lock()
+		delete(upgradeRunning, uid)
+		upgradeLock.Unlock()
+	}()
+
+	e := runStoreKeysUpgrade(dbm, uid, k)
+	if e != nil {
+		log.Printf("store keys upgrade [%x]: %v\n", uid, e)
+	}
+}
+
+func runStoreKeysUpgrade(dbm *orm.DBSessionManager, uid uint64, k *orm.PasswordKey) error {
+	// Get Connection to User's Shard
+	udb, e := dbm.Connect(uid)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// List User's Stores
+	stores, e := orm.UserObjectIDsByType(udb, uid, common.OTYPE_STORE)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	for _, sid := range stores {
+		// Get Connection to Store's Shard
+		sdb, e := dbm.Connect(sid)
+		if e != nil { // YES: Database Error
+			return e
+		}
+
+		// Get Store User Registry Entry
+		o := &orm.ObjectUserRegistry{}
+		e = o.ByKey(sdb, sid, uid)
+		if e != nil { // YES: Database Error
+			return e
+		}
+
+		// User Still Registered with Store?
+		if o.IsNew() { // NO: Skip
+			continue
+		}
+
+		// NOTE: Keys Wrapped with a Previous Password are Left to the Re-Wrap Action
+		modified, e := o.UpgradeStoreKey(k)
+		if e != nil || !modified {
+			continue
+		}
+
+		e = o.Flush(sdb, false)
+		if e != nil { // YES: Database Error
+			return e
+		}
+	}
+
+	return nil
+}
+
+// actionRewrapHashes Unwrap Previous Password Hashes Stored in Action (Most Recent First)
+func actionRewrapHashes(oa *action.Action, hash string) []string {
+	var hashes []string
+
+	picks := actionPropertyString(oa, "picks")
+	if picks == "" {
+		return hashes
+	}
+
+	k, e := orm.NewPasswordKeyHex(hash, nil)
+	if e != nil {
+		return hashes
+	}
+
+	for _, pick := range strings.Split(picks, ",") {
+		b, e := hex.DecodeString(pick)
+		if e != nil { // INVALID: Skip
+			continue
+		}
+
+		h, e := k.Unwrap(b)
+		if e != nil { // NOT Wrapped with this Hash: Skip
+			continue
+		}
+
+		hashes = append(hashes, string(h))
+	}
+
+	return hashes
+}
+
+func actionPropertyString(oa *action.Action, name string) string {
+	v, e := oa.Properties().Get(name)
+	if e != nil || v == nil {
+		return ""
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return ""
+	}
+
+	return s
+}
@@ -13,6 +13,7 @@ package entry
 
 import (
 	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/template"
 	rpf "github.com/objectvault/goginrpf"
 
 	"github.com/gin-gonic/gin"
@@ -50,3 +51,40 @@ func AssertNotRootFolder(r rpf.GINProcessor, c *gin.Context) {
 		return
 	}
 }
+
+func AssertStoreObjectTemplate(r rpf.GINProcessor, c *gin.Context) {
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	t := r.MustGet("store-template-object").(*orm.StoreTemplateObject)
+
+	// Is Built-in Object Type?
+	switch o.Type() {
+	case orm.OBJECT_TYPE_FOLDER: // YES: No Registered Template
+		return
+	case orm.OBJECT_TYPE_ATTACHMENT: // YES: Values have to Match the Fixed Attachment Model
+		values, _ := t.Values().(map[string]interface{})
+		errs := template.ValidateAttachmentValues(values)
+		if len(errs) > 0 {
+			r.Abort(4402, &gin.H{"fields": errs})
+		}
+		return
+	}
+
+	// Create Processing Group
+	group := &rpf.ProcessorGroup{}
+	group.Parent = r
+
+	// Template and Values to Validate
+	group.SetLocal("object-id", r.MustGet("request-store"))
+	group.SetLocal("request-template", t.Template())
+	group.SetLocal("request-template-version", t.Version())
+	group.SetLocal("template-values", t.Values())
+
+	// Template Version has to be Registered with Store and Values have to Match the Model
+	group.Chain = rpf.ProcessChain{
+		template.DBGetObjectTemplateRegistry,
+		template.DBGetTemplateVersion,
+		template.AssertTemplateValues,
+	}
+
+	group.Run()
+}
@@ -0,0 +1,932 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira, skey
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/template"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* NOTE: Store Archive Export and Restore
+ * An export writes every object outside of the trash (with its decrypted
+ * values, and attachment content) into an encrypted archive (see
+ * orm/store.archive.go), which is streamed to the client. A failure while
+ * streaming leaves the archive without its last frame, so that a partial
+ * export can't be restored.
+ * A restore re-creates the archived folder structure under a target folder,
+ * re-encrypting everything with the target store's key. Archived objects that
+ * match (same title and type) an object already in the target folder, are
+ * handled according to the conflict policy:
+ * - skip:      the existing object is kept (existing folders are merged),
+ * - overwrite: the existing object takes the archived value (the previous
+ *              value is kept as a revision, existing folders are merged),
+ * - duplicate: the archived object is restored as a new object.
+ * Templates missing from the target store are registered, if the user is
+ * allowed to register templates, and the template is available to the store's
+ * organization. Objects that can't be restored are listed in the report.
+ */
+
+// Archive Conflict Policies
+const ARCHIVE_CONFLICT_SKIP = "skip"
+const ARCHIVE_CONFLICT_OVERWRITE = "overwrite"
+const ARCHIVE_CONFLICT_DUPLICATE = "duplicate"
+
+// Minimum Length of Archive Passphrase
+const ARCHIVE_PASSPHRASE_MIN = 10
+
+// Archived Object Restore Status
+const RESTORE_STATUS_RESTORED = "restored"
+const RESTORE_STATUS_MERGED = "merged"
+const RESTORE_STATUS_OVERWRITTEN = "overwritten"
+const RESTORE_STATUS_SKIPPED = "skipped"
+const RESTORE_STATUS_FAILED = "failed"
+
+// StoreRestoreReport Restore Result for Archived Object
+type StoreRestoreReport struct {
+	ID       string            `json:"id"`
+	Type     uint8             `json:"type"`
+	Status   string            `json:"status"`
+	Template string            `json:"template,omitempty"`
+	Parent   string            `json:"parent,omitempty"`
+	Object   string            `json:"object,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// StoreRestoreSummary Number of Archived Objects by Status and Templates Registered
+type StoreRestoreSummary struct {
+	Objects     int `json:"objects"`
+	Restored    int `json:"restored"`
+	Merged      int `json:"merged"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	Failed      int `json:"failed"`
+	Templates   int `json:"templates"`
+}
+
+// storeArchiveRestorer Restores Archived Objects into Store Folder
+type storeArchiveRestorer struct {
+	*StoreImporter                                        // Object Creation (Store Policies)
+	conflict       string                                 // Conflict Policy
+	quota          uint64                                 // Store Attachments Quota (0 - No Limit)
+	usage          *uint64                                // Store Attachments Usage (Loaded on First Attachment)
+	revisions      uint16                                 // Store Revisions Policy: Count
+	age            uint16                                 // Store Revisions Policy: Age
+	templates      map[string]string                      // Reason Template can't be Used (Empty if it Can)
+	models         map[string]*storeImportTemplate        // Template Models by Name and Version
+	children       map[uint32]map[string]*orm.StoreObject // Existing Objects by Target Folder (and Type/Title)
+	Summary        StoreRestoreSummary                    // Restore Totals
+}
+
+// ExtractHeaderArchivePassphrase Archive Passphrase ('X-Archive-Passphrase' Header)
+func ExtractHeaderArchivePassphrase(r rpf.GINProcessor, c *gin.Context) {
+	// Do we have a Passphrase (Long Enough)?
+	passphrase := c.GetHeader("X-Archive-Passphrase")
+	if len([]rune(passphrase)) < ARCHIVE_PASSPHRASE_MIN { // NO
+		r.Abort(4224, nil)
+		return
+	}
+
+	r.SetLocal("request-archive-passphrase", []byte(passphrase))
+}
+
+// ExtractURLParameterArchiveConflict Archive Restore Conflict Policy (Optional 'conflict' Query Parameter)
+func ExtractURLParameterArchiveConflict(r rpf.GINProcessor, c *gin.Context) {
+	conflict := strings.ToLower(strings.TrimSpace(c.DefaultQuery("conflict", ARCHIVE_CONFLICT_SKIP)))
+	switch conflict {
+	case ARCHIVE_CONFLICT_SKIP, ARCHIVE_CONFLICT_OVERWRITE, ARCHIVE_CONFLICT_DUPLICATE:
+		r.SetLocal("request-archive-conflict", conflict)
+	default:
+		fmt.Println("Parameter 'conflict' is not a valid conflict policy")
+		r.Abort(3300, nil)
+	}
+}
+
+// AssertStoreArchiveSize Refuse Export of Stores whose Archive would be too Large to Restore
+func AssertStoreArchiveSize(r rpf.GINProcessor, c *gin.Context) {
+	sid := r.MustGet("request-store").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	size, e := orm.StoreArchiveEstimate(db, common.LocalIDFromID(sid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Is the Archive too Large?
+	if size > orm.StoreArchiveMaxSize() { // YES
+		r.Abort(4227, nil)
+		return
+	}
+}
+
+// StreamStoreArchive Send Encrypted Store Archive as the Request Response
+func StreamStoreArchive(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	skey := r.MustGet("store-key").([]byte)
+	passphrase := r.MustGet("request-archive-passphrase").([]byte)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Response Headers
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("store-%x.ovar", sid)}))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	aw, e := orm.NewStoreArchiveWriter(c.Writer, passphrase)
+	if e == nil {
+		e = writeStoreArchive(db, sid, skey, aw)
+	}
+
+	// NOTE: On Failure the Archive is Left without its Last Frame
+	if e != nil {
+		log.Printf("store export [%x] failed: %v\n", sid, e)
+		return
+	}
+
+	e = aw.Close()
+	if e != nil {
+		log.Printf("store export [%x] failed: %v\n", sid, e)
+	}
+}
+
+// writeStoreArchive Write Archive Document (Objects Outside of Trash)
+func writeStoreArchive(db *sql.DB, sid uint64, key []byte, w io.Writer) error {
+	lsid := common.LocalIDFromID(sid)
+
+	// Archive Header
+	header, e := json.Marshal(&struct {
+		Format   string `json:"format"`
+		Version  int    `json:"version"`
+		Store    string `json:"store"`
+		Exported string `json:"exported"`
+	}{
+		Format:   orm.STORE_ARCHIVE_FORMAT,
+		Version:  orm.STORE_ARCHIVE_FORMAT_VERSION,
+		Store:    fmt.Sprintf(":%x", sid),
+		Exported: time.Now().UTC().Format(time.RFC3339),
+	})
+	if e != nil {
+		return e
+	}
+
+	_, e = w.Write(append(header[:len(header)-1], []byte(`,"objects":[`)...))
+	if e != nil {
+		return e
+	}
+
+	// Objects (One at a Time)
+	templates := []string{}
+	used := map[string]bool{}
+	after := uint32(0)
+	count := 0
+	for {
+		objs, e := orm.StoreActiveObjectsBatch(db, lsid, after, 100)
+		if e != nil {
+			return e
+		}
+
+		// Have we Reached the End?
+		if len(objs) == 0 { // YES
+			break
+		}
+
+		for _, o := range objs {
+			after = o.ID()
+
+			ao, e := archiveStoreObject(db, key, o)
+			if e != nil {
+				return fmt.Errorf("object [%d]: %v", o.ID(), e)
+			}
+
+			// Templates Used (Folders and Attachments have no Registered Template)
+			if o.Type() == orm.OBJECT_TYPE_JSON && ao.Object != nil && !used[ao.Object.Template()] {
+				used[ao.Object.Template()] = true
+				templates = append(templates, ao.Object.Template())
+			}
+
+			bs, e := json.Marshal(ao)
+			if e != nil {
+				return e
+			}
+
+			if count > 0 {
+				bs = append([]byte{','}, bs...)
+			}
+			count++
+
+			_, e = w.Write(bs)
+			if e != nil {
+				return e
+			}
+		}
+	}
+
+	// Templates (Titles as Registered with Store)
+	list := []orm.StoreArchiveTemplate{}
+	for _, name := range templates {
+		reg := &orm.ObjectTemplateRegistry{}
+		e = reg.ByTemplate(db, sid, name)
+		if e != nil {
+			return e
+		}
+
+		list = append(list, orm.StoreArchiveTemplate{Name: name, Title: reg.Title()})
+	}
+
+	bs, e := json.Marshal(list)
+	if e != nil {
+		return e
+	}
+
+	_, e = w.Write(append(append([]byte(`],"templates":`), bs...), '}'))
+	return e
+}
+
+// archiveStoreObject Decrypted Object (and Attachment Content) for Archive
+func archiveStoreObject(db *sql.DB, key []byte, o *orm.StoreObject) (*orm.StoreArchiveObject, error) {
+	e := o.OpenTitle(key)
+	if e != nil {
+		return nil, e
+	}
+
+	ao := &orm.StoreArchiveObject{
+		ID:     fmt.Sprintf(":%x", o.ID()),
+		Parent: fmt.Sprintf(":%x", o.Parent()),
+		Type:   o.Type(),
+		Title:  o.Title(),
+	}
+
+	// Does the Object have Values?
+	if len(o.Object()) > 0 { // YES: Decrypt
+		ao.Object = &orm.StoreTemplateObject{}
+		e = ao.Object.DecryptObject(key, o.Store(), o.ID(), o.Object())
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	// Is Attachment?
+	if o.Type() == orm.OBJECT_TYPE_ATTACHMENT { // YES: Include Content
+		a := &orm.StoreObjectAttachment{}
+		e = a.ByKey(db, o.Store(), o.ID())
+		if e != nil {
+			return nil, e
+		}
+
+		// Does the Object have Content?
+		if !a.IsNew() { // YES
+			e = a.Open(key)
+			if e != nil {
+				return nil, e
+			}
+
+			var content bytes.Buffer
+			_, e = readStoreAttachment(db, a, key, &content)
+			if e != nil {
+				return nil, e
+			}
+
+			ao.Attachment = &orm.StoreArchiveAttachment{
+				Name:        a.Name(),
+				ContentType: a.ContentType(),
+				Data:        content.Bytes(),
+			}
+		}
+	}
+
+	return ao, nil
+}
+
+// ReadStoreArchive Decrypt and Parse Archive (Request Body)
+func ReadStoreArchive(r rpf.GINProcessor, c *gin.Context) {
+	passphrase := r.MustGet("request-archive-passphrase").([]byte)
+	max := orm.StoreArchiveMaxSize()
+
+	// Is the Archive too Large?
+	if c.Request.ContentLength > max { // YES
+		r.Abort(4222, nil)
+		return
+	}
+
+	body, e := io.ReadAll(io.LimitReader(c.Request.Body, max+1))
+	if e != nil {
+		r.Abort(5200, nil)
+		return
+	}
+
+	if int64(len(body)) > max { // YES: Content Length not Announced
+		r.Abort(4222, nil)
+		return
+	}
+
+	ar, e := orm.NewStoreArchiveReader(bytes.NewReader(body), passphrase)
+	if e != nil {
+		r.Abort(4226, nil)
+		return
+	}
+
+	archive := &orm.StoreArchive{}
+	e = json.NewDecoder(ar).Decode(archive)
+	if e == orm.ErrStoreArchivePassphrase {
+		r.Abort(4225, nil)
+		return
+	}
+
+	// Is it a Store Archive (that we Understand)?
+	if e == nil {
+		e = validateStoreArchive(archive)
+	}
+
+	if e != nil { // NO
+		fmt.Println(e)
+		r.Abort(4226, nil)
+		return
+	}
+
+	r.SetLocal("store-archive", archive)
+}
+
+func validateStoreArchive(archive *orm.StoreArchive) error {
+	if archive.Format != orm.STORE_ARCHIVE_FORMAT || archive.Version != orm.STORE_ARCHIVE_FORMAT_VERSION {
+		return fmt.Errorf("Unsupported Archive [%s:%d]", archive.Format, archive.Version)
+	}
+
+	ids := map[string]bool{}
+	for _, o := range archive.Objects {
+		if o == nil || o.ID == "" || ids[o.ID] {
+			return fmt.Errorf("Archive has Invalid (or Duplicate) Object IDs")
+		}
+		ids[o.ID] = true
+
+		switch o.Type {
+		case orm.OBJECT_TYPE_FOLDER:
+		case orm.OBJECT_TYPE_JSON, orm.OBJECT_TYPE_ATTACHMENT:
+			if o.Object == nil {
+				return fmt.Errorf("Archive Object [%s] has no Value", o.ID)
+			}
+		default:
+			return fmt.Errorf("Archive Object [%s] has Invalid Type", o.ID)
+		}
+	}
+
+	return nil
+}
+
+// DBStoreArchiveRestore Restore Archive into Parent Folder
+func DBStoreArchiveRestore(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	archive := r.MustGet("store-archive").(*orm.StoreArchive)
+	pid := r.MustGet("request-parent-id").(uint32)
+
+	imp := NewStoreImporterForRequest(r, c)
+	if imp == nil {
+		return
+	}
+
+	rs := &storeArchiveRestorer{
+		StoreImporter: imp,
+		conflict:      r.MustGet("request-archive-conflict").(string),
+		quota:         r.MustGet("store-attachments-quota").(uint64),
+		revisions:     r.MustGet("store-revisions-count").(uint16),
+		age:           r.MustGet("store-revisions-age").(uint16),
+		templates:     map[string]string{},
+		models:        map[string]*storeImportTemplate{},
+		children:      map[uint32]map[string]*orm.StoreObject{},
+	}
+
+	// Register Missing Templates
+	code := rs.registerTemplates(r, c, archive)
+	if code != 0 {
+		r.Abort(code, nil)
+		return
+	}
+
+	reports, e := rs.restore(archive, pid)
+	if e != nil {
+		log.Printf("store restore [%x] interrupted: %v\n", rs.storeID, e)
+		r.Abort(5100, nil)
+		return
+	}
+
+	r.SetLocal("store-restore-summary", &rs.Summary)
+	r.SetLocal("store-restore-report", reports)
+}
+
+// registerTemplates Make Archive Templates Available in Store (if Allowed) returning Error Code
+func (rs *storeArchiveRestorer) registerTemplates(r rpf.GINProcessor, c *gin.Context, archive *orm.StoreArchive) int {
+	store := r.MustGet("store").(*orm.Store)
+	rsu := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
+
+	// Can the User Register Templates?
+	register := rsu.HasRole(orm.Role(orm.CATEGORY_STORE|orm.SUBCATEGORY_TEMPLATE, orm.FUNCTION_CREATE))
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Organization Shard
+	odb, e := dbm.Connect(store.Organization())
+	if e != nil { // YES: Database Error
+		return 5100
+	}
+
+	// Templates Used by Archived Objects
+	for _, o := range archive.Objects {
+		if o.Type != orm.OBJECT_TYPE_JSON {
+			continue
+		}
+
+		name := o.Object.Template()
+		if _, ok := rs.templates[name]; ok {
+			continue
+		}
+
+		// Is Template Registered with Store?
+		exists, e := orm.ExistsRegisteredObjectTemplate(rs.db, rs.storeID, name)
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+
+		if exists { // YES
+			rs.templates[name] = ""
+			continue
+		}
+
+		if !register {
+			rs.templates[name] = "Template not Registered with Store"
+			continue
+		}
+
+		// Is Template Available to Organization?
+		exists, e = orm.ExistsRegisteredObjectTemplate(odb, store.Organization(), name)
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+
+		if !exists { // NO
+			rs.templates[name] = "Template not Registered with Organization"
+			continue
+		}
+
+		t := &orm.Template{}
+		e = t.ByNameLatest(rs.global, name)
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+
+		if !t.IsValid() {
+			rs.templates[name] = "Template does not Exist"
+			continue
+		}
+
+		// Register Template with Store
+		reg := &orm.ObjectTemplateRegistry{}
+		reg.SetKey(rs.storeID, name)
+		reg.SetTitle(t.Title())
+		e = reg.Flush(rs.db, true)
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+
+		rs.templates[name] = ""
+		rs.Summary.Templates++
+	}
+
+	return 0
+}
+
+// restore Restore Archived Objects (Parents before Children) under Parent Folder
+func (rs *storeArchiveRestorer) restore(archive *orm.StoreArchive, parent uint32) ([]*StoreRestoreReport, error) {
+	// Archived Objects by Parent (Objects whose Parent isn't Archived go to the Target Folder)
+	ids := map[string]bool{}
+	for _, o := range archive.Objects {
+		ids[o.ID] = true
+	}
+
+	children := map[string][]*orm.StoreArchiveObject{}
+	for _, o := range archive.Objects {
+		p := o.Parent
+		if !ids[p] || p == o.ID {
+			p = ""
+		}
+		children[p] = append(children[p], o)
+	}
+
+	// Target Folder of Archived Folders (Restored or Merged)
+	targets := map[string]uint32{"": parent}
+	reports := map[string]*StoreRestoreReport{}
+
+	queue := []string{""}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		for _, o := range children[p] {
+			report, id, e := rs.restoreObject(o, targets[p])
+			if e != nil {
+				return rs.reports(archive, reports), e
+			}
+			reports[o.ID] = report
+
+			// Can Folder Contents be Restored?
+			if o.Type == orm.OBJECT_TYPE_FOLDER && report.Status != RESTORE_STATUS_FAILED { // YES
+				targets[o.ID] = id
+				queue = append(queue, o.ID)
+			}
+		}
+	}
+
+	return rs.reports(archive, reports), nil
+}
+
+// reports Reports in Archive Order (Objects not Reached Failed with their Parent)
+func (rs *storeArchiveRestorer) reports(archive *orm.StoreArchive, reports map[string]*StoreRestoreReport) []*StoreRestoreReport {
+	list := make([]*StoreRestoreReport, 0, len(archive.Objects))
+	for _, o := range archive.Objects {
+		report, ok := reports[o.ID]
+		if !ok {
+			report = &StoreRestoreReport{
+				ID:     o.ID,
+				Type:   o.Type,
+				Status: RESTORE_STATUS_FAILED,
+				Errors: map[string]string{"parent": "Parent Folder not Restored"},
+			}
+		}
+
+		rs.Summary.Objects++
+		switch report.Status {
+		case RESTORE_STATUS_RESTORED:
+			rs.Summary.Restored++
+		case RESTORE_STATUS_MERGED:
+			rs.Summary.Merged++
+		case RESTORE_STATUS_OVERWRITTEN:
+			rs.Summary.Overwritten++
+		case RESTORE_STATUS_SKIPPED:
+			rs.Summary.Skipped++
+		default:
+			rs.Summary.Failed++
+		}
+
+		list = append(list, report)
+	}
+
+	return list
+}
+
+// restoreObject Restore Archived Object into Folder (Error only on Database Failure)
+func (rs *storeArchiveRestorer) restoreObject(o *orm.StoreArchiveObject, parent uint32) (*StoreRestoreReport, uint32, error) {
+	report := &StoreRestoreReport{
+		ID:     o.ID,
+		Type:   o.Type,
+		Status: RESTORE_STATUS_FAILED,
+		Parent: fmt.Sprintf(":%x", parent),
+	}
+
+	// Object Value (Folders Exported without a Value are Given One)
+	t := o.Object
+	if t == nil {
+		t = &orm.StoreTemplateObject{}
+		t.SetTemplate("folder")
+		t.SetVersion(IMPORT_FOLDER_VERSION)
+		t.SetTitle(o.Title)
+		t.SetValues(map[string]interface{}{"__title": o.Title})
+	}
+	report.Template = t.Template()
+
+	// Can the Template be Used?
+	if o.Type == orm.OBJECT_TYPE_JSON && rs.templates[t.Template()] != "" { // NO
+		report.Errors = map[string]string{"template": rs.templates[t.Template()]}
+		return report, 0, nil
+	}
+
+	// Do the Values Match the Template Model (at the Archived Version)?
+	if o.Type == orm.OBJECT_TYPE_JSON {
+		m, e := rs.model(t.Template(), t.Version())
+		if e != nil { // YES: Database Error
+			return nil, 0, e
+		}
+
+		if m.err != "" { // NO: Model can't be Used
+			report.Errors = map[string]string{"template": m.err}
+			return report, 0, nil
+		}
+
+		values, _ := t.Values().(map[string]interface{})
+		errs := template.ValidateModelValues(m.fields, values)
+		if len(errs) > 0 { // NO
+			report.Errors = errs
+			return report, 0, nil
+		}
+	}
+
+	// Do the Values Match the Fixed Attachment Model?
+	if o.Type == orm.OBJECT_TYPE_ATTACHMENT {
+		values, _ := t.Values().(map[string]interface{})
+		errs := template.ValidateAttachmentValues(values)
+		if len(errs) > 0 { // NO
+			report.Errors = errs
+			return report, 0, nil
+		}
+	}
+
+	// Does the Object Conflict with an Existing Object?
+	existing, e := rs.existing(parent, o.Type, o.Title)
+	if e != nil { // YES: Database Error
+		return nil, 0, e
+	}
+
+	if existing != nil && rs.conflict != ARCHIVE_CONFLICT_DUPLICATE { // YES
+		report.Object = fmt.Sprintf(":%x", existing.ID())
+		switch {
+		case o.Type == orm.OBJECT_TYPE_FOLDER:
+			report.Status = RESTORE_STATUS_MERGED
+		case rs.conflict == ARCHIVE_CONFLICT_SKIP:
+			report.Status = RESTORE_STATUS_SKIPPED
+		default:
+			code := rs.overwrite(existing, t, o.Attachment)
+			if code == 5100 {
+				return nil, 0, fmt.Errorf("Failed to Overwrite Object [%s]", o.ID)
+			}
+
+			if code != 0 {
+				report.Errors = map[string]string{"object": restoreError(code)}
+				return report, 0, nil
+			}
+			report.Status = RESTORE_STATUS_OVERWRITTEN
+		}
+		return report, existing.ID(), nil
+	}
+
+	// Create Object
+	t.SetTitle(o.Title)
+	id, code := rs.insert(parent, o.Type, t)
+	if code == 0 && o.Attachment != nil {
+		code = rs.attachment(id, 0, o.Attachment)
+		if code != 0 { // Remove Object without Content
+			orm.StoreObjectDelete(rs.db, common.LocalIDFromID(rs.storeID), id)
+		}
+	}
+
+	switch code {
+	case 0:
+	case 5100:
+		return nil, 0, fmt.Errorf("Failed to Restore Object [%s]", o.ID)
+	default:
+		report.Errors = map[string]string{"object": restoreError(code)}
+		return report, 0, nil
+	}
+
+	// Folder Created: Nothing to Conflict with
+	if o.Type == orm.OBJECT_TYPE_FOLDER {
+		rs.children[id] = map[string]*orm.StoreObject{}
+	}
+
+	report.Object = fmt.Sprintf(":%x", id)
+	report.Status = RESTORE_STATUS_RESTORED
+	return report, id, nil
+}
+
+// existing Object in Target Folder with Same Type and Title (nil if None)
+func (rs *storeArchiveRestorer) existing(parent uint32, otype uint8, title string) (*orm.StoreObject, error) {
+	objs, ok := rs.children[parent]
+	if !ok {
+		list, e := orm.StoreObjectChildren(rs.db, common.LocalIDFromID(rs.storeID), parent)
+		if e != nil {
+			return nil, e
+		}
+
+		objs = map[string]*orm.StoreObject{}
+		for _, o := range list {
+			e = o.OpenTitle(rs.key)
+			if e != nil {
+				return nil, e
+			}
+
+			k := fmt.Sprintf("%d/%s", o.Type(), o.Title())
+			if _, ok := objs[k]; !ok {
+				objs[k] = o
+			}
+		}
+		rs.children[parent] = objs
+	}
+
+	return objs[fmt.Sprintf("%d/%s", otype, title)], nil
+}
+
+// model Load (and Cache) Template Model for Archived Object Version
+func (rs *storeArchiveRestorer) model(name string, version uint16) (*storeImportTemplate, error) {
+	k := fmt.Sprintf("%s/%d", name, version)
+	if m, ok := rs.models[k]; ok {
+		return m, nil
+	}
+
+	m := &storeImportTemplate{
+		name:    name,
+		version: version,
+	}
+
+	tv := &orm.Template{}
+	e := tv.ByNameVersion(rs.global, name, version)
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	if !tv.IsValid() {
+		m.err = "Template Version does not Exist"
+	} else if m.fields, e = template.ModelFields(tv); e != nil {
+		m.err = "Template Model is Invalid"
+	}
+
+	rs.models[k] = m
+	return m, nil
+}
+
+// overwrite Replace Existing Object Value (and Attachment Content) returning Error Code
+func (rs *storeArchiveRestorer) overwrite(o *orm.StoreObject, t *orm.StoreTemplateObject, a *orm.StoreArchiveAttachment) int {
+	// Keep Current Value as Revision
+	rev, e := orm.NewStoreObjectRevision(o)
+	if e != nil {
+		rev = nil
+	}
+
+	// Replace Content First (Current Content is Kept on Failure)
+	if a != nil {
+		current := &orm.StoreObjectAttachment{}
+		e = current.ByKey(rs.db, o.Store(), o.ID())
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+
+		code := rs.attachment(o.ID(), current.Size(), a)
+		if code != 0 {
+			return code
+		}
+	}
+
+	t.SetTitle(o.Title())
+	ebs, e := t.EncryptObject(rs.key, o.Store(), o.ID())
+	if e != nil {
+		return 4998 /* TODO: ERROR [Failed to Encrypt Object] */
+	}
+
+	o.SetObject(ebs)
+	o.SetModifier(rs.creator)
+	e = o.Flush(rs.db, true)
+	if e != nil { // YES: Database Error
+		return 5100
+	}
+
+	// Does the Store Index Tags?
+	if rs.indexTags { // YES
+		e = orm.StoreObjectSetTags(rs.db, o.Store(), o.ID(), t.Tags())
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+	}
+
+	// Does the Store Keep Revisions?
+	if rev != nil && rs.revisions > 0 { // YES
+		e = rev.Flush(rs.db)
+		if e == nil {
+			e = orm.StoreObjectRevisionsPrune(rs.db, rev.Store(), rev.Object(), rs.revisions, rs.age)
+		}
+
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+	}
+
+	return 0
+}
+
+// attachment Store Archived Content as (New Generation of) Object Attachment returning Error Code
+func (rs *storeArchiveRestorer) attachment(id uint32, replaced uint64, content *orm.StoreArchiveAttachment) int {
+	lsid := common.LocalIDFromID(rs.storeID)
+
+	// Empty Content?
+	if len(content.Data) == 0 { // YES: Nothing to Store
+		return 0
+	}
+
+	// Is there Space Left in Store?
+	size := uint64(len(content.Data))
+	if rs.quota > 0 {
+		if rs.usage == nil {
+			usage, e := orm.StoreAttachmentsUsage(rs.db, lsid)
+			if e != nil { // YES: Database Error
+				return 5100
+			}
+			rs.usage = &usage
+		}
+
+		// Replaced Content is Removed
+		used := *rs.usage
+		if used > replaced {
+			used -= replaced
+		} else {
+			used = 0
+		}
+
+		if used+size > rs.quota { // NO
+			return 4215
+		}
+	}
+
+	current := &orm.StoreObjectAttachment{}
+	e := current.ByKey(rs.db, lsid, id)
+	if e != nil { // YES: Database Error
+		return 5100
+	}
+
+	a := orm.NewStoreObjectAttachment(current, lsid, id)
+	a.SetName(content.Name)
+	a.SetContentType(content.ContentType)
+	if a.ContentType() == "" {
+		a.SetContentType("application/octet-stream")
+	}
+
+	size, chunks, digest, code := storeAttachmentChunks(rs.db, a, rs.key, bytes.NewReader(content.Data), 0)
+	if code == 0 { // Chunks Stored: Save Manifest
+		a.SetContent(size, chunks, digest)
+		if a.Seal(rs.key) != nil {
+			code = 4998 /* TODO: ERROR [Failed to Encrypt Manifest] */
+		} else if a.Flush(rs.db) != nil {
+			code = 5100
+		}
+	}
+
+	// Did the Upload Fail?
+	if code != 0 { // YES: Remove Chunks of New Generation
+		orm.StoreAttachmentChunksDelete(rs.db, a.Store(), a.Object(), a.Generation())
+		return code
+	}
+
+	// Remove Previous Content
+	e = orm.StoreAttachmentChunksDeleteStale(rs.db, a.Store(), a.Object(), a.Generation())
+	if e != nil { // YES: Database Error
+		return 5100
+	}
+
+	if rs.usage != nil {
+		if *rs.usage > replaced {
+			*rs.usage -= replaced
+		} else {
+			*rs.usage = 0
+		}
+		*rs.usage += size
+	}
+	return 0
+}
+
+// restoreError Report Message for Error Code
+func restoreError(code int) string {
+	switch code {
+	case 4215:
+		return "Store Attachments Quota Exceeded"
+	default:
+		return "Failed to Encrypt Object"
+	}
+}
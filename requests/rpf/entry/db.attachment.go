@@ -0,0 +1,339 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira, skey
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/objectvault/api-services/orm"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AssertAttachmentObject(r rpf.GINProcessor, c *gin.Context) {
+	// Get Object
+	o := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Is Attachment Object?
+	if o.Type() != orm.OBJECT_TYPE_ATTACHMENT { // NO
+		r.Abort(4998 /* TODO: Error [Not Attachment Object] */, nil)
+		return
+	}
+}
+
+func DBStoreAttachmentGet(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	skey := r.MustGet("store-key").([]byte)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Attachment Manifest
+	a := &orm.StoreObjectAttachment{}
+	e = a.ByKey(db, o.Store(), o.ID())
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the Object have Content?
+	if a.IsNew() { // NO
+		r.Abort(4216, nil)
+		return
+	}
+
+	// Decrypt Manifest
+	e = a.Open(skey)
+	if e != nil {
+		r.Abort(4205, nil)
+		return
+	}
+
+	r.SetLocal("store-object-attachment", a)
+}
+
+// DBStoreAttachmentUpload Store Request Body as (New Generation of) Object Attachment
+func DBStoreAttachmentUpload(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	skey := r.MustGet("store-key").([]byte)
+	quota := r.MustGet("store-attachments-quota").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Current Attachment (if Any)
+	current := &orm.StoreObjectAttachment{}
+	e = current.ByKey(db, o.Store(), o.ID())
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Maximum Upload Size (Current Content is Replaced)
+	limit := uint64(0)
+	if quota > 0 {
+		used, e := orm.StoreAttachmentsUsage(db, o.Store())
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		if used > current.Size() {
+			used -= current.Size()
+		} else {
+			used = 0
+		}
+
+		// Is there Space Left (for the Announced Content)?
+		if used >= quota || (c.Request.ContentLength > 0 && uint64(c.Request.ContentLength) > quota-used) { // NO
+			r.Abort(4215, nil)
+			return
+		}
+		limit = quota - used
+	}
+
+	// Create Manifest for New Generation
+	a := orm.NewStoreObjectAttachment(current, o.Store(), o.ID())
+	a.SetContentType(c.ContentType())
+	if a.ContentType() == "" {
+		a.SetContentType("application/octet-stream")
+	}
+
+	name := o.Title()
+	if r.Has("request-attachment-name") {
+		name = r.MustGet("request-attachment-name").(string)
+	}
+	a.SetName(name)
+
+	// Store Content in Chunks
+	size, chunks, digest, code := storeAttachmentChunks(db, a, skey, c.Request.Body, limit)
+	if code == 0 { // Chunks Stored: Save Manifest
+		a.SetContent(size, chunks, digest)
+		if a.Seal(skey) != nil {
+			code = 4998 /* TODO: ERROR [Failed to Encrypt Manifest] */
+		} else if a.Flush(db) != nil {
+			code = 5100
+		}
+	}
+
+	// Did the Upload Fail?
+	if code != 0 { // YES: Remove Chunks of New Generation
+		orm.StoreAttachmentChunksDelete(db, a.Store(), a.Object(), a.Generation())
+		r.Abort(code, nil)
+		return
+	}
+
+	// Remove Previous Content
+	e = orm.StoreAttachmentChunksDeleteStale(db, a.Store(), a.Object(), a.Generation())
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	r.SetLocal("store-object-attachment", a)
+}
+
+// storeAttachmentChunks Encrypt and Store Content (returns size, chunks, SHA-256 or Error Code)
+func storeAttachmentChunks(db *sql.DB, a *orm.StoreObjectAttachment, key []byte, body io.Reader, limit uint64) (uint64, uint32, []byte, int) {
+	h := sha256.New()
+	size := uint64(0)
+
+	br := bufio.NewReaderSize(body, orm.ATTACHMENT_CHUNK_SIZE)
+	buf := make([]byte, orm.ATTACHMENT_CHUNK_SIZE)
+	for index := uint32(0); ; index++ {
+		n, e := io.ReadFull(br, buf)
+		if e == io.EOF { // Empty Content
+			return 0, 0, nil, 4216
+		} else if e != nil && e != io.ErrUnexpectedEOF {
+			log.Printf("attachment upload error: %v\n", e)
+			return 0, 0, nil, 3100
+		}
+
+		// Is this the Last Chunk?
+		last := e == io.ErrUnexpectedEOF
+		if !last {
+			_, e = br.Peek(1)
+			if e == io.EOF {
+				last = true
+			} else if e != nil {
+				log.Printf("attachment upload error: %v\n", e)
+				return 0, 0, nil, 3100
+			}
+		}
+
+		// Is the Content too Big?
+		size += uint64(n)
+		if limit > 0 && size > limit { // YES
+			return 0, 0, nil, 4215
+		}
+
+		h.Write(buf[:n])
+
+		// Encrypt and Store Chunk
+		cbs, e := orm.SealAttachmentChunk(key, a.Store(), a.Object(), a.Generation(), index, last, buf[:n])
+		if e != nil {
+			return 0, 0, nil, 4998 /* TODO: ERROR [Failed to Encrypt Chunk] */
+		}
+
+		e = orm.StoreAttachmentChunkInsert(db, a.Store(), a.Object(), a.Generation(), index, n, cbs)
+		if e != nil { // YES: Database Error
+			return 0, 0, nil, 5100
+		}
+
+		if last {
+			return size, index + 1, h.Sum(nil), 0
+		}
+	}
+}
+
+// AssertStoreAttachmentIntegrity Decrypt all Chunks and Verify Content against Manifest
+func AssertStoreAttachmentIntegrity(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	a := r.MustGet("store-object-attachment").(*orm.StoreObjectAttachment)
+	skey := r.MustGet("store-key").([]byte)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	size, e := readStoreAttachment(db, a, skey, io.Discard)
+	if e != nil || size != a.Size() {
+		log.Printf("attachment [%x:%d] failed verification: %v\n", sid, a.Object(), e)
+		r.Abort(4217, nil)
+		return
+	}
+}
+
+// StreamStoreAttachment Send Attachment Content as the Request Response
+func StreamStoreAttachment(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	a := r.MustGet("store-object-attachment").(*orm.StoreObjectAttachment)
+	skey := r.MustGet("store-key").([]byte)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Response Headers (Digest allows the Client to Verify the Content)
+	c.Header("Content-Type", a.ContentType())
+	c.Header("Content-Length", fmt.Sprintf("%d", a.Size()))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name()}))
+	c.Header("Digest", "sha-256="+base64.StdEncoding.EncodeToString(a.Digest()))
+	c.Status(http.StatusOK)
+
+	// NOTE: Content was Verified before Sending, a Failure here Truncates the Response
+	_, e = readStoreAttachment(db, a, skey, c.Writer)
+	if e != nil {
+		log.Printf("attachment [%x:%d] download failed: %v\n", sid, a.Object(), e)
+	}
+}
+
+func DBStoreAttachmentDelete(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	e = orm.StoreAttachmentDelete(db, o.Store(), o.ID())
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+}
+
+// readStoreAttachment Decrypt Attachment Chunks to Writer, Verifying Content Digest
+func readStoreAttachment(db *sql.DB, a *orm.StoreObjectAttachment, key []byte, w io.Writer) (uint64, error) {
+	h := sha256.New()
+	size := uint64(0)
+
+	for i := uint32(0); i < a.Chunks(); i++ {
+		cbs, e := orm.StoreAttachmentChunkGet(db, a.Store(), a.Object(), a.Generation(), i)
+		if e != nil {
+			return size, e
+		}
+
+		if cbs == nil {
+			return size, fmt.Errorf("missing chunk %d", i)
+		}
+
+		plain, e := orm.OpenAttachmentChunk(key, a.Store(), a.Object(), a.Generation(), i, i == a.Chunks()-1, cbs)
+		if e != nil {
+			return size, e
+		}
+
+		h.Write(plain)
+		_, e = w.Write(plain)
+		if e != nil {
+			return size, e
+		}
+		size += uint64(len(plain))
+	}
+
+	// Does the Content Match the Manifest?
+	if !bytes.Equal(h.Sum(nil), a.Digest()) { // NO
+		return size, fmt.Errorf("content digest mismatch")
+	}
+
+	return size, nil
+}
@@ -0,0 +1,443 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// cSpell:ignore skey
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/importer"
+	"github.com/objectvault/api-services/requests/rpf/template"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* NOTE: Store Imports
+ * Entries are imported one at a time, and an entry that can't be imported
+ * (its template is not registered with the store, or its values don't match
+ * the template model) doesn't stop the import: the report lists, for every
+ * entry, the object created, the fields mapped onto the template and the
+ * fields skipped (not in the template model, or unreadable in the source).
+ * Folders are created, under the import parent, the first time an entry
+ * needs them. Database errors stop the import, and objects already imported
+ * are kept.
+ * The report doesn't include titles or values, as it is also saved with the
+ * import action (for imports run in the background).
+ */
+
+// Import Entry Status
+const IMPORT_STATUS_IMPORTED = "imported"
+const IMPORT_STATUS_FAILED = "failed"
+
+// Template Version of Imported Folders
+const IMPORT_FOLDER_VERSION = 1
+
+// Number of Entries Imported between Progress Calls
+const IMPORT_PROGRESS_BATCH = 100
+
+// StoreImportReport Import Result for Source Entry
+type StoreImportReport struct {
+	Index    int               `json:"index"`
+	Kind     string            `json:"kind"`
+	Status   string            `json:"status"`
+	Template string            `json:"template,omitempty"`
+	Parent   string            `json:"parent"`
+	Object   string            `json:"object,omitempty"`
+	Mapped   []string          `json:"mapped"`
+	Skipped  []string          `json:"skipped"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// StoreImportSummary Number of Entries by Status and Number of Folders Created
+type StoreImportSummary struct {
+	Entries  int `json:"entries"`
+	Imported int `json:"imported"`
+	Failed   int `json:"failed"`
+	Folders  int `json:"folders"`
+}
+
+// StoreImporter Imports Parsed Entries into Store Folder
+type StoreImporter struct {
+	db         *sql.DB                         // Store Shard
+	global     *sql.DB                         // Global Shard (Templates)
+	storeID    uint64                          // Store ID
+	key        []byte                          // Store Key
+	creator    uint64                          // User ID of Object Creator
+	sealTitles bool                            // Store Policy: Seal Titles?
+	indexTags  bool                            // Store Policy: Index Tags?
+	templates  map[string]*storeImportTemplate // Templates by Entry Kind
+	folders    map[string]uint32               // Folders Created (by Parent and Name)
+	progress   func(entries int) error         // (OPTIONAL) Called after every Batch of Entries (Error Stops Import)
+	Summary    StoreImportSummary              // Import Totals
+}
+
+type storeImportTemplate struct {
+	name    string                 // Template Name
+	version uint16                 // Template Version (Latest)
+	fields  map[string]interface{} // Model Fields
+	err     string                 // Reason Template can't be Used
+}
+
+// NewStoreImporter Importer for Store (Store Policies have to be Known)
+func NewStoreImporter(dbm *orm.DBSessionManager, sid uint64, key []byte, creator uint64, sealTitles bool, indexTags bool) (*StoreImporter, error) {
+	db, e := dbm.Connect(sid)
+	if e != nil {
+		return nil, e
+	}
+
+	global, e := dbm.ConnectTo(0, 0)
+	if e != nil {
+		return nil, e
+	}
+
+	return &StoreImporter{
+		db:         db,
+		global:     global,
+		storeID:    sid,
+		key:        append([]byte{}, key...),
+		creator:    creator,
+		sealTitles: sealTitles,
+		indexTags:  indexTags,
+		templates:  map[string]*storeImportTemplate{},
+		folders:    map[string]uint32{},
+	}, nil
+}
+
+// Import Import Entries into Parent Folder (Error only on Database Failure)
+func (imp *StoreImporter) Import(entries []*importer.Entry, parent uint32) ([]*StoreImportReport, error) {
+	reports := []*StoreImportReport{}
+	for _, entry := range entries {
+		report, e := imp.importEntry(entry, parent)
+		if e != nil {
+			return reports, e
+		}
+
+		imp.Summary.Entries++
+		if report.Status == IMPORT_STATUS_IMPORTED {
+			imp.Summary.Imported++
+		} else {
+			imp.Summary.Failed++
+		}
+		reports = append(reports, report)
+
+		// End of Batch?
+		if imp.progress != nil && imp.Summary.Entries%IMPORT_PROGRESS_BATCH == 0 { // YES: Can the Import Continue?
+			e = imp.progress(imp.Summary.Entries)
+			if e != nil { // NO
+				return reports, e
+			}
+		}
+	}
+
+	return reports, nil
+}
+
+// SetProgress Function Called after every Batch of Entries (Returning an Error Stops the Import)
+func (imp *StoreImporter) SetProgress(f func(entries int) error) {
+	imp.progress = f
+}
+
+func (imp *StoreImporter) importEntry(entry *importer.Entry, parent uint32) (*StoreImportReport, error) {
+	report := &StoreImportReport{
+		Index:   entry.Index,
+		Kind:    entry.Kind,
+		Status:  IMPORT_STATUS_FAILED,
+		Mapped:  []string{},
+		Skipped: []string{},
+	}
+
+	// Template for Entry Kind
+	t, e := imp.template(entry.Kind)
+	if e != nil {
+		return nil, e
+	}
+
+	report.Template = t.name
+	if t.err != "" {
+		report.Parent = fmt.Sprintf(":%x", parent)
+		report.Errors = map[string]string{"template": t.err}
+		return report, nil
+	}
+
+	// Map Fields onto Template Model
+	values := map[string]interface{}{
+		"__title": entry.Title,
+	}
+
+	names := make([]string, 0, len(entry.Fields))
+	for name := range entry.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := t.fields[name]; ok && name != "__title" && name != orm.OBJECT_VALUE_TAGS {
+			values[name] = entry.Fields[name]
+			report.Mapped = append(report.Mapped, name)
+		} else {
+			report.Skipped = append(report.Skipped, name)
+		}
+	}
+	report.Skipped = append(report.Skipped, entry.Unreadable...)
+
+	// Do the Values Match the Model?
+	errs := template.ValidateModelValues(t.fields, values)
+	if len(errs) > 0 { // NO
+		report.Parent = fmt.Sprintf(":%x", parent)
+		report.Errors = errs
+		return report, nil
+	}
+
+	to := &orm.StoreTemplateObject{}
+	to.SetTemplate(t.name)
+	to.SetVersion(t.version)
+	to.SetTitle(entry.Title)
+	to.SetValues(values)
+
+	// Tags (Invalid Tags are Skipped)
+	tags := []string{}
+	dropped := false
+	for _, tag := range entry.Tags {
+		n, e := orm.NormalizeTag(tag)
+		if e != nil || len(tags) == orm.TAGS_MAX_PER_OBJECT {
+			dropped = true
+			continue
+		}
+		tags = append(tags, n)
+	}
+
+	if dropped {
+		report.Skipped = append(report.Skipped, orm.OBJECT_VALUE_TAGS)
+	}
+	to.SetTags(tags)
+
+	// Create Folder Path
+	pid, e := imp.folder(parent, entry.Folder)
+	if e != nil {
+		return nil, e
+	}
+	report.Parent = fmt.Sprintf(":%x", pid)
+
+	id, code := imp.insert(pid, orm.OBJECT_TYPE_JSON, to)
+	switch code {
+	case 0:
+	case 5100:
+		return nil, fmt.Errorf("Failed to Save Entry [%d]", entry.Index)
+	default:
+		report.Errors = map[string]string{"object": "Failed to Encrypt Object"}
+		return report, nil
+	}
+
+	report.Object = fmt.Sprintf(":%x", id)
+	report.Status = IMPORT_STATUS_IMPORTED
+	return report, nil
+}
+
+// template Load (and Cache) Template for Entry Kind
+func (imp *StoreImporter) template(kind string) (*storeImportTemplate, error) {
+	if t, ok := imp.templates[kind]; ok {
+		return t, nil
+	}
+
+	t := &storeImportTemplate{
+		name: importer.KindTemplate(kind),
+	}
+	imp.templates[kind] = t
+
+	// Is Template Registered with Store?
+	reg := &orm.ObjectTemplateRegistry{}
+	e := reg.ByTemplate(imp.db, imp.storeID, t.name)
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	if !reg.IsValid() { // NO
+		t.err = "Template not Registered with Store"
+		return t, nil
+	}
+
+	// Latest Template Version
+	tv := &orm.Template{}
+	e = tv.ByNameLatest(imp.global, t.name)
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	if !tv.IsValid() {
+		t.err = "Template does not Exist"
+		return t, nil
+	}
+
+	t.fields, e = template.ModelFields(tv)
+	if e != nil {
+		t.err = "Template Model is Invalid"
+		return t, nil
+	}
+
+	t.version = tv.Version()
+	return t, nil
+}
+
+// folder Folder for Path under Parent (Created if Required)
+func (imp *StoreImporter) folder(parent uint32, path []string) (uint32, error) {
+	for _, name := range path {
+		k := fmt.Sprintf("%x/%s", parent, strings.ToLower(name))
+		if id, ok := imp.folders[k]; ok {
+			parent = id
+			continue
+		}
+
+		to := &orm.StoreTemplateObject{}
+		to.SetTemplate("folder")
+		to.SetVersion(IMPORT_FOLDER_VERSION)
+		to.SetTitle(name)
+		to.SetValues(map[string]interface{}{"__title": name})
+
+		id, code := imp.insert(parent, orm.OBJECT_TYPE_FOLDER, to)
+		if code != 0 {
+			return 0, fmt.Errorf("Failed to Create Folder [%s]", name)
+		}
+
+		imp.folders[k] = id
+		imp.Summary.Folders++
+		parent = id
+	}
+
+	return parent, nil
+}
+
+// insert Create Encrypted Object returning ID or Error Code
+func (imp *StoreImporter) insert(parent uint32, otype uint8, t *orm.StoreTemplateObject) (uint32, int) {
+	o := &orm.StoreObject{}
+	o.SetStore(common.LocalIDFromID(imp.storeID))
+	o.SetParent(parent)
+	o.SetTitle(t.Title())
+	o.SetType(otype)
+	o.SetCreator(imp.creator)
+
+	// Does the Store Seal Titles?
+	if imp.sealTitles { // YES
+		e := o.SealTitle(imp.key)
+		if e != nil {
+			return 0, 4998 /* TODO: ERROR [Failed to Encrypt Title] */
+		}
+	}
+
+	// Object Cipher is Bound to the Object ID (only Known after Insert)
+	failed := false
+	e := orm.StoreObjectInsertEncrypted(imp.db, o, func(id uint32) ([]byte, error) {
+		ebs, e := t.EncryptObject(imp.key, o.Store(), id)
+		failed = e != nil
+		return ebs, e
+	})
+	if e != nil { // YES: Nothing Saved
+		if failed {
+			return 0, 4998 /* TODO: ERROR [Failed to Encrypt Object] */
+		}
+		return 0, 5100
+	}
+
+	// Does the Store Index Tags?
+	if imp.indexTags { // YES
+		e = orm.StoreObjectSetTags(imp.db, o.Store(), o.ID(), t.Tags())
+		if e != nil { // YES: Database Error
+			return 0, 5100
+		}
+	}
+
+	return o.ID(), 0
+}
+
+// ReadStoreImportFile Parse Import File (Request Body) into Entries
+func ReadStoreImportFile(r rpf.GINProcessor, c *gin.Context) {
+	format := r.MustGet("request-import-format").(string)
+	max := importer.MaxSize()
+
+	// Is the File too Large?
+	if c.Request.ContentLength > max { // YES
+		r.Abort(4222, nil)
+		return
+	}
+
+	body, e := io.ReadAll(io.LimitReader(c.Request.Body, max+1))
+	if e != nil {
+		r.Abort(5200, nil)
+		return
+	}
+
+	if int64(len(body)) > max { // YES: Content Length not Announced
+		r.Abort(4222, nil)
+		return
+	}
+
+	entries, e := importer.Parse(format, bytes.NewReader(body))
+	if e != nil {
+		fmt.Println(e)
+		r.Abort(4221, &gin.H{"error": e.Error()})
+		return
+	}
+
+	r.SetLocal("store-import-entries", entries)
+}
+
+// NewStoreImporterForRequest Importer for Request Store (Store Open and Policies Known)
+func NewStoreImporterForRequest(r rpf.GINProcessor, c *gin.Context) *StoreImporter {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	skey := r.MustGet("store-key").([]byte)
+	sealed := r.MustGet("store-titles-sealed").(bool)
+	indexed := r.MustGet("store-tags-indexed").(bool)
+
+	// User ID of Creator
+	uid := r.MustGet("user-id").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	imp, e := NewStoreImporter(dbm, sid, skey, uid, sealed, indexed)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return nil
+	}
+
+	return imp
+}
+
+// DBStoreImport Import Entries into Parent Folder (while the Client Waits)
+func DBStoreImport(r rpf.GINProcessor, c *gin.Context) {
+	entries := r.MustGet("store-import-entries").([]*importer.Entry)
+	pid := r.MustGet("request-parent-id").(uint32)
+
+	imp := NewStoreImporterForRequest(r, c)
+	if imp == nil {
+		return
+	}
+
+	reports, e := imp.Import(entries, pid)
+	if e != nil {
+		fmt.Println(e)
+		r.Abort(5100, nil)
+		return
+	}
+
+	r.SetLocal("store-import-summary", &imp.Summary)
+	r.SetLocal("store-import-report", reports)
+}
@@ -0,0 +1,384 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira, skey, tkey
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"database/sql"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreObjectIDToList Use Request Object as (Single Entry) Object List
+func StoreObjectIDToList(r rpf.GINProcessor, c *gin.Context) {
+	r.SetLocal("request-entry-ids", []uint32{r.MustGet("request-entry-id").(uint32)})
+}
+
+// DBStoreObjectsMove Move Objects to Parent Folder (Objects that Don't Exist or are in Trash are Skipped)
+func DBStoreObjectsMove(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	ids := r.MustGet("request-entry-ids").([]uint32)
+	pid := r.MustGet("request-parent-id").(uint32)
+
+	// User ID of Modifier
+	uid := r.MustGet("user-id").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Validate All Objects before Moving Any
+	lsid := common.LocalIDFromID(sid)
+	objs, e := storeObjectsForTarget(db, lsid, ids, pid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Would a Folder be Moved into itself?
+	if objs == nil { // YES: Abort
+		r.Abort(4214, nil)
+		return
+	}
+
+	moved := []uint32{}
+	for _, o := range objs {
+		// Is Object Already in Target Folder?
+		if o.Parent() != pid { // NO: Move it
+			e = orm.StoreObjectMove(db, lsid, o.ID(), pid, uid)
+			if e != nil { // YES: Database Error
+				r.Abort(5100, nil)
+				return
+			}
+		}
+
+		moved = append(moved, o.ID())
+	}
+
+	r.SetLocal("store-object-ids", moved)
+}
+
+// DBStoreObjectsCopy Copy Objects (and Folder Contents) to Parent Folder in Target Store (Default: Request Store)
+func DBStoreObjectsCopy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	ids := r.MustGet("request-entry-ids").([]uint32)
+	pid := r.MustGet("request-parent-id").(uint32)
+	skey := r.MustGet("store-key").([]byte)
+	quota := r.MustGet("store-attachments-quota").(uint64)
+	sealed := r.MustGet("store-titles-sealed").(bool)
+	indexed := r.MustGet("store-tags-indexed").(bool)
+
+	// Copy to Another Store?
+	tsid := sid
+	tkey := skey
+	if r.Has("target-store") { // YES
+		tsid = r.MustGet("target-store").(uint64)
+		tkey = r.MustGet("target-store-key").([]byte)
+	}
+
+	// User ID of Creator
+	uid := r.MustGet("user-id").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shards
+	sdb, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	tdb, e := dbm.Connect(tsid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Validate All Objects before Copying Any
+	var objs []*orm.StoreObject
+	if tsid == sid { // Same Store: Folder can't be Copied into itself
+		objs, e = storeObjectsForTarget(sdb, common.LocalIDFromID(sid), ids, pid)
+	} else {
+		objs, e = storeObjectsForTarget(sdb, common.LocalIDFromID(sid), ids, 0)
+	}
+
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Would a Folder be Copied into itself?
+	if objs == nil { // YES: Abort
+		r.Abort(4214, nil)
+		return
+	}
+
+	cp := &storeObjectCopier{
+		source:      sdb,
+		sourceKey:   skey,
+		target:      tdb,
+		targetID:    tsid,
+		targetKey:   tkey,
+		creator:     uid,
+		templates:   map[string]bool{},
+		checkTarget: tsid != sid,
+		quota:       quota,
+		sealTitles:  sealed,
+		indexTags:   indexed,
+	}
+
+	copies := []uint32{}
+	for _, o := range objs {
+		id, code := cp.copy(o, pid)
+		if code != 0 { // FAILED
+			r.Abort(code, nil)
+			return
+		}
+
+		copies = append(copies, id)
+	}
+
+	r.SetLocal("store-object-ids", copies)
+}
+
+// storeObjectsForTarget Load Objects to Move or Copy into Folder (nil if a Folder would be Placed in itself)
+func storeObjectsForTarget(db *sql.DB, store uint32, ids []uint32, folder uint32) ([]*orm.StoreObject, error) {
+	objs := []*orm.StoreObject{}
+	for _, id := range ids {
+		o := &orm.StoreObject{}
+		e := o.ByKey(db, store, id)
+		if e != nil {
+			return nil, e
+		}
+
+		// Does the Object Exist (and is it not in Trash)?
+		if o.IsNew() || o.IsTrashed() { // NO: Skip
+			continue
+		}
+
+		// Is the Target Folder the Object or Contained in it?
+		if folder != 0 && o.Type() == orm.OBJECT_TYPE_FOLDER {
+			inside, e := orm.StoreObjectIsAncestor(db, store, id, folder)
+			if e != nil {
+				return nil, e
+			}
+
+			if inside { // YES
+				return nil, nil
+			}
+		}
+
+		objs = append(objs, o)
+	}
+
+	return objs, nil
+}
+
+// storeObjectCopier Copies Objects between Folders (Same or Different Store)
+type storeObjectCopier struct {
+	source      *sql.DB         // Source Store Shard
+	sourceKey   []byte          // Source Store Key
+	target      *sql.DB         // Target Store Shard
+	targetID    uint64          // Target Store ID
+	targetKey   []byte          // Target Store Key
+	creator     uint64          // User ID of Copy Creator
+	templates   map[string]bool // Templates Known to be Registered with Target Store
+	checkTarget bool            // Verify Templates are Registered with Target Store?
+	quota       uint64          // Target Store Attachments Quota in Bytes (0 - No Limit)
+	usage       *uint64         // Target Store Attachments Usage in Bytes (Loaded on First Attachment)
+	sealTitles  bool            // Target Store Policy: Seal Titles?
+	indexTags   bool            // Target Store Policy: Index Tags?
+}
+
+// copy Copy Object (and Folder Contents) returning ID of Copy or Error Code
+func (cp *storeObjectCopier) copy(o *orm.StoreObject, parent uint32) (uint32, int) {
+	// Decrypt Object (Cipher is Bound to the Source Object)
+	t := &orm.StoreTemplateObject{}
+	e := t.DecryptObject(cp.sourceKey, o.Store(), o.ID(), o.Object())
+	if e != nil {
+		return 0, 4205
+	}
+
+	// Is Template Registered with Target Store?
+	if cp.checkTarget && o.Type() != orm.OBJECT_TYPE_FOLDER && !cp.templates[t.Template()] {
+		reg := &orm.ObjectTemplateRegistry{}
+		e = reg.ByTemplate(cp.target, cp.targetID, t.Template())
+		if e != nil { // YES: Database Error
+			return 0, 5100
+		}
+
+		if !reg.IsValid() { // NO
+			return 0, 4401
+		}
+		cp.templates[t.Template()] = true
+	}
+
+	// Decrypt Title (Sealed Titles are Bound to the Source Store)
+	e = o.OpenTitle(cp.sourceKey)
+	if e != nil {
+		return 0, 4205
+	}
+
+	// Create Copy (Cipher is Bound to the Object ID, only Known after Insert)
+	n := &orm.StoreObject{}
+	n.SetStore(common.LocalIDFromID(cp.targetID))
+	n.SetParent(parent)
+	n.SetTitle(o.Title())
+	n.SetType(o.Type())
+	n.SetCreator(cp.creator)
+
+	// Does the Target Store Seal Titles?
+	if cp.sealTitles { // YES
+		e = n.SealTitle(cp.targetKey)
+		if e != nil {
+			return 0, 4998 /* TODO: ERROR [Failed to Encrypt Title] */
+		}
+	}
+
+	// Re-Encrypt Object with Target Store Key
+	failed := false
+	e = orm.StoreObjectInsertEncrypted(cp.target, n, func(id uint32) ([]byte, error) {
+		ebs, e := t.EncryptObject(cp.targetKey, n.Store(), id)
+		failed = e != nil
+		return ebs, e
+	})
+	if e != nil { // YES: Nothing Saved
+		if failed {
+			return 0, 4998 /* TODO: ERROR [Failed to Encrypt Object] */
+		}
+		return 0, 5100
+	}
+
+	// Does the Target Store Index Tags?
+	if cp.indexTags { // YES
+		e = orm.StoreObjectSetTags(cp.target, n.Store(), n.ID(), t.Tags())
+		if e != nil { // YES: Database Error
+			return 0, 5100
+		}
+	}
+
+	// Is Attachment?
+	if o.Type() == orm.OBJECT_TYPE_ATTACHMENT { // YES: Copy Content
+		code := cp.copyAttachment(o, n)
+		if code != 0 {
+			return 0, code
+		}
+	}
+
+	// Is Folder?
+	if o.Type() == orm.OBJECT_TYPE_FOLDER { // YES: Copy Contents
+		children, e := orm.StoreObjectChildren(cp.source, o.Store(), o.ID())
+		if e != nil { // YES: Database Error
+			return 0, 5100
+		}
+
+		for _, child := range children {
+			_, code := cp.copy(child, n.ID())
+			if code != 0 {
+				return 0, code
+			}
+		}
+	}
+
+	return n.ID(), 0
+}
+
+// copyAttachment Copy Attachment Content (Re-Encrypted for the Copy) returning Error Code
+func (cp *storeObjectCopier) copyAttachment(o *orm.StoreObject, n *orm.StoreObject) int {
+	a := &orm.StoreObjectAttachment{}
+	e := a.ByKey(cp.source, o.Store(), o.ID())
+	if e != nil { // YES: Database Error
+		return 5100
+	}
+
+	// Does the Object have Content?
+	if a.IsNew() { // NO: Nothing to Copy
+		return 0
+	}
+
+	e = a.Open(cp.sourceKey)
+	if e != nil {
+		return 4205
+	}
+
+	// Is there Space Left in Target Store?
+	if cp.quota > 0 {
+		if cp.usage == nil {
+			usage, e := orm.StoreAttachmentsUsage(cp.target, n.Store())
+			if e != nil { // YES: Database Error
+				return 5100
+			}
+			cp.usage = &usage
+		}
+
+		if *cp.usage+a.Size() > cp.quota { // NO
+			return 4215
+		}
+		*cp.usage += a.Size()
+	}
+
+	// Copy Chunks
+	na := orm.NewStoreObjectAttachment(nil, n.Store(), n.ID())
+	na.SetName(a.Name())
+	na.SetContentType(a.ContentType())
+	for i := uint32(0); i < a.Chunks(); i++ {
+		last := i == a.Chunks()-1
+		cbs, e := orm.StoreAttachmentChunkGet(cp.source, a.Store(), a.Object(), a.Generation(), i)
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+
+		if cbs == nil { // Missing Chunk
+			return 4217
+		}
+
+		plain, e := orm.OpenAttachmentChunk(cp.sourceKey, a.Store(), a.Object(), a.Generation(), i, last, cbs)
+		if e != nil {
+			return 4217
+		}
+
+		cbs, e = orm.SealAttachmentChunk(cp.targetKey, na.Store(), na.Object(), na.Generation(), i, last, plain)
+		if e != nil {
+			return 4998 /* TODO: ERROR [Failed to Encrypt Chunk] */
+		}
+
+		e = orm.StoreAttachmentChunkInsert(cp.target, na.Store(), na.Object(), na.Generation(), i, len(plain), cbs)
+		if e != nil { // YES: Database Error
+			return 5100
+		}
+	}
+
+	// Save Manifest
+	na.SetContent(a.Size(), a.Chunks(), a.Digest())
+	if na.Seal(cp.targetKey) != nil {
+		return 4998 /* TODO: ERROR [Failed to Encrypt Manifest] */
+	}
+
+	if na.Flush(cp.target) != nil { // YES: Database Error
+		return 5100
+	}
+
+	return 0
+}
@@ -37,9 +37,41 @@ func DBStoreObjectsList(r rpf.GINProcessor, c *gin.Context) {
 		return
 	}
 
-	// List Registered Orgs
+	// Store Key (if the Store is Open) to Decrypt Sealed Titles
+	var skey []byte
+	if r.Has("store-key") {
+		skey = r.MustGet("store-key").([]byte)
+	}
+
+	// Tag Filter: Applied by the Database (Tag Index) or to the Page (Decrypted Objects)
+	var tags, pageTags []string
+	if r.Has("request-tags") {
+		tags = r.MustGet("request-tags").([]string)
+
+		// Does the Store Index Tags?
+		if !r.Has("store-tags-indexed") || !r.MustGet("store-tags-indexed").(bool) { // NO
+			if skey == nil { // Tags can only be Read with the Store Key
+				r.Abort(4202, nil)
+				return
+			}
+
+			pageTags, tags = tags, nil
+		}
+	}
+
+	// List Folder Contents (Sealed Titles can't be Sorted or Filtered by the Database)
+	var objs query.TQueryResults
 	q := r.MustGet("query-conditions").(*query.QueryConditions)
-	objs, err := orm.QueryStoreParentObjects(db, common.LocalIDFromID(sid), pid, q, true)
+	if r.Has("store-titles-sealed") && r.MustGet("store-titles-sealed").(bool) {
+		objs, err = orm.QueryStoreParentSealedObjects(db, common.LocalIDFromID(sid), pid, tags, skey, q, true)
+	} else {
+		objs, err = orm.QueryStoreParentTaggedObjects(db, common.LocalIDFromID(sid), pid, tags, q, true)
+		if err == nil && skey != nil { // Titles Sealed before Policy was Disabled
+			for _, v := range objs.Items() {
+				v.(*orm.StoreObject).OpenTitle(skey)
+			}
+		}
+	}
 
 	// Failed Retrieving User?
 	if err != nil { // YES: Database Error
@@ -47,6 +79,15 @@ func DBStoreObjectsList(r rpf.GINProcessor, c *gin.Context) {
 		return
 	}
 
+	// Filter Page by Tags (Decrypted Objects)?
+	if len(pageTags) > 0 { // YES
+		objs, err = orm.FilterStoreObjectsByTags(db, objs, skey, pageTags)
+		if err != nil {
+			r.Abort(4205, nil)
+			return
+		}
+	}
+
 	// Save List
 	r.Set("store-objects", objs)
 }
@@ -70,20 +111,23 @@ func DBStoreObjectGetByID(r rpf.GINProcessor, c *gin.Context) {
 	obj := &orm.StoreObject{}
 
 	// Failed Retrieving User?
-	e = obj.ByID(db, oid)
+	e = obj.ByKey(db, common.LocalIDFromID(sid), oid)
 	if e != nil { // YES: Database Error
 		r.Abort(5100, nil)
 		return
 	}
 
-	// Did we find the Object?
-	if obj.IsNew() { // NO: Object Does not Exist
+	// Did we find the Object (Objects in Trash are Only Accessible through the Trash)?
+	if obj.IsNew() || obj.IsTrashed() { // NO: Object Does not Exist
 		r.Abort(4000, nil)
 		return
 	}
 
 	// Save Store
 	r.SetLocal("store-object", obj)
+
+	// Decrypt Title (if Sealed)
+	OpenStoreObjectTitle(r, c)
 }
 
 func DBStoreObjectInsert(r rpf.GINProcessor, c *gin.Context) {
@@ -107,18 +151,30 @@ func DBStoreObjectInsert(r rpf.GINProcessor, c *gin.Context) {
 	// Set Object Creator
 	obj.SetCreator(uid)
 
-	// Save Object
+	// Seal Title (Store Policy)
+	SealStoreObjectTitle(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Save Object (Only if not Modified since it was Read)
 	e = obj.Flush(db, true)
+	if e == orm.ErrStoreObjectModified { // Client (If-Match) Version is no Longer Current
+		r.Abort(4219, nil)
+		return
+	}
 	if e != nil { // YES: Database Error
 		r.Abort(5100, nil)
 		return
 	}
 }
 
-func DBStoreObjectUpdate(r rpf.GINProcessor, c *gin.Context) {
+func DBStoreObjectInsertEncrypted(r rpf.GINProcessor, c *gin.Context) {
 	// Get Request Parameters
 	sid := r.MustGet("request-store").(uint64)
 	obj := r.MustGet("store-object").(*orm.StoreObject)
+	ot := r.MustGet("store-template-object").(*orm.StoreTemplateObject)
+	skey := r.MustGet("store-key").([]byte)
 
 	// User ID of Creator
 	uid := r.MustGet("user-id").(uint64)
@@ -134,20 +190,80 @@ func DBStoreObjectUpdate(r rpf.GINProcessor, c *gin.Context) {
 	}
 
 	// Set Object Creator
+	obj.SetCreator(uid)
+
+	// Seal Title (Store Policy)
+	SealStoreObjectTitle(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Save Object (Cipher is Bound to the Object ID, only Known after Insert)
+	failed := false
+	e = orm.StoreObjectInsertEncrypted(db, obj, func(id uint32) ([]byte, error) {
+		ebs, e := ot.EncryptObject(skey, obj.Store(), id)
+		failed = e != nil
+		return ebs, e
+	})
+
+	// Error Occurred?
+	if e != nil { // YES: Nothing Saved
+		if failed {
+			r.Abort(4998 /* TODO: ERROR [Failed to Encrypt Object] */, nil)
+			return
+		}
+		r.Abort(5100, nil)
+	}
+}
+
+func DBStoreObjectUpdate(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	obj := r.MustGet("store-object").(*orm.StoreObject)
+
+	// User ID of Creator
+	uid := r.MustGet("user-id").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Set Object Modifier
 	obj.SetModifier(uid)
 
-	// Save Object
+	// Seal Title (Store Policy)
+	SealStoreObjectTitle(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Save Object (Only if not Modified since it was Read)
 	e = obj.Flush(db, true)
+	if e == orm.ErrStoreObjectModified { // Client (If-Match) Version is no Longer Current
+		r.Abort(4219, nil)
+		return
+	}
 	if e != nil { // YES: Database Error
 		r.Abort(5100, nil)
 		return
 	}
 }
 
+// DBStoreObjectDelete Move Object (and Folder Contents) to Store Trash
 func DBStoreObjectDelete(r rpf.GINProcessor, c *gin.Context) {
 	// Get Request Parameters
 	sid := r.MustGet("request-store").(uint64)
 	obj := r.MustGet("store-object").(*orm.StoreObject)
+	retention := r.MustGet("store-trash-retention").(uint16)
+
+	// User ID of Deleter
+	uid := r.MustGet("user-id").(uint64)
 
 	// Get Database Connection Manager
 	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
@@ -159,14 +275,61 @@ func DBStoreObjectDelete(r rpf.GINProcessor, c *gin.Context) {
 		return
 	}
 
-	switch obj.Type() {
-	case orm.OBJECT_TYPE_FOLDER:
-		orm.StoreObjectDeleteFolder(db, common.LocalIDFromID(sid), obj.ID())
-	default:
-		orm.StoreObjectDelete(db, common.LocalIDFromID(sid), obj.ID())
+	// Move to Trash
+	_, e = orm.StoreObjectTrash(db, common.LocalIDFromID(sid), obj.ID(), uid, retention)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
 	}
 }
 
+// DBStoreObjectsDelete Move Objects to Store Trash (Objects that Don't Exist or are Already in Trash are Skipped)
+func DBStoreObjectsDelete(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	ids := r.MustGet("request-entry-ids").([]uint32)
+	retention := r.MustGet("store-trash-retention").(uint16)
+
+	// User ID of Deleter
+	uid := r.MustGet("user-id").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	lsid := common.LocalIDFromID(sid)
+	deleted := []uint32{}
+	for _, id := range ids {
+		obj := &orm.StoreObject{}
+		e = obj.ByKey(db, lsid, id)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Does the Object Exist (and is it not in Trash, i.e. Contained in a Deleted Folder)?
+		if obj.IsNew() || obj.IsTrashed() { // NO: Skip
+			continue
+		}
+
+		// Move to Trash
+		_, e = orm.StoreObjectTrash(db, lsid, id, uid, retention)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+		deleted = append(deleted, id)
+	}
+
+	r.SetLocal("store-object-ids", deleted)
+}
+
 func DBStoreObjectsDeleteAll(r rpf.GINProcessor, c *gin.Context) {
 	// Get Request Parameters
 	sid := r.MustGet("request-store").(uint64)
@@ -186,4 +349,16 @@ func DBStoreObjectsDeleteAll(r rpf.GINProcessor, c *gin.Context) {
 		r.Abort(5100, nil)
 		return
 	}
+
+	_, e = orm.StoreObjectRevisionsDeleteAll(db, common.LocalIDFromID(sid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	e = orm.StoreAttachmentsDeleteAll(db, common.LocalIDFromID(sid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
 }
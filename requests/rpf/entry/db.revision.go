@@ -0,0 +1,212 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/query"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreObjectKeepRevision Keep Current (Stored) Object Value, to Save as Revision after Update
+func StoreObjectKeepRevision(r rpf.GINProcessor, c *gin.Context) {
+	o := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Does the Object have a Value (i.e. Folders Don't)?
+	rev, e := orm.NewStoreObjectRevision(o)
+	if e == nil { // YES
+		r.SetLocal("store-object-previous", rev)
+	}
+}
+
+func DBStoreObjectSaveRevision(r rpf.GINProcessor, c *gin.Context) {
+	// Do we have a Previous Value?
+	if !r.Has("store-object-previous") { // NO: Nothing to Save
+		return
+	}
+
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	rev := r.MustGet("store-object-previous").(*orm.StoreObjectRevision)
+	count := r.MustGet("store-revisions-count").(uint16)
+	age := r.MustGet("store-revisions-age").(uint16)
+
+	// Does the Store Keep Revisions?
+	if count == 0 { // NO
+		return
+	}
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Save Revision
+	e = rev.Flush(db)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Apply Store Retention Policy
+	e = orm.StoreObjectRevisionsPrune(db, rev.Store(), rev.Object(), count, age)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+}
+
+func DBStoreObjectRevisionsList(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// List Object Revisions
+	q := r.MustGet("query-conditions").(*query.QueryConditions)
+	revs, e := orm.QueryStoreObjectRevisions(db, o.Store(), o.ID(), q, true)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Decrypt Sealed Titles
+	skey := r.MustGet("store-key").([]byte)
+	for _, v := range revs.Items() {
+		e = v.(*orm.StoreObjectRevision).OpenTitle(skey)
+		if e != nil {
+			r.Abort(4205, nil)
+			return
+		}
+	}
+
+	// Save List
+	r.Set("store-object-revisions", revs)
+}
+
+func DBStoreObjectRevisionGet(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	revision := r.MustGet("request-revision").(uint32)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Revision
+	rev := &orm.StoreObjectRevision{}
+	e = rev.ByKey(db, o.Store(), o.ID(), revision)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Did we find the Revision?
+	if rev.IsNew() { // NO: Revision Does not Exist
+		r.Abort(4212, nil)
+		return
+	}
+
+	r.SetLocal("store-object-revision", rev)
+}
+
+func DecryptStoreObjectRevision(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store Key and Revision to Decrypt
+	skey := r.MustGet("store-key").([]byte)
+	rev := r.MustGet("store-object-revision").(*orm.StoreObjectRevision)
+	envelope := r.MustGet("store-objects-envelope").(bool)
+
+	// Decrypt Revision (Cipher is Bound to the Object, Legacy Ciphers Refused once the Store has been Upgraded)
+	ot := &orm.StoreTemplateObject{}
+	var e error
+	if envelope {
+		e = ot.DecryptEnvelope(skey, rev.Store(), rev.Object(), rev.Cipher())
+	} else {
+		e = ot.DecryptObject(skey, rev.Store(), rev.Object(), rev.Cipher())
+	}
+	if e == nil { // Decrypt Title (if Sealed)
+		e = rev.OpenTitle(skey)
+	}
+
+	if e != nil {
+		r.Abort(4205, nil)
+		return
+	}
+
+	// Upgrade Legacy (Pre-Envelope) Cipher (NOTE: Revision is still Readable if it Fails)
+	if ot.IsLegacyCipher() {
+		upgradeStoreObjectRevisionCipher(r, c, rev, ot)
+	}
+
+	r.SetLocal("store-template-object", ot)
+}
+
+// upgradeStoreObjectRevisionCipher Re-Encrypt Revision Decrypted from a Legacy (Pre-Envelope) Cipher
+func upgradeStoreObjectRevisionCipher(r rpf.GINProcessor, c *gin.Context, rev *orm.StoreObjectRevision, ot *orm.StoreTemplateObject) {
+	sid := r.MustGet("request-store").(uint64)
+	skey := r.MustGet("store-key").([]byte)
+
+	// Encrypt Revision in Envelope
+	ebs, e := ot.EncryptObject(skey, rev.Store(), rev.Object())
+	if e == nil {
+		// Get Database Connection Manager
+		dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+		// Get Connection to Store Shard
+		var db *sql.DB
+		db, e = dbm.Connect(sid)
+		if e == nil {
+			e = orm.StoreObjectRevisionUpdateCipher(db, rev.Store(), rev.Object(), rev.Revision(), ebs)
+		}
+	}
+
+	if e != nil {
+		log.Printf("revision [%x:%x:%d] cipher upgrade failed: %v\n", sid, rev.Object(), rev.Revision(), e)
+	}
+}
+
+// RestoreStoreObjectRevision Replace Object Value with Revision (Decrypted) Value
+func RestoreStoreObjectRevision(r rpf.GINProcessor, c *gin.Context) {
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	rev := r.MustGet("store-object-revision").(*orm.StoreObjectRevision)
+
+	// Restore Title (Value is Re-Encrypted with Current Store Key)
+	o.SetTitle(rev.Title())
+	EncryptStoreObject(r, c)
+}
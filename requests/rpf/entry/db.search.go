@@ -0,0 +1,79 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// cSpell:ignore skey
+import (
+	"log"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search Limits
+const SEARCH_MAX_TERMS = 8
+const SEARCH_DEFAULT_LIMIT = 50
+const SEARCH_MAX_LIMIT = 100
+
+// Objects Decrypted per Batch (when Building the Search Index)
+const SEARCH_BATCH_SIZE = 100
+
+func DBStoreSearch(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	terms := r.MustGet("request-search-terms").([]string)
+	limit := r.MustGet("request-search-limit").(int)
+	skey := r.MustGet("store-key").([]byte)
+	session := r.MustGet("store-session-id").(string)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Current Version of Store Contents
+	store := common.LocalIDFromID(sid)
+	version, e := orm.StoreObjectsVersion(db, store)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Do we have a Current Index for the Store Session?
+	fields := orm.StoreSearchFields()
+	index := orm.CachedStoreSearchIndex(session, skey, store, version)
+	if index == nil { // NO: Build it
+		index, e = orm.BuildStoreSearchIndex(db, store, skey, version, fields, SEARCH_BATCH_SIZE)
+		if e != nil {
+			log.Printf("search index error: %v\n", e)
+			r.Abort(4205, nil)
+			return
+		}
+
+		// Failure to Cache only Affects Following Searches
+		e = orm.CacheStoreSearchIndex(session, skey, index)
+		if e != nil {
+			log.Printf("search index error: %v\n", e)
+		}
+	}
+
+	r.SetLocal("store-search-results", index.Search(terms, fields, limit))
+}
@@ -0,0 +1,290 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// cSpell:ignore skey
+import (
+	"fmt"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Objects Decrypted per Batch (when Listing or Indexing Tags)
+const TAGS_BATCH_SIZE = 100
+
+// AssertStoreObjectTags Validate (and Normalize) Tags in Object Values
+func AssertStoreObjectTags(r rpf.GINProcessor, c *gin.Context) {
+	t := r.MustGet("store-template-object").(*orm.StoreTemplateObject)
+
+	// Do we have Values?
+	values, ok := t.Values().(map[string]interface{})
+	if !ok || values == nil { // NO: Nothing to Validate
+		return
+	}
+
+	tags, e := orm.NormalizeTags(values[orm.OBJECT_VALUE_TAGS])
+	if e == nil {
+		e = t.SetTags(tags)
+	}
+
+	if e != nil {
+		fmt.Println(e)
+		r.Abort(4218, nil)
+		return
+	}
+}
+
+// DBStoreObjectTagsIndex Update Object Tags in Store Tag Index (According to Store Policy)
+func DBStoreObjectTagsIndex(r rpf.GINProcessor, c *gin.Context) {
+	// Is the Store Tags Policy Known?
+	if !r.Has("store-tags-indexed") { // NO: Index not Modified
+		return
+	}
+
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	t := r.MustGet("store-template-object").(*orm.StoreTemplateObject)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the Store Index Tags?
+	if r.MustGet("store-tags-indexed").(bool) { // YES
+		e = orm.StoreObjectSetTags(db, o.Store(), o.ID(), t.Tags())
+	} else {
+		e = orm.StoreObjectTagsDelete(db, o.Store(), o.ID())
+	}
+
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+}
+
+// DBStoreTagsList Store Tags from Tag Index, or Decrypted Objects (if not Indexed)
+func DBStoreTagsList(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	indexed := r.MustGet("store-tags-indexed").(bool)
+
+	// Without Index, Tags can only be Read with the Store Key
+	if !indexed && !r.Has("store-key") {
+		r.Abort(4202, nil)
+		return
+	}
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the Store Index Tags?
+	var tags []orm.StoreTagCount
+	if indexed { // YES
+		tags, e = orm.QueryStoreTags(db, common.LocalIDFromID(sid))
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+	} else {
+		skey := r.MustGet("store-key").([]byte)
+		tags, e = orm.StoreObjectsTags(db, common.LocalIDFromID(sid), skey, TAGS_BATCH_SIZE, nil)
+		if e != nil {
+			fmt.Println(e)
+			r.Abort(4205, nil)
+			return
+		}
+	}
+
+	r.SetLocal("store-tags", tags)
+}
+
+// DBStoreTagsReindex Rebuild Store Tag Index (or Remove it, if the Store Policy is Disabled)
+func DBStoreTagsReindex(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	skey := r.MustGet("store-key").([]byte)
+	indexed := r.MustGet("store-tags-indexed").(bool)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Remove Current Index
+	store := common.LocalIDFromID(sid)
+	e = orm.StoreTagsDeleteAll(db, store)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the Store Index Tags?
+	if !indexed { // NO: Done
+		r.SetLocal("store-tags", []orm.StoreTagCount{})
+		return
+	}
+
+	// Index Tags of All Objects (Outside of Trash)
+	var dbe error
+	tags, e := orm.StoreObjectsTags(db, store, skey, TAGS_BATCH_SIZE, func(o *orm.StoreObject, tags []string) error {
+		if len(tags) == 0 {
+			return nil
+		}
+
+		dbe = orm.StoreObjectSetTags(db, store, o.ID(), tags)
+		return dbe
+	})
+
+	if e != nil {
+		// Database Error?
+		if dbe != nil { // YES
+			r.Abort(5100, nil)
+			return
+		}
+
+		fmt.Println(e)
+		r.Abort(4205, nil)
+		return
+	}
+
+	r.SetLocal("store-tags", tags)
+}
+
+// DBStoreObjectsTag Add Tags to Objects (Objects that Don't Exist, are in Trash or Already Tagged are Skipped)
+func DBStoreObjectsTag(r rpf.GINProcessor, c *gin.Context) {
+	tags := r.MustGet("request-tags").([]string)
+	dbStoreObjectsRetag(r, c, func(t *orm.StoreTemplateObject) (bool, error) {
+		return t.AddTags(tags)
+	})
+}
+
+// DBStoreObjectsUntag Remove Tags from Objects (Objects that Don't Exist, are in Trash or Not Tagged are Skipped)
+func DBStoreObjectsUntag(r rpf.GINProcessor, c *gin.Context) {
+	tags := r.MustGet("request-tags").([]string)
+	dbStoreObjectsRetag(r, c, func(t *orm.StoreTemplateObject) (bool, error) {
+		return t.RemoveTags(tags)
+	})
+}
+
+// dbStoreObjectsRetag Modify Object Tags (NOTE: Tag Changes don't Create Object Revisions)
+func dbStoreObjectsRetag(r rpf.GINProcessor, c *gin.Context, modify func(t *orm.StoreTemplateObject) (bool, error)) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	ids := r.MustGet("request-entry-ids").([]uint32)
+	skey := r.MustGet("store-key").([]byte)
+	indexed := r.MustGet("store-tags-indexed").(bool)
+
+	// User ID of Modifier
+	uid := r.MustGet("user-id").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	lsid := common.LocalIDFromID(sid)
+	modified := []uint32{}
+	for _, id := range ids {
+		obj := &orm.StoreObject{}
+		e = obj.ByKey(db, lsid, id)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Does the Object Exist (and is it not in Trash)?
+		if obj.IsNew() || obj.IsTrashed() || len(obj.Object()) == 0 { // NO: Skip
+			continue
+		}
+
+		// Decrypt Object
+		t := &orm.StoreTemplateObject{}
+		e = t.DecryptObject(skey, lsid, id, obj.Object())
+		if e != nil {
+			r.Abort(4205, nil)
+			return
+		}
+
+		// Did the Tags Change?
+		changed, e := modify(t)
+		if e != nil {
+			fmt.Println(e)
+			r.Abort(4218, nil)
+			return
+		}
+
+		if !changed { // NO: Skip
+			continue
+		}
+
+		// Re-Encrypt Object and Save
+		ebs, e := t.EncryptObject(skey, lsid, id)
+		if e != nil {
+			r.Abort(4998 /* TODO: ERROR [Failed to Encrypt Object] */, nil)
+			return
+		}
+
+		obj.SetObject(ebs)
+		obj.SetModifier(uid)
+		e = obj.Flush(db, true)
+		if e == orm.ErrStoreObjectModified { // Object Updated while Tagging
+			r.Abort(4219, nil)
+			return
+		}
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Update Tag Index
+		if indexed {
+			e = orm.StoreObjectSetTags(db, lsid, id, t.Tags())
+			if e != nil { // YES: Database Error
+				r.Abort(5100, nil)
+				return
+			}
+		}
+
+		modified = append(modified, id)
+	}
+
+	r.SetLocal("store-object-ids", modified)
+}
@@ -0,0 +1,171 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/query"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func DBStoreTrashList(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// List Trash Entries
+	q := r.MustGet("query-conditions").(*query.QueryConditions)
+	objs, e := orm.QueryStoreTrash(db, common.LocalIDFromID(sid), q, true)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Decrypt Sealed Titles
+	skey := r.MustGet("store-key").([]byte)
+	for _, v := range objs.Items() {
+		e = v.(*orm.StoreObject).OpenTitle(skey)
+		if e != nil {
+			r.Abort(4205, nil)
+			return
+		}
+	}
+
+	// Save List
+	r.Set("store-objects", objs)
+}
+
+func DBStoreTrashGetByID(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	oid := r.MustGet("request-entry-id").(uint32)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Object
+	obj := &orm.StoreObject{}
+	e = obj.ByKey(db, common.LocalIDFromID(sid), oid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Is the Object a Trash Entry?
+	if obj.IsNew() || obj.Trash() != oid { // NO
+		r.Abort(4213, nil)
+		return
+	}
+
+	r.SetLocal("store-object", obj)
+
+	// Decrypt Title (if Sealed)
+	OpenStoreObjectTitle(r, c)
+}
+
+func DBStoreTrashRestore(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	obj := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Restore Object (and Folder Contents)
+	lsid := common.LocalIDFromID(sid)
+	e = orm.StoreObjectRestore(db, lsid, obj.ID())
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Reload Restored Object
+	e = obj.ByKey(db, lsid, obj.ID())
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+}
+
+func DBStoreTrashPurge(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	obj := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Permanently Delete Object (and Folder Contents)
+	e = orm.StoreObjectPurge(db, common.LocalIDFromID(sid), obj.ID())
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+}
+
+func DBStoreTrashPurgeAll(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Permanently Delete All Objects in Trash
+	count, e := orm.StoreTrashPurge(db, common.LocalIDFromID(sid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	r.SetLocal("purged-objects", count)
+}
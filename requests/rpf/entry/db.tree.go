@@ -0,0 +1,137 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreTreeNode Folder (or Leaf Object) in Store Tree
+type StoreTreeNode struct {
+	Object   *orm.StoreObject // Object (nil for Store Root Folder)
+	Folders  uint32           // Number of Sub-Folders
+	Objects  uint32           // Number of Leaf Objects
+	Children []*StoreTreeNode // Children (nil if Beyond Depth Limit)
+}
+
+func DBStoreObjectTree(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	depth := r.MustGet("request-tree-depth").(uint16)
+	leaves := r.MustGet("request-tree-objects").(bool)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get All Store Objects (Outside of Trash)
+	objs, e := orm.QueryStoreObjects(db, common.LocalIDFromID(sid), "")
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Decrypt Sealed Titles (if the Store is Open)
+	if r.Has("store-key") {
+		skey := r.MustGet("store-key").([]byte)
+		for i := range objs {
+			e = objs[i].OpenTitle(skey)
+			if e != nil {
+				r.Abort(4205, nil)
+				return
+			}
+		}
+	}
+
+	// Sort by Title (Stable Order for Display and ETag)
+	sort.Slice(objs, func(i, j int) bool {
+		ti, tj := strings.ToLower(objs[i].Title()), strings.ToLower(objs[j].Title())
+		if ti != tj {
+			return ti < tj
+		}
+		return objs[i].ID() < objs[j].ID()
+	})
+
+	// Group Objects by Parent Folder
+	children := map[uint32][]*orm.StoreObject{}
+	for i := range objs {
+		o := &objs[i]
+		children[o.Parent()] = append(children[o.Parent()], o)
+	}
+
+	// Build Tree from Root Folder
+	root := buildStoreTreeNode(nil, children, depth, leaves)
+
+	// Entity Tag Depends on Tree Contents and Options
+	var content strings.Builder
+	fmt.Fprintf(&content, "depth=%d;objects=%t\n", depth, leaves)
+	for _, o := range objs {
+		fmt.Fprintf(&content, "%d;%d;%d;%s\n", o.ID(), o.Parent(), o.Type(), o.Title())
+	}
+
+	r.SetLocal("store-tree", root)
+	r.SetLocal("response-etag", shared.ETag([]byte(content.String())))
+}
+
+func buildStoreTreeNode(o *orm.StoreObject, children map[uint32][]*orm.StoreObject, depth uint16, leaves bool) *StoreTreeNode {
+	n := &StoreTreeNode{Object: o}
+
+	var id uint32
+	if o != nil {
+		id = o.ID()
+	}
+
+	// Count Folder Contents
+	for _, child := range children[id] {
+		if child.Type() == orm.OBJECT_TYPE_FOLDER {
+			n.Folders++
+		} else {
+			n.Objects++
+		}
+	}
+
+	// Have we Reached the Depth Limit (0 - No Limit)?
+	if depth == 1 { // YES: Don't Expand Children
+		return n
+	}
+
+	if depth > 1 {
+		depth--
+	}
+
+	n.Children = []*StoreTreeNode{}
+	for _, child := range children[id] {
+		if child.Type() == orm.OBJECT_TYPE_FOLDER {
+			n.Children = append(n.Children, buildStoreTreeNode(child, children, depth, leaves))
+		} else if leaves {
+			n.Children = append(n.Children, &StoreTreeNode{Object: child})
+		}
+	}
+
+	return n
+}
@@ -13,6 +13,9 @@ package entry
 
 // cSpell:ignore skey
 import (
+	"database/sql"
+	"log"
+
 	"github.com/objectvault/api-services/orm"
 
 	rpf "github.com/objectvault/goginrpf"
@@ -24,10 +27,16 @@ func DecryptStoreObject(r rpf.GINProcessor, c *gin.Context) {
 	// Get Store Key and Object to Decrypt
 	skey := r.MustGet("store-key").([]byte)
 	o := r.MustGet("store-object").(*orm.StoreObject)
+	envelope := r.MustGet("store-objects-envelope").(bool)
 
-	// Decrypt Object
+	// Decrypt Object (Legacy Ciphers Refused, once the Store has been Upgraded)
 	ot := &orm.StoreTemplateObject{}
-	e := ot.DecryptObject(skey, o.Object())
+	var e error
+	if envelope {
+		e = ot.DecryptEnvelope(skey, o.Store(), o.ID(), o.Object())
+	} else {
+		e = ot.DecryptObject(skey, o.Store(), o.ID(), o.Object())
+	}
 	if e != nil {
 		r.Abort(4998 /* TODO: ERROR [Invalid Store Key] */, nil)
 		return
@@ -36,16 +45,92 @@ func DecryptStoreObject(r rpf.GINProcessor, c *gin.Context) {
 	r.SetLocal("store-template-object", ot)
 }
 
+// UpgradeStoreObjectCipher Re-Encrypt Object Decrypted from a Legacy (Pre-Envelope) Cipher
+func UpgradeStoreObjectCipher(r rpf.GINProcessor, c *gin.Context) {
+	ot := r.MustGet("store-template-object").(*orm.StoreTemplateObject)
+
+	// Is the Object Cipher Current?
+	if !ot.IsLegacyCipher() { // YES: Nothing to Do
+		return
+	}
+
+	sid := r.MustGet("request-store").(uint64)
+	skey := r.MustGet("store-key").([]byte)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Encrypt Object in Envelope
+	ebs, e := ot.EncryptObject(skey, o.Store(), o.ID())
+	if e == nil {
+		// Get Database Connection Manager
+		dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+		// Get Connection to Store Shard
+		var db *sql.DB
+		db, e = dbm.Connect(sid)
+		if e == nil {
+			e = orm.StoreObjectUpdateCipher(db, o.Store(), o.ID(), ebs)
+		}
+	}
+
+	// NOTE: Object is still Readable, Upgrade is Retried on Next Read
+	if e != nil {
+		log.Printf("object [%x:%x] cipher upgrade failed: %v\n", sid, o.ID(), e)
+		return
+	}
+
+	o.SetObject(ebs)
+}
+
 func EncryptStoreObject(r rpf.GINProcessor, c *gin.Context) {
 	o := r.MustGet("store-object").(*orm.StoreObject)
 	ot := r.MustGet("store-template-object").(*orm.StoreTemplateObject)
 	skey := r.MustGet("store-key").([]byte)
 
-	// Encrypt Object
-	ebs, e := ot.EncryptObject(skey)
+	// Encrypt Object (Bound to Store and Object ID)
+	ebs, e := ot.EncryptObject(skey, o.Store(), o.ID())
 	if e != nil {
 		r.Abort(4998 /* TODO: ERROR [Failed to Encrypt Object] */, nil)
+		return
 	}
 
 	o.SetObject(ebs)
 }
+
+// OpenStoreObjectTitle Decrypt Sealed Object Title (if the Store Key is Available)
+func OpenStoreObjectTitle(r rpf.GINProcessor, c *gin.Context) {
+	// Do we have the Store Key?
+	if !r.Has("store-key") { // NO: Title Stays Sealed
+		return
+	}
+
+	skey := r.MustGet("store-key").([]byte)
+	o := r.MustGet("store-object").(*orm.StoreObject)
+
+	// Decrypt Title
+	e := o.OpenTitle(skey)
+	if e != nil {
+		r.Abort(4205, nil)
+		return
+	}
+}
+
+// SealStoreObjectTitle Store Object Title Sealed, or Plaintext, According to Store Policy
+func SealStoreObjectTitle(r rpf.GINProcessor, c *gin.Context) {
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	sealed := r.MustGet("store-titles-sealed").(bool)
+
+	// Does the Store Policy Require Sealed Titles?
+	if sealed { // YES: Encrypt Title
+		skey := r.MustGet("store-key").([]byte)
+		e := o.SealTitle(skey)
+		if e != nil {
+			r.Abort(4998 /* TODO: ERROR [Failed to Encrypt Title] */, nil)
+		}
+		return
+	}
+
+	// Policy Disabled: Opened Titles are Saved as Plaintext
+	if o.HasSealedTitle() && o.Title() != "" {
+		o.SetTitle(o.Title())
+	}
+}
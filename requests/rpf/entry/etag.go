@@ -0,0 +1,104 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package entry
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// cSpell:ignore skey
+import (
+	"encoding/binary"
+
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/shared"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* NOTE: Object Entity Tags
+ * The entity tag of an object is derived from its stored version: every save
+ * encrypts the object with a new nonce, so the cipher changes with every
+ * update (even if the values are the same), and moves change the parent.
+ * This avoids depending on the 'modified' timestamp (one second resolution),
+ * and allows the new entity tag to be returned by updates without re-reading
+ * the object.
+ * The update itself is conditional on the cipher that was checked, so an
+ * update saved between the check and the write also fails (412).
+ */
+
+// StoreObjectETag Entity Tag for Stored Object Version
+func StoreObjectETag(o *orm.StoreObject) string {
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:4], o.Store())
+	binary.BigEndian.PutUint32(header[4:8], o.ID())
+	binary.BigEndian.PutUint32(header[8:12], o.Parent())
+	header[12] = o.Type()
+
+	return shared.ETag(append(header, o.Object()...))
+}
+
+// SetStoreObjectETag Set Response 'ETag' for Store Object
+func SetStoreObjectETag(r rpf.GINProcessor, c *gin.Context) {
+	o := r.MustGet("store-object").(*orm.StoreObject)
+
+	etag := StoreObjectETag(o)
+	r.SetLocal("response-etag", etag)
+	c.Header("ETag", etag)
+}
+
+// AssertStoreObjectUnmodified Object has not Changed since the Client Read it ('If-Match', Required by Store Policy)
+func AssertStoreObjectUnmodified(r rpf.GINProcessor, c *gin.Context) {
+	o := r.MustGet("store-object").(*orm.StoreObject)
+	required := r.MustGet("store-if-match-required").(bool)
+
+	// Did the Client Send the Object Version?
+	header := c.GetHeader("If-Match")
+	if header == "" { // NO
+		if required {
+			r.Abort(4220, nil)
+		}
+		return
+	}
+
+	// Is the Client's Version Current?
+	etag := StoreObjectETag(o)
+	if shared.ETagMatches(header, etag) { // YES
+		return
+	}
+
+	// Conflict: Return Current Version
+	c.Header("ETag", etag)
+	data := gin.H{"etag": etag}
+
+	// Do we have the Current Values?
+	t, ok := r.Get("store-template-object").(*orm.StoreTemplateObject)
+	if !ok && r.Has("store-key") && len(o.Object()) > 0 { // NO: Decrypt
+		t = &orm.StoreTemplateObject{}
+		if t.DecryptObject(r.MustGet("store-key").([]byte), o.Store(), o.ID(), o.Object()) == nil {
+			ok = true
+		}
+	}
+
+	if ok {
+		data["object"] = &FullStoreObjectToJSON{
+			Store:    r.MustGet("request-store").(uint64),
+			Object:   o,
+			Template: t,
+		}
+	} else {
+		data["object"] = &BasicStoreObjectToJSON{
+			Store:  r.MustGet("request-store").(uint64),
+			Object: o,
+		}
+	}
+
+	r.Abort(4219, &data)
+}
@@ -12,6 +12,8 @@ package entry
  */
 
 import (
+	"fmt"
+
 	"github.com/objectvault/api-services/orm"
 	"github.com/objectvault/api-services/orm/query"
 	"github.com/objectvault/api-services/requests/rpf/shared"
@@ -99,3 +101,159 @@ func ExportStoreObjectJSON(r rpf.GINProcessor, c *gin.Context) {
 
 	r.SetResponseDataValue("object", d)
 }
+
+// REVISIONS //
+func ExportStoreObjectRevisions(r rpf.GINProcessor, c *gin.Context) {
+	// Get List
+	sid := r.MustGet("request-store").(uint64)
+	revs := r.Get("store-object-revisions").(query.TQueryResults)
+
+	ores := &shared.ExportList{
+		List: revs,
+		ValueMapper: func(v interface{}) interface{} {
+			return &StoreObjectRevisionToJSON{
+				Store:    sid,
+				Revision: v.(*orm.StoreObjectRevision),
+			}
+		},
+		FieldMapper: func(f string) string {
+			switch f {
+			case "revision":
+				return "revision"
+			default:
+				return ""
+			}
+		},
+	}
+
+	r.SetResponseDataValue("revisions", ores)
+}
+
+func ExportStoreObjectRevision(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	sid := r.MustGet("request-store").(uint64)
+	rev := r.MustGet("store-object-revision").(*orm.StoreObjectRevision)
+	t := r.MustGet("store-template-object").(*orm.StoreTemplateObject)
+
+	// Transform for Export
+	d := &FullStoreObjectRevisionToJSON{
+		Store:    sid,
+		Revision: rev,
+		Template: t,
+	}
+
+	r.SetResponseDataValue("revision", d)
+}
+
+func ExportStoreObjectIDs(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	ids := r.MustGet("store-object-ids").([]uint32)
+
+	// Transform for Export
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = fmt.Sprintf(":%x", id)
+	}
+
+	r.SetResponseDataValue("objects", list)
+}
+
+// TRASH //
+func ExportStoreTrashList(r rpf.GINProcessor, c *gin.Context) {
+	// Get List
+	sid := r.MustGet("request-store").(uint64)
+	objs := r.Get("store-objects").(query.TQueryResults)
+
+	ores := &shared.ExportList{
+		List: objs,
+		ValueMapper: func(v interface{}) interface{} {
+			return &TrashStoreObjectToJSON{
+				Store:  sid,
+				Object: v.(*orm.StoreObject),
+			}
+		},
+		FieldMapper: func(f string) string {
+			switch f {
+			case "deleted":
+				return "deleted_at"
+			default:
+				return ""
+			}
+		},
+	}
+
+	r.SetResponseDataValue("trash", ores)
+}
+
+// TREE //
+func ExportStoreTree(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	sid := r.MustGet("request-store").(uint64)
+	root := r.MustGet("store-tree").(*StoreTreeNode)
+
+	r.SetResponseDataValue("tree", &StoreTreeToJSON{
+		Store: sid,
+		Node:  root,
+	})
+}
+
+// ATTACHMENTS //
+func ExportStoreObjectAttachment(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	sid := r.MustGet("request-store").(uint64)
+	a := r.MustGet("store-object-attachment").(*orm.StoreObjectAttachment)
+
+	r.SetResponseDataValue("attachment", &StoreObjectAttachmentToJSON{
+		Store:      sid,
+		Attachment: a,
+	})
+}
+
+// SEARCH //
+func ExportStoreSearchResults(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	sid := r.MustGet("request-store").(uint64)
+	results := r.MustGet("store-search-results").([]orm.StoreSearchResult)
+
+	list := make([]*StoreSearchResultToJSON, len(results))
+	for i := range results {
+		list[i] = &StoreSearchResultToJSON{
+			Store:  sid,
+			Result: &results[i],
+		}
+	}
+
+	r.SetResponseDataValue("results", list)
+}
+
+// TAGS //
+func ExportStoreTags(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	tags := r.MustGet("store-tags").([]orm.StoreTagCount)
+
+	list := make([]*StoreTagToJSON, len(tags))
+	for i := range tags {
+		list[i] = &StoreTagToJSON{Tag: &tags[i]}
+	}
+
+	r.SetResponseDataValue("tags", list)
+}
+
+// IMPORT //
+func ExportStoreImportReport(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	summary := r.MustGet("store-import-summary").(*StoreImportSummary)
+	reports := r.MustGet("store-import-report").([]*StoreImportReport)
+
+	r.SetResponseDataValue("summary", summary)
+	r.SetResponseDataValue("report", reports)
+}
+
+func ExportStoreRestoreReport(r rpf.GINProcessor, c *gin.Context) {
+	// Get Required Information
+	summary := r.MustGet("store-restore-summary").(*StoreRestoreSummary)
+	reports := r.MustGet("store-restore-report").([]*StoreRestoreReport)
+
+	r.SetResponseDataValue("summary", summary)
+	r.SetResponseDataValue("report", reports)
+}
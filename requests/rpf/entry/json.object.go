@@ -12,9 +12,11 @@ package entry
  */
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/objectvault/api-services/orm"
 )
@@ -35,12 +37,14 @@ func (o *BasicStoreObjectToJSON) MarshalJSON() ([]byte, error) {
 		ID     string `json:"id"`
 		Parent string `json:"parent"`
 		Title  string `json:"title"`
+		Sealed bool   `json:"sealed,omitempty"` // Title Sealed (Store not Open)
 		Type   uint8  `json:"type"`
 	}{
 		Store:  fmt.Sprintf(":%x", o.Store),
 		ID:     fmt.Sprintf(":%x", o.Object.ID()),
 		Parent: fmt.Sprintf(":%x", o.Object.Parent()),
 		Title:  o.Object.Title(),
+		Sealed: o.Object.HasSealedTitle() && o.Object.Title() == "",
 		Type:   o.Object.Type(),
 	})
 }
@@ -119,3 +123,262 @@ func (o *StoreFolderObjectToJSON) MarshalJSON() ([]byte, error) {
 		Title:  o.Object.Title(),
 	})
 }
+
+type StoreObjectRevisionToJSON struct {
+	Store    uint64 // Store SHARD ID
+	Revision *orm.StoreObjectRevision
+}
+
+// Store Object Revision JSON Export
+func (o *StoreObjectRevisionToJSON) MarshalJSON() ([]byte, error) {
+	if o.Revision == nil {
+		return nil, errors.New("Missing or Invalid Value [Revision]")
+	}
+
+	return json.Marshal(&struct {
+		Store    string     `json:"store"`
+		Object   string     `json:"object"`
+		Revision uint32     `json:"revision"`
+		Title    string     `json:"title"`
+		Modifier string     `json:"modifier"`
+		Created  *time.Time `json:"created,omitempty"`
+	}{
+		Store:    fmt.Sprintf(":%x", o.Store),
+		Object:   fmt.Sprintf(":%x", o.Revision.Object()),
+		Revision: o.Revision.Revision(),
+		Title:    o.Revision.Title(),
+		Modifier: fmt.Sprintf(":%x", o.Revision.Modifier()),
+		Created:  o.Revision.Created(),
+	})
+}
+
+type FullStoreObjectRevisionToJSON struct {
+	Store    uint64 // Store SHARD ID
+	Revision *orm.StoreObjectRevision
+	Template *orm.StoreTemplateObject
+}
+
+// Store Object Revision JSON Export (with Decrypted Values)
+func (o *FullStoreObjectRevisionToJSON) MarshalJSON() ([]byte, error) {
+	if o.Revision == nil || o.Template == nil {
+		return nil, errors.New("Missing or Invalid Value [Revision, Template]")
+	}
+
+	// Template Information
+	template := &struct {
+		Name    string `json:"name"`
+		Version uint16 `json:"version"`
+	}{
+		Name:    o.Template.Template(),
+		Version: o.Template.Version(),
+	}
+
+	return json.Marshal(&struct {
+		Store    string      `json:"store"`
+		Object   string      `json:"object"`
+		Revision uint32      `json:"revision"`
+		Title    string      `json:"title"`
+		Modifier string      `json:"modifier"`
+		Created  *time.Time  `json:"created,omitempty"`
+		Template interface{} `json:"template"`
+		Values   interface{} `json:"values"`
+	}{
+		Store:    fmt.Sprintf(":%x", o.Store),
+		Object:   fmt.Sprintf(":%x", o.Revision.Object()),
+		Revision: o.Revision.Revision(),
+		Title:    o.Revision.Title(),
+		Modifier: fmt.Sprintf(":%x", o.Revision.Modifier()),
+		Created:  o.Revision.Created(),
+		Template: template,
+		Values:   o.Template.Values(),
+	})
+}
+
+type TrashStoreObjectToJSON struct {
+	Store  uint64 // Store SHARD ID
+	Object *orm.StoreObject
+}
+
+// Store Trash Entry JSON Export
+func (o *TrashStoreObjectToJSON) MarshalJSON() ([]byte, error) {
+	if o.Object == nil || !o.Object.IsTrashed() {
+		return nil, errors.New("Missing or Invalid Value [Object]")
+	}
+
+	deleter := ""
+	if o.Object.Deleter() != nil {
+		deleter = fmt.Sprintf(":%x", *o.Object.Deleter())
+	}
+
+	return json.Marshal(&struct {
+		Store   string     `json:"store"`
+		ID      string     `json:"id"`
+		Parent  string     `json:"parent"`
+		Title   string     `json:"title"`
+		Type    uint8      `json:"type"`
+		Deleter string     `json:"deleted_by,omitempty"`
+		Deleted *time.Time `json:"deleted_at,omitempty"`
+		Purge   *time.Time `json:"purge_at,omitempty"`
+	}{
+		Store:   fmt.Sprintf(":%x", o.Store),
+		ID:      fmt.Sprintf(":%x", o.Object.ID()),
+		Parent:  fmt.Sprintf(":%x", o.Object.Parent()),
+		Title:   o.Object.Title(),
+		Type:    o.Object.Type(),
+		Deleter: deleter,
+		Deleted: o.Object.Deleted(),
+		Purge:   o.Object.Purge(),
+	})
+}
+
+type StoreTreeToJSON struct {
+	Store uint64 // Store SHARD ID
+	Node  *StoreTreeNode
+}
+
+// Store Tree (Node) JSON Export
+func (o *StoreTreeToJSON) MarshalJSON() ([]byte, error) {
+	if o.Node == nil {
+		return nil, errors.New("Missing or Invalid Value [Node]")
+	}
+
+	// Export Children
+	var children []*StoreTreeToJSON
+	if o.Node.Children != nil {
+		children = make([]*StoreTreeToJSON, len(o.Node.Children))
+		for i, child := range o.Node.Children {
+			children[i] = &StoreTreeToJSON{Store: o.Store, Node: child}
+		}
+	}
+
+	// Is Store Root Folder?
+	if o.Node.Object == nil { // YES
+		return json.Marshal(&struct {
+			Store    string             `json:"store"`
+			ID       string             `json:"id"`
+			Folders  uint32             `json:"folders"`
+			Objects  uint32             `json:"objects"`
+			Children []*StoreTreeToJSON `json:"children,omitempty"`
+		}{
+			Store:    fmt.Sprintf(":%x", o.Store),
+			ID:       ":0",
+			Folders:  o.Node.Folders,
+			Objects:  o.Node.Objects,
+			Children: children,
+		})
+	}
+
+	// Is Leaf Object?
+	if o.Node.Object.Type() != orm.OBJECT_TYPE_FOLDER { // YES
+		return json.Marshal(&struct {
+			ID     string `json:"id"`
+			Title  string `json:"title"`
+			Sealed bool   `json:"sealed,omitempty"` // Title Sealed (Store not Open)
+			Type   uint8  `json:"type"`
+		}{
+			ID:     fmt.Sprintf(":%x", o.Node.Object.ID()),
+			Title:  o.Node.Object.Title(),
+			Sealed: o.Node.Object.HasSealedTitle() && o.Node.Object.Title() == "",
+			Type:   o.Node.Object.Type(),
+		})
+	}
+
+	return json.Marshal(&struct {
+		ID       string             `json:"id"`
+		Title    string             `json:"title"`
+		Sealed   bool               `json:"sealed,omitempty"` // Title Sealed (Store not Open)
+		Type     uint8              `json:"type"`
+		Folders  uint32             `json:"folders"`
+		Objects  uint32             `json:"objects"`
+		Children []*StoreTreeToJSON `json:"children,omitempty"`
+	}{
+		ID:       fmt.Sprintf(":%x", o.Node.Object.ID()),
+		Title:    o.Node.Object.Title(),
+		Sealed:   o.Node.Object.HasSealedTitle() && o.Node.Object.Title() == "",
+		Type:     o.Node.Object.Type(),
+		Folders:  o.Node.Folders,
+		Objects:  o.Node.Objects,
+		Children: children,
+	})
+}
+
+type StoreObjectAttachmentToJSON struct {
+	Store      uint64 // Store SHARD ID
+	Attachment *orm.StoreObjectAttachment
+}
+
+// Store Object Attachment (Manifest) JSON Export
+func (o *StoreObjectAttachmentToJSON) MarshalJSON() ([]byte, error) {
+	if o.Attachment == nil {
+		return nil, errors.New("Missing or Invalid Value [Attachment]")
+	}
+
+	return json.Marshal(&struct {
+		Store  string `json:"store"`
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Type   string `json:"type"`
+		Size   uint64 `json:"size"`
+		Chunks uint32 `json:"chunks"`
+		Digest string `json:"sha256"`
+	}{
+		Store:  fmt.Sprintf(":%x", o.Store),
+		ID:     fmt.Sprintf(":%x", o.Attachment.Object()),
+		Name:   o.Attachment.Name(),
+		Type:   o.Attachment.ContentType(),
+		Size:   o.Attachment.Size(),
+		Chunks: o.Attachment.Chunks(),
+		Digest: hex.EncodeToString(o.Attachment.Digest()),
+	})
+}
+
+type StoreSearchResultToJSON struct {
+	Store  uint64 // Store SHARD ID
+	Result *orm.StoreSearchResult
+}
+
+// Store Search Result JSON Export
+func (o *StoreSearchResultToJSON) MarshalJSON() ([]byte, error) {
+	if o.Result == nil || o.Result.Entry == nil {
+		return nil, errors.New("Missing or Invalid Value [Result]")
+	}
+
+	return json.Marshal(&struct {
+		Store    string   `json:"store"`
+		ID       string   `json:"id"`
+		Parent   string   `json:"parent"`
+		Title    string   `json:"title"`
+		Type     uint8    `json:"type"`
+		Template string   `json:"template,omitempty"`
+		Score    uint32   `json:"score"`
+		Matched  []string `json:"matched"`
+	}{
+		Store:    fmt.Sprintf(":%x", o.Store),
+		ID:       fmt.Sprintf(":%x", o.Result.Entry.ID),
+		Parent:   fmt.Sprintf(":%x", o.Result.Entry.Parent),
+		Title:    o.Result.Entry.Title,
+		Type:     o.Result.Entry.Type,
+		Template: o.Result.Entry.Template,
+		Score:    o.Result.Score,
+		Matched:  o.Result.Fields,
+	})
+}
+
+type StoreTagToJSON struct {
+	Tag *orm.StoreTagCount
+}
+
+// Store Tag JSON Export
+func (o *StoreTagToJSON) MarshalJSON() ([]byte, error) {
+	if o.Tag == nil {
+		return nil, errors.New("Missing or Invalid Value [Tag]")
+	}
+
+	return json.Marshal(&struct {
+		Tag   string `json:"tag"`
+		Count uint64 `json:"count"`
+	}{
+		Tag:   o.Tag.Tag,
+		Count: o.Tag.Count,
+	})
+}
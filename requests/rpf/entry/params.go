@@ -13,8 +13,11 @@ package entry
 
 import (
 	"fmt"
+	"math"
 	"strings"
 
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/importer"
 	"github.com/objectvault/api-services/requests/rpf/utils"
 	rpf "github.com/objectvault/goginrpf"
 
@@ -44,6 +47,38 @@ func ExtractGINParameterEntryID(r rpf.GINProcessor, c *gin.Context) {
 	r.SetLocal("request-entry-id", uint32(*id))
 }
 
+// ExtractGINParameterParentAsEntryID Object ID from the 'parent' Parameter (Routes that Share the ':parent' Wildcard)
+func ExtractGINParameterParentAsEntryID(r rpf.GINProcessor, c *gin.Context) {
+	ExtractGINParameterParentID(r, c)
+	if !r.IsFinished() {
+		r.SetLocal("request-entry-id", r.MustGet("request-parent-id"))
+	}
+}
+
+func ExtractGINParameterRevision(r rpf.GINProcessor, c *gin.Context) {
+	// Initial Post Parameter Tests
+	v, message := utils.ValidateGinParameter(c, "rev", true, true, false)
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3100, nil)
+		return
+	}
+
+	// See if it is valid
+	rev, message := utils.ValidateUintParameter("rev", v, false)
+	if message == "" && (*rev == 0 || *rev > math.MaxUint32) {
+		message = "Parameter 'rev' is not a valid revision"
+	}
+
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3100, nil)
+		return
+	}
+
+	r.SetLocal("request-revision", uint32(*rev))
+}
+
 func ExtractGINParameterParentID(r rpf.GINProcessor, c *gin.Context) {
 	// Initial Post Parameter Tests
 	v, message := utils.ValidateGinParameter(c, "parent", true, true, false)
@@ -78,3 +113,229 @@ func ExtractURLParameterTitle(r rpf.GINProcessor, c *gin.Context) {
 
 	r.SetLocal("request-entry-title", v)
 }
+
+// ExtractJSONEntryIDs Object IDs from JSON Body ({ "objects": [ ":<hex id>" | <id>, ... ] })
+func ExtractJSONEntryIDs(r rpf.GINProcessor, c *gin.Context) {
+	m := r.MustGet("request-json").(map[string]interface{})
+
+	// Do we have a List of Objects?
+	list, ok := m["objects"].([]interface{})
+	if !ok || len(list) == 0 { // NO
+		r.Abort(3100, nil)
+		return
+	}
+
+	ids := make([]uint32, 0, len(list))
+	for _, v := range list {
+		// NOTE: Numbers Imported from JSON are float64
+		if n, ok := v.(float64); ok {
+			v = fmt.Sprintf("%d", int64(n))
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			r.Abort(3100, nil)
+			return
+		}
+
+		// See if it is valid
+		id, message := utils.ValidateObjectID("objects", strings.TrimSpace(s))
+		if message == "" && (*id == 0 || *id > math.MaxUint32) {
+			message = "Parameter 'objects' contains an invalid object"
+		}
+
+		if message != "" {
+			fmt.Println(message)
+			r.Abort(3100, nil)
+			return
+		}
+
+		ids = append(ids, uint32(*id))
+	}
+
+	r.SetLocal("request-entry-ids", ids)
+}
+
+// ExtractURLParameterTargetStore Optional Target Store ('store' Query Parameter) for Copy Requests
+func ExtractURLParameterTargetStore(r rpf.GINProcessor, c *gin.Context) {
+	// Initial Post Parameter Tests
+	v, message := utils.ValidateURLParameter(c, "store", false, true, false)
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+
+	// Target Store Given?
+	if v == "" { // NO: Use Request Store
+		return
+	}
+
+	// See if it is valid
+	id, message := utils.ValidateStoreID(strings.ToLower(v))
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+
+	// Same as Request Store?
+	if id.(uint64) == r.MustGet("request-store").(uint64) { // YES: Ignore
+		return
+	}
+
+	r.SetLocal("request-target-store", id)
+}
+
+// ExtractURLParameterTreeOptions Tree Depth ('depth', 0 - No Limit) and Include Leaf Objects ('objects')
+func ExtractURLParameterTreeOptions(r rpf.GINProcessor, c *gin.Context) {
+	// Depth Limit
+	depth := uint16(0)
+	v, message := utils.ValidateURLParameter(c, "depth", false, true, true)
+	if message == "" && v != "" {
+		var d *uint64
+		d, message = utils.ValidateUintParameter("depth", v, false)
+		if message == "" && *d > math.MaxUint16 {
+			message = "Parameter 'depth' is out of range"
+		} else if message == "" {
+			depth = uint16(*d)
+		}
+	}
+
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+	r.SetLocal("request-tree-depth", depth)
+
+	// Include Leaf Objects?
+	v, message = utils.ValidateURLParameter(c, "objects", false, true, true)
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+
+	switch strings.ToLower(v) {
+	case "", "0", "false":
+		r.SetLocal("request-tree-objects", false)
+	case "1", "true":
+		r.SetLocal("request-tree-objects", true)
+	default:
+		fmt.Println("Parameter 'objects' is not a valid boolean")
+		r.Abort(3300, nil)
+	}
+}
+
+// ExtractURLParameterAttachmentName Optional File Name ('name' Query Parameter) for Attachment Uploads
+func ExtractURLParameterAttachmentName(r rpf.GINProcessor, c *gin.Context) {
+	// Initial Post Parameter Tests
+	v, message := utils.ValidateURLParameter(c, "name", false, true, false)
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+
+	// Name Given?
+	if v != "" { // YES
+		r.SetLocal("request-attachment-name", v)
+	}
+}
+
+// ExtractURLParameterSearch Search Terms ('q') and Optional Maximum Number of Results ('limit')
+func ExtractURLParameterSearch(r rpf.GINProcessor, c *gin.Context) {
+	// Search Terms
+	v, message := utils.ValidateURLParameter(c, "q", true, true, false)
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+
+	// Terms are Matched Case Insensitive
+	terms := strings.Fields(strings.ToLower(v))
+	if len(terms) == 0 || len(terms) > SEARCH_MAX_TERMS {
+		fmt.Println("Parameter 'q' has no (or too many) terms")
+		r.Abort(3300, nil)
+		return
+	}
+	r.SetLocal("request-search-terms", terms)
+
+	// Maximum Number of Results
+	limit := SEARCH_DEFAULT_LIMIT
+	v, message = utils.ValidateURLParameter(c, "limit", false, true, true)
+	if message == "" && v != "" {
+		var l *uint64
+		l, message = utils.ValidateUintParameter("limit", v, false)
+		if message == "" && (*l == 0 || *l > SEARCH_MAX_LIMIT) {
+			message = "Parameter 'limit' is out of range"
+		} else if message == "" {
+			limit = int(*l)
+		}
+	}
+
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+	r.SetLocal("request-search-limit", limit)
+}
+
+// ExtractURLParameterTags Optional Tag Filter ('tag' Query Parameter, Repeated or Comma Separated)
+func ExtractURLParameterTags(r rpf.GINProcessor, c *gin.Context) {
+	values := c.QueryArray("tag")
+	if len(values) == 0 { // No Filter
+		return
+	}
+
+	list := []string{}
+	for _, v := range values {
+		list = append(list, strings.Split(v, ",")...)
+	}
+
+	tags, e := orm.NormalizeTags(list)
+	if e != nil {
+		fmt.Println(e)
+		r.Abort(3300, nil)
+		return
+	}
+
+	r.SetLocal("request-tags", tags)
+}
+
+// ExtractJSONTags Tags from JSON Body ({ "tags": [ "<tag>", ... ] })
+func ExtractJSONTags(r rpf.GINProcessor, c *gin.Context) {
+	m := r.MustGet("request-json").(map[string]interface{})
+
+	// Do we have a List of Tags?
+	tags, e := orm.NormalizeTags(m["tags"])
+	if e != nil || len(tags) == 0 { // NO
+		if e != nil {
+			fmt.Println(e)
+		}
+		r.Abort(4218, nil)
+		return
+	}
+
+	r.SetLocal("request-tags", tags)
+}
+
+// ExtractURLParameterImportFormat Format of Import File ('format' Query Parameter)
+func ExtractURLParameterImportFormat(r rpf.GINProcessor, c *gin.Context) {
+	// Initial Post Parameter Tests
+	v, message := utils.ValidateURLParameter(c, "format", true, true, false)
+	if message == "" && !importer.IsFormat(strings.ToLower(v)) {
+		message = "Parameter 'format' is not a supported import format"
+	}
+
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+
+	r.SetLocal("request-import-format", strings.ToLower(v))
+}
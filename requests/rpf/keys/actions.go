@@ -23,7 +23,10 @@ func KeyExtractBytes(r rpf.GINProcessor, c *gin.Context) {
 	key := r.MustGet("key-object").(*orm.Key)
 	pick := r.MustGet("key-pick").([]byte)
 
-	bytes, e := key.DecryptKey(pick)
+	// Server Key Encryption Key (if Configured)
+	kek, _ := c.MustGet("kek").(orm.KEK)
+
+	bytes, e := key.DecryptKey(kek, pick)
 	if e != nil { // ERROR: Unexpected
 		r.Abort(5900, nil)
 		return
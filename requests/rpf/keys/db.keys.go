@@ -32,8 +32,11 @@ func DBCreateKey(r rpf.GINProcessor, c *gin.Context) {
 	// Get Expiration
 	expiration := r.MustGet("key-expiration").(*time.Time)
 
+	// Server Key Encryption Key (if Configured)
+	kek, _ := c.MustGet("kek").(orm.KEK)
+
 	// Create a Key Object
-	key, k, err := orm.NewKey(user, bytes, *expiration)
+	key, k, err := orm.NewKey(kek, user, bytes, *expiration)
 	if err != nil { // ERROR: Unexpected
 		r.Abort(5900, nil)
 		return
@@ -12,6 +12,7 @@ package org
  */
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -19,12 +20,18 @@ import (
 	"github.com/objectvault/api-services/orm"
 	"github.com/objectvault/api-services/requests/rpf/utils"
 	"github.com/objectvault/api-services/xjson"
+	"github.com/objectvault/common/maps"
 
 	rpf "github.com/objectvault/goginrpf"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Organization Settings: Store Session Policy (Ceiling for Organization Stores)
+const SETTING_STORE_SESSION_IDLE = "stores.session.idle"         // POLICY: Store Session Idle Timeout in Minutes
+const SETTING_STORE_SESSION_LIFETIME = "stores.session.lifetime" // POLICY: Store Session Maximum Lifetime in Minutes
+const SETTING_STORE_SESSION_EXTEND = "stores.session.extend"     // POLICY: Can a Status Check Extend a Store Session?
+
 func CreateFromJSON(r rpf.GINProcessor, c *gin.Context) {
 	// Extract and Validat JSON Message
 	m := r.MustGet("request-json").(xjson.T_xMap)
@@ -122,4 +129,98 @@ func ManagerUpdateFromJSON(r rpf.GINProcessor, c *gin.Context) {
 		}
 		return nil
 	})
+
+	// OPTIONAL: Store Key Escrow Public Key (HEX X25519 Public Key, "" to Clear)
+	vmap.Optional("escrow_key", nil, func(v interface{}) (interface{}, error) {
+		v, e := xjson.F_xToTrimmedString(v)
+		if e != nil {
+			return nil, e
+		}
+
+		s := strings.ToLower(v.(string))
+		if s != "" && !utils.IsValidKey256(s) {
+			return nil, errors.New("Value does not contain a valid public key")
+		}
+		return s, nil
+	}, nil, func(v interface{}) error {
+		if v != nil {
+			// NOTE: Only the Public Key is Stored (Private Key is Held Offline)
+			key, _ := hex.DecodeString(v.(string))
+			_, e := o.SetEscrowKey(key)
+			return e
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Session Policy - Idle Timeout in Minutes (0 to Clear)
+	vmap.Optional("store_session_idle", nil, F_xToMinutes, nil, func(v interface{}) error {
+		if v != nil {
+			return SetSettingMinutes(o.Settings(), SETTING_STORE_SESSION_IDLE, v.(uint64))
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Session Policy - Maximum Lifetime in Minutes (0 to Clear)
+	vmap.Optional("store_session_lifetime", nil, F_xToMinutes, nil, func(v interface{}) error {
+		if v != nil {
+			return SetSettingMinutes(o.Settings(), SETTING_STORE_SESSION_LIFETIME, v.(uint64))
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Session Policy - Can a Status Check Extend a Store Session?
+	vmap.Optional("store_session_extend", nil, xjson.F_xToBoolean, nil, func(v interface{}) error {
+		if v != nil {
+			return o.Settings().Set(SETTING_STORE_SESSION_EXTEND, v.(bool), true)
+		}
+		return nil
+	})
+
+	// Did we have an Error Processing the Map?
+	if vmap.Error != nil {
+		r.Abort(5202, nil)
+		return
+	}
+}
+
+// F_xToMinutes Session Time in Minutes (0 - 65535)
+func F_xToMinutes(v interface{}) (interface{}, error) {
+	v, e := xjson.F_xToUint64(v)
+	if e != nil {
+		return nil, e
+	}
+
+	if v.(uint64) > 65535 {
+		return nil, errors.New("Value has to be between 0 and 65535")
+	}
+	return v, nil
+}
+
+// SetSettingMinutes Set Session Time in Minutes (0 Clears the Setting)
+func SetSettingMinutes(settings *maps.MapWrapper, path string, v uint64) error {
+	if v == 0 {
+		if settings.Has(path) {
+			return settings.Clear(path)
+		}
+		return nil
+	}
+	return settings.Set(path, v, true)
+}
+
+// SettingUint16 Numeric Setting (0 if not Set or Invalid)
+func SettingUint16(settings *maps.MapWrapper, path string) uint16 {
+	v, _ := settings.GetDefault(path, nil)
+
+	// NOTE: Numbers Imported from JSON are float64
+	switch n := v.(type) {
+	case float64:
+		if n >= 0 && n <= 65535 {
+			return uint16(n)
+		}
+	case uint64:
+		if n <= 65535 {
+			return uint16(n)
+		}
+	}
+	return 0
 }
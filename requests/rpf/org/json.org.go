@@ -12,6 +12,7 @@ package org
  */
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -58,12 +59,20 @@ func (o *FullOrgToJSON) MarshalJSON() ([]byte, error) {
 		Name   string `json:"name"`
 		State  uint16 `json:"state"`
 		System bool   `json:"is_system"`
+		Escrow string `json:"escrow_key,omitempty"`
+		Idle   uint16 `json:"store_session_idle,omitempty"`
+		Life   uint16 `json:"store_session_lifetime,omitempty"`
+		Extend bool   `json:"store_session_extend"`
 	}{
 		ID:     fmt.Sprintf(":%x", o.Registry.ID()),
 		Alias:  o.Organization.Alias(),
 		Name:   o.Organization.Name(),
 		State:  o.Registry.State(),
 		System: o.Organization.IsSystem(),
+		Escrow: hex.EncodeToString(o.Organization.EscrowKey()),
+		Idle:   SettingUint16(o.Organization.Settings(), SETTING_STORE_SESSION_IDLE),
+		Life:   SettingUint16(o.Organization.Settings(), SETTING_STORE_SESSION_LIFETIME),
+		Extend: settingExtend(o.Organization),
 	})
 }
 
@@ -111,3 +120,11 @@ func (o *FullRegOrgToJSON) MarshalJSON() ([]byte, error) {
 		State: o.Registry.State(),
 	})
 }
+
+func settingExtend(o *orm.Organization) bool {
+	v, _ := o.Settings().GetDefault(SETTING_STORE_SESSION_EXTEND, true)
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/objectvault/api-services/common"
 	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/user"
 )
 
 // Utilityu: Store ID to Store Session Key
@@ -29,10 +30,12 @@ func CreateStoreKey(id uint64) string {
 	return skey
 }
 
-/* TODO: PROBLEM: Currently if User Changes Password
- * All of the Stores Keys Have to be unsealed and re-sealed with
- * the new password, since it's used as the decryption key for store
- * passwords
+/* NOTE: Store Keys are Wrapped with the User's Password Hash
+ * On a Password Change, the Store Keys are re-sealed with the new password
+ * by a background action (see action.ActionCreateStoreKeysRewrap)
+ *
+ * The Store Session Policy (mode, idle timeout, lifetime...) is taken from the
+ * "store-session-*" values, if set (see store.DBStoreSessionPolicy)
  */
 func SessionStoreOpen(r rpf.GINProcessor, c *gin.Context) {
 	// Get Session Store
@@ -41,6 +44,12 @@ func SessionStoreOpen(r rpf.GINProcessor, c *gin.Context) {
 	// Get Registry Object
 	rus := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
 
+	// Store Session Mode
+	mode := common.STORE_SESSION_DEFAULT
+	if r.Has("store-session-mode") {
+		mode = r.MustGet("store-session-mode").(uint8)
+	}
+
 	// Create Session Key
 	skey := CreateStoreKey(rus.Object())
 
@@ -52,9 +61,11 @@ func SessionStoreOpen(r rpf.GINProcessor, c *gin.Context) {
 	iss := session.Get(skey)
 	if iss != nil { // YES: Import it and Validate
 
-		// TODO: Make the Store Open Configurable
-		ss, e = common.ImportStoreSession(iss.(string), 5)
-		if e != nil || ss.IsExpired() { // Not Valid: Clear Session
+		// NOTE: Default Idle Timeout only Applies to Sessions Exported without Policy
+		ss, e = common.ImportStoreSession(iss.(string), common.STORE_SESSION_IDLE_DEFAULT)
+		if e != nil || ss.IsExpired() || !common.StoreSessions().IsOpen(ss.ID()) { // Not Valid: Clear Session
+			ss = nil
+		} else if mode != common.STORE_SESSION_DEFAULT || ss.Mode() != mode { // Restricted Session: Always Re-Validate Credentials
 			ss = nil
 		} else { // Valid: Extend Session
 			ss.Extend(0)
@@ -64,19 +75,66 @@ func SessionStoreOpen(r rpf.GINProcessor, c *gin.Context) {
 	// Have Existing Session?
 	if ss == nil { // NO: Create Session
 		// Validate User Credentials
-		hash := r.MustGet("user-credentials").([]byte)
-		key, e := rus.StoreKeyBytes(hash)
+		user.DBUserPasswordKey(r, c)
+		if r.IsFinished() {
+			return
+		}
+
+		key, e := rus.StoreKey(r.MustGet("user-password-key").(*orm.PasswordKey))
 		if e != nil {
 			r.Abort(3998 /* TODO: Error Code - Invalid Credentials */, nil)
 			return
 		}
 
-		// TODO: Make the Store Open Configurable
-		ss, e = common.NewStoreSession(rus.Object(), key, 5)
+		// Store Session Idle Timeout
+		idle := common.STORE_SESSION_IDLE_DEFAULT
+		if r.Has("store-session-idle") {
+			idle = r.MustGet("store-session-idle").(uint16)
+		}
+
+		ss, e = common.NewStoreSession(rus.Object(), key, idle)
+		if e != nil {
+			r.Abort(5010 /* TODO: Failed to Create Store Session */, nil)
+			return
+		}
+
+		// Store Session Lifetime
+		if r.Has("store-session-lifetime") {
+			ss.SetLifetime(r.MustGet("store-session-lifetime").(uint16))
+		}
+
+		if r.Has("store-session-extend") {
+			ss.SetExtendOnCheck(r.MustGet("store-session-extend").(bool))
+		}
+
+		// Store Organization (Allows Closing Sessions by Organization)
+		if r.Has("store") {
+			ss.SetOrganization(r.MustGet("store").(*orm.Store).Organization())
+		}
+
+		// Set Session Mode
+		var operations uint16
+		if r.Has("store-session-operations") {
+			operations = r.MustGet("store-session-operations").(uint16)
+		}
+
+		e = ss.SetMode(mode, operations)
 		if e != nil || !ss.IsValid() {
 			r.Abort(5010 /* TODO: Failed to Create Store Session */, nil)
 			return
 		}
+
+		// Register Store Session
+		e = common.StoreSessions().Register(ss.ID(), rus.User(), rus.Object(), ss.Organization(), ss.Operations(), ss.ExpireUnix())
+		if e != nil {
+			r.Abort(5010 /* TODO: Failed to Create Store Session */, nil)
+			return
+		}
+
+		// Store Key for Request (Not Kept in Session in Transaction Mode)
+		r.SetLocal("store-key", key)
+	} else {
+		r.SetLocal("store-key", ss.Key())
 	}
 
 	r.SetLocal("store-session", ss)
@@ -90,26 +148,42 @@ func ExtendStoreSession(r rpf.GINProcessor, c *gin.Context) {
 	sid := r.MustGet("request-store").(uint64)
 	skey := CreateStoreKey(sid)
 
-	// Have Existing Store Session?
-	iss := session.Get(skey)
-	if iss == nil {
-		r.Abort(5010 /* TODO: No Existing Store Session */, nil)
-		return
+	// Store Session Validated (and Operation Counted) by Store Assert?
+	var ss *common.StoreSession
+	if r.Has("store-session") { // YES: Session Used by Request
+		ss = r.MustGet("store-session").(*common.StoreSession)
+	} else { // NO: Import Existing Store Session
+		iss := session.Get(skey)
+		if iss == nil {
+			r.Abort(5010 /* TODO: No Existing Store Session */, nil)
+			return
+		}
+
+		var e error
+		ss, e = common.ImportStoreSession(iss.(string), common.STORE_SESSION_IDLE_DEFAULT)
+		if e != nil || ss.IsExpired() { // Not Valid: Clear Session
+			r.Abort(5010 /* TODO: Failed to Create Store Session */, nil)
+			return
+		}
 	}
 
-	// TODO: Make the Store Open Configurable
-	ss, e := common.ImportStoreSession(iss.(string), 5)
-	if e != nil || ss.IsExpired() { // Not Valid: Clear Session
-		r.Abort(5010 /* TODO: Failed to Create Store Session */, nil)
+	// Has the Store Session been Closed (i.e. Store Locked)?
+	if !common.StoreSessions().IsOpen(ss.ID()) { // YES
+		session.Delete(skey)
+		r.Abort(4202, nil)
 		return
 	}
 
-	// Valid: Extend Session
+	// Valid: Extend Session (Closed on Save if the Last Operation was Used)
 	ss.Extend(0)
 
-	// Save Session and Key
+	// Save Session
 	r.SetLocal("store-session", ss)
-	r.SetLocal("store-key", ss.Key())
+
+	// Is Store Key Kept in Session?
+	if !ss.IsTransactional() { // YES: Save Key
+		r.SetLocal("store-key", ss.Key())
+	}
 }
 
 func SessionStoreSave(r rpf.GINProcessor, c *gin.Context) {
@@ -119,6 +193,19 @@ func SessionStoreSave(r rpf.GINProcessor, c *gin.Context) {
 	// Get Store Session
 	ss := r.MustGet("store-session").(*common.StoreSession)
 
+	// Have all Session Operations been Used?
+	if ss.IsExhausted() { // YES: Close Store Session
+		common.StoreSessions().Close(ss.ID())
+		session.Delete(CreateStoreKey(ss.Store()))
+		return
+	}
+
+	// Has the Store Session been Closed (i.e. Store Locked)?
+	if !common.StoreSessions().Touch(ss.ID(), ss.ExpireUnix()) { // YES: Remove it
+		session.Delete(CreateStoreKey(ss.Store()))
+		return
+	}
+
 	// Create Link between Store ID and Store Key
 	svalue, e := ss.Export()
 	if e != nil {
@@ -142,6 +229,77 @@ func SessionStoreClose(r rpf.GINProcessor, c *gin.Context) {
 	// Have Existing Store Session?
 	iss := session.Get(skey)
 	if iss != nil { // YES: Import it and Validate
+		ss, e := common.ImportStoreSession(iss.(string), common.STORE_SESSION_IDLE_DEFAULT)
+		if e == nil {
+			common.StoreSessions().Close(ss.ID())
+		}
 		session.Delete(skey)
 	}
 }
+
+// CloseUserStoreSessions Close all Open Stores of the Session User (All Clients)
+func CloseUserStoreSessions(r rpf.GINProcessor, c *gin.Context) {
+	// Get Session Store
+	session := sessions.Default(c)
+
+	// Do we have a Session User?
+	uid := session.Get("user-id")
+	if uid == nil { // NO: Nothing to Close
+		r.SetLocal("closed-stores", 0)
+		return
+	}
+
+	// NOTE: Store Session Entries in this Client's Session are Removed when Used
+	r.SetLocal("closed-stores", common.StoreSessions().CloseUser(uid.(uint64)))
+}
+
+/* NOTE: Store Session Revocation
+ * Lock (Read Only), Block, Inactive or Delete states close every matching
+ * open store session (see common.StoreSessionRegistry).
+ * The steps below are used after the state change has been saved.
+ */
+const storeSessionRevokeStates = orm.STATE_INACTIVE | orm.STATE_BLOCKED | orm.STATE_READONLY | orm.STATE_DELETE
+
+// RevokeUserStoreSessions Close all Open Stores of "registry-user" (if Locked or Blocked)
+func RevokeUserStoreSessions(r rpf.GINProcessor, c *gin.Context) {
+	registry := r.MustGet("registry-user").(*orm.UserRegistry)
+
+	if registry.HasAnyStates(storeSessionRevokeStates) {
+		common.StoreSessions().CloseUser(registry.ID())
+	}
+}
+
+// RevokeOrgStoreSessions Close all Open Stores of "registry-org" (if Locked or Blocked)
+func RevokeOrgStoreSessions(r rpf.GINProcessor, c *gin.Context) {
+	registry := r.MustGet("registry-org").(*orm.OrgRegistry)
+
+	if registry.HasAnyStates(storeSessionRevokeStates) {
+		common.StoreSessions().CloseOrg(registry.ID())
+	}
+}
+
+// RevokeStoreSessions Close all Open Sessions of "registry-store" (if Locked or Blocked)
+func RevokeStoreSessions(r rpf.GINProcessor, c *gin.Context) {
+	registry := r.MustGet("registry-store").(*orm.OrgStoreRegistry)
+
+	if registry.HasAnyStates(storeSessionRevokeStates) {
+		common.StoreSessions().CloseStore(registry.Store())
+	}
+}
+
+// RevokeObjectUserStoreSessions Close Open Stores of "registry-object-user" User in Organization or Store (if Locked or Blocked)
+func RevokeObjectUserStoreSessions(r rpf.GINProcessor, c *gin.Context) {
+	registry := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
+
+	if !registry.HasAnyStates(storeSessionRevokeStates) {
+		return
+	}
+
+	// Object Type
+	switch common.ObjectTypeFromID(registry.Object()) {
+	case common.OTYPE_ORG:
+		common.StoreSessions().CloseOrgUser(registry.Object(), registry.User())
+	case common.OTYPE_STORE:
+		common.StoreSessions().CloseStoreUser(registry.Object(), registry.User())
+	}
+}
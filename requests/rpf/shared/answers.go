@@ -40,3 +40,13 @@ func JSONResponse(r rpf.GINProcessor, c *gin.Context) {
 	// Set Request Response
 	c.JSON(httpCode, message)
 }
+
+// StreamResponse Response for Requests that Write their own Content (JSON Response Only if Nothing was Written)
+func StreamResponse(r rpf.GINProcessor, c *gin.Context) {
+	// Was Content Already Sent?
+	if c.Writer.Written() { // YES
+		return
+	}
+
+	JSONResponse(r, c)
+}
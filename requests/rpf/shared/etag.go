@@ -0,0 +1,51 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package shared
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag Create (Strong) Entity Tag from Resource Content
+func ETag(content []byte) string {
+	h := sha256.Sum256(content)
+	return "\"" + hex.EncodeToString(h[:16]) + "\""
+}
+
+// ETagMatches Does Entity Tag Match a (Comma Separated) List of Tags from a Request Header?
+func ETagMatches(header string, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertModified Set Response 'ETag' and End Request with 'Not Modified' if it Matches 'If-None-Match'
+func AssertModified(r rpf.GINProcessor, c *gin.Context) {
+	etag := r.MustGet("response-etag").(string)
+	c.Header("ETag", etag)
+
+	// Does the Client already have the Current Version?
+	header := c.GetHeader("If-None-Match")
+	if header != "" && ETagMatches(header, etag) { // YES
+		r.Answer(1004)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/objectvault/api-services/orm"
 	"github.com/objectvault/api-services/requests/rpf/object"
 	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/user"
 
 	rpf "github.com/objectvault/goginrpf"
 
@@ -66,22 +67,115 @@ func AssertStoreOpen(r rpf.GINProcessor, c *gin.Context) {
 		return
 	}
 
-	// TODO: Make the Store Open Configurable
-	ss, e := common.ImportStoreSession(key.(string), 5)
+	ss, e := common.ImportStoreSession(key.(string), common.STORE_SESSION_IDLE_DEFAULT)
 	if e != nil {
 		r.Abort(5010 /* TODO: Failed to Create Store Session */, nil)
 		return
 	}
 
+	// Have all Session Operations been Used?
+	if ss.IsExhausted() { // YES: Close Store Session
+		s.Delete(skey)
+		r.Abort(4210, nil)
+		return
+	}
+
 	if ss.IsExpired() {
 		s.Delete(skey)
 		r.Abort(5010 /* TODO: Using Expired Store Session */, nil)
 		return
 	}
 
+	// Count Operation against Session (before the Operation Runs)
+	if !ss.Use() { // FAILED: Session Closed (i.e. Store Locked) or no Operations Left
+		s.Delete(skey)
+		if common.StoreSessions().IsOpen(ss.ID()) {
+			r.Abort(4210, nil)
+		} else {
+			r.Abort(4202, nil)
+		}
+		return
+	}
+
+	r.SetLocal("store-session-id", ss.ID())
+	r.SetLocal("store-session", ss)
+
+	// Is the Store Key Kept in the Session?
+	if ss.IsTransactional() { // NO: Unlock it with Request Credentials
+		assertStoreUnlocked(r, c)
+		return
+	}
+
+	r.SetLocal("store-key", ss.Key())
+}
+
+// StoreKeyIfOpen Use the Store Key if the Store is Open (Key Kept in Session), without Requiring it
+func StoreKeyIfOpen(r rpf.GINProcessor, c *gin.Context) {
+	// Store ID
+	id := r.MustGet("request-store").(uint64)
+
+	// Get Store Key from Session
+	key := sessions.Default(c).Get(session.CreateStoreKey(id))
+	if key == nil { // NO: Store is not Open
+		return
+	}
+
+	ss, e := common.ImportStoreSession(key.(string), common.STORE_SESSION_IDLE_DEFAULT)
+	if e != nil {
+		return
+	}
+
+	// Is the Store Session Usable (without Request Credentials)?
+	if ss.IsExhausted() || ss.IsExpired() || ss.IsTransactional() || !common.StoreSessions().IsOpen(ss.ID()) { // NO
+		return
+	}
+
 	r.SetLocal("store-key", ss.Key())
 }
 
+func assertStoreUnlocked(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request Credentials
+	user.ExtractHeaderCredentials(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Get Session User's Store Registry Entry
+	DBStoreUserGet(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	rus := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
+	hash := r.MustGet("user-credentials").([]byte)
+
+	// User's Password Key (Store Key is Wrapped with it)
+	user.DBUserPasswordKey(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Had the Store Key been Rotated since the User last Unlocked it?
+	pending := rus.HasPendingStoreKey()
+
+	// Unlock Current Store Key (Applying any Rotations)
+	key, e := unlockStoreKey(c, rus, r.MustGet("user-password-key").(*orm.PasswordKey), hash)
+	if e != nil {
+		r.Abort(3998 /* TODO: Error Code - Invalid Credentials */, nil)
+		return
+	}
+
+	// Were Rotations Applied?
+	if pending { // YES: Save Registry Entry
+		DBStoreUserUpdate(r, c)
+		if r.IsFinished() {
+			return
+		}
+	}
+
+	r.SetLocal("store-key", key)
+}
+
 // REGISTRY STORE <--> USER  //
 
 func AssertStoreUserUnblocked(r rpf.GINProcessor, c *gin.Context) {
@@ -107,7 +107,11 @@ func DBRegisterUserWithNewStore(r rpf.GINProcessor, c *gin.Context) {
 
 	// Get User Information
 	user := r.MustGet("registry-user").(*orm.UserRegistry)
-	userHash := r.MustGet("hash").(string)
+	userKey, err := user.PasswordKeyHex(r.MustGet("hash").(string))
+	if err != nil {
+		r.Abort(3100, nil)
+		return
+	}
 
 	// Create Registry Entry
 	o := &orm.ObjectUserRegistry{}
@@ -126,12 +130,21 @@ func DBRegisterUserWithNewStore(r rpf.GINProcessor, c *gin.Context) {
 	}
 
 	// Create Store Key
-	err := o.CreateStoreKey(userHash)
+	err = o.CreateStoreKey(userKey)
 	if err != nil { // YES: Database Error
 		r.Abort(5100, nil)
 		return
 	}
 
+	// Does the User have an Account Recovery Key?
+	if user.HasRecoveryKey() { // YES: Seal Store Key to it
+		err = o.SealRecoveryKey(userKey, user.RecoveryKey())
+		if err != nil {
+			r.Abort(5100, nil)
+			return
+		}
+	}
+
 	// Flush Changes
 	r.SetLocal("registry-object-user", o)
 	object.DBObjectUserFlush(r, c)
@@ -148,7 +161,11 @@ func DBRegisterUserWithExistingStore(r rpf.GINProcessor, c *gin.Context) {
 
 	// Get User Information
 	user := r.MustGet("registry-user").(*orm.UserRegistry)
-	userHash := r.MustGet("hash").(string)
+	userKey, err := user.PasswordKeyHex(r.MustGet("hash").(string))
+	if err != nil {
+		r.Abort(3100, nil)
+		return
+	}
 
 	// Create Registry Entry
 	o := &orm.ObjectUserRegistry{}
@@ -167,12 +184,21 @@ func DBRegisterUserWithExistingStore(r rpf.GINProcessor, c *gin.Context) {
 	}
 
 	// Set Store Key
-	err := o.SetStoreKey(userHash, storeKey)
+	err = o.SetStoreKey(userKey, storeKey)
 	if err != nil { // YES: Database Error
 		r.Abort(5100, nil)
 		return
 	}
 
+	// Does the User have an Account Recovery Key?
+	if user.HasRecoveryKey() { // YES: Seal Store Key to it
+		err = o.SealRecoveryKey(userKey, user.RecoveryKey())
+		if err != nil {
+			r.Abort(5100, nil)
+			return
+		}
+	}
+
 	// Flush Changes
 	r.SetLocal("registry-object-user", o)
 	object.DBObjectUserFlush(r, c)
@@ -182,6 +208,69 @@ func DBRegisterUserWithExistingStore(r rpf.GINProcessor, c *gin.Context) {
 	}
 }
 
+func DBRegisterUserWithRecoveredStore(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store Global ID and Recovered Key
+	sid := r.MustGet("request-store").(uint64)
+	storeKey := r.MustGet("store-key").([]byte)
+
+	// Get User Information
+	user := r.MustGet("registry-user").(*orm.UserRegistry)
+	userKey, err := user.PasswordKeyHex(r.MustGet("hash").(string))
+	if err != nil {
+		r.Abort(3100, nil)
+		return
+	}
+
+	// Is User Already Registered with Store?
+	r.SetLocal("object-id", sid)
+	object.DBObjectUserFindOrNil(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Existing Registry Entry?
+	if !r.Has("registry-object-user") { // NO: Register User with Store
+		DBRegisterUserWithExistingStore(r, c)
+		if !r.IsFinished() {
+			DBRegisterStoreWithUser(r, c)
+		}
+		return
+	}
+
+	// YES: Replace Store Key and Make User a Store Administrator
+	o := r.MustGet("registry-object-user").(*orm.ObjectUserRegistry)
+	o.ClearStates(orm.STATE_INACTIVE | orm.STATE_BLOCKED | orm.STATE_READONLY)
+	if r.Has("register-roles") {
+		o.AddRoles(r.MustGet("register-roles").([]uint32))
+	}
+	if r.Has("register-as-admin") {
+		o.SetStates(orm.STATE_SYSTEM)
+	}
+
+	// Replace Store Key (Current One Might not be Recoverable)
+	err = o.ReplaceStoreKey(userKey, storeKey)
+	if err != nil {
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the User have an Account Recovery Key?
+	if user.HasRecoveryKey() { // YES: Seal Store Key to it
+		err = o.SealRecoveryKey(userKey, user.RecoveryKey())
+		if err != nil {
+			r.Abort(5100, nil)
+			return
+		}
+	}
+
+	// Flush Changes
+	object.DBObjectUserFlush(r, c)
+	if !r.Aborted() {
+		// Save Entry
+		r.SetLocal("registry-store-user", o)
+	}
+}
+
 func DBStoreUserUpdate(r rpf.GINProcessor, c *gin.Context) {
 	// Get Registry Entry
 	r.SetLocal("registry-object-user", r.MustGet("registry-store-user"))
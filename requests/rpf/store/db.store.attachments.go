@@ -0,0 +1,84 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/org"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Store Settings: Attachments
+const SETTING_ATTACHMENTS_QUOTA = "attachments.quota" // POLICY: Total Size of Attachments in MiB (0 - No Limit)
+
+// Default Attachments Quota in MiB
+const ATTACHMENTS_QUOTA_DEFAULT = 100
+
+// StoreAttachmentsQuota Total Size of Attachments Allowed in Store in MiB
+func StoreAttachmentsQuota(store *orm.Store) uint16 {
+	settings := store.Settings()
+	if settings.Has(SETTING_ATTACHMENTS_QUOTA) {
+		return org.SettingUint16(settings, SETTING_ATTACHMENTS_QUOTA)
+	}
+	return ATTACHMENTS_QUOTA_DEFAULT
+}
+
+func DBStoreAttachmentsPolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Store Attachments Quota (in Bytes)
+	r.SetLocal("store-attachments-quota", uint64(StoreAttachmentsQuota(store))*1024*1024)
+}
+
+// DBTargetStorePolicy Attachments Quota and Titles Policy of the Target Store of a Copy
+func DBTargetStorePolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Target Store Identifier
+	tsid := r.MustGet("request-target-store").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Target Store's Shard
+	db, e := dbm.Connect(tsid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Target Store
+	store := &orm.Store{}
+	e = store.ByID(db, common.LocalIDFromID(tsid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Did we find the Store?
+	if !store.IsValid() { // NO: Store does not exist
+		r.Abort(4200, nil)
+		return
+	}
+
+	// Get Target Store Attachments Quota (in Bytes), Titles and Tags Policies
+	r.SetLocal("store-attachments-quota", uint64(StoreAttachmentsQuota(store))*1024*1024)
+	r.SetLocal("store-titles-sealed", StoreTitlesSealed(store))
+	r.SetLocal("store-tags-indexed", StoreTagsIndexed(store))
+}
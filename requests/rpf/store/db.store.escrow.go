@@ -0,0 +1,217 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/hex"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* NOTE: Store Key Escrow
+ * If the Store's Organization has an Escrow Public Key, the Store Key is
+ * sealed to it (the Private Key is held offline by the Organization).
+ * This allows an Organization Administrator to recover access to a Store,
+ * even if all the Store's users have left or lost their credentials.
+ *
+ * A recovered key is only accepted if it matches the Store's Key Check Value
+ * (set with the escrow, and on stores opened before it existed), so a wrong
+ * key can't be registered and split the store.
+ */
+
+// DBStoreEscrowSeal Set Store Key Check Value and Seal Store Key to Organization Escrow Key (if Any)
+func DBStoreEscrowSeal(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Organization's Shard
+	oid := store.Organization()
+	db, e := dbm.Connect(oid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Store's Organization
+	org := &orm.Organization{}
+	e = org.ByID(db, common.LocalIDFromID(oid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Store Key
+	var key []byte
+	if r.Has("store-key") { // Existing Store: Key Already Known
+		key = r.MustGet("store-key").([]byte)
+	} else { // New Store: Unlock Key in Registry Entry
+		rsu := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
+		ur := r.MustGet("registry-user").(*orm.UserRegistry)
+
+		var k *orm.PasswordKey
+		k, e = ur.PasswordKeyHex(r.MustGet("hash").(string))
+		if e == nil {
+			key, e = rsu.StoreKey(k)
+		}
+		if e != nil {
+			r.Abort(5100, nil)
+			return
+		}
+	}
+
+	// Key Check Value for Current Store Key
+	e = store.SetKeyCheck(key)
+	if e != nil {
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the Organization Escrow Store Keys?
+	if org.HasEscrowKey() { // YES: Seal Store Key to Escrow Key
+		e = store.SealEscrow(org.EscrowKey(), key)
+		if e != nil {
+			r.Abort(5100, nil)
+			return
+		}
+	}
+
+	// Save Store (if Modified)
+	if store.IsDirty() {
+		DBStoreUpdate(r, c)
+	}
+}
+
+// DBStoreKeyCheckIfMissing Set Key Check Value on Stores Created before it Existed (Key Unlocked by Store User)
+func DBStoreKeyCheckIfMissing(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	if !r.Has("store") {
+		DBStoreGetByID(r, c)
+		if r.IsFinished() {
+			return
+		}
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Does the Store have a Key Check Value?
+	if store.HasKeyCheck() { // YES: Nothing to Do
+		return
+	}
+
+	// Do we have the Current Store Key?
+	if !r.Has("store-key") { // NO: Unlock it
+		DBStoreUserUnlockKey(r, c)
+		if r.IsFinished() {
+			return
+		}
+	}
+
+	e := store.SetKeyCheck(r.MustGet("store-key").([]byte))
+	if e != nil {
+		r.Abort(5100, nil)
+		return
+	}
+
+	DBStoreUpdate(r, c)
+}
+
+func AssertStoreEscrowed(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	store := r.MustGet("store").(*orm.Store)
+
+	// Is Store Key Escrowed?
+	if !store.HasEscrow() { // NO: Abort
+		r.Abort(4206, nil)
+		return
+	}
+}
+
+func AssertStoreKey(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store and Key to Verify
+	store := r.MustGet("store").(*orm.Store)
+	key := r.MustGet("store-key").([]byte)
+
+	// Does the Store have a Key Check Value?
+	if store.HasKeyCheck() { // YES: Key has to Match it
+		if !store.VerifyKey(key) {
+			r.Abort(4205, nil)
+		}
+		return
+	}
+
+	// NO: Verify Key against an Encrypted Object
+	sid := r.MustGet("request-store").(uint64)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store's Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Find an Encrypted Object in the Store (Folders are also Encrypted)
+	lsid := common.LocalIDFromID(sid)
+	id, e := orm.StoreObjectFirst(db, lsid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the Store have Objects?
+	if id == 0 { // NO: Nothing to Verify the Key Against
+		r.Abort(4205, nil)
+		return
+	}
+
+	// Get Object
+	o := &orm.StoreObject{}
+	e = o.ByKey(db, lsid, id)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Can the Key Decrypt the Object?
+	t := &orm.StoreTemplateObject{}
+	e = t.DecryptObject(key, lsid, id, o.Object())
+	if e != nil { // NO: Wrong Key
+		r.Abort(4205, nil)
+		return
+	}
+
+	// Key Verified: Set Key Check Value (for Future Recoveries)
+	e = store.SetKeyCheck(key)
+	if e != nil {
+		r.Abort(5100, nil)
+		return
+	}
+
+	DBStoreUpdate(r, c)
+}
+
+func ExportStoreEscrow(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	store := r.MustGet("store").(*orm.Store)
+
+	// Sealed Store Key (to be Opened Offline with the Escrow Private Key)
+	r.SetResponseDataValue("escrow", hex.EncodeToString(store.Escrow()))
+}
@@ -0,0 +1,40 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Store Settings: Object Concurrency
+const SETTING_OBJECTS_IF_MATCH = "objects.if_match" // POLICY: Object Updates and Deletes Require 'If-Match' Header
+
+// StoreRequiresIfMatch Do Object Updates (and Deletes) have to Send the Object's Entity Tag?
+func StoreRequiresIfMatch(store *orm.Store) bool {
+	return settingBool(store.Settings(), SETTING_OBJECTS_IF_MATCH, false)
+}
+
+func DBStoreIfMatchPolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Store Concurrency Policy
+	r.SetLocal("store-if-match-required", StoreRequiresIfMatch(store))
+}
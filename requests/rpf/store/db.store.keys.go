@@ -0,0 +1,869 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/keygen"
+	"github.com/objectvault/api-services/requests/rpf/session"
+	"github.com/objectvault/api-services/requests/rpf/user"
+	"github.com/objectvault/common/maps"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* NOTE: Store Key Rotation
+ * A rotation generates a new store key, re-encrypts every store object with
+ * it and re-wraps it for the store's users.
+ *
+ * The server only has the password hash of the user requesting the rotation,
+ * so, for every other user, the new key is sealed to the user's Share Public
+ * Key, and is applied the next time the user opens the store. The new key is
+ * never stored wrapped with the previous key (a removed user knows it).
+ * Users marked for removal are not given the new key. Users without a Share
+ * Key can't be given the new key, so the rotation is refused, unless the
+ * request accepts removing them from the store.
+ *
+ * Every step is idempotent and, until the rotation completes, the store
+ * settings keep the new key (sealed to the requesting user's Share Key) and
+ * the previous key (wrapped with the new key), so an interrupted rotation is
+ * resumed (with the same key) by the requesting user, or by any store user
+ * that has already applied the new key.
+ */
+
+// Store Settings: Key Rotation
+const SETTING_ROTATE_ON_REMOVE = "keys.rotate_on_remove" // POLICY: Rotate Key when a User is Removed
+const SETTING_ROTATE_PENDING = "keys.rotate"             // Key Rotation Requested
+const SETTING_ROTATE_NEXT = "keys.next"                  // Key Rotation in Progress (New Key Sealed to Requesting User's Share Key)
+const SETTING_ROTATE_PREV = "keys.prev"                  // Key Rotation in Progress (Previous Key Wrapped with New)
+
+// Store Settings: Object Ciphers
+const SETTING_OBJECTS_ENVELOPE = "objects.envelope" // All Objects Encrypted in Envelopes (Legacy Ciphers Refused)
+
+// Number of Objects Re-Encrypted per Query
+const ROTATE_BATCH_SIZE = 100
+
+// Attempts to Seal New Key to a User whose Registry Entry is Modified Concurrently
+const ROTATE_SEAL_ATTEMPTS = 3
+
+func DBStoreUserUnlockKey(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store User Registry Entry and Credentials
+	rus := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
+	hash := r.MustGet("user-credentials").([]byte)
+
+	// User's Password Key (Store Key is Wrapped with it)
+	user.DBUserPasswordKey(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Had the Store Key been Rotated since the User last Opened the Store?
+	pending := rus.HasPendingStoreKey()
+
+	// Unlock Current Store Key (Applying any Rotations)
+	key, e := unlockStoreKey(c, rus, r.MustGet("user-password-key").(*orm.PasswordKey), hash)
+	if e != nil {
+		r.Abort(3998 /* TODO: Error Code - Invalid Credentials */, nil)
+		return
+	}
+
+	// Were Rotations Applied?
+	if pending { // YES: Save Registry Entry
+		DBStoreUserUpdate(r, c)
+		if r.IsFinished() {
+			return
+		}
+
+		// Existing Store Session has Previous Key
+		session.SessionStoreClose(r, c)
+	}
+
+	r.SetLocal("store-key", key)
+}
+
+func DBStoreUserApplyPendingKey(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store User Registry Entry
+	rus := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
+
+	// Had the Store Key been Rotated since the User last Opened the Store?
+	if rus.HasPendingStoreKey() { // YES: Apply Rotations
+		DBStoreUserUnlockKey(r, c)
+	}
+}
+
+// StoreObjectsInEnvelope Are all Store Objects Encrypted in Envelopes (Legacy Ciphers Refused)?
+func StoreObjectsInEnvelope(store *orm.Store) bool {
+	return settingBool(store.Settings(), SETTING_OBJECTS_ENVELOPE, false)
+}
+
+func DBStoreEnvelopePolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Store Object Cipher Policy
+	r.SetLocal("store-objects-envelope", StoreObjectsInEnvelope(store))
+}
+
+func DBStoreKeyRotateIfPending(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Has a Key Rotation been Requested (or was Interrupted)?
+	settings := store.Settings()
+	v, _ := settings.GetDefault(SETTING_ROTATE_PENDING, false)
+	if b, ok := v.(bool); (!ok || !b) && !settings.Has(SETTING_ROTATE_NEXT) { // NO: Nothing to Do
+		return
+	}
+
+	// Do we have the Current Store Key?
+	if !r.Has("store-key") { // NO: Unlock it
+		DBStoreUserUnlockKey(r, c)
+		if r.IsFinished() {
+			return
+		}
+	}
+
+	// Share Keys of Requesting User (New Key is Sealed to it while Rotating)
+	DBStoreUserShareKeys(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Are there Store Users the New Key can't be Sealed to?
+	sid := r.MustGet("request-store").(uint64)
+	if storeHasUnreachableUsers(c, sid, r.MustGet("user-id").(uint64)) { // YES: Requires an Explicit Rotation (Removing them)
+		log.Printf("store key rotation [%x]: pending, until unreachable users are removed\n", sid)
+		return
+	}
+
+	// Was a Rotation Interrupted?
+	if settings.Has(SETTING_ROTATE_NEXT) { // YES: Can the User Complete it?
+		var private []byte
+		if r.Has("user-share-private") {
+			private = r.MustGet("user-share-private").([]byte)
+		}
+
+		_, _, e := rotateKeys(store, r.MustGet("store-key").([]byte), nil, private)
+		if e != nil { // NO: Leave it to a User with the New Key
+			log.Printf("store key rotation [%x]: %v\n", store.ID(), e)
+			return
+		}
+	}
+
+	// Rotate Store Key
+	DBStoreKeyRotate(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Existing Store Session has Previous Key
+	session.SessionStoreClose(r, c)
+
+	// Re-Seal Rotated Key for Organization
+	DBStoreEscrowSeal(r, c)
+}
+
+func DBStoreKeyRotateOnRemove(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Does the Store Policy Require Key Rotation on User Removal?
+	v, _ := store.Settings().GetDefault(SETTING_ROTATE_ON_REMOVE, false)
+	if b, ok := v.(bool); !ok || !b { // NO: Nothing to Do
+		return
+	}
+
+	// Request Key Rotation (Done the Next Time the Store is Opened)
+	store.Settings().Set(SETTING_ROTATE_PENDING, true, true)
+	DBStoreUpdate(r, c)
+}
+
+func DBStoreKeyRotate(r rpf.GINProcessor, c *gin.Context) {
+	// Share Keys and Password Key of Requesting User
+	DBStoreUserShareKeys(r, c)
+	if !r.IsFinished() {
+		user.DBUserPasswordKey(r, c)
+	}
+	if r.IsFinished() {
+		return
+	}
+
+	// Get Request Parameters
+	sid := r.MustGet("request-store").(uint64)
+	uid := r.MustGet("user-id").(uint64)
+	store := r.MustGet("store").(*orm.Store)
+	rus := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
+	key := r.MustGet("store-key").([]byte)
+	public := r.MustGet("user-share-public").([]byte)
+	private := r.MustGet("user-share-private").([]byte)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Store's Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Connection to Global Registry (Always in Group 0: Shard 0)
+	rdb, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Are there Store Users the New Key can't be Sealed to?
+	unreachable, e := unreachableStoreUsers(db, rdb, sid, uid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	if len(unreachable) > 0 {
+		// Did the Request Accept Removing them from the Store?
+		if !r.Has("request-remove-unreachable") || !r.MustGet("request-remove-unreachable").(bool) { // NO
+			r.Abort(4228, nil)
+			return
+		}
+
+		e = removeStoreUsers(dbm, db, sid, unreachable)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+	}
+
+	// Resume a Previous Rotation or Start a New One
+	oldKey, newKey, e := rotateKeys(store, key, public, private)
+	if e != nil {
+		r.Abort(4207, nil)
+		return
+	}
+
+	// Save Keys in Store (Allows Resuming the Rotation)
+	store.SetModifier(uid)
+	e = store.Flush(db, false)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// STEP 1: Seal New Key to Other Store Users (Before Objects are Re-Encrypted)
+	users, e := orm.ObjectUserIDs(db, sid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	delivered := 1 // Requesting User Always Gets the New Key
+	for _, user := range users {
+		// Requesting User is Handled Directly
+		if user == uid {
+			continue
+		}
+
+		ok, e := sealRotatedStoreKey(db, rdb, sid, user, newKey)
+		if e == errNoShareKey { // User Registered (or Share Key Removed) during Rotation
+			r.Abort(4228, nil)
+			return
+		}
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		if ok {
+			delivered++
+		}
+	}
+
+	// STEP 2: Re-Encrypt Store Objects
+	objects, failed, e := rotateStoreObjects(db, common.LocalIDFromID(sid), oldKey, newKey)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Were all Objects Re-Encrypted (in Envelopes)?
+	upgraded := failed == 0
+
+	// Objects that could not be Decrypted, with Either Key, are Left Untouched
+	if failed > 0 {
+		log.Printf("store key rotation [%x]: %d objects not re-encrypted\n", sid, failed)
+	}
+
+	// STEP 2.1: Re-Encrypt Store Object Revisions
+	_, failed, e = rotateStoreObjectRevisions(db, common.LocalIDFromID(sid), oldKey, newKey)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+	upgraded = upgraded && failed == 0
+
+	if failed > 0 {
+		log.Printf("store key rotation [%x]: %d revisions not re-encrypted\n", sid, failed)
+	}
+
+	// STEP 2.2: Re-Encrypt Store Object Attachments
+	_, failed, e = rotateStoreAttachments(db, common.LocalIDFromID(sid), oldKey, newKey)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	if failed > 0 {
+		log.Printf("store key rotation [%x]: %d attachments not re-encrypted\n", sid, failed)
+	}
+
+	// STEP 2.3: Re-Seal Object Titles
+	_, failed, e = rotateStoreTitles(db, common.LocalIDFromID(sid), oldKey, newKey)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	if failed > 0 {
+		log.Printf("store key rotation [%x]: %d titles not re-sealed\n", sid, failed)
+	}
+
+	// STEP 3: Re-Wrap New Key for Requesting User
+	u := &orm.UserRegistry{}
+	e = u.ByID(rdb, uid)
+	if e == nil {
+		e = rus.ReplaceStoreKey(r.MustGet("user-password-key").(*orm.PasswordKey), newKey)
+	}
+	if e == nil {
+		e = rotateRecoveryKey(u, rus, newKey)
+	}
+	if e == nil {
+		e = rus.Flush(db, false)
+	}
+	if e != nil {
+		r.Abort(5100, nil)
+		return
+	}
+
+	// STEP 4: Mark Rotation Complete
+	settings := store.Settings()
+	settings.Clear(SETTING_ROTATE_NEXT)
+	settings.Clear(SETTING_ROTATE_PREV)
+	settings.Clear(SETTING_ROTATE_PENDING)
+
+	// Are there Legacy Ciphers Left?
+	if upgraded { // NO: Refuse them from Now On
+		settings.Set(SETTING_OBJECTS_ENVELOPE, true, true)
+	}
+	e = store.Flush(db, false)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Save Rotation Results
+	r.SetLocal("store-key", newKey)
+	r.SetLocal("rotated-objects", objects)
+	r.SetLocal("rotated-users", delivered)
+	r.SetLocal("rotated-removed", len(unreachable))
+}
+
+var errNoShareKey = errors.New("User has no Share Key")
+
+// unreachableStoreUsers Store Users (other than the Requesting User) without a Share Key (New Key can't be Sealed to them)
+func unreachableStoreUsers(db *sql.DB, rdb *sql.DB, sid uint64, uid uint64) ([]uint64, error) {
+	users, e := orm.ObjectUserIDs(db, sid)
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	unreachable := []uint64{}
+	for _, user := range users {
+		// Requesting User is Handled Directly
+		if user == uid {
+			continue
+		}
+
+		o := &orm.ObjectUserRegistry{}
+		e = o.ByKey(db, sid, user)
+		if e != nil { // YES: Database Error
+			return nil, e
+		}
+
+		// Is User being Removed from Store?
+		if o.IsNew() || o.IsDeleted() { // YES: Does not get New Key
+			continue
+		}
+
+		u := &orm.UserRegistry{}
+		e = u.ByID(rdb, user)
+		if e != nil { // YES: Database Error
+			return nil, e
+		}
+
+		if !u.IsValid() || !u.HasShareKey() {
+			log.Printf("store key rotation [%x]: user [%x] has no share key\n", sid, user)
+			unreachable = append(unreachable, user)
+		}
+	}
+
+	return unreachable, nil
+}
+
+// storeHasUnreachableUsers Does the Store have Users without a Share Key? (TRUE on Database Error)
+func storeHasUnreachableUsers(c *gin.Context, sid uint64, uid uint64) bool {
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		return true
+	}
+
+	rdb, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		return true
+	}
+
+	unreachable, e := unreachableStoreUsers(db, rdb, sid, uid)
+	return e != nil || len(unreachable) > 0
+}
+
+// removeStoreUsers Remove Users (and their Store Sessions) from Store
+func removeStoreUsers(dbm *orm.DBSessionManager, db *sql.DB, sid uint64, users []uint64) error {
+	for _, user := range users {
+		// Remove Store's User Registry Entry
+		_, e := orm.ObjectUserDelete(db, sid, user)
+		if e != nil { // YES: Database Error
+			return e
+		}
+
+		// Remove User's Store Registry Entry
+		udb, e := dbm.Connect(user)
+		if e != nil { // YES: Database Error
+			return e
+		}
+
+		_, e = orm.UserObjectDelete(udb, user, sid)
+		if e != nil { // YES: Database Error
+			return e
+		}
+
+		common.StoreSessions().CloseStoreUser(sid, user)
+	}
+
+	return nil
+}
+
+// sealRotatedStoreKey Seal New Key to Store User (returns FALSE if User is being Removed from Store)
+func sealRotatedStoreKey(db *sql.DB, rdb *sql.DB, sid uint64, user uint64, newKey []byte) (bool, error) {
+	// Get User's Registry Entry (Share and Recovery Public Keys)
+	u := &orm.UserRegistry{}
+	e := u.ByID(rdb, user)
+	if e != nil { // YES: Database Error
+		return false, e
+	}
+
+	// Can the New Key be Sealed to the User?
+	if !u.IsValid() || !u.HasShareKey() { // NO
+		return false, errNoShareKey
+	}
+
+	// NOTE: Entry is Reloaded if Modified Concurrently (i.e. User Applying a Pending Key)
+	for attempt := 1; ; attempt++ {
+		o := &orm.ObjectUserRegistry{}
+		e = o.ByKey(db, sid, user)
+		if e != nil { // YES: Database Error
+			return false, e
+		}
+
+		// Is User being Removed from Store?
+		if o.IsNew() || o.IsDeleted() { // YES: Does not get New Key
+			return false, nil
+		}
+
+		if o.IsPendingShare() { // Store Shared, but not yet Accepted: Re-Seal Shared Key
+			e = o.ShareStoreKey(u.ShareKey(), newKey)
+		} else {
+			e = o.SealPendingStoreKey(u.ShareKey(), newKey)
+		}
+		if e == nil {
+			e = rotateRecoveryKey(u, o, newKey)
+		}
+		if e == nil {
+			e = o.Flush(db, false)
+		}
+
+		if e != orm.ErrObjectUserRegistryModified || attempt >= ROTATE_SEAL_ATTEMPTS {
+			return e == nil, e
+		}
+	}
+}
+
+// rotateKeys Previous and New Store Keys for Rotation (Resumes an Interrupted Rotation)
+func rotateKeys(store *orm.Store, key []byte, public []byte, private []byte) ([]byte, []byte, error) {
+	settings := store.Settings()
+
+	// Do we have an Interrupted Rotation?
+	next := rotateSettingBytes(settings, SETTING_ROTATE_NEXT)
+	if next == nil { // NO: Start a New Rotation
+		if len(public) == 0 {
+			return nil, nil, errors.New("Missing Share Public Key")
+		}
+
+		newKey, e := keygen.Key()
+		if e != nil {
+			return nil, nil, e
+		}
+
+		// Seal New Key to Requesting User (NEVER Wrapped with the Previous Key)
+		snext, e := orm.SealToPublicKey(public, newKey)
+		if e != nil {
+			return nil, nil, e
+		}
+
+		// Previous Key Wrapped with New Key (Only Users with the New Key can Resume)
+		wprev, e := orm.EncryptWithHash(hex.EncodeToString(newKey), key)
+		if e != nil {
+			return nil, nil, e
+		}
+
+		settings.Set(SETTING_ROTATE_NEXT, hex.EncodeToString(snext), true)
+		settings.Set(SETTING_ROTATE_PREV, hex.EncodeToString(wprev), true)
+		return key, newKey, nil
+	}
+
+	// Did the User Start the Rotation (New Key Sealed to it)?
+	newKey := key // NO: User might have Already Applied the New Key
+	if len(private) > 0 {
+		k, e := orm.OpenSealed(private, next)
+		if e == nil { // YES
+			newKey = k
+		}
+	}
+
+	// Unlock Previous Key with New Key
+	prev := rotateSettingBytes(settings, SETTING_ROTATE_PREV)
+	if prev != nil {
+		oldKey, e := orm.DecryptWithHash(hex.EncodeToString(newKey), prev)
+		if e == nil && (bytes.Equal(key, oldKey) || bytes.Equal(key, newKey)) { // User has One of the Keys
+			return oldKey, newKey, nil
+		}
+	}
+
+	return nil, nil, errors.New("Store Key Rotation in Progress with Another Key")
+}
+
+func rotateSettingBytes(settings *maps.MapWrapper, name string) []byte {
+	v, _ := settings.GetDefault(name, "")
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return nil
+	}
+
+	b, e := hex.DecodeString(s)
+	if e != nil {
+		return nil
+	}
+	return b
+}
+
+// rotateStoreObjects Re-Encrypt Store Objects in Batches (returns re-encrypted and failed counts)
+func rotateStoreObjects(db *sql.DB, store uint32, oldKey []byte, newKey []byte) (int, int, error) {
+	rotated := 0
+	failed := 0
+
+	after := uint32(0)
+	for {
+		batch, e := orm.StoreObjectsBatch(db, store, after, ROTATE_BATCH_SIZE)
+		if e != nil { // YES: Database Error
+			return rotated, failed, e
+		}
+
+		for _, o := range batch {
+			after = o.ID()
+
+			// Already Encrypted with New Key (i.e. Resumed Rotation)?
+			t := &orm.StoreTemplateObject{}
+			if t.DecryptObject(newKey, store, o.ID(), o.Object()) == nil { // YES: Skip
+				continue
+			}
+
+			// Decrypt with Previous Key
+			e = t.DecryptObject(oldKey, store, o.ID(), o.Object())
+			if e != nil { // FAILED: Skip
+				failed++
+				continue
+			}
+
+			// Encrypt with New Key
+			cbs, e := t.EncryptObject(newKey, store, o.ID())
+			if e != nil { // FAILED: Skip
+				failed++
+				continue
+			}
+
+			e = orm.StoreObjectUpdateCipher(db, store, o.ID(), cbs)
+			if e != nil { // YES: Database Error
+				return rotated, failed, e
+			}
+			rotated++
+		}
+
+		// Last Batch?
+		if len(batch) < ROTATE_BATCH_SIZE { // YES
+			break
+		}
+	}
+
+	return rotated, failed, nil
+}
+
+// rotateStoreObjectRevisions Re-Encrypt Store Object Revisions in Batches (returns re-encrypted and failed counts)
+func rotateStoreObjectRevisions(db *sql.DB, store uint32, oldKey []byte, newKey []byte) (int, int, error) {
+	rotated := 0
+	failed := 0
+
+	object := uint32(0)
+	revision := uint32(0)
+	for {
+		batch, e := orm.StoreObjectRevisionsBatch(db, store, object, revision, ROTATE_BATCH_SIZE)
+		if e != nil { // YES: Database Error
+			return rotated, failed, e
+		}
+
+		for _, o := range batch {
+			object = o.Object()
+			revision = o.Revision()
+
+			// Re-Seal Revision Title
+			if o.HasSealedTitle() {
+				t, ok := rotateTitle(store, o.SealedTitle(), oldKey, newKey)
+				if !ok { // FAILED: Skip
+					failed++
+				} else if t != "" {
+					e = orm.StoreObjectRevisionUpdateTitle(db, store, o.Object(), o.Revision(), t)
+					if e != nil { // YES: Database Error
+						return rotated, failed, e
+					}
+				}
+			}
+
+			// Already Encrypted with New Key (i.e. Resumed Rotation)?
+			t := &orm.StoreTemplateObject{}
+			if t.DecryptObject(newKey, store, o.Object(), o.Cipher()) == nil { // YES: Skip
+				continue
+			}
+
+			// Decrypt with Previous Key
+			e = t.DecryptObject(oldKey, store, o.Object(), o.Cipher())
+			if e != nil { // FAILED: Skip
+				failed++
+				continue
+			}
+
+			// Encrypt with New Key
+			cbs, e := t.EncryptObject(newKey, store, o.Object())
+			if e != nil { // FAILED: Skip
+				failed++
+				continue
+			}
+
+			e = orm.StoreObjectRevisionUpdateCipher(db, store, o.Object(), o.Revision(), cbs)
+			if e != nil { // YES: Database Error
+				return rotated, failed, e
+			}
+			rotated++
+		}
+
+		// Last Batch?
+		if len(batch) < ROTATE_BATCH_SIZE { // YES
+			break
+		}
+	}
+
+	return rotated, failed, nil
+}
+
+// rotateStoreAttachments Re-Encrypt Attachment Manifests and Chunks in Batches (returns re-encrypted and failed counts)
+func rotateStoreAttachments(db *sql.DB, store uint32, oldKey []byte, newKey []byte) (int, int, error) {
+	rotated := 0
+	failed := 0
+
+	after := uint32(0)
+	for {
+		batch, e := orm.StoreAttachmentsBatch(db, store, after, ROTATE_BATCH_SIZE)
+		if e != nil { // YES: Database Error
+			return rotated, failed, e
+		}
+
+		for _, a := range batch {
+			after = a.Object()
+
+			// Manifest Already Encrypted with New Key (i.e. Resumed Rotation)?
+			if a.Open(newKey) != nil { // NO: Decrypt with Previous Key
+				if a.Open(oldKey) != nil { // FAILED: Skip
+					failed++
+					continue
+				}
+
+				// Encrypt with New Key
+				if a.Seal(newKey) != nil { // FAILED: Skip
+					failed++
+					continue
+				}
+
+				e = orm.StoreAttachmentUpdateCipher(db, store, a.Object(), a.Cipher())
+				if e != nil { // YES: Database Error
+					return rotated, failed, e
+				}
+			}
+
+			// Re-Encrypt Chunks (Chunks already Encrypted with New Key are Skipped)
+			for i := uint32(0); i < a.Chunks(); i++ {
+				last := i == a.Chunks()-1
+				cbs, e := orm.StoreAttachmentChunkGet(db, store, a.Object(), a.Generation(), i)
+				if e != nil { // YES: Database Error
+					return rotated, failed, e
+				}
+
+				if cbs == nil { // Missing Chunk: Skip
+					continue
+				}
+
+				// Already Encrypted with New Key?
+				if _, e = orm.OpenAttachmentChunk(newKey, store, a.Object(), a.Generation(), i, last, cbs); e == nil { // YES: Skip
+					continue
+				}
+
+				plain, e := orm.OpenAttachmentChunk(oldKey, store, a.Object(), a.Generation(), i, last, cbs)
+				if e != nil { // FAILED: Skip
+					failed++
+					continue
+				}
+
+				cbs, e = orm.SealAttachmentChunk(newKey, store, a.Object(), a.Generation(), i, last, plain)
+				if e != nil { // FAILED: Skip
+					failed++
+					continue
+				}
+
+				e = orm.StoreAttachmentChunkUpdate(db, store, a.Object(), a.Generation(), i, cbs)
+				if e != nil { // YES: Database Error
+					return rotated, failed, e
+				}
+			}
+			rotated++
+		}
+
+		// Last Batch?
+		if len(batch) < ROTATE_BATCH_SIZE { // YES
+			break
+		}
+	}
+
+	return rotated, failed, nil
+}
+
+// rotateRecoveryKey Re-Seal Rotated Store Key to User's Account Recovery Key
+func rotateRecoveryKey(u *orm.UserRegistry, o *orm.ObjectUserRegistry, key []byte) error {
+	// Does the User have an Account Recovery Key?
+	if !u.IsValid() || !u.HasRecoveryKey() { // NO: Nothing to Do
+		return nil
+	}
+
+	return o.SealRecoveryKeyBytes(u.RecoveryKey(), key)
+}
+
+// rotateStoreTitles Re-Seal Object Titles in Batches (returns re-sealed and failed counts)
+func rotateStoreTitles(db *sql.DB, store uint32, oldKey []byte, newKey []byte) (int, int, error) {
+	rotated := 0
+	failed := 0
+
+	after := uint32(0)
+	for {
+		batch, e := orm.StoreObjectSealedTitlesBatch(db, store, after, ROTATE_BATCH_SIZE)
+		if e != nil { // YES: Database Error
+			return rotated, failed, e
+		}
+
+		for _, o := range batch {
+			after = o.ID()
+
+			t, ok := rotateTitle(store, o.SealedTitle(), oldKey, newKey)
+			if !ok { // FAILED: Skip
+				failed++
+				continue
+			}
+
+			// Already Sealed with New Key?
+			if t == "" { // YES: Skip
+				continue
+			}
+
+			e = orm.StoreObjectUpdateTitle(db, store, o.ID(), t)
+			if e != nil { // YES: Database Error
+				return rotated, failed, e
+			}
+			rotated++
+		}
+
+		// Last Batch?
+		if len(batch) < ROTATE_BATCH_SIZE { // YES
+			break
+		}
+	}
+
+	return rotated, failed, nil
+}
+
+// rotateTitle Re-Seal Title with New Key (returns "" if Already Sealed with New Key, false on Failure)
+func rotateTitle(store uint32, sealed string, oldKey []byte, newKey []byte) (string, bool) {
+	// Already Sealed with New Key (i.e. Resumed Rotation)?
+	if _, e := orm.OpenStoreTitle(newKey, store, sealed); e == nil { // YES
+		return "", true
+	}
+
+	// Open with Previous Key
+	t, e := orm.OpenStoreTitle(oldKey, store, sealed)
+	if e != nil {
+		return "", false
+	}
+
+	// Seal with New Key
+	t, e = orm.SealStoreTitle(newKey, store, t)
+	if e != nil {
+		return "", false
+	}
+	return t, true
+}
@@ -0,0 +1,58 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/org"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Store Settings: Object Revisions
+const SETTING_REVISIONS_COUNT = "revisions.count" // POLICY: Revisions Kept per Object (0 - No Revisions)
+const SETTING_REVISIONS_AGE = "revisions.age"     // POLICY: Maximum Age of Revisions in Days (0 - No Limit)
+
+// Default Revisions Kept per Object
+const REVISIONS_COUNT_DEFAULT = 10
+
+// Policy Limits: Revisions Kept per Object and Maximum Age in Days
+const REVISIONS_COUNT_MAX = 1000
+const REVISIONS_AGE_MAX = 3650
+
+// StoreRevisionPolicy Revisions Kept per Object and Maximum Age in Days
+func StoreRevisionPolicy(store *orm.Store) (uint16, uint16) {
+	settings := store.Settings()
+
+	count := uint16(REVISIONS_COUNT_DEFAULT)
+	if settings.Has(SETTING_REVISIONS_COUNT) {
+		count = org.SettingUint16(settings, SETTING_REVISIONS_COUNT)
+	}
+
+	return count, org.SettingUint16(settings, SETTING_REVISIONS_AGE)
+}
+
+func DBStoreRevisionPolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Store Revision Policy
+	count, age := StoreRevisionPolicy(store)
+	r.SetLocal("store-revisions-count", count)
+	r.SetLocal("store-revisions-age", age)
+}
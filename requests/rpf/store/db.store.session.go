@@ -0,0 +1,171 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/org"
+	"github.com/objectvault/common/maps"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* NOTE: Store Session Policy
+ * Store sessions are configured by the store settings and, for the idle
+ * timeout, maximum lifetime and status check extension, also by the settings
+ * of the store's organization.
+ * The organization settings are a ceiling: the shorter of the idle timeouts
+ * and lifetimes is used, and a status check can only extend the session if
+ * both allow it.
+ */
+
+// Store Settings: Store Session
+const SETTING_SESSION_MODE = "session.mode"             // POLICY: Store Session Mode ("default", "operations" or "transaction")
+const SETTING_SESSION_OPERATIONS = "session.operations" // POLICY: Operations Allowed per Store Session (Operations Mode)
+const SETTING_SESSION_IDLE = "session.idle"             // POLICY: Idle Timeout in Minutes
+const SETTING_SESSION_LIFETIME = "session.lifetime"     // POLICY: Maximum Lifetime in Minutes
+const SETTING_SESSION_EXTEND = "session.extend"         // POLICY: Can a Status Check (IsStoreOpen) Extend the Session?
+
+// Store Session Modes (Settings Values)
+const SESSION_MODE_DEFAULT = "default"
+const SESSION_MODE_OPERATIONS = "operations"
+const SESSION_MODE_TRANSACTION = "transaction"
+
+// Default Operations per Store Session (Operations Mode)
+const SESSION_OPERATIONS_DEFAULT = 1
+
+// Store Session Policy
+type SessionPolicy struct {
+	Mode       uint8  // Store Session Mode
+	Operations uint16 // Operations Allowed per Session (Operations Mode)
+	Idle       uint16 // Idle Timeout in Minutes
+	Lifetime   uint16 // Maximum Lifetime in Minutes (0 - No Limit)
+	Extend     bool   // Can a Status Check Extend the Session?
+}
+
+func DBStoreSessionPolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Store's Organization
+	o, e := dbStoreOrganization(c, store)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Store Session Policy
+	p := StoreSessionPolicy(store, o)
+	r.SetLocal("store-session-mode", p.Mode)
+	r.SetLocal("store-session-operations", p.Operations)
+	r.SetLocal("store-session-idle", p.Idle)
+	r.SetLocal("store-session-lifetime", p.Lifetime)
+	r.SetLocal("store-session-extend", p.Extend)
+}
+
+func dbStoreOrganization(c *gin.Context, store *orm.Store) (*orm.Organization, error) {
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Organization's Shard
+	db, e := dbm.Connect(store.Organization())
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	// Get Organization
+	entry := &orm.Organization{}
+	e = entry.ByID(db, common.LocalIDFromID(store.Organization()))
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	// Did we find the Organization?
+	if !entry.IsValid() { // NO: Use Store Settings Only
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// StoreSessionPolicy Store Session Policy (from Store and Organization Settings)
+func StoreSessionPolicy(store *orm.Store, o *orm.Organization) *SessionPolicy {
+	settings := store.Settings()
+	p := &SessionPolicy{
+		Mode:   common.STORE_SESSION_DEFAULT,
+		Idle:   common.STORE_SESSION_IDLE_DEFAULT,
+		Extend: true,
+	}
+
+	// Store Session Mode
+	v, _ := settings.GetDefault(SETTING_SESSION_MODE, SESSION_MODE_DEFAULT)
+	switch v {
+	case SESSION_MODE_OPERATIONS:
+		p.Mode = common.STORE_SESSION_OPERATIONS
+		p.Operations = SESSION_OPERATIONS_DEFAULT
+		if n := org.SettingUint16(settings, SETTING_SESSION_OPERATIONS); n > 0 {
+			p.Operations = n
+		}
+	case SESSION_MODE_TRANSACTION:
+		p.Mode = common.STORE_SESSION_TRANSACTION
+	}
+
+	// Store Session Lifetime (Shortest of Store and Organization)
+	var idle, lifetime uint16
+	extend := true
+	if o != nil {
+		idle = org.SettingUint16(o.Settings(), org.SETTING_STORE_SESSION_IDLE)
+		lifetime = org.SettingUint16(o.Settings(), org.SETTING_STORE_SESSION_LIFETIME)
+		extend = settingBool(o.Settings(), org.SETTING_STORE_SESSION_EXTEND, true)
+	}
+
+	if n := org.SettingUint16(settings, SETTING_SESSION_IDLE); n > 0 && (idle == 0 || n < idle) {
+		idle = n
+	}
+
+	if n := org.SettingUint16(settings, SETTING_SESSION_LIFETIME); n > 0 && (lifetime == 0 || n < lifetime) {
+		lifetime = n
+	}
+
+	if idle > 0 {
+		p.Idle = idle
+	}
+	p.Lifetime = lifetime
+	p.Extend = extend && settingBool(settings, SETTING_SESSION_EXTEND, true)
+	return p
+}
+
+// StoreSessionModeSetting Store Session Mode to Settings Value
+func StoreSessionModeSetting(mode uint8) string {
+	switch mode {
+	case common.STORE_SESSION_OPERATIONS:
+		return SESSION_MODE_OPERATIONS
+	case common.STORE_SESSION_TRANSACTION:
+		return SESSION_MODE_TRANSACTION
+	}
+
+	return SESSION_MODE_DEFAULT
+}
+
+func settingBool(settings *maps.MapWrapper, path string, d bool) bool {
+	v, _ := settings.GetDefault(path, d)
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return d
+}
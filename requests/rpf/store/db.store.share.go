@@ -0,0 +1,240 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/hex"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/object"
+	"github.com/objectvault/api-services/requests/rpf/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* NOTE: Store Sharing
+ * Every user has a Share Key Pair (the Private Key is encrypted with the
+ * user's password hash). A store user, with the store open, can add an
+ * existing user to the store by sealing the Store Key to the user's Share
+ * Public Key. The user accepts the Store Key (re-wrapping it with their
+ * password hash) the next time they open the store.
+ */
+
+func AssertUserHasShareKey(r rpf.GINProcessor, c *gin.Context) {
+	// Get User to Share Store With
+	user := r.MustGet("registry-user").(*orm.UserRegistry)
+
+	// Does the User have a Share Key?
+	if !user.HasShareKey() { // NO: User has to Login Once to Create One
+		r.Abort(4208, nil)
+		return
+	}
+}
+
+func DBShareStoreWithUser(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store Global ID and Key (from Open Store Session)
+	sid := r.MustGet("request-store").(uint64)
+	key := r.MustGet("store-key").([]byte)
+
+	// Get User to Share Store With
+	user := r.MustGet("registry-user").(*orm.UserRegistry)
+
+	// Is User Already Registered with Store?
+	r.SetLocal("object-id", sid)
+	r.SetLocal("user-id", user.ID())
+	object.DBObjectUserFindOrNil(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	if r.Has("registry-object-user") { // YES: Abort
+		r.Abort(4209, nil)
+		return
+	}
+
+	// Create Registry Entry
+	o := &orm.ObjectUserRegistry{}
+	o.SetKey(sid, user.ID())
+	o.SetUserName(user.UserName())
+
+	// Are specific roles to be set?
+	if r.Has("register-roles") { // YES
+		o.AddRoles(r.MustGet("register-roles").([]uint32))
+	}
+
+	// Is User Store Admin?
+	if r.Has("register-as-admin") { // YES
+		o.SetStates(orm.STATE_SYSTEM)
+	}
+
+	// Seal Store Key to User's Share Key
+	e := o.ShareStoreKey(user.ShareKey(), key)
+	if e != nil {
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the User have an Account Recovery Key?
+	if user.HasRecoveryKey() { // YES: Seal Store Key to it
+		e = o.SealRecoveryKeyBytes(user.RecoveryKey(), key)
+		if e != nil {
+			r.Abort(5100, nil)
+			return
+		}
+	}
+
+	// Flush Changes
+	r.SetLocal("registry-object-user", o)
+	object.DBObjectUserFlush(r, c)
+	if !r.Aborted() {
+		// Save Entry
+		r.SetLocal("registry-store-user", o)
+	}
+}
+
+func DBStoreUserAcceptShare(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store User Registry Entry
+	rus := r.MustGet("registry-store-user").(*orm.ObjectUserRegistry)
+
+	// Was the Store Shared with the User (and not yet Accepted)?
+	if !rus.IsPendingShare() { // NO: Nothing to Do
+		return
+	}
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to User's Shard
+	uid := rus.User()
+	db, e := dbm.Connect(uid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get User
+	u := &orm.User{}
+	e = u.ByID(db, common.LocalIDFromID(uid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Unlock User's Share Private Key
+	hash := hex.EncodeToString(r.MustGet("user-credentials").([]byte))
+	private, e := u.ShareKeyPrivate(hash)
+	if e != nil {
+		r.Abort(3998 /* TODO: Error Code - Invalid Credentials */, nil)
+		return
+	}
+
+	// User's Password Key (Accepted Store Key is Wrapped with it)
+	user.DBUserPasswordKey(r, c)
+	if r.IsFinished() {
+		return
+	}
+
+	// Accept Store Key
+	_, e = rus.AcceptSharedStoreKey(private, r.MustGet("user-password-key").(*orm.PasswordKey))
+	if e != nil {
+		r.Abort(4205, nil)
+		return
+	}
+
+	// Save Registry Entry
+	DBStoreUserUpdate(r, c)
+}
+
+// DBStoreUserShareKeys Share Key Pair of Session User (Required to Rotate the Store Key)
+func DBStoreUserShareKeys(r rpf.GINProcessor, c *gin.Context) {
+	// Already Unlocked?
+	if r.Has("user-share-private") { // YES: Nothing to Do
+		return
+	}
+
+	// Get Session User and Credentials
+	uid := r.MustGet("user-id").(uint64)
+	hash := hex.EncodeToString(r.MustGet("user-credentials").([]byte))
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to User's Shard
+	db, e := dbm.Connect(uid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get User
+	u := &orm.User{}
+	e = u.ByID(db, common.LocalIDFromID(uid))
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Does the User have a Share Key?
+	if !u.HasShareKey() { // NO: User has to Login Once to Create One
+		r.Abort(4208, nil)
+		return
+	}
+
+	// Unlock User's Share Private Key
+	private, e := u.ShareKeyPrivate(hash)
+	if e != nil {
+		r.Abort(3998 /* TODO: Error Code - Invalid Credentials */, nil)
+		return
+	}
+
+	r.SetLocal("user-share-public", u.ShareKey())
+	r.SetLocal("user-share-private", private)
+}
+
+// dbUserSharePrivate Unlock Store User's Share Private Key (Opens Rotated Store Keys Sealed to the User)
+func dbUserSharePrivate(c *gin.Context, uid uint64, hash []byte) ([]byte, error) {
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to User's Shard
+	db, e := dbm.Connect(uid)
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	// Get User
+	u := &orm.User{}
+	e = u.ByID(db, common.LocalIDFromID(uid))
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	return u.ShareKeyPrivate(hex.EncodeToString(hash))
+}
+
+// unlockStoreKey Unlock Store User's Store Key, Applying any Pending Rotation
+func unlockStoreKey(c *gin.Context, rus *orm.ObjectUserRegistry, k *orm.PasswordKey, hash []byte) ([]byte, error) {
+	// Is a Rotated Key Pending (Sealed to the User's Share Key)?
+	var private []byte
+	if rus.HasPendingStoreKey() { // YES: Unlock Share Private Key
+		var e error
+		private, e = dbUserSharePrivate(c, rus.User(), hash)
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	return rus.ApplyPendingStoreKey(k, private)
+}
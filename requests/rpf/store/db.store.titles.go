@@ -0,0 +1,40 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Store Settings: Object Titles
+const SETTING_TITLES_SEALED = "titles.sealed" // POLICY: Encrypt Object Titles (and Folder Names) with the Store Key
+
+// StoreTitlesSealed Are Object Titles Encrypted with the Store Key?
+func StoreTitlesSealed(store *orm.Store) bool {
+	return settingBool(store.Settings(), SETTING_TITLES_SEALED, false)
+}
+
+func DBStoreTitlesPolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Store Titles Policy
+	r.SetLocal("store-titles-sealed", StoreTitlesSealed(store))
+}
@@ -0,0 +1,48 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package store
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/org"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Store Settings: Trash
+const SETTING_TRASH_RETENTION = "trash.retention" // POLICY: Days Deleted Objects are Kept in Trash (0 - Until Purged by a User)
+
+// Default Days Deleted Objects are Kept in Trash
+const TRASH_RETENTION_DEFAULT = 30
+
+// StoreTrashRetention Days Deleted Objects are Kept in Trash
+func StoreTrashRetention(store *orm.Store) uint16 {
+	settings := store.Settings()
+	if settings.Has(SETTING_TRASH_RETENTION) {
+		return org.SettingUint16(settings, SETTING_TRASH_RETENTION)
+	}
+	return TRASH_RETENTION_DEFAULT
+}
+
+func DBStoreTrashPolicy(r rpf.GINProcessor, c *gin.Context) {
+	// Get Store
+	DBStoreGetByID(r, c)
+	if r.IsFinished() {
+		return
+	}
+	store := r.MustGet("store").(*orm.Store)
+
+	// Get Store Trash Retention
+	r.SetLocal("store-trash-retention", StoreTrashRetention(store))
+}
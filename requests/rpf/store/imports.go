@@ -17,8 +17,10 @@ import (
 	"strings"
 
 	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/org"
 	"github.com/objectvault/api-services/requests/rpf/utils"
 	"github.com/objectvault/api-services/xjson"
+	"github.com/objectvault/common/maps"
 
 	rpf "github.com/objectvault/goginrpf"
 
@@ -106,6 +108,132 @@ func UpdateFromJSON(r rpf.GINProcessor, c *gin.Context) {
 		return nil
 	})
 
+	// OPTIONAL: Store Policy - Rotate Store Key when a User is Removed
+	vmap.Optional("rotate_on_remove", nil, xjson.F_xToBoolean, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_ROTATE_ON_REMOVE, v.(bool), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Store Session Mode
+	vmap.Optional("session_mode", nil, func(v interface{}) (interface{}, error) {
+		v, e := xjson.F_xToTrimmedString(v)
+		if e != nil {
+			return nil, e
+		}
+
+		s := strings.ToLower(v.(string))
+		switch s {
+		case SESSION_MODE_DEFAULT, SESSION_MODE_OPERATIONS, SESSION_MODE_TRANSACTION:
+			return s, nil
+		}
+		return nil, errors.New("Value is not a valid store session mode")
+	}, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_SESSION_MODE, v.(string), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Operations per Store Session (Operations Mode)
+	vmap.Optional("session_operations", nil, func(v interface{}) (interface{}, error) {
+		v, e := xjson.F_xToUint64(v)
+		if e != nil {
+			return nil, e
+		}
+
+		if v.(uint64) == 0 || v.(uint64) > 65535 {
+			return nil, errors.New("Value has to be between 1 and 65535")
+		}
+		return v, nil
+	}, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_SESSION_OPERATIONS, v.(uint64), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Store Session Idle Timeout in Minutes (0 to Use Organization Policy)
+	vmap.Optional("session_idle", nil, org.F_xToMinutes, nil, func(v interface{}) error {
+		if v != nil {
+			return org.SetSettingMinutes(e.Settings(), SETTING_SESSION_IDLE, v.(uint64))
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Store Session Maximum Lifetime in Minutes (0 to Use Organization Policy)
+	vmap.Optional("session_lifetime", nil, org.F_xToMinutes, nil, func(v interface{}) error {
+		if v != nil {
+			return org.SetSettingMinutes(e.Settings(), SETTING_SESSION_LIFETIME, v.(uint64))
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Can a Status Check Extend the Store Session?
+	vmap.Optional("session_extend", nil, xjson.F_xToBoolean, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_SESSION_EXTEND, v.(bool), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Revisions Kept per Object (0 - No Revisions)
+	vmap.Optional("revisions_count", nil, F_xToRevisionsCount, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_REVISIONS_COUNT, v.(uint64), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Maximum Age of Revisions in Days (0 - No Limit)
+	vmap.Optional("revisions_age", nil, F_xToRevisionsAge, nil, func(v interface{}) error {
+		if v != nil {
+			return setSettingDays(e.Settings(), SETTING_REVISIONS_AGE, v.(uint64))
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Days Deleted Objects are Kept in Trash (0 - Until Purged by a User)
+	vmap.Optional("trash_retention", nil, org.F_xToMinutes, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_TRASH_RETENTION, v.(uint64), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Total Size of Attachments in MiB (0 - No Limit)
+	vmap.Optional("attachments_quota", nil, org.F_xToMinutes, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_ATTACHMENTS_QUOTA, v.(uint64), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Encrypt Object Titles with the Store Key
+	vmap.Optional("sealed_titles", nil, xjson.F_xToBoolean, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_TITLES_SEALED, v.(bool), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Keep a Plaintext Index of Object Tags
+	vmap.Optional("indexed_tags", nil, xjson.F_xToBoolean, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_TAGS_INDEXED, v.(bool), true)
+		}
+		return nil
+	})
+
+	// OPTIONAL: Store Policy - Object Updates and Deletes Require 'If-Match' Header
+	vmap.Optional("require_if_match", nil, xjson.F_xToBoolean, nil, func(v interface{}) error {
+		if v != nil {
+			return e.Settings().Set(SETTING_OBJECTS_IF_MATCH, v.(bool), true)
+		}
+		return nil
+	})
+
 	// Did we have an Error Processing the Map?
 	if vmap.Error != nil {
 		fmt.Println(vmap.Error)
@@ -113,4 +241,44 @@ func UpdateFromJSON(r rpf.GINProcessor, c *gin.Context) {
 		r.Abort(5202, nil)
 		return
 	}
+
+	// New Stores have no Legacy (Pre-Envelope) Object Ciphers
+	e.Settings().Set(SETTING_OBJECTS_ENVELOPE, true, true)
+}
+
+// F_xToRevisionsCount Revisions Kept per Object (0 - REVISIONS_COUNT_MAX)
+func F_xToRevisionsCount(v interface{}) (interface{}, error) {
+	v, e := xjson.F_xToUint64(v)
+	if e != nil {
+		return nil, e
+	}
+
+	if v.(uint64) > REVISIONS_COUNT_MAX {
+		return nil, fmt.Errorf("Value has to be between 0 and %d", REVISIONS_COUNT_MAX)
+	}
+	return v, nil
+}
+
+// F_xToRevisionsAge Maximum Age of Revisions in Days (0 - REVISIONS_AGE_MAX)
+func F_xToRevisionsAge(v interface{}) (interface{}, error) {
+	v, e := xjson.F_xToUint64(v)
+	if e != nil {
+		return nil, e
+	}
+
+	if v.(uint64) > REVISIONS_AGE_MAX {
+		return nil, fmt.Errorf("Value has to be between 0 and %d days", REVISIONS_AGE_MAX)
+	}
+	return v, nil
+}
+
+// setSettingDays Set Period in Days (0 Clears the Setting, i.e. No Limit)
+func setSettingDays(settings *maps.MapWrapper, path string, v uint64) error {
+	if v == 0 {
+		if settings.Has(path) {
+			return settings.Clear(path)
+		}
+		return nil
+	}
+	return settings.Set(path, v, true)
 }
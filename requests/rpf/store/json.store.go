@@ -17,6 +17,7 @@ import (
 	"fmt"
 
 	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/requests/rpf/org"
 )
 
 type BasicStoreToJSON struct {
@@ -54,17 +55,51 @@ func (o *FullStoreToJSON) MarshalJSON() ([]byte, error) {
 		return nil, errors.New("Missing or Invalid Value [Registry, Store]")
 	}
 
+	// Store Policies
+	v, _ := o.Store.Settings().GetDefault(SETTING_ROTATE_ON_REMOVE, false)
+	rotate, _ := v.(bool)
+	// NOTE: Store Settings Only (Organization Policy is Applied when the Store is Opened)
+	p := StoreSessionPolicy(o.Store, nil)
+	revisions, age := StoreRevisionPolicy(o.Store)
+	settings := o.Store.Settings()
+
 	return json.Marshal(&struct {
-		ID    string `json:"id"`
-		Org   string `json:"organization"`
-		Alias string `json:"alias"`
-		Name  string `json:"name"`
-		State uint16 `json:"state"`
+		ID     string `json:"id"`
+		Org    string `json:"organization"`
+		Alias  string `json:"alias"`
+		Name   string `json:"name"`
+		State  uint16 `json:"state"`
+		Rotate bool   `json:"rotate_on_remove"`
+		Mode   string `json:"session_mode"`
+		Ops    uint16 `json:"session_operations,omitempty"`
+		Idle   uint16 `json:"session_idle,omitempty"`
+		Life   uint16 `json:"session_lifetime,omitempty"`
+		Extend bool   `json:"session_extend"`
+		Revs   uint16 `json:"revisions_count"`
+		RevAge uint16 `json:"revisions_age,omitempty"`
+		Trash  uint16 `json:"trash_retention"`
+		Quota  uint16 `json:"attachments_quota"`
+		Sealed bool   `json:"sealed_titles"`
+		Tags   bool   `json:"indexed_tags"`
+		Match  bool   `json:"require_if_match"`
 	}{
-		ID:    fmt.Sprintf(":%x", o.Registry.Store()),
-		Org:   fmt.Sprintf(":%x", o.Registry.Organization()),
-		Alias: o.Store.Alias(),
-		Name:  o.Store.Name(),
-		State: o.Registry.State(),
+		ID:     fmt.Sprintf(":%x", o.Registry.Store()),
+		Org:    fmt.Sprintf(":%x", o.Registry.Organization()),
+		Alias:  o.Store.Alias(),
+		Name:   o.Store.Name(),
+		State:  o.Registry.State(),
+		Rotate: rotate,
+		Mode:   StoreSessionModeSetting(p.Mode),
+		Ops:    p.Operations,
+		Idle:   org.SettingUint16(settings, SETTING_SESSION_IDLE),
+		Life:   org.SettingUint16(settings, SETTING_SESSION_LIFETIME),
+		Extend: p.Extend,
+		Revs:   revisions,
+		RevAge: age,
+		Trash:  StoreTrashRetention(o.Store),
+		Quota:  StoreAttachmentsQuota(o.Store),
+		Sealed: StoreTitlesSealed(o.Store),
+		Tags:   StoreTagsIndexed(o.Store),
+		Match:  StoreRequiresIfMatch(o.Store),
 	})
 }
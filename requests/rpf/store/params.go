@@ -64,3 +64,23 @@ func ExtractGINParameterStoreID(r rpf.GINProcessor, c *gin.Context) {
 
 	r.SetLocal("request-store", iid)
 }
+
+// ExtractURLParameterRemoveUnreachable Key Rotation Removes Store Users without a Share Key ('remove-unreachable' Query Parameter)
+func ExtractURLParameterRemoveUnreachable(r rpf.GINProcessor, c *gin.Context) {
+	v, message := utils.ValidateURLParameter(c, "remove-unreachable", false, true, true)
+	if message != "" {
+		fmt.Println(message)
+		r.Abort(3300, nil)
+		return
+	}
+
+	switch strings.ToLower(v) {
+	case "", "0", "false":
+		r.SetLocal("request-remove-unreachable", false)
+	case "1", "true":
+		r.SetLocal("request-remove-unreachable", true)
+	default:
+		fmt.Println("Parameter 'remove-unreachable' is not a valid boolean")
+		r.Abort(3300, nil)
+	}
+}
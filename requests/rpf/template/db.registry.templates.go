@@ -76,7 +76,7 @@ func DBGetObjectTemplateRegistry(r rpf.GINProcessor, c *gin.Context) {
 
 	// Did we find the Template?
 	if !o.IsValid() { // NO: Template Not Registered
-		r.Abort(4998 /* TODO: Error [Template not registered with Object] */, nil)
+		r.Abort(4401, nil)
 		return
 	}
 
@@ -0,0 +1,281 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package template
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/common/maps"
+
+	rpf "github.com/objectvault/goginrpf"
+)
+
+/* NOTE: Template Model
+ * The template model describes the object values in "fields", by field name:
+ * {
+ *   "fields": {
+ *     "<name>": {
+ *       "label": "...",
+ *       "type": "string" | "text" | "password" | "email" | "url" | "number" | "integer" | "boolean" | ...,
+ *       "settings": {
+ *         "required": true | false,
+ *         "max-length": <characters>,
+ *         "options": [ <value> | { "value": <value>, "label": "..." }, ... ]
+ *       },
+ *       "checks": {
+ *         "allow-empty": true | false
+ *       }
+ *     }
+ *   }
+ * }
+ * Field types that are not known to the validator are not type checked.
+ * Values for fields that are not in the model are left as is.
+ */
+
+// Standard Definition for the Object Title Field
+var titleField = map[string]interface{}{
+	"label": "Title",
+	"type":  "string",
+	"settings": map[string]interface{}{
+		"max-length": 40,
+		"required":   true,
+	},
+	"checks": map[string]interface{}{
+		"allow-empty": false,
+	},
+}
+
+// Fixed Model for Attachment Objects (Built-in Type, without a Registered Template)
+var attachmentFields = map[string]interface{}{
+	"__title": titleField,
+	"notes": map[string]interface{}{
+		"label": "Notes",
+		"type":  "text",
+		"settings": map[string]interface{}{
+			"max-length": 4096,
+		},
+	},
+}
+
+// Field Types that hold Strings
+var stringFieldTypes = map[string]bool{
+	"string":   true,
+	"text":     true,
+	"password": true,
+	"email":    true,
+	"url":      true,
+	"date":     true,
+}
+
+// ModelFields Template Model Field Definitions (Including the Title Field)
+func ModelFields(t *orm.Template) (map[string]interface{}, error) {
+	model, e := t.ModelJSON()
+	if e != nil {
+		return nil, e
+	}
+
+	fields := map[string]interface{}{}
+	if v, ok := model["fields"].(map[string]interface{}); ok {
+		for name, field := range v {
+			fields[name] = field
+		}
+	}
+
+	// Does the Model Define the Title Field?
+	if _, ok := fields["__title"]; !ok { // NO: Use Standard Definition
+		fields["__title"] = titleField
+	}
+	return fields, nil
+}
+
+// ValidateModelValues Validate Object Values against Template Model (Returns Errors by Field Name)
+func ValidateModelValues(fields map[string]interface{}, values map[string]interface{}) map[string]string {
+	errs := map[string]string{}
+	for name, v := range fields {
+		field, ok := v.(map[string]interface{})
+		if !ok { // Invalid Field Definition: Skip
+			continue
+		}
+
+		if msg := validateField(field, values[name]); msg != "" {
+			errs[name] = msg
+		}
+	}
+
+	// Is the Title Reserved (Looks like a Sealed Title)?
+	if s, ok := values["__title"].(string); ok && orm.IsSealedTitle(s) { // YES
+		errs["__title"] = "Value can't start with '" + orm.TITLE_SEALED_PREFIX + "'"
+	}
+
+	return errs
+}
+
+// ValidateAttachmentValues Validate Attachment Object Values against the Fixed Attachment Model (Returns Errors by Field Name)
+func ValidateAttachmentValues(values map[string]interface{}) map[string]string {
+	errs := ValidateModelValues(attachmentFields, values)
+
+	// NOTE: Attachment Objects only Hold the Model Fields
+	for name := range values {
+		if _, ok := attachmentFields[name]; !ok {
+			errs[name] = "Field is not Allowed"
+		}
+	}
+
+	return errs
+}
+
+func validateField(field map[string]interface{}, v interface{}) string {
+	required := modelBool(field, "settings.required", false)
+	allowEmpty := modelBool(field, "checks.allow-empty", true)
+
+	// Do we have a Value?
+	if v == nil { // NO
+		if required {
+			return "Value is Required"
+		}
+		return ""
+	}
+
+	// Validate Value Type
+	t, _ := maps.GetDefault(field, "type", "")
+	ft := strings.ToLower(fmt.Sprint(t))
+	switch {
+	case stringFieldTypes[ft]:
+		s, ok := v.(string)
+		if !ok {
+			return "Value is not a String"
+		}
+
+		// Is Value Empty?
+		if strings.TrimSpace(s) == "" { // YES
+			if required || !allowEmpty {
+				return "Value is Required"
+			}
+			return ""
+		}
+
+		// Is Value too Long?
+		max := modelNumber(field, "settings.max-length")
+		if max > 0 && float64(utf8.RuneCountInString(s)) > max { // YES
+			return fmt.Sprintf("Value is Longer than %d Characters", int(max))
+		}
+	case ft == "number":
+		if _, ok := v.(float64); !ok {
+			return "Value is not a Number"
+		}
+	case ft == "integer":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return "Value is not an Integer"
+		}
+	case ft == "boolean":
+		if _, ok := v.(bool); !ok {
+			return "Value is not a Boolean"
+		}
+	}
+
+	// Is Value Limited to a Set of Options?
+	options, _ := maps.GetDefault(field, "settings.options", nil)
+	if list, ok := options.([]interface{}); ok && len(list) > 0 { // YES
+		for _, option := range list {
+			// Option with Label?
+			if m, ok := option.(map[string]interface{}); ok { // YES: Use Value
+				option = m["value"]
+			}
+
+			if option == v {
+				return ""
+			}
+		}
+		return "Value is not a Valid Option"
+	}
+
+	return ""
+}
+
+func modelBool(field map[string]interface{}, path string, d bool) bool {
+	v, _ := maps.GetDefault(field, path, d)
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return d
+}
+
+func modelNumber(field map[string]interface{}, path string) float64 {
+	v, _ := maps.GetDefault(field, path, nil)
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	}
+	return 0
+}
+
+func DBGetTemplateVersion(r rpf.GINProcessor, c *gin.Context) {
+	// Get Template
+	name := r.MustGet("request-template").(string)
+	version := r.MustGet("request-template-version").(uint16)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to Global Shard
+	db, err := dbm.ConnectTo(0, 0)
+	if err != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Get Template Version
+	template := &orm.Template{}
+	err = template.ByNameVersion(db, name, version)
+
+	// Failed Retrieving Template?
+	if err != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// Did we find the Template Version?
+	if !template.IsValid() { // NO
+		r.Abort(4400, nil)
+		return
+	}
+
+	r.Set("template", template)
+}
+
+func AssertTemplateValues(r rpf.GINProcessor, c *gin.Context) {
+	// Get Template and Values
+	template := r.MustGet("template").(*orm.Template)
+	values := r.MustGet("template-values").(map[string]interface{})
+
+	// Get Model Fields
+	fields, e := ModelFields(template)
+	if e != nil { // YES: Invalid Template Model
+		r.Abort(5901, nil)
+		return
+	}
+
+	// Do the Values Match the Model?
+	errs := ValidateModelValues(fields, values)
+	if len(errs) > 0 { // NO
+		r.Abort(4402, &gin.H{"fields": errs})
+		return
+	}
+}
@@ -0,0 +1,270 @@
+// cSpell:ignore goginrpf, gonic, keygen, paulo ferreira, vmap, wordlist, xjson
+package tools
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/objectvault/api-services/orm/keygen"
+	"github.com/objectvault/api-services/xjson"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Generator Types
+const GENERATE_PASSWORD = "password"
+const GENERATE_PASSPHRASE = "passphrase"
+
+// Maximum Number of Values Generated per Request
+const GENERATE_MAX_COUNT = 20
+
+// Maximum Number of Personal Inputs (Names, E-Mails, ...) for Strength Estimate
+const STRENGTH_MAX_INPUTS = 20
+
+// Password Generator Defaults
+const DEFAULT_PASSWORD_LENGTH = 20
+const DEFAULT_PASSPHRASE_WORDS = 6
+const DEFAULT_PASSPHRASE_SEPARATOR = "-"
+
+func toInt(v interface{}) (interface{}, error) {
+	v, e := xjson.F_xToInt64(v)
+	if e != nil {
+		return nil, e
+	}
+
+	return int(v.(int64)), nil
+}
+
+// ExtractJSONGeneratorOptions Password or Passphrase Generator Options from JSON Body
+func ExtractJSONGeneratorOptions(r rpf.GINProcessor, c *gin.Context) {
+	// Extract and Validate JSON Message
+	m := r.MustGet("request-json").(xjson.T_xMap)
+	vmap := xjson.S_xJSONMap{Source: m}
+
+	// OPTIONAL: Generator Type (DEFAULT: password)
+	gtype := GENERATE_PASSWORD
+	vmap.Optional("type", nil, func(v interface{}) (interface{}, error) {
+		v, e := xjson.F_xToTrimmedString(v)
+		if e != nil {
+			return nil, e
+		}
+
+		s := strings.ToLower(v.(string))
+		if s != GENERATE_PASSWORD && s != GENERATE_PASSPHRASE {
+			return nil, errors.New("Value is not a valid generator type")
+		}
+		return s, nil
+	}, GENERATE_PASSWORD, func(v interface{}) error {
+		gtype = v.(string)
+		return nil
+	})
+
+	// OPTIONAL: Number of Values to Generate (DEFAULT: 1)
+	vmap.Optional("count", nil, func(v interface{}) (interface{}, error) {
+		v, e := toInt(v)
+		if e != nil {
+			return nil, e
+		}
+
+		if v.(int) < 1 || v.(int) > GENERATE_MAX_COUNT {
+			return nil, fmt.Errorf("Value has to be between 1 and %d", GENERATE_MAX_COUNT)
+		}
+		return v, nil
+	}, 1, func(v interface{}) error {
+		r.SetLocal("generate-count", v.(int))
+		return nil
+	})
+
+	// Did we have an Error Processing the Map?
+	if vmap.Error != nil {
+		fmt.Println(vmap.Error)
+		fmt.Println(vmap.StringSrc())
+		r.Abort(5202, nil)
+		return
+	}
+
+	// Generator Options
+	if gtype == GENERATE_PASSPHRASE {
+		extractPassphraseOptions(r, &vmap)
+	} else {
+		extractPasswordOptions(r, &vmap)
+	}
+}
+
+func extractPasswordOptions(r rpf.GINProcessor, vmap *xjson.S_xJSONMap) {
+	o := &keygen.PasswordOptions{}
+
+	// OPTIONAL: Password Length
+	vmap.Optional("length", nil, toInt, DEFAULT_PASSWORD_LENGTH, func(v interface{}) error {
+		o.Length = v.(int)
+		return nil
+	})
+
+	// OPTIONAL: Character Classes (DEFAULT: All)
+	vmap.Optional("lower", nil, xjson.F_xToBoolean, true, func(v interface{}) error {
+		o.Lower = v.(bool)
+		return nil
+	})
+	vmap.Optional("upper", nil, xjson.F_xToBoolean, true, func(v interface{}) error {
+		o.Upper = v.(bool)
+		return nil
+	})
+	vmap.Optional("digits", nil, xjson.F_xToBoolean, true, func(v interface{}) error {
+		o.Digits = v.(bool)
+		return nil
+	})
+	vmap.Optional("symbols", nil, xjson.F_xToBoolean, true, func(v interface{}) error {
+		o.Symbols = v.(bool)
+		return nil
+	})
+
+	// OPTIONAL: Excluded Characters
+	vmap.Optional("exclude", nil, xjson.F_xToString, "", func(v interface{}) error {
+		o.Exclude = v.(string)
+		return nil
+	})
+	vmap.Optional("exclude_ambiguous", nil, xjson.F_xToBoolean, false, func(v interface{}) error {
+		o.ExcludeAmbiguous = v.(bool)
+		return nil
+	})
+
+	// Did we have an Error Processing the Map?
+	if vmap.Error != nil {
+		fmt.Println(vmap.Error)
+		fmt.Println(vmap.StringSrc())
+		r.Abort(5202, nil)
+		return
+	}
+
+	// Can Passwords be Generated with Options?
+	e := o.Validate()
+	if e != nil { // NO
+		fmt.Println(e)
+		r.Abort(3100, nil)
+		return
+	}
+
+	r.SetLocal("generate-type", GENERATE_PASSWORD)
+	r.SetLocal("password-options", o)
+}
+
+func extractPassphraseOptions(r rpf.GINProcessor, vmap *xjson.S_xJSONMap) {
+	o := &keygen.PassphraseOptions{}
+
+	// OPTIONAL: Number of Words
+	vmap.Optional("words", nil, toInt, DEFAULT_PASSPHRASE_WORDS, func(v interface{}) error {
+		o.Words = v.(int)
+		return nil
+	})
+
+	// OPTIONAL: Word Separator
+	vmap.Optional("separator", nil, xjson.F_xToString, DEFAULT_PASSPHRASE_SEPARATOR, func(v interface{}) error {
+		o.Separator = v.(string)
+		return nil
+	})
+
+	// OPTIONAL: Capitalize Words / Add Digit
+	vmap.Optional("capitalize", nil, xjson.F_xToBoolean, false, func(v interface{}) error {
+		o.Capitalize = v.(bool)
+		return nil
+	})
+	vmap.Optional("digit", nil, xjson.F_xToBoolean, false, func(v interface{}) error {
+		o.Digit = v.(bool)
+		return nil
+	})
+
+	// OPTIONAL: Word List
+	vmap.Optional("wordlist", nil, xjson.F_xToTrimmedString, keygen.WORDLIST_DEFAULT, func(v interface{}) error {
+		o.Wordlist = strings.ToLower(v.(string))
+		return nil
+	})
+
+	// Did we have an Error Processing the Map?
+	if vmap.Error != nil {
+		fmt.Println(vmap.Error)
+		fmt.Println(vmap.StringSrc())
+		r.Abort(5202, nil)
+		return
+	}
+
+	// Can Passphrases be Generated with Options?
+	e := o.Validate()
+	if e != nil { // NO
+		fmt.Println(e)
+		r.Abort(3100, nil)
+		return
+	}
+
+	r.SetLocal("generate-type", GENERATE_PASSPHRASE)
+	r.SetLocal("passphrase-options", o)
+}
+
+// ExtractJSONPasswordStrength Password (and Personal Inputs) to Evaluate from JSON Body
+func ExtractJSONPasswordStrength(r rpf.GINProcessor, c *gin.Context) {
+	// Extract and Validate JSON Message
+	m := r.MustGet("request-json").(xjson.T_xMap)
+	vmap := xjson.S_xJSONMap{Source: m}
+
+	// REQUIRED: Password (NOTE: Not Trimmed, Spaces are part of the Password)
+	vmap.Required("password", nil, func(v interface{}) (interface{}, error) {
+		v, e := xjson.F_xToString(v)
+		if e != nil {
+			return nil, e
+		}
+
+		l := utf8.RuneCountInString(v.(string))
+		if l == 0 || l > keygen.PASSWORD_STRENGTH_MAX_LENGTH {
+			return nil, fmt.Errorf("Value has to have between 1 and %d characters", keygen.PASSWORD_STRENGTH_MAX_LENGTH)
+		}
+		return v, nil
+	}, func(v interface{}) error {
+		r.SetLocal("request-password", v.(string))
+		return nil
+	})
+
+	// OPTIONAL: Personal Inputs (User Names, E-Mails, Site Names, ...)
+	vmap.Optional("inputs", nil, func(v interface{}) (interface{}, error) {
+		list, ok := v.([]interface{})
+		if !ok || len(list) > STRENGTH_MAX_INPUTS {
+			return nil, fmt.Errorf("Value has to be a list with at most %d strings", STRENGTH_MAX_INPUTS)
+		}
+
+		inputs := make([]string, 0, len(list))
+		for _, i := range list {
+			s, ok := i.(string)
+			if !ok {
+				return nil, errors.New("Value contains an invalid input")
+			}
+
+			s = strings.TrimSpace(s)
+			if s != "" {
+				inputs = append(inputs, s)
+			}
+		}
+		return inputs, nil
+	}, []string{}, func(v interface{}) error {
+		r.SetLocal("request-password-inputs", v.([]string))
+		return nil
+	})
+
+	// Did we have an Error Processing the Map? (NOTE: Source not Logged, it has the Password)
+	if vmap.Error != nil {
+		fmt.Println(vmap.Error)
+		r.Abort(5202, nil)
+		return
+	}
+}
@@ -0,0 +1,88 @@
+// cSpell:ignore goginrpf, gonic, keygen, paulo ferreira
+package tools
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/objectvault/api-services/orm"
+	"github.com/objectvault/api-services/orm/keygen"
+
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeneratePasswords Generate Passwords (or Passphrases) with Request Options
+func GeneratePasswords(r rpf.GINProcessor, c *gin.Context) {
+	count := r.MustGet("generate-count").(int)
+	gtype := r.MustGet("generate-type").(string)
+
+	var entropy float64
+	var generate func() (string, error)
+	if gtype == GENERATE_PASSPHRASE {
+		o := r.MustGet("passphrase-options").(*keygen.PassphraseOptions)
+		entropy = o.Entropy()
+		generate = func() (string, error) {
+			return keygen.Passphrase(o)
+		}
+	} else {
+		o := r.MustGet("password-options").(*keygen.PasswordOptions)
+		entropy = o.Entropy()
+		generate = func() (string, error) {
+			return keygen.Password(o)
+		}
+	}
+
+	list := make([]string, count)
+	for i := range list {
+		v, e := generate()
+		if e != nil { // Random Source Failure
+			fmt.Println(e)
+			r.Abort(5900, nil)
+			return
+		}
+		list[i] = v
+	}
+
+	r.SetLocal("generated-passwords", list)
+	r.SetLocal("generated-entropy", entropy)
+}
+
+// EvaluatePasswordStrength Estimate Strength of Request Password
+func EvaluatePasswordStrength(r rpf.GINProcessor, c *gin.Context) {
+	password := r.MustGet("request-password").(string)
+	inputs := r.MustGet("request-password-inputs").([]string)
+
+	// Session User Information is Always Considered Personal Input
+	if r.Has("registry-user") {
+		u := r.Get("registry-user").(*orm.UserRegistry)
+		inputs = append(inputs, u.UserName(), u.Name(), u.Email())
+	}
+
+	r.SetLocal("password-strength", keygen.EvaluatePassword(password, inputs))
+}
+
+// EXPORTS //
+func ExportGeneratedPasswords(r rpf.GINProcessor, c *gin.Context) {
+	r.SetResponseDataValue("type", r.MustGet("generate-type").(string))
+	r.SetResponseDataValue("passwords", r.MustGet("generated-passwords").([]string))
+
+	// Entropy (Rounded to 2 Decimal Places)
+	entropy := r.MustGet("generated-entropy").(float64)
+	r.SetResponseDataValue("entropy", math.Round(entropy*100)/100)
+}
+
+func ExportPasswordStrength(r rpf.GINProcessor, c *gin.Context) {
+	r.SetResponseDataValue("strength", r.MustGet("password-strength").(*keygen.PasswordStrength))
+}
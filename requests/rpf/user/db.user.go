@@ -13,6 +13,7 @@ package user
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/objectvault/api-services/common"
 	"github.com/objectvault/api-services/orm"
@@ -123,3 +124,154 @@ func DBUserUpdate(r rpf.GINProcessor, c *gin.Context) {
 		return
 	}
 }
+
+func DBUserUpgradeHash(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request User and (Verified) Password Hash
+	ur := r.MustGet("registry-user").(*orm.UserRegistry)
+	hash := r.MustGet("hash").(string)
+
+	// NOTE: Password Cipher Text, and Share Private Key, Checked against Current KDF
+	// IMPORTANT: Failure to Upgrade should not Stop the Request (Retried on Next Login)
+	e := dbUserUpgradeHash(c.MustGet("dbm").(*orm.DBSessionManager), ur, hash)
+	if e != nil {
+		log.Printf("user hash upgrade [%x]: %v\n", ur.ID(), e)
+	}
+}
+
+func dbUserUpgradeHash(dbm *orm.DBSessionManager, ur *orm.UserRegistry, hash string) error {
+	// Get Connection to User Shard
+	db, e := dbm.Connect(ur.ID())
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Get User
+	u := &orm.User{}
+	e = u.ByID(db, common.LocalIDFromID(ur.ID()))
+	if e != nil || u.IsNew() { // YES: Database Error or Missing User
+		return e
+	}
+
+	// Re-Encrypt Password Cipher Text and Share Private Key
+	modified, e := u.UpgradeHash(hash)
+	if e != nil || !modified {
+		return e
+	}
+
+	// Save User (Self Modified)
+	u.SetModifier(ur.ID())
+	e = u.Flush(db, false)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Get Connection to Global Registry Shard
+	rdb, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Update Registry Copy
+	ur.UpdatePassword(u)
+	return ur.Flush(rdb, false)
+}
+
+// DBUserPasswordKey Key Wrapping Key Derived from Request User's Credentials (Store Keys)
+func DBUserPasswordKey(r rpf.GINProcessor, c *gin.Context) {
+	// Already Derived?
+	if r.Has("user-password-key") { // YES: Nothing to Do
+		return
+	}
+
+	// Get Request User and Credentials
+	uid := r.MustGet("user-id").(uint64)
+	hash := r.MustGet("user-credentials").([]byte)
+
+	// Do we have the User's Registry Entry (KDF Parameters and Salt)?
+	ur, ok := r.Get("registry-user").(*orm.UserRegistry)
+	if !ok || ur.ID() != uid { // NO: Get it
+		// Get Database Connection Manager
+		dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+		// Get Connection to User Registry
+		db, e := dbm.ConnectTo(0, 0)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		ur = &orm.UserRegistry{}
+		e = ur.ByID(db, uid)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Did we find the User?
+		if !ur.IsValid() { // NO: User does not exist
+			r.Abort(4000, nil)
+			return
+		}
+	}
+
+	r.SetLocal("user-password-key", ur.PasswordKey(hash))
+}
+
+func DBUserCreateShareKey(r rpf.GINProcessor, c *gin.Context) {
+	// Get Request User and (Verified) Password Hash
+	ur := r.MustGet("registry-user").(*orm.UserRegistry)
+	hash := r.MustGet("hash").(string)
+
+	// Does the User have a Store Sharing Key?
+	if ur.HasShareKey() { // YES: Nothing to Do
+		return
+	}
+
+	// IMPORTANT: Failure to Create should not Stop the Request (Retried on Next Login)
+	e := dbUserCreateShareKey(c.MustGet("dbm").(*orm.DBSessionManager), ur, hash)
+	if e != nil {
+		log.Printf("user share key [%x]: %v\n", ur.ID(), e)
+	}
+}
+
+func dbUserCreateShareKey(dbm *orm.DBSessionManager, ur *orm.UserRegistry, hash string) error {
+	// Get Connection to User Shard
+	db, e := dbm.Connect(ur.ID())
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Get User
+	u := &orm.User{}
+	e = u.ByID(db, common.LocalIDFromID(ur.ID()))
+	if e != nil || u.IsNew() { // YES: Database Error or Missing User
+		return e
+	}
+
+	// Create Key Pair
+	created, e := u.CreateShareKey(hash)
+	if e != nil || !created {
+		return e
+	}
+
+	// Save User (Self Modified)
+	u.SetModifier(ur.ID())
+	e = u.Flush(db, false)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Get Connection to Global Registry Shard
+	rdb, e := dbm.ConnectTo(0, 0)
+	if e != nil { // YES: Database Error
+		return e
+	}
+
+	// Update Registry Copy
+	e = ur.UpdateRegistry(u)
+	if e != nil {
+		return e
+	}
+
+	return ur.Flush(rdb, false)
+}
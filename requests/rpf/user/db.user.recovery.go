@@ -0,0 +1,242 @@
+// cSpell:ignore goginrpf, gonic, paulo ferreira
+package user
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+	rpf "github.com/objectvault/goginrpf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Store Reported in Account Recovery
+type recoveryStore struct {
+	ID    string `json:"id"`
+	Alias string `json:"alias"`
+}
+
+func AssertRecoveryKey(r rpf.GINProcessor, c *gin.Context) {
+	// Get User and Recovery Key
+	u := r.MustGet("user").(*orm.User)
+	key := r.MustGet("recovery-key").(string)
+
+	// Is it the User's Account Recovery Key?
+	if !u.TestRecoveryKey(key) { // NO
+		r.Abort(3005, nil)
+		return
+	}
+}
+
+// DBUserStoresRecoverable List Stores that can (or can't) be Recovered after a Password Reset
+func DBUserStoresRecoverable(r rpf.GINProcessor, c *gin.Context) {
+	// Get User
+	uid := r.MustGet("user-id").(uint64)
+	u := r.MustGet("user").(*orm.User)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to User Shard
+	db, e := dbm.Connect(uid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// List User Stores
+	stores, e := orm.UserObjectIDsByType(db, uid, common.OTYPE_STORE)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	recoverable := []recoveryStore{}
+	lost := []recoveryStore{}
+	for _, sid := range stores {
+		// Get Store User Registry Entry
+		o, e := dbGetStoreUserEntry(dbm, sid, uid)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Still Registered?
+		if o == nil { // NO: Skip
+			continue
+		}
+
+		s, e := dbRecoveryStore(db, uid, sid)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Is Store Shared but not yet Accepted?
+		if o.IsPendingShare() { // YES: Sealed to Share Key, Kept only if Share Key can be Recovered
+			if u.HasShareRecovery() {
+				recoverable = append(recoverable, s)
+			} else {
+				lost = append(lost, s)
+			}
+			continue
+		}
+
+		// Can Store Key be Recovered?
+		if u.HasRecoveryKey() && o.HasRecoveryKey() { // YES
+			recoverable = append(recoverable, s)
+		} else { // NO
+			lost = append(lost, s)
+		}
+	}
+
+	r.SetResponseDataValue("recovery", u.HasRecoveryKey())
+	r.SetResponseDataValue("recoverable", recoverable)
+	r.SetResponseDataValue("lost", lost)
+}
+
+// DBUserStoresRecover Re-Wrap Store Keys with New Password Hash, using the Account Recovery Key
+func DBUserStoresRecover(r rpf.GINProcessor, c *gin.Context) {
+	// Get User and New Password Hash
+	uid := r.MustGet("user-id").(uint64)
+	hash := r.MustGet("request-hash").(string)
+
+	// New Password Key (Registry Entry has New KDF Parameters and Salt)
+	k, e := r.MustGet("registry-user").(*orm.UserRegistry).PasswordKeyHex(hash)
+	if e != nil {
+		r.Abort(3100, nil)
+		return
+	}
+
+	// Recovery Key (if any)
+	key := ""
+	if r.Has("recovery-key") {
+		key = r.MustGet("recovery-key").(string)
+	}
+
+	// Was Store Sharing Key Pair Kept? (Pending Shares Remain Valid)
+	kept := r.Has("user-share-kept") && r.MustGet("user-share-kept").(bool)
+
+	// Get Database Connection Manager
+	dbm := c.MustGet("dbm").(*orm.DBSessionManager)
+
+	// Get Connection to User Shard
+	db, e := dbm.Connect(uid)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	// List User Stores
+	stores, e := orm.UserObjectIDsByType(db, uid, common.OTYPE_STORE)
+	if e != nil { // YES: Database Error
+		r.Abort(5100, nil)
+		return
+	}
+
+	recovered := []recoveryStore{}
+	lost := []recoveryStore{}
+	for _, sid := range stores {
+		// Get Store User Registry Entry
+		o, e := dbGetStoreUserEntry(dbm, sid, uid)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Still Registered?
+		if o == nil { // NO: Skip
+			continue
+		}
+
+		s, e := dbRecoveryStore(db, uid, sid)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		// Is Store Shared but not yet Accepted?
+		if o.IsPendingShare() { // YES: Nothing to Re-Wrap
+			if kept {
+				recovered = append(recovered, s)
+			} else {
+				lost = append(lost, s)
+			}
+			continue
+		}
+
+		// Can Store Key be Recovered?
+		if key == "" || o.RecoverStoreKey(key, k) != nil { // NO: Store Lost
+			lost = append(lost, s)
+			continue
+		}
+
+		// Save Re-Wrapped Store Key
+		sdb, e := dbm.Connect(sid)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		e = o.Flush(sdb, false)
+		if e != nil { // YES: Database Error
+			r.Abort(5100, nil)
+			return
+		}
+
+		recovered = append(recovered, s)
+	}
+
+	r.SetResponseDataValue("recovered", recovered)
+	r.SetResponseDataValue("lost", lost)
+}
+
+func dbGetStoreUserEntry(dbm *orm.DBSessionManager, sid uint64, uid uint64) (*orm.ObjectUserRegistry, error) {
+	// Get Connection to Store Shard
+	db, e := dbm.Connect(sid)
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	// Get Store User Registry Entry
+	o := &orm.ObjectUserRegistry{}
+	e = o.ByKey(db, sid, uid)
+	if e != nil { // YES: Database Error
+		return nil, e
+	}
+
+	// Entry Exists?
+	if o.IsNew() { // NO
+		return nil, nil
+	}
+
+	return o, nil
+}
+
+func dbRecoveryStore(db *sql.DB, uid uint64, sid uint64) (recoveryStore, error) {
+	s := recoveryStore{
+		ID: fmt.Sprintf(":%x", sid),
+	}
+
+	// Get User's Store Alias
+	o := &orm.UserObjectRegistry{}
+	e := o.ByKey(db, uid, sid)
+	if e != nil { // YES: Database Error
+		return s, e
+	}
+
+	s.Alias = o.Alias()
+	return s, nil
+}
@@ -65,6 +65,13 @@ func ExportUserMe(r rpf.GINProcessor, c *gin.Context) {
 	r.SetResponseDataValue("user", d)
 }
 
+func ExportRecoveryKey(r rpf.GINProcessor, c *gin.Context) {
+	// Was an Account Recovery Key Created?
+	if r.Has("recovery-key") { // YES: Return it (Only Time it is Available)
+		r.SetResponseDataValue("recovery_key", r.MustGet("recovery-key"))
+	}
+}
+
 func ExportUserSystem(r rpf.GINProcessor, c *gin.Context) {
 	// Get User Information
 	registry := r.MustGet("registry-user").(*orm.UserRegistry)
@@ -105,3 +105,27 @@ func ExtractFormParameterCredentials(r rpf.GINProcessor, c *gin.Context) {
 
 	r.SetLocal("user-credentials", bytes)
 }
+
+// ExtractHeaderCredentials Credentials Sent with Request Header (i.e. Transaction Mode Stores)
+func ExtractHeaderCredentials(r rpf.GINProcessor, c *gin.Context) {
+	// Do we have Credentials?
+	hash := strings.TrimSpace(c.GetHeader("X-Store-Credentials"))
+	if hash == "" { // NO
+		r.Abort(4211, nil)
+		return
+	}
+
+	hash, message := utils.ValidateHash(hash)
+	if message != "" {
+		r.Abort(3100, nil)
+		return
+	}
+
+	bytes, e := hex.DecodeString(hash)
+	if e != nil {
+		r.Abort(3100, nil)
+		return
+	}
+
+	r.SetLocal("user-credentials", bytes)
+}
@@ -30,6 +30,12 @@ var rMatchOrgAlias = regexp.MustCompile(`^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9_\-
 // REGEXP - Password Hash (Unsalted) = SHA256
 var rMatchPasswordHash = regexp.MustCompile(`^[a-f0-9]{64}$`)
 
+// REGEXP - Account Recovery Key (X25519 Private Key)
+var rMatchRecoveryKey = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// REGEXP - 256 Bit Key (X25519 Public Key or Store Key)
+var rMatchKey256 = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
 // REGEXP - Invitation Unique ID SHA1
 var rMatchUID = regexp.MustCompile(`^[a-f0-9]{40}$`)
 
@@ -42,6 +48,14 @@ var rMatchEmail = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0
 // Roles CSV String
 var rMatchRolesCSV = regexp.MustCompile(`^\s*\d+(\s*,\s*\d+)*\s*$`)
 
+func IsValidRecoveryKey(v string) bool {
+	return rMatchRecoveryKey.Match([]byte(v))
+}
+
+func IsValidKey256(v string) bool {
+	return rMatchKey256.Match([]byte(v))
+}
+
 func IsValidEmail(v string) bool {
 	return rMatchEmail.Match([]byte(v))
 }
@@ -0,0 +1,42 @@
+// cSpell:ignore paulo, ferreira
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"log"
+
+	"github.com/objectvault/api-services/requests/rpf/action"
+)
+
+/* NOTE: Interrupted Store Imports
+ * Background imports can't be resumed, so imports that stopped saving their
+ * progress (i.e. interrupted by a server stop) are marked as processed, with
+ * an error, when the server starts. Imports running in other server
+ * instances keep saving their progress, and are left untouched.
+ */
+func cleanupStoreImports() {
+	dbm, e := databaseManager()
+	if e != nil {
+		log.Printf("[cleanupStoreImports] Database Manager Error: %v\n", e)
+		return
+	}
+
+	count, e := action.MarkStaleStoreImports(dbm)
+	if e != nil {
+		log.Printf("[cleanupStoreImports] Error: %v\n", e)
+		return
+	}
+
+	if count > 0 {
+		log.Printf("[cleanupStoreImports] Marked [%d] Interrupted Imports\n", count)
+	}
+}
@@ -0,0 +1,78 @@
+// cSpell:ignore paulo, ferreira
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"log"
+	"time"
+
+	"github.com/objectvault/api-services/common"
+	"github.com/objectvault/api-services/orm"
+)
+
+// Default Minutes between Trash Purges
+const TRASH_PURGE_INTERVAL_DEFAULT = 60.0
+
+/* NOTE: Trash Purge
+ * Objects in a store's trash are scheduled for purge (by the store's retention
+ * period) when deleted. The server purges expired objects, in all store
+ * shards, every "trash.purge-interval" minutes (0 disables the purge, i.e.
+ * when another server instance is responsible for it).
+ */
+func startTrashPurge() {
+	/* DEFAULT = 60.0 not 60 because json decoder converts
+	 * numbers to (float64) and not int
+	 */
+	interval, ok := common.ConfigProperty(Config, "trash.purge-interval", TRASH_PURGE_INTERVAL_DEFAULT).(float64)
+	if !ok || interval <= 0 { // Purge Disabled
+		log.Println("[startTrashPurge] Trash Purge Disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			purgeTrash()
+			<-ticker.C
+		}
+	}()
+}
+
+func purgeTrash() {
+	dbm, e := databaseManager()
+	if e != nil {
+		log.Printf("[purgeTrash] Database Manager Error: %v\n", e)
+		return
+	}
+
+	// NOTE: Shard Group 0 is the Global Shard (no Stores)
+	for g := 1; g < dbm.Groups(); g++ {
+		// TODO: Multiple Shards per Group
+		db, e := dbm.ConnectTo(uint16(g), 0)
+		if e != nil {
+			log.Printf("[purgeTrash] Shard Group [%d] Connection Error: %v\n", g, e)
+			continue
+		}
+
+		count, e := orm.StoreTrashPurgeExpired(db)
+		if e != nil {
+			log.Printf("[purgeTrash] Shard Group [%d] Purge Error: %v\n", g, e)
+			continue
+		}
+
+		if count > 0 {
+			log.Printf("[purgeTrash] Shard Group [%d] Purged [%d] Objects\n", g, count)
+		}
+	}
+}